@@ -0,0 +1,331 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+// Package asyncapi loads AsyncAPI 2.x and 3.x specifications, and validates the payloads of the messages sent on
+// their channels. Only the channels, operations and message payloads are read, payloads are JSON schemas compiled
+// from the specification itself, so references between them resolve as they do within the document.
+package asyncapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// resourceURL is the location the specification is added to the schema compiler at.
+const resourceURL = "asyncapi.json"
+
+// Direction is the way a message travels, relative to the application the specification describes.
+type Direction int
+
+const (
+	// Received messages are sent by clients to the application, the 'publish' operations of AsyncAPI 2 and the
+	// 'receive' operations of AsyncAPI 3.
+	Received Direction = iota
+
+	// Sent messages are sent by the application to its clients, the 'subscribe' operations of AsyncAPI 2 and the
+	// 'send' operations of AsyncAPI 3.
+	Sent
+)
+
+// Document is a loaded AsyncAPI specification.
+type Document struct {
+	Version  string
+	channels []*channel
+}
+
+type channel struct {
+	name     string
+	address  *regexp.Regexp
+	messages map[Direction][]*message
+	all      []*message // every message of the channel, for directions without an operation.
+}
+
+// message is a message of a channel, a nil schema is a message that accepts any payload.
+type message struct {
+	name   string
+	schema *jsonschema.Schema
+}
+
+// loader resolves references within the specification, and compiles each message once.
+type loader struct {
+	root     map[string]any
+	compiler *jsonschema.Compiler
+	messages map[string]*message // by the pointer to the message.
+}
+
+// LoadDocument parses an AsyncAPI specification, in YAML or JSON, and compiles the payload schemas of its messages.
+func LoadDocument(spec []byte) (*Document, error) {
+	var decoded any
+	if err := yaml.Unmarshal(spec, &decoded); err != nil {
+		return nil, fmt.Errorf("unable to parse AsyncAPI specification: %w", err)
+	}
+	// the compiler expects the numbers of a decoded JSON document.
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse AsyncAPI specification: %w", err)
+	}
+	root, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse AsyncAPI specification: %w", err)
+	}
+	object, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("the AsyncAPI specification is not an object")
+	}
+
+	doc := &Document{}
+	doc.Version, _ = object["asyncapi"].(string)
+	l := &loader{root: object, compiler: jsonschema.NewCompiler(), messages: make(map[string]*message)}
+	if err = l.compiler.AddResource(resourceURL, root); err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasPrefix(doc.Version, "2."):
+		doc.channels, err = l.channelsV2()
+	case strings.HasPrefix(doc.Version, "3."):
+		doc.channels, err = l.channelsV3()
+	default:
+		return nil, fmt.Errorf("AsyncAPI version '%s' is not supported, only 2.x and 3.x are", doc.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// channelsV2 reads the channels of AsyncAPI 2, where operations are defined on their channel.
+func (l *loader) channelsV2() ([]*channel, error) {
+	channels, _ := l.root["channels"].(map[string]any)
+	var loaded []*channel
+	for _, name := range slices.Sorted(maps.Keys(channels)) {
+		item, pointer := l.resolve(channels[name], "/channels/"+escapePointer(name))
+		ch := newChannel(name, name)
+		for direction, operation := range []string{Received: "publish", Sent: "subscribe"} {
+			op, opPointer := l.resolve(item[operation], pointer+"/"+operation)
+			if op == nil {
+				continue
+			}
+			messages, err := l.operationMessagesV2(op["message"], opPointer+"/message")
+			if err != nil {
+				return nil, err
+			}
+			ch.add(Direction(direction), messages...)
+		}
+		loaded = append(loaded, ch)
+	}
+	return loaded, nil
+}
+
+// operationMessagesV2 compiles the message of an AsyncAPI 2 operation, which may be one of several.
+func (l *loader) operationMessagesV2(node any, pointer string) ([]*message, error) {
+	resolved, pointer := l.resolve(node, pointer)
+	if resolved == nil {
+		return nil, nil
+	}
+	if options, ok := resolved["oneOf"].([]any); ok {
+		var messages []*message
+		for i, option := range options {
+			found, err := l.operationMessagesV2(option, fmt.Sprintf("%s/oneOf/%d", pointer, i))
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, found...)
+		}
+		return messages, nil
+	}
+	m, err := l.message(resolved, pointer)
+	if err != nil {
+		return nil, err
+	}
+	return []*message{m}, nil
+}
+
+// channelsV3 reads the channels of AsyncAPI 3, where operations reference the channel and messages they use.
+func (l *loader) channelsV3() ([]*channel, error) {
+	channels, _ := l.root["channels"].(map[string]any)
+	byPointer := make(map[string]*channel)
+	var loaded []*channel
+	for _, key := range slices.Sorted(maps.Keys(channels)) {
+		item, pointer := l.resolve(channels[key], "/channels/"+escapePointer(key))
+		if item == nil {
+			continue
+		}
+		address, ok := item["address"].(string)
+		if !ok {
+			address = key
+		}
+		ch := newChannel(key, address)
+		messages, _ := item["messages"].(map[string]any)
+		for _, name := range slices.Sorted(maps.Keys(messages)) {
+			resolved, messagePointer := l.resolve(messages[name], pointer+"/messages/"+escapePointer(name))
+			m, err := l.message(resolved, messagePointer)
+			if err != nil {
+				return nil, err
+			}
+			ch.all = append(ch.all, m)
+		}
+		byPointer[pointer] = ch
+		loaded = append(loaded, ch)
+	}
+
+	operations, _ := l.root["operations"].(map[string]any)
+	for _, key := range slices.Sorted(maps.Keys(operations)) {
+		op, _ := l.resolve(operations[key], "/operations/"+escapePointer(key))
+		if op == nil {
+			continue
+		}
+		direction := Received
+		if op["action"] == "send" {
+			direction = Sent
+		}
+		_, channelPointer := l.resolve(op["channel"], "")
+		ch := byPointer[channelPointer]
+		if ch == nil {
+			continue
+		}
+		references, _ := op["messages"].([]any)
+		if len(references) == 0 {
+			ch.add(direction, ch.all...)
+			continue
+		}
+		for _, reference := range references {
+			resolved, messagePointer := l.resolve(reference, "")
+			m, err := l.message(resolved, messagePointer)
+			if err != nil {
+				return nil, err
+			}
+			ch.add(direction, m)
+		}
+	}
+	return loaded, nil
+}
+
+// message compiles the payload schema of a message. Payloads in a schema format other than JSON schema or the
+// AsyncAPI schema are not validated.
+func (l *loader) message(node map[string]any, pointer string) (*message, error) {
+	if m, ok := l.messages[pointer]; ok {
+		return m, nil
+	}
+	m := &message{name: unescapePointer(pointer[strings.LastIndex(pointer, "/")+1:])}
+	if name, ok := node["name"].(string); ok && name != "" {
+		m.name = name
+	}
+	l.messages[pointer] = m
+
+	payload, ok := node["payload"].(map[string]any)
+	if !ok {
+		return m, nil
+	}
+	format, _ := node["schemaFormat"].(string)
+	payloadPointer := pointer + "/payload"
+	// AsyncAPI 3 defines the format of a payload with the payload, as a multi format schema.
+	if multiFormat, isString := payload["schemaFormat"].(string); isString {
+		if _, hasSchema := payload["schema"]; hasSchema {
+			format, payloadPointer = multiFormat, payloadPointer+"/schema"
+		}
+	}
+	if format != "" && !strings.Contains(format, "schema+") && !strings.Contains(format, "asyncapi") {
+		return m, nil
+	}
+
+	schema, err := l.compiler.Compile(resourceURL + "#" + (&url.URL{Fragment: payloadPointer}).EscapedFragment())
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile the payload of message '%s': %w", m.name, err)
+	}
+	m.schema = schema
+	return m, nil
+}
+
+// resolve follows the local references of a node, and returns the object it resolves to with its JSON pointer.
+func (l *loader) resolve(node any, pointer string) (map[string]any, string) {
+	for range 32 {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil, pointer
+		}
+		ref, isRef := object["$ref"].(string)
+		if !isRef || !strings.HasPrefix(ref, "#") {
+			return object, pointer
+		}
+		pointer = strings.TrimPrefix(ref, "#")
+		node = l.lookup(pointer)
+	}
+	return nil, pointer
+}
+
+// lookup returns the node at a JSON pointer of the specification.
+func (l *loader) lookup(pointer string) any {
+	var node any = l.root
+	for _, segment := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		switch current := node.(type) {
+		case map[string]any:
+			node = current[unescapePointer(segment)]
+		case []any:
+			var i int
+			if _, err := fmt.Sscanf(segment, "%d", &i); err != nil || i < 0 || i >= len(current) {
+				return nil
+			}
+			node = current[i]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+func newChannel(name, address string) *channel {
+	// channel parameters match a single segment of a path.
+	pieces := regexp.MustCompile(`\{[^}]+}`).Split(strings.TrimPrefix(address, "/"), -1)
+	for i := range pieces {
+		pieces[i] = regexp.QuoteMeta(pieces[i])
+	}
+	return &channel{
+		name:     name,
+		address:  regexp.MustCompile("^" + strings.Join(pieces, "[^/]+") + "$"),
+		messages: make(map[Direction][]*message),
+	}
+}
+
+func (ch *channel) add(direction Direction, messages ...*message) {
+	for _, m := range messages {
+		ch.messages[direction] = append(ch.messages[direction], m)
+		if !containsMessage(ch.all, m) {
+			ch.all = append(ch.all, m)
+		}
+	}
+}
+
+// messagesFor returns the messages of the direction, or every message of the channel when no operation defines
+// the direction.
+func (ch *channel) messagesFor(direction Direction) []*message {
+	if messages := ch.messages[direction]; len(messages) > 0 {
+		return messages
+	}
+	return ch.all
+}
+
+func containsMessage(messages []*message, m *message) bool {
+	for _, existing := range messages {
+		if existing == m {
+			return true
+		}
+	}
+	return false
+}
+
+func escapePointer(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package asyncapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var chatV2 = []byte(`asyncapi: 2.6.0
+info: {title: chat, version: "1"}
+channels:
+  /rooms/{room}:
+    publish:
+      message:
+        $ref: '#/components/messages/Post'
+    subscribe:
+      message:
+        oneOf:
+          - $ref: '#/components/messages/Post'
+          - name: Joined
+            payload:
+              type: object
+              required: [user]
+              properties:
+                user: {type: string}
+components:
+  messages:
+    Post:
+      payload:
+        $ref: '#/components/schemas/Post'
+  schemas:
+    Post:
+      type: object
+      required: [text]
+      properties:
+        text: {type: string, maxLength: 10}
+`)
+
+var chatV3 = []byte(`asyncapi: 3.0.0
+info: {title: chat, version: "1"}
+channels:
+  rooms:
+    address: /rooms/{room}
+    messages:
+      post:
+        $ref: '#/components/messages/Post'
+      ticker:
+        name: Ticker
+        payload: {type: integer}
+operations:
+  sendPost:
+    action: receive
+    channel: {$ref: '#/channels/rooms'}
+    messages:
+      - $ref: '#/channels/rooms/messages/post'
+components:
+  messages:
+    Post:
+      payload:
+        schemaFormat: application/schema+json;version=draft-07
+        schema:
+          type: object
+          required: [text]
+          properties:
+            text: {type: string}
+`)
+
+func TestLoadDocument_V2(t *testing.T) {
+	doc, err := LoadDocument(chatV2)
+	require.NoError(t, err)
+	assert.Equal(t, "2.6.0", doc.Version)
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/rooms/beef", nil)
+
+	assert.Empty(t, doc.Validate(request, Received, "WebSocket message", []byte(`{"text":"hello"}`)))
+	errs := doc.Validate(request, Received, "WebSocket message", []byte(`{"text":"hello, princess"}`))
+	require.Len(t, errs, 1)
+	assert.Equal(t, MessageValidation, errs[0].ValidationType)
+	assert.Equal(t, "WebSocket message on channel '/rooms/{room}' failed to validate message 'Post'", errs[0].Message)
+	assert.Equal(t, "/rooms/{room}", errs[0].SpecPath)
+	require.Len(t, errs[0].SchemaValidationErrors, 1)
+	assert.Equal(t, "/text", errs[0].SchemaValidationErrors[0].Location)
+
+	// the application sends either message.
+	assert.Empty(t, doc.Validate(request, Sent, "WebSocket message", []byte(`{"user":"dave"}`)))
+	errs = doc.Validate(request, Sent, "WebSocket message", []byte(`not json`))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "WebSocket message on channel '/rooms/{room}' does not match any of its 2 messages", errs[0].Message)
+
+	// paths without a channel are not validated.
+	request, _ = http.NewRequest(http.MethodGet, "http://localhost/rooms/beef/members", nil)
+	assert.Empty(t, doc.Validate(request, Received, "WebSocket message", []byte(`nope`)))
+}
+
+func TestLoadDocument_V3(t *testing.T) {
+	doc, err := LoadDocument(chatV3)
+	require.NoError(t, err)
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/rooms/beef", nil)
+
+	// received messages are the messages of the operation.
+	assert.Empty(t, doc.Validate(request, Received, "WebSocket message", []byte(`{"text":"hi"}`)))
+	errs := doc.Validate(request, Received, "WebSocket message", []byte(`42`))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "WebSocket message on channel 'rooms' failed to validate message 'Post'", errs[0].Message)
+
+	// no operation sends on the channel, any of its messages is valid.
+	assert.Empty(t, doc.Validate(request, Sent, "SSE event", []byte(`42`)))
+	assert.Len(t, doc.Validate(request, Sent, "SSE event", []byte(`"pizza"`)), 1)
+}
+
+func TestLoadDocument_Unsupported(t *testing.T) {
+	_, err := LoadDocument([]byte(`asyncapi: 1.2.0`))
+	assert.ErrorContains(t, err, "AsyncAPI version '1.2.0' is not supported")
+
+	_, err = LoadDocument([]byte(`- not an object`))
+	assert.Error(t, err)
+}
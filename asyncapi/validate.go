@@ -0,0 +1,114 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package asyncapi
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	textmessage "golang.org/x/text/message"
+)
+
+// MessageValidation is the validation type of messages that do not match their AsyncAPI channel.
+const MessageValidation = "message"
+
+var printer = textmessage.NewPrinter(language.English)
+
+// findChannel returns the channel whose address matches the path, or nil.
+func (d *Document) findChannel(path string) *channel {
+	path = strings.TrimPrefix(path, "/")
+	for _, ch := range d.channels {
+		if ch.address.MatchString(path) {
+			return ch
+		}
+	}
+	return nil
+}
+
+// Validate checks a message sent on the channel of the request path, in a direction. The message is valid when
+// it matches the payload of any message of the channel in that direction. Payloads that are not JSON are
+// validated as a string. Paths that match no channel are not validated. The kind describes the message in
+// errors, for example 'WebSocket message'.
+func (d *Document) Validate(request *http.Request, direction Direction, kind string,
+	payload []byte) []*errors.ValidationError {
+
+	if d == nil {
+		return nil
+	}
+	ch := d.findChannel(request.URL.Path)
+	if ch == nil {
+		return nil
+	}
+	messages := ch.messagesFor(direction)
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var decoded any = string(payload)
+	if value, err := jsonschema.UnmarshalJSON(bytes.NewReader(payload)); err == nil {
+		decoded = value
+	}
+
+	var failures []*errors.SchemaValidationFailure
+	for _, m := range messages {
+		if m.schema == nil {
+			return nil
+		}
+		err := m.schema.Validate(decoded)
+		if err == nil {
+			return nil
+		}
+		if ve, ok := err.(*jsonschema.ValidationError); ok {
+			failures = appendFailures(failures, ve, payload)
+		}
+	}
+
+	ve := &errors.ValidationError{
+		ValidationType:    MessageValidation,
+		ValidationSubType: helpers.Schema,
+		Message: fmt.Sprintf("%s on channel '%s' failed to validate message '%s'", kind, ch.name,
+			messages[0].name),
+		Reason:                 fmt.Sprintf("The %s does not match the payload of the message", kind),
+		HowToFix:               errors.HowToFixInvalidSchema,
+		RequestPath:            request.URL.Path,
+		RequestMethod:          request.Method,
+		SpecPath:               ch.name,
+		SchemaValidationErrors: failures,
+	}
+	if len(messages) > 1 {
+		ve.Message = fmt.Sprintf("%s on channel '%s' does not match any of its %d messages", kind, ch.name,
+			len(messages))
+		ve.Reason = fmt.Sprintf("The %s does not match the payload of any message", kind)
+	}
+	return []*errors.ValidationError{ve}
+}
+
+// appendFailures adds a failure for each leaf error of a validation, the errors that say what is wrong.
+func appendFailures(failures []*errors.SchemaValidationFailure, ve *jsonschema.ValidationError,
+	payload []byte) []*errors.SchemaValidationFailure {
+
+	if len(ve.Causes) > 0 {
+		for _, cause := range ve.Causes {
+			failures = appendFailures(failures, cause, payload)
+		}
+		return failures
+	}
+	location := ve.SchemaURL
+	if i := strings.Index(location, "#"); i >= 0 {
+		location = location[i:]
+	}
+	return append(failures, &errors.SchemaValidationFailure{
+		Reason:           ve.ErrorKind.LocalizedString(printer),
+		Location:         "/" + strings.Join(ve.InstanceLocation, "/"),
+		AbsoluteLocation: location,
+		ReferenceObject:  string(payload),
+		OriginalError:    ve,
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/pb33f/harhar"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/har"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+const (
+	defaultContractJUnitReport = "wiretap-contract-test.xml"
+	defaultContractJSONReport  = "wiretap-contract-test.json"
+)
+
+// runContractTest validates the HAR file against the baseline and candidate specifications, writes the JUnit and JSON
+// reports and returns an error when the candidate introduces regressions, so CI pipelines fail.
+func runContractTest(config *shared.WiretapConfiguration, harFile *harhar.HAR) error {
+	contract := config.ContractTest
+	if contract.BaselineSpec == "" || contract.CandidateSpec == "" {
+		return errors.New("contract testing requires both a baseline and a candidate specification")
+	}
+	if harFile == nil {
+		return errors.New("contract testing requires a recorded session, use '-z' / '--har' to load a HAR file")
+	}
+	if contract.JUnitReport == "" {
+		contract.JUnitReport = defaultContractJUnitReport
+	}
+	if contract.JSONReport == "" {
+		contract.JSONReport = defaultContractJSONReport
+	}
+
+	pterm.Printf("📜 Comparing baseline specification %s with candidate specification %s\n",
+		pterm.LightMagenta(contract.BaselineSpec), pterm.LightCyan(contract.CandidateSpec))
+	pterm.Println()
+
+	baseline, err := loadContractModel(contract.BaselineSpec, config.Base)
+	if err != nil {
+		return fmt.Errorf("cannot load baseline specification: %w", err)
+	}
+	candidate, err := loadContractModel(contract.CandidateSpec, config.Base)
+	if err != nil {
+		return fmt.Errorf("cannot load candidate specification: %w", err)
+	}
+
+	report, err := har.RunContractTest(harFile, baseline, candidate, config)
+	if err != nil {
+		return err
+	}
+
+	junit, err := report.JUnit()
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(contract.JUnitReport, junit, 0644); err != nil {
+		return err
+	}
+	b, _ := json.MarshalIndent(report, "", "  ")
+	if err = os.WriteFile(contract.JSONReport, b, 0644); err != nil {
+		return err
+	}
+	pterm.Printf("Reports generated and saved to: %s and %s\n",
+		pterm.LightMagenta(contract.JUnitReport), pterm.LightMagenta(contract.JSONReport))
+	pterm.Println()
+
+	for _, result := range report.Results {
+		for _, e := range result.Regressions {
+			pterm.Error.Printf("%s %s: %s\n", result.Method, result.Path, e.String())
+		}
+		for _, e := range result.Fixed {
+			pterm.Success.Printf("%s %s: fixed %s\n", result.Method, result.Path, e.String())
+		}
+	}
+
+	if report.Regressions > 0 {
+		pterm.Println()
+		return fmt.Errorf("candidate specification has %d %s and %d fixed %s across %d recorded %s",
+			report.Regressions, shared.Pluralize(report.Regressions, "regression", "regressions"),
+			report.Fixed, shared.Pluralize(report.Fixed, "error", "errors"),
+			report.Entries, shared.Pluralize(report.Entries, "entry", "entries"))
+	}
+	pterm.Success.Printf("Candidate specification has no regressions and %d fixed %s across %d recorded %s\n",
+		report.Fixed, shared.Pluralize(report.Fixed, "error", "errors"),
+		report.Entries, shared.Pluralize(report.Entries, "entry", "entries"))
+	pterm.Println()
+	return nil
+}
+
+func loadContractModel(spec, base string) (*v3.Document, error) {
+	doc, err := loadOpenAPISpec(spec, base)
+	if err != nil {
+		return nil, err
+	}
+	model, errs := doc.BuildV3Model()
+	if model == nil {
+		return nil, errors.Join(errs...)
+	}
+	return &model.Model, nil
+}
@@ -4,8 +4,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/handlers"
@@ -37,8 +40,8 @@ func handleHttpTraffic(hht *HandleHttpTraffic) {
 				HttpResponseWriter: w,
 			}
 
-			// if static-mock-dir is set, then we call the handler of staticMockService
-			if len(wiretapConfig.StaticMockDir) != 0 {
+			// if static mocks are configured, then we call the handler of staticMockService
+			if wiretapConfig.StaticMocksEnabled() {
 				staticMockService.HandleStaticMockRequest(requestModel)
 			} else { // else call the wiretap service handler
 				wtService.HandleHttpRequest(requestModel)
@@ -55,31 +58,66 @@ func handleHttpTraffic(hht *HandleHttpTraffic) {
 			wtService.HandleWebsocketRequest(requestModel)
 		}
 
-		// create a new mux.
-		mux := http.NewServeMux()
-
-		// handle the index
-		mux.HandleFunc("/", handleTraffic)
-
-		// Handle Websockets
-		for websocket := range wiretapConfig.WebsocketConfigs {
-			mux.HandleFunc(websocket, handleWebsocket)
+		// admin endpoints are served on their own port, if configured.
+		if wiretapConfig.AdminServedSeparately() {
+			serveAdmin(hht)
 		}
+		mux := newProxyMux(hht, handleTraffic, handleWebsocket)
 
-		pterm.Info.Println(pterm.LightMagenta(fmt.Sprintf("API Gateway UI booting on port %s...", wiretapConfig.Port)))
+		pterm.Info.Println(pterm.LightMagenta(fmt.Sprintf("API Gateway UI booting on %s...", wiretapConfig.GetListenAddress())))
 
-		var httpErr error
-		if wiretapConfig.CertificateKey != "" && wiretapConfig.Certificate != "" {
-			httpErr = http.ListenAndServeTLS(fmt.Sprintf(":%s", wiretapConfig.Port),
-				wiretapConfig.Certificate,
-				wiretapConfig.CertificateKey,
-				handlers.CompressHandler(mux))
-		} else {
-			httpErr = http.ListenAndServe(fmt.Sprintf(":%s", wiretapConfig.Port), handlers.CompressHandler(mux))
+		server := &http.Server{
+			Addr:    wiretapConfig.GetListenAddress(),
+			Handler: handlers.CompressHandler(mux),
 		}
+		daemon.ConfigureTimeouts(server, wiretapConfig)
+		daemon.ConfigureKeepAlive(server, wiretapConfig)
+		listeners, err := daemon.ListenProxy(wiretapConfig)
+		if err != nil {
+			pterm.Error.Println(err)
+			return
+		}
+		listeners = wtService.ConfigureConnectionLimit(server, listeners)
+		daemon.ConfigureH2C(server, wiretapConfig)
+		wtService.SetProxyServer(server)
 
-		if httpErr != nil {
-			pterm.Error.Println(httpErr)
+		// every listener is served by the same server.
+		var wg sync.WaitGroup
+		for _, ln := range listeners {
+			wg.Add(1)
+			go func(ln net.Listener) {
+				defer wg.Done()
+				var httpErr error
+				if wiretapConfig.CertificateKey != "" && wiretapConfig.Certificate != "" {
+					httpErr = server.ServeTLS(ln, wiretapConfig.Certificate, wiretapConfig.CertificateKey)
+				} else {
+					httpErr = server.Serve(ln)
+				}
+				if httpErr != nil && !errors.Is(httpErr, http.ErrServerClosed) {
+					pterm.Error.Println(httpErr)
+				}
+			}(ln)
 		}
+		wg.Wait()
 	}()
 }
+
+// newProxyMux routes requests to the proxy and websockets, and to the admin endpoints unless they are served on their
+// own port.
+func newProxyMux(hht *HandleHttpTraffic, handleTraffic, handleWebsocket http.HandlerFunc) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// handle the index
+	mux.HandleFunc("/", handleTraffic)
+
+	if !hht.WiretapConfig.AdminServedSeparately() {
+		hht.WiretapService.RegisterAdminRoutes(mux)
+		hht.StaticMockService.RegisterAdminRoutes(mux)
+	}
+
+	// Handle Websockets
+	for websocket := range hht.WiretapConfig.WebsocketConfigs {
+		mux.HandleFunc(websocket, handleWebsocket)
+	}
+	return mux
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	staticMock "github.com/pb33f/wiretap/static-mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// routedPattern returns the pattern a mux routes a request to.
+func routedPattern(mux *http.ServeMux, method, path string) string {
+	_, pattern := mux.Handler(httptest.NewRequest(method, path, nil))
+	return pattern
+}
+
+func TestNewProxyMux(t *testing.T) {
+	traffic := func(w http.ResponseWriter, r *http.Request) {}
+	tests := []struct {
+		name   string
+		config *shared.WiretapConfiguration
+		proxy  bool // the admin endpoints are served by the proxy.
+	}{
+		{"admin served by the proxy", &shared.WiretapConfiguration{}, true},
+		{"admin served on its own port", &shared.WiretapConfiguration{AdminListenPort: 9091}, false},
+		{"admin served on a unix socket", &shared.WiretapConfiguration{AdminUnixSocket: "/tmp/wiretap.sock"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.WebsocketConfigs = map[string]*shared.WiretapWebsocketConfig{"/ws": {}}
+			hht := &HandleHttpTraffic{WiretapConfig: test.config, WiretapService: &daemon.WiretapService{},
+				StaticMockService: &staticMock.StaticMockService{}}
+			proxy := newProxyMux(hht, traffic, traffic)
+
+			adminRoutes := map[string]string{
+				http.MethodGet + " " + daemon.AdminStatusPath:             "GET " + daemon.AdminStatusPath,
+				http.MethodPost + " " + daemon.AdminExportCSVPath:         "POST " + daemon.AdminExportCSVPath,
+				http.MethodGet + " " + staticMock.AdminApiBasePath:        "GET " + staticMock.AdminApiBasePath,
+				http.MethodPost + " " + staticMock.AdminApiBasePath:       "POST " + staticMock.AdminApiBasePath,
+				http.MethodDelete + " /wiretap/mocks/beef":                "DELETE " + staticMock.AdminApiBasePath + "/{id}",
+				http.MethodPost + " " + daemon.AdminSpecCompatibilityPath: "POST " + daemon.AdminSpecCompatibilityPath,
+			}
+			for route, pattern := range adminRoutes {
+				method, path, _ := strings.Cut(route, " ")
+				if test.proxy {
+					assert.Equal(t, pattern, routedPattern(proxy, method, path), route)
+				} else {
+					// the path is proxied to the API, like any other.
+					assert.Equal(t, "/", routedPattern(proxy, method, path), route)
+					assert.Equal(t, pattern, routedPattern(newAdminMux(hht), method, path), route)
+				}
+			}
+			assert.Equal(t, "/", routedPattern(proxy, http.MethodGet, "/pets/1"))
+			assert.Equal(t, "/ws", routedPattern(proxy, http.MethodGet, "/ws"))
+
+			// only the admin endpoints are served on the admin port.
+			assert.Empty(t, routedPattern(newAdminMux(hht), http.MethodGet, "/pets/1"))
+			assert.Empty(t, routedPattern(newAdminMux(hht), http.MethodGet, "/ws"))
+		})
+	}
+}
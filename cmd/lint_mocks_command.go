@@ -0,0 +1,129 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/shared"
+	staticMock "github.com/pb33f/wiretap/static-mock"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	lintOutputText = "text"
+	lintOutputJSON = "json"
+)
+
+var lintMocksCmd = &cobra.Command{
+	SilenceUsage: true,
+	Use:          "lint-mocks [files or directories]",
+	Short:        "Check static mock definitions for problems.",
+	Long: `Check static mock definitions for duplicate IDs, broken inheritance, mocks that can never match,
+invalid regex patterns and, when an OpenAPI specification is provided, paths that are not in the specification
+and responses that do not match it. Exits with an error when any error level findings are reported.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec, _ := cmd.Flags().GetString("spec")
+		base, _ := cmd.Flags().GetString("base")
+		staticMockDir, _ := cmd.Flags().GetString("static-mock-dir")
+		mockDefinitionsDir, _ := cmd.Flags().GetString("mock-definitions-dir")
+		format, _ := cmd.Flags().GetString("mock-definition-format")
+		output, _ := cmd.Flags().GetString("output")
+
+		if output != lintOutputText && output != lintOutputJSON {
+			return fmt.Errorf("unknown output '%s', expected '%s' or '%s'", output, lintOutputText, lintOutputJSON)
+		}
+
+		locations := args
+		if len(locations) == 0 {
+			switch {
+			case mockDefinitionsDir != "":
+				locations = []string{mockDefinitionsDir}
+			case staticMockDir != "":
+				locations = []string{staticMockDir + staticMock.MockDefinitionsPath}
+			default:
+				return errors.New("no mock definitions to lint, provide files or directories, " +
+					"or use --static-mock-dir / --mock-definitions-dir")
+			}
+		}
+
+		options := staticMock.MockLintOptions{
+			Format:        format,
+			StaticMockDir: staticMockDir,
+		}
+		if spec != "" {
+			doc, err := loadOpenAPISpec(spec, base)
+			if err != nil {
+				return err
+			}
+			model, errs := doc.BuildV3Model()
+			if model == nil {
+				return errors.Join(errs...)
+			}
+			options.Document = &model.Model
+		}
+
+		findings, err := staticMock.LintMockDefinitions(locations, options)
+		if err != nil {
+			return err
+		}
+
+		failed := printLintFindings(findings, output, options.Document)
+		if failed > 0 {
+			return fmt.Errorf("%d mock definition %s", failed, shared.Pluralize(failed, "error", "errors"))
+		}
+		return nil
+	},
+}
+
+// printLintFindings writes the findings in the requested output format and returns the number of errors.
+func printLintFindings(findings []*staticMock.MockLintFinding, output string, doc *v3.Document) int {
+	failed := 0
+	for _, finding := range findings {
+		if finding.Severity == staticMock.LintSeverityError {
+			failed++
+		}
+	}
+
+	if output == lintOutputJSON {
+		if findings == nil {
+			findings = []*staticMock.MockLintFinding{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(findings)
+		return failed
+	}
+
+	if doc == nil {
+		pterm.Info.Println("No OpenAPI specification provided, mocks are not checked against a specification")
+	}
+	for _, finding := range findings {
+		if finding.Severity == staticMock.LintSeverityError {
+			pterm.Error.Println(finding.String())
+		} else {
+			pterm.Warning.Println(finding.String())
+		}
+	}
+	if len(findings) == 0 {
+		pterm.Success.Println("No problems found in mock definitions")
+	}
+	return failed
+}
+
+// registerLintMocksCommand adds the lint-mocks sub command, and its flags, to the root command.
+func registerLintMocksCommand() {
+	lintMocksCmd.Flags().StringP("spec", "s", "", "Set the path to the OpenAPI specification to check mocks against")
+	lintMocksCmd.Flags().StringP("base", "b", "", "Set a base path to resolve relative file references from, or a overriding base URL to resolve remote references from")
+	lintMocksCmd.Flags().String("static-mock-dir", "", "Directory containing static mock definitions, used to resolve response body files")
+	lintMocksCmd.Flags().String("mock-definitions-dir", "", "Directory containing mock definition files, when outside the static mock directory")
+	lintMocksCmd.Flags().String("mock-definition-format", "", "Format of mock definition files without a recognized extension (json, yaml or toml)")
+	lintMocksCmd.Flags().StringP("output", "o", lintOutputText, "Output format for findings (text or json)")
+	rootCmd.AddCommand(lintMocksCmd)
+}
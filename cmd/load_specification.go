@@ -4,9 +4,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"github.com/pb33f/libopenapi"
 	"github.com/pb33f/libopenapi/datamodel"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/asyncapi"
+	"github.com/pb33f/wiretap/shared"
 	"github.com/pterm/pterm"
 	"io"
 	"log/slog"
@@ -16,12 +20,14 @@ import (
 	"strings"
 )
 
-func loadOpenAPISpec(contract, base string) (libopenapi.Document, error) {
+// readSpecification reads the bytes of a specification from a URL or a file, the kind names the specification
+// in messages.
+func readSpecification(kind, location string) ([]byte, error) {
 	var specBytes []byte
 
-	if strings.HasPrefix(contract, "http://") || strings.HasPrefix(contract, "https://") {
-		if docUrl, err := url.Parse(contract); err == nil {
-			pterm.Info.Printf("Fetching OpenAPI Specification from URL: '%s'\n", docUrl.String())
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		if docUrl, err := url.Parse(location); err == nil {
+			pterm.Info.Printf("Fetching %s Specification from URL: '%s'\n", kind, docUrl.String())
 			resp, er := http.Get(docUrl.String())
 			if er != nil {
 				return nil, er
@@ -38,16 +44,30 @@ func loadOpenAPISpec(contract, base string) (libopenapi.Document, error) {
 
 		// not a URL, is it a file?
 		var er error
-		if _, er = os.Stat(contract); er != nil {
+		if _, er = os.Stat(location); er != nil {
 			return nil, er
 		}
-		specBytes, er = os.ReadFile(contract)
+		specBytes, er = os.ReadFile(location)
 		if er != nil {
 			return nil, er
 		}
 	}
 	if len(specBytes) <= 0 {
-		return nil, fmt.Errorf("no bytes in OpenAPI Specification")
+		return nil, fmt.Errorf("no bytes in %s Specification", kind)
+	}
+	return specBytes, nil
+}
+
+func loadOpenAPISpec(contract, base string) (libopenapi.Document, error) {
+	specBytes, err := readSpecification("OpenAPI", contract)
+	if err != nil {
+		return nil, err
+	}
+	if isRAMLSpec(specBytes) {
+		pterm.Info.Printf("RAML specification detected, converting to OpenAPI %s\n", ConvertedOpenAPIVersion)
+		if specBytes, err = convertRAML(specBytes); err != nil {
+			return nil, fmt.Errorf("unable to convert RAML specification: %w", err)
+		}
 	}
 
 	docConfig := datamodel.NewDocumentConfiguration()
@@ -70,5 +90,44 @@ func loadOpenAPISpec(contract, base string) (libopenapi.Document, error) {
 	docConfig.Logger = slog.New(handler)
 	pterm.DefaultLogger.Level = pterm.LogLevelError
 
-	return libopenapi.NewDocumentWithConfiguration(specBytes, docConfig)
+	doc, err := libopenapi.NewDocumentWithConfiguration(specBytes, docConfig)
+	if err != nil || !isSwaggerSpec(doc) {
+		return doc, err
+	}
+	return loadSwaggerSpec(doc, docConfig)
+}
+
+// loadOpenAPIModel loads a specification and builds its model. Issues found building the model are printed as
+// warnings, the model is only an error if it could not be built at all.
+func loadOpenAPIModel(contract, base string) (libopenapi.Document, *libopenapi.DocumentModel[v3.Document], error) {
+	doc, err := loadOpenAPISpec(contract, base)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// build a model
+	docModel, errs := doc.BuildV3Model()
+	if len(errs) > 0 && docModel != nil {
+		pterm.Warning.Printf("OpenAPI Specification loaded, but there %s %d %s detected...\n",
+			shared.Pluralize(len(errs), "was", "were"),
+			len(errs),
+			shared.Pluralize(len(errs), "issue", "issues"))
+		for _, e := range errs {
+			pterm.Warning.Printf("--> %s\n", e.Error())
+		}
+	}
+	if len(errs) > 0 && docModel == nil {
+		pterm.Error.Printf("Failed to load / read OpenAPI specification.")
+		return nil, nil, errors.Join(errs...)
+	}
+	return doc, docModel, nil
+}
+
+// loadAsyncAPISpec loads the AsyncAPI specification websocket messages and server-sent events are validated against.
+func loadAsyncAPISpec(location string) (*asyncapi.Document, error) {
+	specBytes, err := readSpecification("AsyncAPI", location)
+	if err != nil {
+		return nil, err
+	}
+	return asyncapi.LoadDocument(specBytes)
 }
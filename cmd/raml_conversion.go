@@ -0,0 +1,445 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ramlHeader starts every RAML document, followed by its version.
+const ramlHeader = "#%RAML"
+
+// ramlMethods are the methods a RAML resource can define.
+var ramlMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// ramlFacets are the facets of a RAML type declaration that are the same in a schema.
+var ramlFacets = []string{"description", "enum", "pattern", "minLength", "maxLength", "minimum", "maximum",
+	"format", "multipleOf", "minItems", "maxItems", "uniqueItems", "default", "example", "additionalProperties"}
+
+// ramlScalarTypes maps the RAML built-in types to a schema type and format.
+var ramlScalarTypes = map[string][2]string{
+	"string":        {"string", ""},
+	"number":        {"number", ""},
+	"integer":       {"integer", ""},
+	"boolean":       {"boolean", ""},
+	"object":        {"object", ""},
+	"array":         {"array", ""},
+	"date-only":     {"string", "date"},
+	"datetime":      {"string", "date-time"},
+	"datetime-only": {"string", ""},
+	"time-only":     {"string", ""},
+	"file":          {"string", "binary"},
+}
+
+// ramlURIParameter matches the parameters of a resource path.
+var ramlURIParameter = regexp.MustCompile(`\{([^}]+)}`)
+
+// isRAMLSpec is true when the bytes of a specification are a RAML document.
+func isRAMLSpec(specBytes []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(specBytes), []byte(ramlHeader))
+}
+
+// ramlConversion converts a RAML 1.0 specification to OpenAPI 3. Resources are flattened into paths, methods
+// become operations, types become component schemas and bodies get the schema for each media type.
+// Resource types, traits and libraries are not applied.
+type ramlConversion struct {
+	raml       *yaml.Node
+	mediaTypes []string
+}
+
+// convertRAML converts the bytes of a RAML specification to the bytes of an OpenAPI 3 specification.
+func convertRAML(specBytes []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(specBytes, &root); err != nil {
+		return nil, err
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the specification is not an object")
+	}
+	rc := &ramlConversion{raml: root.Content[0]}
+	rc.mediaTypes = stringList(mapValue(rc.raml, "mediaType"))
+	if mediaType := scalarValue(mapValue(rc.raml, "mediaType")); mediaType != "" {
+		rc.mediaTypes = []string{mediaType}
+	}
+	if len(rc.mediaTypes) == 0 {
+		rc.mediaTypes = []string{"application/json"}
+	}
+	return yaml.Marshal(rc.convert())
+}
+
+func (rc *ramlConversion) convert() *yaml.Node {
+	out := newMapNode()
+	setValue(out, "openapi", scalarNode(ConvertedOpenAPIVersion))
+
+	info := newMapNode()
+	setValue(info, "title", scalarNode(scalarValue(mapValue(rc.raml, "title"))))
+	version := scalarValue(mapValue(rc.raml, "version"))
+	setValue(info, "version", scalarNode(version))
+	if description := mapValue(rc.raml, "description"); description != nil {
+		setValue(info, "description", description)
+	}
+	setValue(out, "info", info)
+
+	if baseUri := scalarValue(mapValue(rc.raml, "baseUri")); baseUri != "" {
+		server := newMapNode()
+		setValue(server, "url", scalarNode(strings.ReplaceAll(baseUri, "{version}", version)))
+		setValue(out, "servers", &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{server}})
+	}
+
+	paths := newMapNode()
+	rc.convertResources(rc.raml, "", nil, paths)
+	setValue(out, "paths", paths)
+
+	schemas := newMapNode()
+	for _, key := range []string{"types", "schemas"} {
+		if types := mapValue(rc.raml, key); types != nil && types.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(types.Content); i += 2 {
+				setValue(schemas, types.Content[i].Value, convertRAMLType(types.Content[i+1]))
+			}
+		}
+	}
+	if len(schemas.Content) > 0 {
+		components := newMapNode()
+		setValue(components, "schemas", schemas)
+		setValue(out, "components", components)
+	}
+	return out
+}
+
+// convertResources adds a path for each resource of a node that has methods, and for its nested resources. The
+// URI parameters of a resource apply to its nested resources.
+func (rc *ramlConversion) convertResources(node *yaml.Node, prefix string, uriParameters map[string]*yaml.Node,
+	paths *yaml.Node) {
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, resource := node.Content[i].Value, node.Content[i+1]
+		if !strings.HasPrefix(key, "/") {
+			continue
+		}
+		path := prefix + key
+		parameters := make(map[string]*yaml.Node, len(uriParameters))
+		for name, param := range uriParameters {
+			parameters[name] = param
+		}
+		if declared := mapValue(resource, "uriParameters"); declared != nil && declared.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(declared.Content); j += 2 {
+				parameters[declared.Content[j].Value] = declared.Content[j+1]
+			}
+		}
+
+		item := newMapNode()
+		if params := pathParameters(path, parameters); params != nil {
+			setValue(item, "parameters", params)
+		}
+		operations := 0
+		for j := 0; j+1 < len(resource.Content); j += 2 {
+			method := strings.TrimSuffix(resource.Content[j].Value, "?")
+			if slices.Contains(ramlMethods, method) {
+				setValue(item, method, rc.convertMethod(resource.Content[j+1]))
+				operations++
+			}
+		}
+		if operations > 0 {
+			setValue(paths, path, item)
+		}
+		rc.convertResources(resource, path, parameters, paths)
+	}
+}
+
+// pathParameters returns the parameters of a path, with the schema of their declaration, or a string.
+func pathParameters(path string, declared map[string]*yaml.Node) *yaml.Node {
+	matches := ramlURIParameter.FindAllStringSubmatch(path, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	params := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, match := range matches {
+		declaration := declared[match[1]]
+		if declaration == nil {
+			declaration = scalarNode("string")
+		}
+		param := convertRAMLParameter(match[1], "path", declaration)
+		setValue(param, "required", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+		params.Content = append(params.Content, param)
+	}
+	return params
+}
+
+func (rc *ramlConversion) convertMethod(method *yaml.Node) *yaml.Node {
+	out := newMapNode()
+	if displayName := mapValue(method, "displayName"); displayName != nil {
+		setValue(out, "summary", displayName)
+	}
+	if description := mapValue(method, "description"); description != nil {
+		setValue(out, "description", description)
+	}
+
+	params := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, declaration := range [][2]string{{"headers", "header"}, {"queryParameters", "query"}} {
+		key, in := declaration[0], declaration[1]
+		declared := mapValue(method, key)
+		if declared == nil || declared.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(declared.Content); i += 2 {
+			params.Content = append(params.Content, convertRAMLParameter(declared.Content[i].Value, in,
+				declared.Content[i+1]))
+		}
+	}
+	if len(params.Content) > 0 {
+		setValue(out, "parameters", params)
+	}
+
+	if body := mapValue(method, "body"); body != nil {
+		requestBody := newMapNode()
+		setValue(requestBody, "content", rc.convertBody(body))
+		setValue(requestBody, "required", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+		setValue(out, "requestBody", requestBody)
+	}
+
+	responses := newMapNode()
+	if declared := mapValue(method, "responses"); declared != nil && declared.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(declared.Content); i += 2 {
+			setValue(responses, declared.Content[i].Value, rc.convertResponse(declared.Content[i+1]))
+		}
+	}
+	if len(responses.Content) == 0 {
+		// every OpenAPI operation has a response.
+		response := newMapNode()
+		setValue(response, "description", scalarNode(""))
+		setValue(responses, "default", response)
+	}
+	setValue(out, "responses", responses)
+	return out
+}
+
+func (rc *ramlConversion) convertResponse(response *yaml.Node) *yaml.Node {
+	out := newMapNode()
+	setValue(out, "description", scalarNode(scalarValue(mapValue(response, "description"))))
+	if body := mapValue(response, "body"); body != nil {
+		setValue(out, "content", rc.convertBody(body))
+	}
+	if headers := mapValue(response, "headers"); headers != nil && headers.Kind == yaml.MappingNode {
+		converted := newMapNode()
+		for i := 0; i+1 < len(headers.Content); i += 2 {
+			header := convertRAMLParameter(headers.Content[i].Value, "header", headers.Content[i+1])
+			// headers are named by their key, drop the name and location the parameter starts with.
+			header.Content = header.Content[4:]
+			setValue(converted, strings.TrimSuffix(headers.Content[i].Value, "?"), header)
+		}
+		setValue(out, "headers", converted)
+	}
+	return out
+}
+
+// convertBody returns the content of a body. A body is either keyed by media type, or is the type of every
+// default media type.
+func (rc *ramlConversion) convertBody(body *yaml.Node) *yaml.Node {
+	content := newMapNode()
+	byMediaType := body.Kind == yaml.MappingNode && len(body.Content) > 0 &&
+		strings.Contains(body.Content[0].Value, "/")
+	if byMediaType {
+		for i := 0; i+1 < len(body.Content); i += 2 {
+			media := newMapNode()
+			setValue(media, "schema", convertRAMLType(body.Content[i+1]))
+			setValue(content, body.Content[i].Value, media)
+		}
+		return content
+	}
+	for _, mediaType := range rc.mediaTypes {
+		media := newMapNode()
+		setValue(media, "schema", convertRAMLType(body))
+		setValue(content, mediaType, media)
+	}
+	return content
+}
+
+// convertRAMLParameter converts a query, header or URI parameter. Parameters are required unless their name ends
+// with '?' or they are declared as not required.
+func convertRAMLParameter(name, in string, declaration *yaml.Node) *yaml.Node {
+	out := newMapNode()
+	required := !strings.HasSuffix(name, "?")
+	setValue(out, "name", scalarNode(strings.TrimSuffix(name, "?")))
+	setValue(out, "in", scalarNode(in))
+	if description := mapValue(declaration, "description"); description != nil {
+		setValue(out, "description", description)
+	}
+	if scalarValue(mapValue(declaration, "required")) == "false" {
+		required = false
+	}
+	if required {
+		setValue(out, "required", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+	}
+	setValue(out, "schema", convertRAMLType(declaration))
+	return out
+}
+
+// convertRAMLType converts a RAML type declaration, a type expression or a JSON schema to a schema.
+func convertRAMLType(declaration *yaml.Node) *yaml.Node {
+	if declaration == nil || (declaration.Kind == yaml.ScalarNode && declaration.Tag == "!!null") {
+		return ramlTypeExpression("string")
+	}
+	if declaration.Kind == yaml.ScalarNode {
+		if strings.HasPrefix(strings.TrimSpace(declaration.Value), "{") {
+			return jsonSchemaNode(declaration.Value)
+		}
+		return ramlTypeExpression(declaration.Value)
+	}
+	if declaration.Kind != yaml.MappingNode {
+		return newMapNode()
+	}
+
+	properties := mapValue(declaration, "properties")
+	typeNode := mapValue(declaration, "type")
+	if typeNode == nil {
+		typeNode = mapValue(declaration, "schema")
+	}
+	expression := scalarValue(typeNode)
+	if expression == "" {
+		expression = "string"
+		if properties != nil {
+			expression = "object"
+		} else if mapValue(declaration, "items") != nil {
+			expression = "array"
+		}
+	}
+
+	var schema *yaml.Node
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(expression), "{"):
+		schema = jsonSchemaNode(expression)
+	case typeNode != nil && typeNode.Kind == yaml.MappingNode:
+		schema = convertRAMLType(typeNode)
+	default:
+		schema = ramlTypeExpression(expression)
+	}
+	// a declaration that adds to a named type inherits it.
+	if mapValue(schema, "$ref") != nil && (properties != nil || hasFacets(declaration)) {
+		own := newMapNode()
+		addRAMLFacets(own, declaration, properties)
+		inherited := newMapNode()
+		setValue(inherited, "allOf", &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq",
+			Content: []*yaml.Node{schema, own}})
+		return inherited
+	}
+	addRAMLFacets(schema, declaration, properties)
+	return schema
+}
+
+func hasFacets(declaration *yaml.Node) bool {
+	for _, facet := range ramlFacets {
+		if mapValue(declaration, facet) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// addRAMLFacets adds the facets, items and properties of a declaration to its schema. Properties are required
+// unless their name ends with '?' or they are declared as not required.
+func addRAMLFacets(schema, declaration, properties *yaml.Node) {
+	for _, facet := range ramlFacets {
+		if value := mapValue(declaration, facet); value != nil {
+			setValue(schema, facet, value)
+		}
+	}
+	if items := mapValue(declaration, "items"); items != nil {
+		setValue(schema, "items", convertRAMLType(items))
+	}
+	if properties == nil || properties.Kind != yaml.MappingNode {
+		return
+	}
+	converted := newMapNode()
+	required := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for i := 0; i+1 < len(properties.Content); i += 2 {
+		name, property := properties.Content[i].Value, properties.Content[i+1]
+		optional := strings.HasSuffix(name, "?") || scalarValue(mapValue(property, "required")) == "false"
+		name = strings.TrimSuffix(name, "?")
+		setValue(converted, name, convertRAMLType(property))
+		if !optional {
+			required.Content = append(required.Content, scalarNode(name))
+		}
+	}
+	setValue(schema, "type", scalarNode("object"))
+	setValue(schema, "properties", converted)
+	if len(required.Content) > 0 {
+		setValue(schema, "required", required)
+	}
+}
+
+// ramlTypeExpression converts a type expression: a built-in type, a declared type, an array of a type with '[]',
+// or a union of types with '|'. A union with 'nil' is nullable.
+func ramlTypeExpression(expression string) *yaml.Node {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "(") && strings.HasSuffix(expression, ")") {
+		expression = strings.TrimSpace(expression[1 : len(expression)-1])
+	}
+	schema := newMapNode()
+
+	if strings.Contains(expression, "|") {
+		nullable := false
+		options := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, option := range strings.Split(expression, "|") {
+			if strings.TrimSpace(option) == "nil" {
+				nullable = true
+				continue
+			}
+			options.Content = append(options.Content, ramlTypeExpression(option))
+		}
+		if len(options.Content) == 1 {
+			schema = options.Content[0]
+		} else {
+			setValue(schema, "oneOf", options)
+		}
+		if nullable {
+			setValue(schema, "nullable", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+		}
+		return schema
+	}
+	if strings.HasSuffix(expression, "[]") {
+		setValue(schema, "type", scalarNode("array"))
+		setValue(schema, "items", ramlTypeExpression(strings.TrimSuffix(expression, "[]")))
+		return schema
+	}
+	switch expression {
+	case "any":
+		return schema
+	case "nil":
+		setValue(schema, "nullable", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+		return schema
+	}
+	if builtin, ok := ramlScalarTypes[expression]; ok {
+		setValue(schema, "type", scalarNode(builtin[0]))
+		if builtin[1] != "" {
+			setValue(schema, "format", scalarNode(builtin[1]))
+		}
+		return schema
+	}
+	setValue(schema, "$ref", scalarNode("#/components/schemas/"+expression))
+	return schema
+}
+
+// jsonSchemaNode parses a JSON schema embedded in a RAML document. Its '$schema' is dropped, as the schema is
+// part of the OpenAPI document.
+func jsonSchemaNode(source string) *yaml.Node {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(source), &document); err != nil || len(document.Content) == 0 ||
+		document.Content[0].Kind != yaml.MappingNode {
+		return newMapNode()
+	}
+	schema := document.Content[0]
+	for i := 0; i+1 < len(schema.Content); i += 2 {
+		if schema.Content[i].Value == "$schema" {
+			schema.Content = slices.Delete(schema.Content, i, i+2)
+			break
+		}
+	}
+	return schema
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertRAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		raml     string
+		path     []string
+		expected string
+	}{
+		{
+			name: "nested resources become paths",
+			raml: `
+/pets:
+  get:
+  /{petId}:
+    uriParameters:
+      petId: {type: integer, minimum: 1}
+    get:
+    /photos:
+      post:
+  /owners:
+    /{ownerId}:
+      delete:`,
+			path: []string{"paths"},
+			expected: `
+/pets:
+  get: {responses: {default: {description: ''}}}
+/pets/{petId}:
+  parameters: [{name: petId, in: path, required: true, schema: {type: integer, minimum: 1}}]
+  get: {responses: {default: {description: ''}}}
+/pets/{petId}/photos:
+  parameters: [{name: petId, in: path, required: true, schema: {type: integer, minimum: 1}}]
+  post: {responses: {default: {description: ''}}}
+/pets/owners/{ownerId}:
+  parameters: [{name: ownerId, in: path, required: true, schema: {type: string}}]
+  delete: {responses: {default: {description: ''}}}`,
+		},
+		{
+			name: "query parameters and headers become parameters",
+			raml: `
+/pets:
+  get:
+    displayName: list pets
+    headers:
+      X-Trace: string
+    queryParameters:
+      limit?: integer
+      sort: {type: string, enum: [name, age], required: false}`,
+			path: []string{"paths", "/pets", "get"},
+			expected: `
+summary: list pets
+parameters:
+  - {name: X-Trace, in: header, required: true, schema: {type: string}}
+  - {name: limit, in: query, schema: {type: integer}}
+  - {name: sort, in: query, schema: {type: string, enum: [name, age]}}
+responses: {default: {description: ''}}`,
+		},
+		{
+			name: "type expressions become schemas",
+			raml: `
+types:
+  Pet:
+    properties:
+      name: string
+      tags: string[]
+      owner: Owner | nil
+      born?: date-only
+      kind: (Cat | Dog)
+  Owner: object
+  Cat: object
+  Dog: object`,
+			path: []string{"components", "schemas", "Pet"},
+			expected: `
+type: object
+properties:
+  name: {type: string}
+  tags: {type: array, items: {type: string}}
+  owner: {$ref: '#/components/schemas/Owner', nullable: true}
+  born: {type: string, format: date}
+  kind: {oneOf: [{$ref: '#/components/schemas/Cat'}, {$ref: '#/components/schemas/Dog'}]}
+required: [name, tags, owner, kind]`,
+		},
+		{
+			name: "a type that adds to another type inherits it",
+			raml: `
+types:
+  Pet:
+    properties:
+      name: string
+  Dog:
+    type: Pet
+    description: a good dog
+    properties:
+      breed?: string
+  Name:
+    type: string
+    maxLength: 20`,
+			path: []string{"components", "schemas"},
+			expected: `
+Pet: {type: object, properties: {name: {type: string}}, required: [name]}
+Dog:
+  allOf:
+    - {$ref: '#/components/schemas/Pet'}
+    - {description: a good dog, type: object, properties: {breed: {type: string}}}
+Name: {type: string, maxLength: 20}`,
+		},
+		{
+			name: "bodies keyed by media type",
+			raml: `
+/pets:
+  post:
+    body:
+      application/json:
+        type: Pet
+      application/xml:
+        schema: '{"$schema": "http://json-schema.org/draft-04/schema#", "type": "object"}'
+    responses:
+      201:
+        description: created
+        headers:
+          Location: string
+          X-Rate?: integer
+        body:
+          text/plain: string`,
+			path: []string{"paths", "/pets", "post"},
+			expected: `
+requestBody:
+  required: true
+  content:
+    application/json: {schema: {$ref: '#/components/schemas/Pet'}}
+    application/xml: {schema: {type: object}}
+responses:
+  '201':
+    description: created
+    content: {text/plain: {schema: {type: string}}}
+    headers:
+      Location: {required: true, schema: {type: string}}
+      X-Rate: {schema: {type: integer}}`,
+		},
+		{
+			name: "bodies without a media type use the default media types",
+			raml: `
+mediaType: [application/json, application/yaml]
+/pets:
+  put:
+    body: Pet[]`,
+			path: []string{"paths", "/pets", "put", "requestBody", "content"},
+			expected: `
+application/json: {schema: {type: array, items: {$ref: '#/components/schemas/Pet'}}}
+application/yaml: {schema: {type: array, items: {$ref: '#/components/schemas/Pet'}}}`,
+		},
+		{
+			name: "bodies are json unless a media type is declared",
+			raml: `
+/pets:
+  put:
+    body:
+      type: Pet`,
+			path:     []string{"paths", "/pets", "put", "requestBody", "content"},
+			expected: `{application/json: {schema: {$ref: '#/components/schemas/Pet'}}}`,
+		},
+		{
+			name: "the base uri becomes a server",
+			raml: `
+baseUri: https://api.example.com/{version}`,
+			path:     []string{"servers"},
+			expected: `[{url: 'https://api.example.com/v1'}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raml := []byte("#%RAML 1.0\ntitle: pets\nversion: v1\n" + test.raml)
+			require.True(t, isRAMLSpec(raml))
+			converted, err := convertRAML(raml)
+			require.NoError(t, err)
+			assert.Equal(t, ConvertedOpenAPIVersion, convertedValue(t, converted, "openapi"))
+			assert.Equal(t, map[string]any{"title": "pets", "version": "v1"}, convertedValue(t, converted, "info"))
+			assert.Equal(t, yamlValue(t, test.expected), convertedValue(t, converted, test.path...))
+		})
+	}
+}
+
+func TestConvertRAML_Invalid(t *testing.T) {
+	_, err := convertRAML([]byte("#%RAML 1.0\ntitle: [pets"))
+	assert.Error(t, err)
+
+	_, err = convertRAML([]byte("#%RAML 1.0\n- title"))
+	assert.ErrorContains(t, err, "the specification is not an object")
+
+	assert.False(t, isRAMLSpec([]byte("openapi: 3.1.0")))
+}
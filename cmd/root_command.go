@@ -6,18 +6,23 @@ package cmd
 import (
 	"embed"
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pb33f/harhar"
 	"github.com/pb33f/libopenapi"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/pb33f/wiretap/daemon"
 	"github.com/pb33f/wiretap/har"
 	"github.com/pb33f/wiretap/shared"
+	staticMock "github.com/pb33f/wiretap/static-mock"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -85,6 +90,8 @@ var (
 			harFlag, _ := cmd.Flags().GetString("har")
 			harValidate, _ := cmd.Flags().GetBool("har-validate")
 			harWhiteList, _ := cmd.Flags().GetStringArray("har-allow")
+			contractBaseline, _ := cmd.Flags().GetString("contract-baseline")
+			contractCandidate, _ := cmd.Flags().GetString("contract-candidate")
 
 			debug, _ := cmd.Flags().GetBool("debug")
 			staticMockDir, _ = cmd.Flags().GetString("static-mock-dir")
@@ -95,7 +102,18 @@ var (
 			hardErrorReturnCode, _ = cmd.Flags().GetInt("hard-validation-return-code")
 			streamReport, _ := cmd.Flags().GetBool("stream-report")
 			strictRedirectLocation, _ := cmd.Flags().GetBool("strict-redirect-location")
-
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			faker, _ := cmd.Flags().GetBool("faker")
+			mutationTest, _ := cmd.Flags().GetBool("mutation-test")
+			samplingRate, _ := cmd.Flags().GetFloat64("validation-sampling-rate")
+			hostValidation, _ := cmd.Flags().GetString("host-validation")
+			strictStatusCodes, _ := cmd.Flags().GetBool("strict-status-codes")
+			reportFormat, _ := cmd.Flags().GetString("report-format")
+			junitReport, _ := cmd.Flags().GetString("junit-report")
+			htmlReport, _ := cmd.Flags().GetString("html-report")
+
+			listenAddress, _ := cmd.Flags().GetString("listen-address")
+			asyncAPISpec, _ := cmd.Flags().GetString("asyncapi")
 			portFlag, _ := cmd.Flags().GetString("port")
 			if portFlag != "" {
 				port = portFlag
@@ -210,6 +228,21 @@ var (
 						config.StrictRedirectLocation = true
 					}
 				}
+				if dryRun {
+					if !config.DryRun {
+						config.DryRun = true
+					}
+				}
+				if faker {
+					if !config.FakerEnabled {
+						config.FakerEnabled = true
+					}
+				}
+				if mutationTest {
+					if !config.MutationTest {
+						config.MutationTest = true
+					}
+				}
 
 				if reportFilename != "" {
 					config.ReportFile = reportFilename
@@ -249,6 +282,15 @@ var (
 				if strictRedirectLocation {
 					config.StrictRedirectLocation = true
 				}
+				if dryRun {
+					config.DryRun = true
+				}
+				if faker {
+					config.FakerEnabled = true
+				}
+				if mutationTest {
+					config.MutationTest = true
+				}
 				if base != "" {
 					config.Base = base
 				}
@@ -258,6 +300,42 @@ var (
 				config.HARPathAllowList = harWhiteList
 			}
 
+			if samplingRate > 0 {
+				config.ValidationSamplingRate = samplingRate
+			}
+
+			if hostValidation != "" {
+				config.HostValidation = hostValidation
+			}
+
+			if strictStatusCodes {
+				config.StrictStatusCodes = true
+			}
+
+			if reportFormat != "" {
+				config.ReportFormat = reportFormat
+			}
+
+			if junitReport != "" {
+				config.JUnitReport = junitReport
+			}
+
+			if htmlReport != "" {
+				config.HTMLReport = htmlReport
+			}
+
+			if contractBaseline != "" || contractCandidate != "" {
+				if config.ContractTest == nil {
+					config.ContractTest = &shared.ContractTestConfig{}
+				}
+				if contractBaseline != "" {
+					config.ContractTest.BaselineSpec = contractBaseline
+				}
+				if contractCandidate != "" {
+					config.ContractTest.CandidateSpec = contractCandidate
+				}
+			}
+
 			if spec == "" {
 				pterm.Println()
 				pterm.Warning.Println("No OpenAPI specification provided. " +
@@ -276,7 +354,16 @@ var (
 				return nil
 			}
 
-			if !mockMode && redirectURL == "" && harFlag == "" {
+			if config.DryRun && spec == "" {
+				pterm.Println()
+				pterm.Error.Println("Cannot enable dry-run mode, no OpenAPI specification provided!\n" +
+					"Please provide a path to an OpenAPI specification using the --spec or -s flags.\n" +
+					"Without an OpenAPI specification, wiretap will not be able to generate or validate responses")
+				pterm.Println()
+				return nil
+			}
+
+			if !mockMode && !config.DryRun && redirectURL == "" && harFlag == "" {
 				pterm.Println()
 				pterm.Error.Println("No redirect URL provided. " +
 					"Please provide a URL to redirect API traffic to using the --url or -u flags.")
@@ -310,15 +397,48 @@ var (
 			if spec != "" {
 				config.Contract = spec
 			}
+			// RAML specifications are converted to OpenAPI when the contract is loaded.
+			if config.RAMLSpec != "" {
+				if config.Contract != "" {
+					pterm.Println()
+					pterm.Error.Printf("Cannot use both an OpenAPI specification '%s' and a RAML specification '%s'\n\n",
+						config.Contract, config.RAMLSpec)
+					pterm.Println()
+					return nil
+				}
+				config.Contract = config.RAMLSpec
+			}
 			config.RedirectURL = redirectURL
 			config.RedirectHost = redirectHost
 			config.RedirectBasePath = redirectBasePath
 			config.RedirectPort = redirectPort
 			config.RedirectProtocol = redirectScheme
 
+			if config.ListenPort < 0 || config.ListenPort > 65535 {
+				pterm.Println()
+				pterm.Error.Printf("Listen port must be between 1 and 65535, not %d\n\n", config.ListenPort)
+				pterm.Println()
+				return nil
+			}
+			if config.ListenPort > 0 {
+				config.Port = strconv.Itoa(config.ListenPort)
+			}
 			if config.Port == "" {
 				config.Port = port
 			}
+			if listenAddress != "" {
+				config.ListenAddress = listenAddress
+			}
+			if asyncAPISpec != "" {
+				config.AsyncAPISpec = asyncAPISpec
+			}
+			if config.DualStack && config.ListenAddress != "" {
+				pterm.Println()
+				pterm.Error.Printf("Dual-stack listens on every interface, it cannot be used with listen address '%s'\n\n",
+					config.ListenAddress)
+				pterm.Println()
+				return nil
+			}
 			if config.MonitorPort == "" {
 				config.MonitorPort = monitorPort
 			}
@@ -418,6 +538,342 @@ var (
 				printLoadedValidationAllowList(config.ValidationAllowList)
 			}
 
+			if len(config.BlockedPaths) > 0 {
+				config.CompileBlockedPaths()
+				printLoadedBlockedPaths(config.BlockedPaths)
+			}
+
+			if len(config.BlockedMethods) > 0 {
+				printLoadedBlockedMethods(config.BlockedMethods)
+			}
+
+			if len(config.AllowedPaths) > 0 {
+				config.CompileAllowedPaths()
+				printLoadedAllowedPaths(config.AllowedPaths)
+			}
+
+			if len(config.FailoverUpstreams) > 0 {
+				if fErr := config.CompileFailoverUpstreams(); fErr != nil {
+					pterm.Println()
+					pterm.Error.Printf("Failover upstreams are not valid: %s\n\n", fErr.Error())
+					pterm.Println()
+					return nil
+				}
+				printLoadedFailoverUpstreams(config.FailoverUpstreams)
+			}
+
+			if len(config.TrustedProxies) > 0 {
+				if tErr := config.CompileTrustedProxies(); tErr != nil {
+					pterm.Println()
+					pterm.Error.Printf("Trusted proxies are not valid: %s\n\n", tErr.Error())
+					pterm.Println()
+					return nil
+				}
+			}
+			if config.UseForwardedHeader {
+				pterm.Printf("🧭 Clients are forwarded upstream in the %s header\n", pterm.LightCyan("Forwarded"))
+				pterm.Println()
+			} else if config.ForwardClientIP {
+				pterm.Printf("🧭 Clients are forwarded upstream in %s headers, trusting %d %s\n",
+					pterm.LightCyan("X-Forwarded-*"), len(config.TrustedProxies),
+					shared.Pluralize(len(config.TrustedProxies), "proxy range", "proxy ranges"))
+				pterm.Println()
+			}
+
+			if len(config.Notifications) > 0 {
+				if nErr := config.ValidateNotifications(); nErr != nil {
+					pterm.Println()
+					pterm.Error.Printf("Notifications are not valid: %s\n\n", nErr.Error())
+					pterm.Println()
+					return nil
+				}
+				printLoadedNotifications(config.Notifications)
+			}
+
+			if config.SMTPNotification != nil {
+				if sErr := config.ValidateSMTPNotification(); sErr != nil {
+					pterm.Println()
+					pterm.Error.Printf("Email notification is not valid: %s\n\n", sErr.Error())
+					pterm.Println()
+					return nil
+				}
+				pterm.Printf("📧 Emailing %s when more than %s validation errors are found\n",
+					pterm.LightCyan(strings.Join(config.SMTPNotification.To, ", ")),
+					pterm.LightMagenta(config.SMTPNotification.MinErrors))
+				pterm.Println()
+			}
+
+			if config.PagerDuty != nil && config.PagerDuty.AlertOnValidationErrors {
+				if config.PagerDuty.IntegrationKey == "" {
+					pterm.Println()
+					pterm.Error.Println("PagerDuty alerting requires an integration key")
+					pterm.Println()
+					return nil
+				}
+				pterm.Printf("📟 %s. Validation errors will trigger an incident, resolved by the next window without errors\n",
+					pterm.LightCyan("PagerDuty alerting enabled"))
+				pterm.Println()
+			}
+
+			if config.NATS != nil && config.NATS.ServerURL != "" {
+				subject := config.NATS.Subject
+				if subject == "" {
+					subject = daemon.DefaultNATSSubject
+				}
+				// credentials in the URLs are not printed.
+				servers := strings.Split(config.NATS.ServerURL, ",")
+				for i, server := range servers {
+					server = strings.TrimSpace(server)
+					if at := strings.LastIndex(server, "@"); at >= 0 {
+						scheme, _, found := strings.Cut(server, "://")
+						server = server[at+1:]
+						if found {
+							server = scheme + "://" + server
+						}
+					}
+					servers[i] = server
+				}
+				pterm.Printf("📡 Validation errors are published to NATS subject %s at %s\n",
+					pterm.LightCyan(subject), pterm.LightCyan(strings.Join(servers, ", ")))
+				pterm.Println()
+			}
+
+			if config.ValidationWorkers > 0 {
+				pterm.Printf("🏭 Requests and responses are validated by a pool of %s\n",
+					pterm.LightCyan(pterm.Sprintf("%d workers", config.ValidationWorkers)))
+				pterm.Println()
+			}
+
+			if config.TransactionStoreShards != 0 && !daemon.IsPowerOfTwo(config.TransactionStoreShards) {
+				pterm.Println()
+				pterm.Error.Printf("Transaction store shards must be a power of 2, not %d\n\n", config.TransactionStoreShards)
+				pterm.Println()
+				return nil
+			}
+			if config.TransactionStoreShards > 1 {
+				pterm.Printf("🧩 Transactions are stored across %s\n",
+					pterm.LightCyan(pterm.Sprintf("%d shards", config.TransactionStoreShards)))
+				pterm.Println()
+			}
+
+			if config.RedisTransactionStore != nil {
+				if config.RedisTransactionStore.Address == "" {
+					pterm.Println()
+					pterm.Error.Println("The Redis transaction store requires an address")
+					pterm.Println()
+					return nil
+				}
+				pterm.Printf("🗄️  Transactions are shared through Redis at %s\n",
+					pterm.LightCyan(config.RedisTransactionStore.Address))
+				pterm.Println()
+			}
+
+			if config.H2CEnabled {
+				if config.GetHttpProtocol() == "https" {
+					pterm.Warning.Println("H2C cannot be used with TLS, HTTP/2 is negotiated over TLS instead")
+				} else {
+					pterm.Printf("⚡️ Proxy accepts %s over cleartext\n", pterm.LightCyan("HTTP/2 (h2c)"))
+				}
+				pterm.Println()
+			}
+
+			if config.ReadTimeoutMs < 0 || config.WriteTimeoutMs < 0 || config.IdleTimeoutMs < 0 ||
+				config.ReadHeaderTimeoutMs < 0 || config.ShutdownTimeoutMs < 0 {
+				pterm.Println()
+				pterm.Error.Printf("Proxy server timeouts cannot be negative\n\n")
+				pterm.Println()
+				return nil
+			}
+			if config.ReadTimeoutMs > 0 || config.WriteTimeoutMs > 0 || config.IdleTimeoutMs > 0 ||
+				config.ReadHeaderTimeoutMs > 0 {
+				pterm.Printf("⏱️  Proxy server timeouts: read %s, write %s, idle %s, read header %s\n",
+					pterm.LightCyan(timeoutLabel(config.ReadTimeoutMs)),
+					pterm.LightCyan(timeoutLabel(config.WriteTimeoutMs)),
+					pterm.LightCyan(timeoutLabel(config.IdleTimeoutMs)),
+					pterm.LightCyan(timeoutLabel(config.ReadHeaderTimeoutMs)))
+				pterm.Println()
+			}
+
+			if config.AdminListenPort < 0 || config.AdminListenPort > 65535 {
+				pterm.Println()
+				pterm.Error.Printf("Admin listen port must be between 1 and 65535, not %d\n\n", config.AdminListenPort)
+				pterm.Println()
+				return nil
+			}
+			if config.AdminListenAddress != "" && !config.AdminServedSeparately() {
+				pterm.Println()
+				pterm.Error.Printf("Admin listen address '%s' requires an admin listen port\n\n", config.AdminListenAddress)
+				pterm.Println()
+				return nil
+			}
+			if config.AdminUnixSocket != "" && (config.AdminListenPort > 0 || config.AdminListenAddress != "") {
+				pterm.Println()
+				pterm.Error.Printf("The admin API is served on either a unix socket or an address, not both\n\n")
+				pterm.Println()
+				return nil
+			}
+			if config.AdminUnixSocket != "" {
+				pterm.Printf("🔐 Admin API is served separately from the proxy, on unix socket %s\n",
+					pterm.LightCyan(config.AdminUnixSocket))
+				pterm.Println()
+			} else if config.AdminServedSeparately() {
+				if strconv.Itoa(config.AdminListenPort) == config.Port {
+					pterm.Println()
+					pterm.Error.Printf("Admin listen port %d must be different to the proxy port\n\n", config.AdminListenPort)
+					pterm.Println()
+					return nil
+				}
+				pterm.Printf("🔐 Admin API is served separately from the proxy, on %s\n",
+					pterm.LightCyan(config.GetAdminListenAddress()))
+				pterm.Println()
+			}
+
+			if config.DualStack {
+				pterm.Printf("🌐 Proxy listens on %s and %s\n", pterm.LightCyan("IPv4"), pterm.LightCyan("IPv6"))
+				pterm.Println()
+			}
+
+			if config.MaxConcurrentConnections < 0 {
+				pterm.Println()
+				pterm.Error.Printf("Max concurrent connections cannot be negative, not %d\n\n", config.MaxConcurrentConnections)
+				pterm.Println()
+				return nil
+			}
+			if config.MaxConcurrentConnections > 0 {
+				pterm.Printf("🚦 Proxy serves up to %s at once, others are sent a %s\n",
+					pterm.LightCyan(pterm.Sprintf("%d connections", config.MaxConcurrentConnections)),
+					pterm.LightMagenta("503"))
+				pterm.Println()
+			}
+
+			if config.KeepAliveIdleSeconds < 0 || config.MaxRequestsPerConnection < 0 {
+				pterm.Println()
+				pterm.Error.Printf("Keep-alive idle seconds and max requests per connection cannot be negative\n\n")
+				pterm.Println()
+				return nil
+			}
+			if config.KeepAliveEnabled != nil && !*config.KeepAliveEnabled {
+				pterm.Printf("🔌 Keep-alive is %s, every proxy connection serves a single request\n",
+					pterm.LightMagenta("disabled"))
+				pterm.Println()
+			} else if config.KeepAliveIdleSeconds > 0 || config.MaxRequestsPerConnection > 0 {
+				idle, requests := "default idle timeout", "unlimited requests"
+				if config.KeepAliveIdleSeconds > 0 {
+					idle = pterm.Sprintf("%ds idle timeout", config.KeepAliveIdleSeconds)
+				}
+				if config.MaxRequestsPerConnection > 0 {
+					requests = pterm.Sprintf("%d requests", config.MaxRequestsPerConnection)
+				}
+				pterm.Printf("🔌 Proxy connections are kept alive with a %s, for up to %s\n",
+					pterm.LightCyan(idle), pterm.LightMagenta(requests))
+				pterm.Println()
+			}
+
+			if config.MaxRequestHeaderSizeBytes < 0 || config.MaxSingleHeaderBytes < 0 {
+				pterm.Println()
+				pterm.Error.Printf("Request header size limits cannot be negative\n\n")
+				pterm.Println()
+				return nil
+			}
+			if config.MaxRequestHeaderSizeBytes > 0 || config.MaxSingleHeaderBytes > 0 {
+				total, single := "unlimited", "unlimited"
+				if config.MaxRequestHeaderSizeBytes > 0 {
+					total = pterm.Sprintf("%d bytes", config.MaxRequestHeaderSizeBytes)
+				}
+				if config.MaxSingleHeaderBytes > 0 {
+					single = pterm.Sprintf("%d bytes", config.MaxSingleHeaderBytes)
+				}
+				pterm.Printf("📏 Request headers are limited to %s in total, and %s for a single header\n",
+					pterm.LightCyan(total), pterm.LightMagenta(single))
+				pterm.Println()
+			}
+
+			if config.ValidationSamplingRate < 0 || config.ValidationSamplingRate > 1 {
+				pterm.Println()
+				pterm.Error.Printf("Validation sampling rate must be between 0.0 and 1.0, not %v\n\n", config.ValidationSamplingRate)
+				pterm.Println()
+				return nil
+			}
+			for prefix, rate := range config.ValidationSamplingOverrides {
+				if rate < 0 || rate > 1 {
+					pterm.Println()
+					pterm.Error.Printf("Validation sampling rate for '%s' must be between 0.0 and 1.0, not %v\n\n", prefix, rate)
+					pterm.Println()
+					return nil
+				}
+			}
+			if config.ValidationSamplingRate > 0 && config.ValidationSamplingRate < 1 {
+				pterm.Printf("🎲 Validating a random %s of requests, all requests are proxied\n",
+					pterm.LightCyan(pterm.Sprintf("%.1f%%", config.ValidationSamplingRate*100)))
+				pterm.Println()
+			}
+			if len(config.ValidationSamplingOverrides) > 0 {
+				printLoadedValidationSamplingOverrides(config.ValidationSamplingOverrides)
+			}
+
+			if config.StrictStatusCodes {
+				pterm.Printf("🚦 %s. Response status codes not defined by the operation will fail validation, even with a default response\n",
+					pterm.LightCyan("Strict status codes enabled"))
+				pterm.Println()
+			}
+
+			switch config.HostValidation {
+			case "":
+			case daemon.HostValidationWarn, daemon.HostValidationError:
+				pterm.Printf("🏠 %s. Requests to a host not in the OpenAPI servers will be reported as %s\n",
+					pterm.LightCyan("Host validation enabled"), pterm.LightMagenta(config.HostValidation))
+				pterm.Println()
+			default:
+				pterm.Println()
+				pterm.Error.Printf("Host validation must be '%s' or '%s', not '%s'\n\n",
+					daemon.HostValidationWarn, daemon.HostValidationError, config.HostValidation)
+				pterm.Println()
+				return nil
+			}
+
+			for from, to := range config.StatusCodeOverrides {
+				if from < 100 || from > 599 || to < 100 || to > 599 {
+					pterm.Println()
+					pterm.Error.Printf("Status code override %d -> %d is not valid, status codes must be between 100 and 599\n\n", from, to)
+					pterm.Println()
+					return nil
+				}
+			}
+			if len(config.StatusCodeOverrides) > 0 {
+				printLoadedStatusCodeOverrides(config.StatusCodeOverrides)
+			}
+
+			if len(config.MaskFields) > 0 {
+				if mErr := config.CompileMaskFields(); mErr != nil {
+					pterm.Println()
+					pterm.Error.Printf("Mask fields are not valid: %s\n\n", mErr.Error())
+					pterm.Println()
+					return nil
+				}
+				printLoadedMaskFields(config.MaskFields)
+			}
+
+			if tzErr := config.CompileReportTimezone(); tzErr != nil {
+				pterm.Println()
+				pterm.Error.Printf("Report timezone is not valid: %s\n\n", tzErr.Error())
+				pterm.Println()
+				return nil
+			}
+			if config.ReportTimezone != "" {
+				pterm.Printf("🕰️  Report timestamps are written in the %s time zone\n", pterm.LightCyan(config.ReportTimezone))
+				pterm.Println()
+			}
+
+			if config.HashRouting != nil {
+				if hErr := config.CompileHashRouting(); hErr != nil {
+					pterm.Println()
+					pterm.Error.Printf("Hash routing is not valid: %s\n\n", hErr.Error())
+					pterm.Println()
+					return nil
+				}
+				printLoadedHashRouting(config.HashRouting)
+			}
+
 			// static headers
 			if config.Headers != nil && len(config.Headers.DropHeaders) > 0 {
 				pterm.Info.Printf("Dropping the following %d %s globally:\n", len(config.Headers.DropHeaders),
@@ -428,6 +884,49 @@ var (
 				pterm.Println()
 			}
 
+			// audit log
+			if config.AuditLog != nil && config.AuditLog.Enabled {
+				auditFile := config.AuditLog.File
+				if auditFile == "" {
+					auditFile = daemon.DefaultAuditLogFile
+				}
+				auditFormat := config.AuditLog.Format
+				if auditFormat == "" {
+					auditFormat = shared.AuditLogFormatJSON
+				}
+				pterm.Printf("📜 %s. Every transaction will be recorded in %s format to: %s\n",
+					pterm.LightCyan("Audit logging enabled"), pterm.LightMagenta(auditFormat), pterm.LightMagenta(auditFile))
+				pterm.Println()
+			}
+
+			// response cache
+			if config.Cache != nil && config.Cache.Enabled {
+				maxEntries := config.Cache.MaxEntries
+				if maxEntries <= 0 {
+					maxEntries = daemon.DefaultCacheMaxEntries
+				}
+				pterm.Printf("💾 %s. Up to %d upstream responses will be cached and revalidated with the API.\n",
+					pterm.LightCyan("Response caching enabled"), maxEntries)
+				pterm.Println()
+			}
+
+			// redacted fields
+			if len(config.RedactFields) > 0 {
+				pterm.Info.Printf("Redacting the following %d %s from captured bodies and reports:\n", len(config.RedactFields),
+					shared.Pluralize(len(config.RedactFields), "field", "fields"))
+				for _, field := range config.RedactFields {
+					pterm.Printf("🔒 %s\n", pterm.LightRed(field))
+				}
+				pterm.Println()
+			}
+
+			// body size limit
+			if config.MaxBodyBytesInReport > 0 {
+				pterm.Printf("✂️  Captured bodies larger than %s are truncated in reports\n",
+					pterm.LightCyan(pterm.Sprintf("%d bytes", config.MaxBodyBytesInReport)))
+				pterm.Println()
+			}
+
 			// static paths
 			if len(config.StaticPaths) > 0 && config.StaticDir != "" {
 				staticPath := filepath.Join(config.StaticDir, config.StaticIndex)
@@ -454,6 +953,82 @@ var (
 				pterm.Println()
 			}
 
+			// mock definitions dir
+			if len(config.MockDefinitionsDir) != 0 {
+				pterm.Printf("Ⓜ️ %s. Mock definitions will be loaded and watched from: %s\n",
+					pterm.LightCyan("Mock definitions directory defined"), pterm.LightMagenta(config.MockDefinitionsDir))
+				pterm.Println()
+			}
+
+			// remote mock definitions
+			if len(config.MockDefinitionsURL) != 0 {
+				refresh := "once, at startup"
+				if config.MockDefinitionsRefreshIntervalSec > 0 {
+					refresh = pterm.Sprintf("every %d seconds", config.MockDefinitionsRefreshIntervalSec)
+				}
+				pterm.Printf("Ⓜ️ %s. Mock definitions will be loaded from: %s, %s\n",
+					pterm.LightCyan("Mock definitions URL defined"), pterm.LightMagenta(config.MockDefinitionsURL), refresh)
+				pterm.Println()
+			}
+
+			// git mock definitions
+			if len(config.MockDefinitionsGitRepo) != 0 {
+				refresh := "once, at startup"
+				if config.MockDefinitionsRefreshIntervalSec > 0 {
+					refresh = pterm.Sprintf("pulled every %d seconds", config.MockDefinitionsRefreshIntervalSec)
+				}
+				branch := config.MockDefinitionsGitBranch
+				if branch == "" {
+					branch = "default branch"
+				}
+				pterm.Printf("Ⓜ️ %s. Mock definitions will be cloned from: %s (%s), %s\n",
+					pterm.LightCyan("Mock definitions git repository defined"),
+					pterm.LightMagenta(config.MockDefinitionsGitRepo), branch, refresh)
+				pterm.Println()
+			}
+
+			// etcd mock synchronization
+			if config.Etcd != nil && len(config.Etcd.Endpoints) > 0 {
+				prefix := config.Etcd.Prefix
+				if prefix == "" {
+					prefix = staticMock.DefaultEtcdPrefix
+				}
+				pterm.Printf("Ⓜ️ %s. Mock definitions are synchronized under %s with: %s\n",
+					pterm.LightCyan("etcd synchronization enabled"), pterm.LightMagenta(prefix),
+					strings.Join(config.Etcd.Endpoints, ", "))
+				pterm.Println()
+			}
+
+			// active mock tags
+			if len(config.ActiveTags) > 0 {
+				pterm.Printf("🏷️ %s. Only mock definitions tagged with %s will be matched.\n",
+					pterm.LightCyan("Active mock tags defined"), pterm.LightMagenta(strings.Join(config.ActiveTags, ", ")))
+				pterm.Println()
+			}
+
+			// request path normalization
+			if config.NormalizeRequestPath {
+				pterm.Printf("🧹 %s. Duplicate slashes, trailing slashes and encoded characters will be normalized before matching mocks.\n",
+					pterm.LightCyan("Request path normalization enabled"))
+				pterm.Println()
+			}
+
+			// concurrent mock checking
+			if config.MockCheckWorkers > 1 {
+				pterm.Printf("🧵 %s. Requests are checked against %d or more mock definitions by %d workers.\n",
+					pterm.LightCyan("Concurrent mock checking enabled"), staticMock.MinConcurrentMockDefinitions,
+					config.MockCheckWorkers)
+				pterm.Println()
+			}
+
+			// concurrent mock loading
+			if config.MockLoadWorkers > 1 {
+				pterm.Printf("🧵 %s. Mock definition files with %d or more definitions are loaded by %d workers.\n",
+					pterm.LightCyan("Concurrent mock loading enabled"), staticMock.MinConcurrentMockDefinitions,
+					config.MockLoadWorkers)
+				pterm.Println()
+			}
+
 			// mock mode
 			if config.MockMode {
 				pterm.Printf("Ⓜ️ %s. All responses will be mocked and no traffic will be sent to the target API.\n",
@@ -461,6 +1036,27 @@ var (
 				pterm.Println()
 			}
 
+			// dry-run mode
+			if config.DryRun {
+				pterm.Printf("🧪 %s. Requests and synthetic responses will be validated, no traffic will be sent to the target API.\n",
+					pterm.LightCyan("Dry-run mode enabled"))
+				pterm.Println()
+			}
+
+			// faker
+			if config.FakerEnabled {
+				pterm.Printf("🎭 %s. Fake data will be generated from the schema for responses without examples.\n",
+					pterm.LightCyan("Faker enabled"))
+				pterm.Println()
+			}
+
+			// mutation testing
+			if config.MutationTest {
+				pterm.Printf("🧬 %s. Captured requests can be mutated and re-validated with POST %s\n",
+					pterm.LightCyan("Mutation testing enabled"), pterm.LightMagenta(daemon.AdminMutationTestPath))
+				pterm.Println()
+			}
+
 			// using TLS?
 			if config.CertificateKey != "" && config.Certificate != "" {
 				pterm.Printf("🔐 Running over %s using certificate: %s and key: %s\n",
@@ -470,7 +1066,104 @@ var (
 
 			// streaming violations?
 			if config.StreamReport {
-				pterm.Printf("⏩  Streaming API violations to file: %s\n", pterm.LightMagenta(config.ReportFile))
+				switch config.ReportFormat {
+				case "", daemon.ReportFormatJSON, daemon.ReportFormatNDJSON:
+				default:
+					pterm.Println()
+					pterm.Error.Printf("Report format must be '%s' or '%s', not '%s'\n\n",
+						daemon.ReportFormatJSON, daemon.ReportFormatNDJSON, config.ReportFormat)
+					pterm.Println()
+					return nil
+				}
+				switch strings.ToLower(config.ReportEncoding) {
+				case "", daemon.ReportEncodingUTF8, daemon.ReportEncodingUTF16LE, daemon.ReportEncodingUTF16BE:
+				default:
+					pterm.Println()
+					pterm.Error.Printf("Report encoding must be '%s', '%s' or '%s', not '%s'\n\n", daemon.ReportEncodingUTF8,
+						daemon.ReportEncodingUTF16LE, daemon.ReportEncodingUTF16BE, config.ReportEncoding)
+					pterm.Println()
+					return nil
+				}
+				if config.ReportFormat == daemon.ReportFormatNDJSON {
+					pterm.Printf("⏩  Streaming API violations to file, one per line: %s\n", pterm.LightMagenta(config.ReportFile))
+				} else if config.ReportPrettyPrint {
+					pterm.Printf("⏩  Streaming API violations to file, indented: %s\n", pterm.LightMagenta(config.ReportFile))
+				} else {
+					pterm.Printf("⏩  Streaming API violations to file: %s\n", pterm.LightMagenta(config.ReportFile))
+				}
+				if config.DeduplicateErrors {
+					pterm.Printf("🧮 Repeated violations are counted, only the first occurrence is reported\n")
+				}
+				if config.ErrorStreamSamplingRate > 0 && config.ErrorStreamSamplingRate < 1 && !config.DeduplicateErrors {
+					pterm.Printf("🎲 Writing a random %s of repeated violations, the first occurrence is always written\n",
+						pterm.LightCyan(pterm.Sprintf("%.1f%%", config.ErrorStreamSamplingRate*100)))
+				}
+				if config.MaxReportFileSizeBytes > 0 {
+					pterm.Printf("🔄 Reports larger than %s continue in a new file\n",
+						pterm.LightCyan(pterm.Sprintf("%d bytes", config.MaxReportFileSizeBytes)))
+				}
+				if config.CompressRotatedFiles {
+					pterm.Printf("🗜️  Rotated reports are compressed with gzip\n")
+				}
+				if config.MaxRetainedFiles > 0 {
+					pterm.Printf("🗑️  Keeping the %s newest rotated reports\n", pterm.LightCyan(config.MaxRetainedFiles))
+				}
+				if config.MaxReportAgeHours > 0 {
+					pterm.Printf("⌛ Reports older than %s are deleted\n",
+						pterm.LightCyan(pterm.Sprintf("%d hours", config.MaxReportAgeHours)))
+				}
+				if config.ReportChecksums {
+					pterm.Printf("🔏 Finished reports are written with a %s checksum file\n", pterm.LightCyan("SHA-256"))
+				}
+				if config.VerifyOnOpen {
+					pterm.Printf("🔍 Existing reports are verified against their checksums on startup\n")
+				}
+				if upload := config.RotationUpload; upload != nil {
+					if (upload.Type != daemon.UploadS3 && upload.Type != daemon.UploadGCS) || upload.Bucket == "" {
+						pterm.Println()
+						pterm.Error.Printf("Rotation upload requires a bucket, and a type of '%s' or '%s', not '%s'\n\n",
+							daemon.UploadS3, daemon.UploadGCS, upload.Type)
+						pterm.Println()
+						return nil
+					}
+					pterm.Printf("☁️  Rotated reports are uploaded to %s bucket: %s\n", upload.Type,
+						pterm.LightMagenta(upload.Bucket+"/"+upload.Prefix))
+				}
+				pterm.Println()
+			}
+
+			if config.StreamChannelBufferSize > 0 {
+				pterm.Printf("🪣 Buffering up to %s batches of violations, violations are dropped when the buffer is full\n",
+					pterm.LightCyan(config.StreamChannelBufferSize))
+				pterm.Println()
+			}
+
+			if config.ErrorStreamSamplingRate < 0 || config.ErrorStreamSamplingRate > 1 {
+				pterm.Println()
+				pterm.Error.Printf("Error stream sampling rate must be between 0 and 1, not %v\n", config.ErrorStreamSamplingRate)
+				pterm.Println()
+				return nil
+			}
+
+			if config.ErrorRateWindow < 0 || config.ErrorRateThreshold < 0 {
+				pterm.Println()
+				pterm.Error.Println("Error rate window and threshold cannot be negative")
+				pterm.Println()
+				return nil
+			}
+			if config.ErrorRateWindow > 0 && config.ErrorRateThreshold > 0 {
+				pterm.Printf("📈 Alerting when validation errors go over %s per second, over %s seconds\n",
+					pterm.LightCyan(config.ErrorRateThreshold), pterm.LightCyan(config.ErrorRateWindow))
+				pterm.Println()
+			}
+
+			if config.JUnitReport != "" {
+				pterm.Printf("🧪 Writing a JUnit report of API violations on shutdown to: %s\n", pterm.LightMagenta(config.JUnitReport))
+				pterm.Println()
+			}
+
+			if config.HTMLReport != "" {
+				pterm.Printf("📊 Writing an HTML report of API violations on shutdown to: %s\n", pterm.LightMagenta(config.HTMLReport))
 				pterm.Println()
 			}
 
@@ -565,32 +1258,45 @@ var (
 			var doc libopenapi.Document
 			var docModel *libopenapi.DocumentModel[v3.Document]
 			var err error
-			if config.Contract != "" {
-				doc, err = loadOpenAPISpec(config.Contract, config.Base)
+			if config.LazySpecLoading && config.Contract != "" {
+				// mocks are generated from the specification, and HAR files are validated against it up front.
+				if config.MockMode || config.DryRun || len(config.MockModeList) > 0 || config.HARValidate ||
+					config.ContractTest != nil {
+					pterm.Warning.Println("Lazy specification loading is not available when mocking or validating " +
+						"HAR files, the specification is loaded before starting")
+					config.LazySpecLoading = false
+				}
+			}
+			if config.Contract != "" && !config.LazySpecLoading {
+				doc, docModel, err = loadOpenAPIModel(config.Contract, config.Base)
 				if err != nil {
 					return err
 				}
-
-				// build a model
-				var errs []error
-				docModel, errs = doc.BuildV3Model()
-				if len(errs) > 0 && docModel != nil {
-					pterm.Warning.Printf("OpenAPI Specification loaded, but there %s %d %s detected...\n",
-						shared.Pluralize(len(errs), "was", "were"),
-						len(errs),
-						shared.Pluralize(len(errs), "issue", "issues"))
-					for _, e := range errs {
-						pterm.Warning.Printf("--> %s\n", e.Error())
-					}
-				}
-				if len(errs) > 0 && docModel == nil {
-					pterm.Error.Printf("Failed to load / read OpenAPI specification.")
-					return errors.Join(errs...)
-				}
 			}
 
 			if doc != nil {
 				pterm.Info.Printf("OpenAPI Specification: '%s' parsed and read\n", config.Contract)
+			} else if config.LazySpecLoading && config.Contract != "" {
+				pterm.Info.Printf("OpenAPI Specification: '%s' will be loaded in the background, "+
+					"requests are not validated until it has loaded\n", config.Contract)
+			}
+
+			if config.AsyncAPISpec != "" {
+				asyncDoc, aErr := loadAsyncAPISpec(config.AsyncAPISpec)
+				if aErr != nil {
+					pterm.Println()
+					pterm.Error.Printf("AsyncAPI Specification cannot be loaded: %s\n\n", aErr.Error())
+					pterm.Println()
+					return nil
+				}
+				config.AsyncAPIDocument = asyncDoc
+				pterm.Info.Printf("AsyncAPI Specification: '%s' (version %s) parsed and read, websocket messages and "+
+					"server-sent events are validated against it\n", config.AsyncAPISpec, asyncDoc.Version)
+			}
+
+			// contract testing compares two specifications against the HAR file, there is no service to run.
+			if config.ContractTest != nil {
+				return runContractTest(&config, harFile)
 			}
 
 			if !config.HARValidate {
@@ -699,6 +1405,8 @@ func Execute(version, commit, date string, fs embed.FS) {
 	rootCmd.Flags().StringP("url", "u", "", "Set the redirect URL for wiretap to send traffic to")
 	rootCmd.Flags().IntP("delay", "d", 0, "Set a global delay for all API requests")
 	rootCmd.Flags().StringP("port", "p", "", "Set port on which to listen for HTTP traffic (default is 9090)")
+	rootCmd.Flags().String("listen-address", "", "Set the address on which to listen for HTTP traffic (default is all interfaces)")
+	rootCmd.Flags().String("asyncapi", "", "Set the AsyncAPI specification to validate websocket messages and server-sent events against")
 	rootCmd.Flags().StringP("monitor-port", "m", "", "Set port on which to serve the monitor UI (default is 9091)")
 	rootCmd.Flags().StringP("ws-port", "w", "", "Set port on which to serve the monitor UI websocket (default is 9092)")
 	rootCmd.Flags().StringP("ws-host", "v", "localhost", "Set the backend hostname for wiretap, for remotely deployed service")
@@ -719,9 +1427,22 @@ func Execute(version, commit, date string, fs embed.FS) {
 	rootCmd.Flags().StringP("har", "z", "", "Load a HAR file instead of sniffing traffic")
 	rootCmd.Flags().BoolP("har-validate", "g", false, "Load a HAR file instead of sniffing traffic, and validate against the OpenAPI specification (requires -s)")
 	rootCmd.Flags().StringArrayP("har-allow", "j", nil, "Add a path to the HAR allow list, can use arg multiple times")
+	rootCmd.Flags().String("contract-baseline", "", "Set the baseline OpenAPI specification to compare against a candidate, using the HAR file (requires -z)")
+	rootCmd.Flags().String("contract-candidate", "", "Set the candidate OpenAPI specification to compare against the baseline, using the HAR file (requires -z)")
 	rootCmd.Flags().StringP("report-filename", "f", "wiretap-report.json", "Filename for any headless report generation output")
 	rootCmd.Flags().BoolP("stream-report", "a", false, "Stream violations to report JSON file as they occur (headless mode)")
+	rootCmd.Flags().String("html-report", "", "Write a self-contained HTML report of validation errors to this file on shutdown")
+	rootCmd.Flags().String("junit-report", "", "Write a JUnit XML report of validation errors to this file on shutdown, a test suite per operation")
+	rootCmd.Flags().String("report-format", "", "Format of the streamed violation report: 'json' (an array, the default) or 'ndjson' (one violation per line)")
 	rootCmd.Flags().BoolP("strict-redirect-location", "r", false, "Rewrite the redirect `Location` header on redirect responses to wiretap's API Gateway Host")
+	rootCmd.Flags().Bool("dry-run", false, "Validate requests and synthetic responses generated from the OpenAPI spec, without sending traffic to the target API (requires OpenAPI spec)")
+	rootCmd.Flags().Bool("faker", false, "Generate fake data from the schema for mocked responses that have no examples in the OpenAPI spec")
+	rootCmd.Flags().Float64("validation-sampling-rate", 0, "Validate a random fraction (0.0 - 1.0) of requests, all requests are still proxied (default validates every request)")
+	rootCmd.Flags().Bool("strict-status-codes", false, "Fail response validation when the status code is not defined by the operation, even if it has a default response")
+	rootCmd.Flags().String("host-validation", "", "Report requests with a Host header that matches no server in the OpenAPI spec, as a 'warn'ing or an 'error'")
+	rootCmd.Flags().Bool("mutation-test", false, "Enable the mutation testing admin endpoint, which mutates captured requests to find gaps in the OpenAPI spec")
+
+	registerLintMocksCommand()
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -853,6 +1574,123 @@ func printLoadedValidationAllowList(validationAllowList []string) {
 	pterm.Println()
 }
 
+func printLoadedBlockedPaths(blockedPaths []string) {
+	pterm.Info.Printf("Loaded %d blocked %s :\n", len(blockedPaths),
+		shared.Pluralize(len(blockedPaths), "path", "paths"))
+
+	for _, x := range blockedPaths {
+		pterm.Printf("⛔ Paths matching '%s' will be refused with a 403\n", pterm.LightCyan(x))
+	}
+	pterm.Println()
+}
+
+func printLoadedBlockedMethods(blockedMethods []string) {
+	pterm.Info.Printf("Loaded %d blocked %s :\n", len(blockedMethods),
+		shared.Pluralize(len(blockedMethods), "method", "methods"))
+
+	for _, x := range blockedMethods {
+		pterm.Printf("⛔ '%s' requests will be refused with a 405\n", pterm.LightCyan(strings.ToUpper(x)))
+	}
+	pterm.Println()
+}
+
+func printLoadedAllowedPaths(allowedPaths []string) {
+	pterm.Info.Printf("Loaded %d allowed %s, all other paths will be refused with a 404 :\n", len(allowedPaths),
+		shared.Pluralize(len(allowedPaths), "path", "paths"))
+
+	for _, x := range allowedPaths {
+		pterm.Printf("✅ Paths matching '%s' will be served\n", pterm.LightCyan(x))
+	}
+	pterm.Println()
+}
+
+func printLoadedValidationSamplingOverrides(overrides map[string]float64) {
+	pterm.Info.Printf("Loaded %d validation sampling %s:\n", len(overrides),
+		shared.Pluralize(len(overrides), "override", "overrides"))
+
+	prefixes := make([]string, 0, len(overrides))
+	for prefix := range overrides {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		pterm.Printf("🎲 Paths starting with '%s' will have %s of requests validated\n", pterm.LightCyan(prefix),
+			pterm.LightMagenta(pterm.Sprintf("%.1f%%", overrides[prefix]*100)))
+	}
+	pterm.Println()
+}
+
+func printLoadedStatusCodeOverrides(overrides map[int]int) {
+	pterm.Info.Printf("Loaded %d status code %s:\n", len(overrides),
+		shared.Pluralize(len(overrides), "override", "overrides"))
+
+	codes := make([]int, 0, len(overrides))
+	for code := range overrides {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		pterm.Printf("🔀 Upstream responses with a %s status code will be returned as %s\n",
+			pterm.LightCyan(code), pterm.LightMagenta(overrides[code]))
+	}
+	pterm.Println()
+}
+
+func printLoadedMaskFields(rules []*shared.MaskRule) {
+	pterm.Info.Printf("Loaded %d mock response mask %s:\n", len(rules),
+		shared.Pluralize(len(rules), "rule", "rules"))
+
+	for _, x := range rules {
+		if x.MaskWith == "" {
+			pterm.Printf("🎭 Values matching '%s' in mock responses will be masked with format preserving fake values\n",
+				pterm.LightCyan(x.JSONPath))
+		} else {
+			pterm.Printf("🎭 Values matching '%s' in mock responses will be masked with '%s'\n",
+				pterm.LightCyan(x.JSONPath), pterm.LightMagenta(x.MaskWith))
+		}
+	}
+	pterm.Println()
+}
+
+func printLoadedHashRouting(routing *shared.HashRoutingConfig) {
+	pterm.Info.Printf("Routing requests by the hash of '%s' across %d %s:\n", pterm.LightMagenta(routing.JSONPath),
+		len(routing.Upstreams), shared.Pluralize(len(routing.Upstreams), "upstream", "upstreams"))
+
+	for i, x := range routing.Upstreams {
+		pterm.Printf("🔀 %d. '%s'\n", i+1, pterm.LightCyan(x.URL))
+	}
+	pterm.Println()
+}
+
+func printLoadedNotifications(notifications []*shared.NotificationConfig) {
+	pterm.Info.Printf("Loaded %d validation error %s:\n", len(notifications),
+		shared.Pluralize(len(notifications), "notification", "notifications"))
+
+	for i, x := range notifications {
+		minimum := x.MinimumErrorCount
+		if minimum < 1 {
+			minimum = 1
+		}
+		window := daemon.DefaultNotificationWindow
+		if x.WindowSeconds > 0 {
+			window = time.Duration(x.WindowSeconds) * time.Second
+		}
+		pterm.Printf("🔔 %d. %s will be notified after %s in %s\n", i+1, pterm.LightCyan(x.Type),
+			pterm.LightMagenta(pterm.Sprintf("%d %s", minimum, shared.Pluralize(minimum, "error", "errors"))), window)
+	}
+	pterm.Println()
+}
+
+func printLoadedFailoverUpstreams(upstreams []*shared.UpstreamConfig) {
+	pterm.Info.Printf("Loaded %d failover %s:\n", len(upstreams),
+		shared.Pluralize(len(upstreams), "upstream", "upstreams"))
+
+	for i, x := range upstreams {
+		pterm.Printf("🛟 %d. Requests failing with a 5xx or an error will be retried against '%s'\n", i+1, pterm.LightCyan(x.URL))
+	}
+	pterm.Println()
+}
+
 func printLoadedMockModeList(mockModeList []string) {
 	pterm.Info.Printf("Loaded %d %s from mock mode list:\n", len(mockModeList),
 		shared.Pluralize(len(mockModeList), "path", "paths"))
@@ -872,3 +1710,11 @@ func printLoadedHardErrorList(HardErrorList []string) {
 	}
 	pterm.Println()
 }
+
+// timeoutLabel prints a timeout in milliseconds, or 'none' when it is not configured.
+func timeoutLabel(ms int) string {
+	if ms <= 0 {
+		return "none"
+	}
+	return (time.Duration(ms) * time.Millisecond).String()
+}
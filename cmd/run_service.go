@@ -7,6 +7,7 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/pb33f/libopenapi"
 	"github.com/pb33f/ranch/bus"
@@ -19,6 +20,7 @@ import (
 	"github.com/pb33f/wiretap/shared"
 	"github.com/pb33f/wiretap/specs"
 	staticMock "github.com/pb33f/wiretap/static-mock"
+	"github.com/pterm/pterm"
 )
 
 func runWiretapService(wiretapConfig *shared.WiretapConfiguration, doc libopenapi.Document) (server.PlatformServer, error) {
@@ -38,6 +40,10 @@ func runWiretapService(wiretapConfig *shared.WiretapConfiguration, doc libopenap
 	ranchConfig.Port, _ = strconv.Atoi(wiretapConfig.WebSocketPort)
 	ranchConfig.FabricConfig.EndpointConfig.Heartbeat = 0
 	ranchConfig.Logger = wiretapConfig.Logger
+	// leave the proxy server time to shut down, before ranch gives up waiting on the services.
+	if shutdownTimeout := daemon.ShutdownTimeout(wiretapConfig); ranchConfig.ShutdownTimeout < shutdownTimeout {
+		ranchConfig.ShutdownTimeout = shutdownTimeout
+	}
 
 	// running TLS?
 	if wiretapConfig.CertificateKey != "" && wiretapConfig.Certificate != "" {
@@ -72,7 +78,10 @@ func runWiretapService(wiretapConfig *shared.WiretapConfiguration, doc libopenap
 		panic(err)
 	}
 
-	staticMockService := staticMock.NewStaticMockService(wtService, wiretapConfig.Logger)
+	staticMockService, err := staticMock.NewStaticMockService(wtService, wiretapConfig, wiretapConfig.Logger)
+	if err != nil {
+		return nil, err
+	}
 	// register Static-Mock Service
 	if err = platformServer.RegisterService(
 		staticMockService, staticMock.StaticMockServiceChan); err != nil {
@@ -80,13 +89,22 @@ func runWiretapService(wiretapConfig *shared.WiretapConfiguration, doc libopenap
 	}
 	// Start watcher to look for changes to static mock definitions
 	staticMockService.StartWatcher()
+	// refresh remote mock definitions periodically
+	staticMockService.StartRemoteRefresh()
+	// synchronize mock definitions with other instances
+	staticMockService.StartEtcdSync()
 
 	// register spec service
-	if err = platformServer.RegisterService(
-		specs.NewSpecService(doc), specs.SpecServiceChan); err != nil {
+	specService := specs.NewSpecService(doc)
+	if err = platformServer.RegisterService(specService, specs.SpecServiceChan); err != nil {
 		panic(err)
 	}
 
+	// load the specification in the background, if it's lazily loaded.
+	if wtService.SpecLoading() {
+		go loadSpecInBackground(wiretapConfig, wtService, specService)
+	}
+
 	// register control service
 	if err = platformServer.RegisterService(
 		controls.NewControlsService(), controls.ControlServiceChan); err != nil {
@@ -137,3 +155,21 @@ func runWiretapService(wiretapConfig *shared.WiretapConfiguration, doc libopenap
 	platformServer.StartServer(sysChan)
 	return platformServer, nil
 }
+
+// loadSpecInBackground loads the specification while wiretap is already proxying requests, and enables validation
+// once it has loaded. If it fails to load, requests carry on without validation.
+func loadSpecInBackground(wiretapConfig *shared.WiretapConfiguration, wtService *daemon.WiretapService,
+	specService *specs.SpecService) {
+	start := time.Now()
+	doc, _, err := loadOpenAPIModel(wiretapConfig.Contract, wiretapConfig.Base)
+	if err != nil {
+		pterm.Error.Printf("Cannot load OpenAPI Specification '%s', requests will not be validated: %s\n",
+			wiretapConfig.Contract, err.Error())
+		wtService.SetDocument(nil)
+		return
+	}
+	specService.SetDocument(doc)
+	wtService.SetDocument(doc)
+	pterm.Info.Printf("OpenAPI Specification: '%s' parsed and read in %s, requests are now validated\n",
+		wiretapConfig.Contract, time.Since(start).Round(time.Millisecond))
+}
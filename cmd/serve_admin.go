@@ -0,0 +1,60 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/handlers"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pterm/pterm"
+)
+
+// serveAdmin serves the admin API on its own address or unix socket, so it can be kept internal while the proxy is
+// exposed. The admin endpoints act on the same wiretap and static mock services as the proxy. The unix socket is
+// removed when the server is shut down.
+func serveAdmin(hht *HandleHttpTraffic) {
+	wiretapConfig := hht.WiretapConfig
+
+	server := &http.Server{
+		Addr:    wiretapConfig.GetAdminListenAddress(),
+		Handler: handlers.CompressHandler(newAdminMux(hht)),
+	}
+	hht.WiretapService.SetAdminServer(server)
+
+	go func() {
+		var err error
+		if wiretapConfig.AdminUnixSocket != "" {
+			// local connections only, so there is no need for TLS.
+			var ln net.Listener
+			if ln, err = daemon.ListenUnixSocket(wiretapConfig.AdminUnixSocket,
+				wiretapConfig.AdminUnixSocketGroupAccess); err == nil {
+				pterm.Info.Println(pterm.LightMagenta(fmt.Sprintf("Admin API booting on unix socket %s...",
+					wiretapConfig.AdminUnixSocket)))
+				err = server.Serve(ln)
+			}
+		} else {
+			pterm.Info.Println(pterm.LightMagenta(fmt.Sprintf("Admin API booting on %s...", server.Addr)))
+			if wiretapConfig.CertificateKey != "" && wiretapConfig.Certificate != "" {
+				err = server.ListenAndServeTLS(wiretapConfig.Certificate, wiretapConfig.CertificateKey)
+			} else {
+				err = server.ListenAndServe()
+			}
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			pterm.Error.Println(err)
+		}
+	}()
+}
+
+// newAdminMux routes requests to the admin endpoints only.
+func newAdminMux(hht *HandleHttpTraffic) *http.ServeMux {
+	mux := http.NewServeMux()
+	hht.WiretapService.RegisterAdminRoutes(mux)
+	hht.StaticMockService.RegisterAdminRoutes(mux)
+	return mux
+}
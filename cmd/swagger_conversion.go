@@ -0,0 +1,641 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel"
+	"github.com/pb33f/libopenapi/utils"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+)
+
+// ConvertedOpenAPIVersion is the OpenAPI version Swagger 2.0 and RAML specifications are converted to.
+const ConvertedOpenAPIVersion = "3.0.3"
+
+// swaggerParameterSchemaKeys are the keys of a Swagger parameter, header or items object that describe its schema.
+var swaggerParameterSchemaKeys = []string{"type", "format", "items", "default", "maximum", "exclusiveMaximum",
+	"minimum", "exclusiveMinimum", "maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems",
+	"enum", "multipleOf"}
+
+// swaggerOperations are the operations of a Swagger path item.
+var swaggerOperations = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// swaggerOAuthFlows maps the Swagger OAuth2 flows to their OpenAPI 3 names.
+var swaggerOAuthFlows = map[string]string{
+	"implicit":    "implicit",
+	"password":    "password",
+	"application": "clientCredentials",
+	"accessCode":  "authorizationCode",
+}
+
+// loadSwaggerSpec converts a Swagger 2.0 document to OpenAPI 3, so it is validated and mocked like any other
+// specification. The Swagger model is built first, so a broken specification reports its own issues.
+func loadSwaggerSpec(doc libopenapi.Document, docConfig *datamodel.DocumentConfiguration) (libopenapi.Document, error) {
+	swaggerModel, errs := doc.BuildV2Model()
+	if swaggerModel == nil {
+		pterm.Error.Printf("Failed to load / read Swagger specification.")
+		return nil, errors.Join(errs...)
+	}
+	pterm.Info.Printf("Swagger %s specification detected, converting to OpenAPI %s\n",
+		doc.GetSpecInfo().Version, ConvertedOpenAPIVersion)
+
+	converted, err := convertSwagger(*doc.GetSpecInfo().SpecBytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert Swagger specification: %w", err)
+	}
+	return libopenapi.NewDocumentWithConfiguration(converted, docConfig)
+}
+
+// isSwaggerSpec is true when the document is a Swagger 2.0 specification.
+func isSwaggerSpec(doc libopenapi.Document) bool {
+	return doc.GetSpecInfo().SpecType == utils.OpenApi2
+}
+
+// swaggerConversion converts a Swagger 2.0 specification to OpenAPI 3. Body and form parameters become request
+// bodies, response schemas become content for each produced media type, definitions become component schemas and
+// parameter references are inlined. References to definitions and responses are rewritten to their components.
+type swaggerConversion struct {
+	swagger    *yaml.Node
+	parameters *yaml.Node // the shared parameters, inlined where they are referenced.
+	consumes   []string
+	produces   []string
+}
+
+// convertSwagger converts the bytes of a Swagger 2.0 specification to the bytes of an OpenAPI 3 specification.
+func convertSwagger(specBytes []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(specBytes, &root); err != nil {
+		return nil, err
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the specification is not an object")
+	}
+	sc := &swaggerConversion{swagger: root.Content[0]}
+	sc.parameters = mapValue(sc.swagger, "parameters")
+	sc.consumes = stringList(mapValue(sc.swagger, "consumes"))
+	sc.produces = stringList(mapValue(sc.swagger, "produces"))
+
+	converted := sc.convert()
+	rewriteSwaggerRefs(converted)
+	return yaml.Marshal(converted)
+}
+
+func (sc *swaggerConversion) convert() *yaml.Node {
+	out := newMapNode()
+	for i := 0; i+1 < len(sc.swagger.Content); i += 2 {
+		key, value := sc.swagger.Content[i].Value, sc.swagger.Content[i+1]
+		switch key {
+		case "swagger":
+			setValue(out, "openapi", scalarNode(ConvertedOpenAPIVersion))
+		case "info":
+			setValue(out, key, value)
+			if servers := sc.servers(); servers != nil {
+				setValue(out, "servers", servers)
+			}
+		case "paths":
+			setValue(out, key, sc.convertPaths(value))
+		case "host", "basePath", "schemes", "consumes", "produces", "definitions", "parameters", "responses",
+			"securityDefinitions":
+			// converted into servers and components.
+		default:
+			setValue(out, key, value)
+		}
+	}
+	if components := sc.components(); len(components.Content) > 0 {
+		setValue(out, "components", components)
+	}
+	return out
+}
+
+// servers returns a server for each scheme of the host and base path. Swagger defaults to the scheme the
+// specification was fetched with, which is not known here, so https is used.
+func (sc *swaggerConversion) servers() *yaml.Node {
+	host := scalarValue(mapValue(sc.swagger, "host"))
+	basePath := scalarValue(mapValue(sc.swagger, "basePath"))
+	if host == "" && basePath == "" {
+		return nil
+	}
+	servers := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	if host == "" {
+		server := newMapNode()
+		setValue(server, "url", scalarNode(basePath))
+		servers.Content = append(servers.Content, server)
+		return servers
+	}
+	schemes := stringList(mapValue(sc.swagger, "schemes"))
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	for _, scheme := range schemes {
+		server := newMapNode()
+		setValue(server, "url", scalarNode(fmt.Sprintf("%s://%s%s", scheme, host, basePath)))
+		servers.Content = append(servers.Content, server)
+	}
+	return servers
+}
+
+func (sc *swaggerConversion) components() *yaml.Node {
+	components := newMapNode()
+	if definitions := mapValue(sc.swagger, "definitions"); definitions != nil && definitions.Kind == yaml.MappingNode {
+		for i := 1; i < len(definitions.Content); i += 2 {
+			convertSwaggerSchema(definitions.Content[i])
+		}
+		setValue(components, "schemas", definitions)
+	}
+	if responses := mapValue(sc.swagger, "responses"); responses != nil && responses.Kind == yaml.MappingNode {
+		converted := newMapNode()
+		for i := 0; i+1 < len(responses.Content); i += 2 {
+			setValue(converted, responses.Content[i].Value, convertSwaggerResponse(responses.Content[i+1], sc.produces))
+		}
+		setValue(components, "responses", converted)
+	}
+	if security := mapValue(sc.swagger, "securityDefinitions"); security != nil && security.Kind == yaml.MappingNode {
+		converted := newMapNode()
+		for i := 0; i+1 < len(security.Content); i += 2 {
+			setValue(converted, security.Content[i].Value, convertSecurityScheme(security.Content[i+1]))
+		}
+		setValue(components, "securitySchemes", converted)
+	}
+	return components
+}
+
+func (sc *swaggerConversion) convertPaths(paths *yaml.Node) *yaml.Node {
+	if paths.Kind != yaml.MappingNode {
+		return paths
+	}
+	out := newMapNode()
+	for i := 0; i+1 < len(paths.Content); i += 2 {
+		key, item := paths.Content[i].Value, paths.Content[i+1]
+		if strings.HasPrefix(key, "x-") || item.Kind != yaml.MappingNode {
+			setValue(out, key, item)
+			continue
+		}
+		setValue(out, key, sc.convertPathItem(item))
+	}
+	return out
+}
+
+// convertPathItem converts the operations of a path. Path parameters are kept on the path item, but body and form
+// parameters move down to each operation, as they become part of its request body.
+func (sc *swaggerConversion) convertPathItem(item *yaml.Node) *yaml.Node {
+	var inherited []*yaml.Node
+	if params := mapValue(item, "parameters"); params != nil {
+		inherited = params.Content
+	}
+	out := newMapNode()
+	for i := 0; i+1 < len(item.Content); i += 2 {
+		key, value := item.Content[i].Value, item.Content[i+1]
+		switch {
+		case key == "parameters":
+			var kept []*yaml.Node
+			for _, param := range value.Content {
+				if in := scalarValue(mapValue(sc.resolveParameter(param), "in")); in != "body" && in != "formData" {
+					kept = append(kept, param)
+				}
+			}
+			if converted, _ := sc.convertParameters(kept, nil, nil); converted != nil {
+				setValue(out, key, converted)
+			}
+		case slices.Contains(swaggerOperations, key) && value.Kind == yaml.MappingNode:
+			setValue(out, key, sc.convertOperation(value, inherited))
+		default:
+			setValue(out, key, value)
+		}
+	}
+	return out
+}
+
+func (sc *swaggerConversion) convertOperation(operation *yaml.Node, inherited []*yaml.Node) *yaml.Node {
+	consumes := sc.consumes
+	if list := stringList(mapValue(operation, "consumes")); len(list) > 0 {
+		consumes = list
+	}
+	if len(consumes) == 0 {
+		consumes = []string{"application/json"}
+	}
+	produces := sc.produces
+	if list := stringList(mapValue(operation, "produces")); len(list) > 0 {
+		produces = list
+	}
+	if len(produces) == 0 {
+		produces = []string{"application/json"}
+	}
+
+	// the body and form parameters of the path, unless the operation overrides them.
+	var body []*yaml.Node
+	for _, param := range inherited {
+		if in := scalarValue(mapValue(sc.resolveParameter(param), "in")); in == "body" || in == "formData" {
+			body = append(body, param)
+		}
+	}
+	var params []*yaml.Node
+	if list := mapValue(operation, "parameters"); list != nil {
+		params = list.Content
+	}
+
+	out := newMapNode()
+	for i := 0; i+1 < len(operation.Content); i += 2 {
+		key, value := operation.Content[i].Value, operation.Content[i+1]
+		switch key {
+		case "consumes", "produces", "parameters":
+		case "responses":
+			setValue(out, key, convertSwaggerResponses(value, produces))
+		default:
+			setValue(out, key, value)
+		}
+	}
+	converted, requestBody := sc.convertParameters(params, body, consumes)
+	if converted != nil {
+		setValue(out, "parameters", converted)
+	}
+	if requestBody != nil {
+		setValue(out, "requestBody", requestBody)
+	}
+	return out
+}
+
+// resolveParameter returns the shared parameter a parameter references, or the parameter itself.
+func (sc *swaggerConversion) resolveParameter(param *yaml.Node) *yaml.Node {
+	ref := scalarValue(mapValue(param, "$ref"))
+	if !strings.HasPrefix(ref, "#/parameters/") || sc.parameters == nil {
+		return param
+	}
+	if resolved := mapValue(sc.parameters, strings.TrimPrefix(ref, "#/parameters/")); resolved != nil {
+		return resolved
+	}
+	return param
+}
+
+// convertParameters converts the parameters of an operation, after the inherited body and form parameters. The
+// operation parameters override inherited parameters with the same name and location. The parameters are returned
+// with the request body the body or form parameters become.
+func (sc *swaggerConversion) convertParameters(params, inherited []*yaml.Node,
+	consumes []string) (*yaml.Node, *yaml.Node) {
+	var resolved []*yaml.Node
+	for _, param := range params {
+		resolved = append(resolved, sc.resolveParameter(param))
+	}
+	for _, param := range inherited {
+		param = sc.resolveParameter(param)
+		overridden := slices.ContainsFunc(resolved, func(p *yaml.Node) bool {
+			return scalarValue(mapValue(p, "name")) == scalarValue(mapValue(param, "name")) &&
+				scalarValue(mapValue(p, "in")) == scalarValue(mapValue(param, "in"))
+		})
+		if !overridden {
+			resolved = append(resolved, param)
+		}
+	}
+
+	var converted, body *yaml.Node
+	var form []*yaml.Node
+	for _, param := range resolved {
+		switch scalarValue(mapValue(param, "in")) {
+		case "body":
+			body = param
+		case "formData":
+			form = append(form, param)
+		default:
+			if converted == nil {
+				converted = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			}
+			converted.Content = append(converted.Content, convertSwaggerParameter(param))
+		}
+	}
+
+	switch {
+	case body != nil:
+		return converted, convertSwaggerBody(body, consumes)
+	case len(form) > 0:
+		return converted, convertSwaggerForm(form, consumes)
+	}
+	return converted, nil
+}
+
+// convertSwaggerParameter converts a query, header or path parameter. Its type moves into a schema, and its
+// collection format becomes a style.
+func convertSwaggerParameter(param *yaml.Node) *yaml.Node {
+	out := newMapNode()
+	schema := newMapNode()
+	collectionFormat := ""
+	for i := 0; i+1 < len(param.Content); i += 2 {
+		key, value := param.Content[i].Value, param.Content[i+1]
+		switch {
+		case key == "collectionFormat":
+			collectionFormat = value.Value
+		case slices.Contains(swaggerParameterSchemaKeys, key):
+			setValue(schema, key, value)
+		default:
+			setValue(out, key, value)
+		}
+	}
+	convertSwaggerItems(schema)
+	setValue(out, "schema", schema)
+
+	if scalarValue(mapValue(schema, "type")) == "array" {
+		in := scalarValue(mapValue(param, "in"))
+		style, explode := "", "false"
+		switch collectionFormat {
+		case "", "csv":
+			style = "simple"
+			if in == "query" || in == "cookie" {
+				style = "form"
+			}
+		case "ssv":
+			style = "spaceDelimited"
+		case "pipes":
+			style = "pipeDelimited"
+		case "multi":
+			style, explode = "form", "true"
+		}
+		if style != "" {
+			setValue(out, "style", scalarNode(style))
+			setValue(out, "explode", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: explode})
+		}
+	}
+	return out
+}
+
+// convertSwaggerItems converts the items of a parameter, header or items object into a schema, dropping the
+// collection formats that have no equivalent in a schema.
+func convertSwaggerItems(schema *yaml.Node) {
+	if scalarValue(mapValue(schema, "type")) == "file" {
+		setValue(schema, "type", scalarNode("string"))
+		setValue(schema, "format", scalarNode("binary"))
+	}
+	items := mapValue(schema, "items")
+	if items == nil || items.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(items.Content); i += 2 {
+		if items.Content[i].Value == "collectionFormat" {
+			items.Content = slices.Delete(items.Content, i, i+2)
+			break
+		}
+	}
+	convertSwaggerItems(items)
+}
+
+// convertSwaggerBody converts a body parameter to a request body, with the schema for each consumed media type.
+func convertSwaggerBody(param *yaml.Node, consumes []string) *yaml.Node {
+	out := newMapNode()
+	if description := mapValue(param, "description"); description != nil {
+		setValue(out, "description", description)
+	}
+	schema := mapValue(param, "schema")
+	if schema == nil {
+		schema = newMapNode()
+	}
+	convertSwaggerSchema(schema)
+	content := newMapNode()
+	for _, mediaType := range consumes {
+		media := newMapNode()
+		setValue(media, "schema", schema)
+		setValue(content, mediaType, media)
+	}
+	setValue(out, "content", content)
+	if required := mapValue(param, "required"); required != nil {
+		setValue(out, "required", required)
+	}
+	return out
+}
+
+// convertSwaggerForm converts form parameters to a request body with an object schema, sent as a multipart form
+// when the operation consumes one or uploads a file, and as an url encoded form otherwise.
+func convertSwaggerForm(params []*yaml.Node, consumes []string) *yaml.Node {
+	mediaType := "application/x-www-form-urlencoded"
+	if slices.Contains(consumes, "multipart/form-data") {
+		mediaType = "multipart/form-data"
+	}
+	properties := newMapNode()
+	var required []string
+	for _, param := range params {
+		name := scalarValue(mapValue(param, "name"))
+		property := mapValue(convertSwaggerParameter(param), "schema")
+		if description := mapValue(param, "description"); description != nil {
+			setValue(property, "description", description)
+		}
+		if scalarValue(mapValue(param, "type")) == "file" {
+			mediaType = "multipart/form-data"
+		}
+		setValue(properties, name, property)
+		if scalarValue(mapValue(param, "required")) == "true" {
+			required = append(required, name)
+		}
+	}
+
+	schema := newMapNode()
+	setValue(schema, "type", scalarNode("object"))
+	setValue(schema, "properties", properties)
+	if len(required) > 0 {
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, name := range required {
+			list.Content = append(list.Content, scalarNode(name))
+		}
+		setValue(schema, "required", list)
+	}
+	media := newMapNode()
+	setValue(media, "schema", schema)
+	content := newMapNode()
+	setValue(content, mediaType, media)
+	out := newMapNode()
+	setValue(out, "content", content)
+	if len(required) > 0 {
+		setValue(out, "required", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+	}
+	return out
+}
+
+func convertSwaggerResponses(responses *yaml.Node, produces []string) *yaml.Node {
+	if responses.Kind != yaml.MappingNode {
+		return responses
+	}
+	out := newMapNode()
+	for i := 0; i+1 < len(responses.Content); i += 2 {
+		key, response := responses.Content[i].Value, responses.Content[i+1]
+		if strings.HasPrefix(key, "x-") || mapValue(response, "$ref") != nil {
+			setValue(out, key, response)
+			continue
+		}
+		setValue(out, key, convertSwaggerResponse(response, produces))
+	}
+	return out
+}
+
+// convertSwaggerResponse converts a response, its schema and examples become content for each produced media type
+// and its headers get a schema.
+func convertSwaggerResponse(response *yaml.Node, produces []string) *yaml.Node {
+	out := newMapNode()
+	setValue(out, "description", scalarNode(scalarValue(mapValue(response, "description"))))
+	content := newMapNode()
+	if schema := mapValue(response, "schema"); schema != nil {
+		convertSwaggerSchema(schema)
+		for _, mediaType := range produces {
+			media := newMapNode()
+			setValue(media, "schema", schema)
+			setValue(content, mediaType, media)
+		}
+	}
+	if examples := mapValue(response, "examples"); examples != nil && examples.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(examples.Content); i += 2 {
+			media := mapValue(content, examples.Content[i].Value)
+			if media == nil {
+				media = newMapNode()
+				setValue(content, examples.Content[i].Value, media)
+			}
+			setValue(media, "example", examples.Content[i+1])
+		}
+	}
+	if len(content.Content) > 0 {
+		setValue(out, "content", content)
+	}
+	if headers := mapValue(response, "headers"); headers != nil && headers.Kind == yaml.MappingNode {
+		converted := newMapNode()
+		for i := 0; i+1 < len(headers.Content); i += 2 {
+			setValue(converted, headers.Content[i].Value, convertSwaggerParameter(headers.Content[i+1]))
+		}
+		setValue(out, "headers", converted)
+	}
+	for i := 0; i+1 < len(response.Content); i += 2 {
+		if strings.HasPrefix(response.Content[i].Value, "x-") {
+			setValue(out, response.Content[i].Value, response.Content[i+1])
+		}
+	}
+	return out
+}
+
+// convertSwaggerSchema converts the Swagger extensions of a schema and its subschemas in place: file types become
+// binary strings, 'x-nullable' becomes 'nullable' and discriminators become objects.
+func convertSwaggerSchema(schema *yaml.Node) {
+	if schema == nil {
+		return
+	}
+	switch schema.Kind {
+	case yaml.SequenceNode:
+		for _, item := range schema.Content {
+			convertSwaggerSchema(item)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(schema.Content); i += 2 {
+			key, value := schema.Content[i], schema.Content[i+1]
+			switch {
+			case key.Value == "type" && value.Kind == yaml.ScalarNode && value.Value == "file":
+				value.Value = "string"
+				setValue(schema, "format", scalarNode("binary"))
+			case key.Value == "x-nullable" && value.Kind == yaml.ScalarNode:
+				key.Value = "nullable"
+			case key.Value == "discriminator" && value.Kind == yaml.ScalarNode:
+				discriminator := newMapNode()
+				setValue(discriminator, "propertyName", scalarNode(value.Value))
+				schema.Content[i+1] = discriminator
+			default:
+				convertSwaggerSchema(value)
+			}
+		}
+	}
+}
+
+func convertSecurityScheme(scheme *yaml.Node) *yaml.Node {
+	out := newMapNode()
+	switch scalarValue(mapValue(scheme, "type")) {
+	case "basic":
+		setValue(out, "type", scalarNode("http"))
+		setValue(out, "scheme", scalarNode("basic"))
+	case "oauth2":
+		setValue(out, "type", scalarNode("oauth2"))
+		flow := newMapNode()
+		for _, key := range []string{"authorizationUrl", "tokenUrl", "scopes"} {
+			if value := mapValue(scheme, key); value != nil {
+				setValue(flow, key, value)
+			}
+		}
+		if mapValue(flow, "scopes") == nil {
+			setValue(flow, "scopes", newMapNode())
+		}
+		flows := newMapNode()
+		setValue(flows, swaggerOAuthFlows[scalarValue(mapValue(scheme, "flow"))], flow)
+		setValue(out, "flows", flows)
+	default:
+		for _, key := range []string{"type", "name", "in"} {
+			if value := mapValue(scheme, key); value != nil {
+				setValue(out, key, value)
+			}
+		}
+	}
+	if description := mapValue(scheme, "description"); description != nil {
+		setValue(out, "description", description)
+	}
+	return out
+}
+
+// rewriteSwaggerRefs points references to definitions and responses at their components.
+func rewriteSwaggerRefs(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "$ref" && node.Content[i+1].Kind == yaml.ScalarNode {
+				ref := node.Content[i+1]
+				ref.Value = strings.Replace(ref.Value, "#/definitions/", "#/components/schemas/", 1)
+				ref.Value = strings.Replace(ref.Value, "#/responses/", "#/components/responses/", 1)
+			}
+		}
+	}
+	for _, child := range node.Content {
+		rewriteSwaggerRefs(child)
+	}
+}
+
+func newMapNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// mapValue returns the value of a key of a mapping node, or nil.
+func mapValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setValue sets the value of a key of a mapping node, keeping its position when the key is already set.
+func setValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, scalarNode(key), value)
+}
+
+func scalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+func stringList(node *yaml.Node) []string {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var list []string
+	for _, item := range node.Content {
+		list = append(list, item.Value)
+	}
+	return list
+}
@@ -0,0 +1,292 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// convertedValue returns the value at a path of keys in a converted specification.
+func convertedValue(t *testing.T, converted []byte, path ...string) any {
+	var document map[string]any
+	require.NoError(t, yaml.Unmarshal(converted, &document))
+	var value any = document
+	for _, key := range path {
+		object, ok := value.(map[string]any)
+		require.True(t, ok, "'%s' is not in an object", key)
+		value, ok = object[key]
+		require.True(t, ok, "'%s' is not set", key)
+	}
+	return value
+}
+
+func yamlValue(t *testing.T, source string) any {
+	var value any
+	require.NoError(t, yaml.Unmarshal([]byte(source), &value))
+	return value
+}
+
+func TestConvertSwagger(t *testing.T) {
+	tests := []struct {
+		name     string
+		swagger  string
+		path     []string
+		expected string
+	}{
+		{
+			name: "body parameter becomes a request body",
+			swagger: `
+paths:
+  /pets:
+    post:
+      parameters:
+        - {in: body, name: pet, required: true, description: the pet, schema: {$ref: '#/definitions/Pet'}}
+      responses: {201: {description: created}}`,
+			path: []string{"paths", "/pets", "post", "requestBody"},
+			expected: `
+description: the pet
+required: true
+content:
+  application/json: {schema: {$ref: '#/components/schemas/Pet'}}`,
+		},
+		{
+			name: "form parameters become an url encoded request body",
+			swagger: `
+paths:
+  /pets:
+    post:
+      parameters:
+        - {in: formData, name: name, type: string, required: true}
+        - {in: formData, name: age, type: integer}
+      responses: {201: {description: created}}`,
+			path: []string{"paths", "/pets", "post", "requestBody"},
+			expected: `
+required: true
+content:
+  application/x-www-form-urlencoded:
+    schema:
+      type: object
+      properties: {name: {type: string}, age: {type: integer}}
+      required: [name]`,
+		},
+		{
+			name: "file form parameters become a multipart request body",
+			swagger: `
+paths:
+  /pets/{id}/photo:
+    parameters:
+      - {in: path, name: id, type: string, required: true}
+      - {in: formData, name: photo, type: file}
+    put:
+      responses: {204: {description: stored}}`,
+			path: []string{"paths", "/pets/{id}/photo", "put", "requestBody", "content"},
+			expected: `
+multipart/form-data:
+  schema: {type: object, properties: {photo: {type: string, format: binary}}}`,
+		},
+		{
+			name: "path parameters stay on the path item",
+			swagger: `
+paths:
+  /pets/{id}/photo:
+    parameters:
+      - {in: path, name: id, type: string, required: true}
+      - {in: formData, name: photo, type: file}
+    put:
+      responses: {204: {description: stored}}`,
+			path:     []string{"paths", "/pets/{id}/photo", "parameters"},
+			expected: `[{in: path, name: id, required: true, schema: {type: string}}]`,
+		},
+		{
+			name: "collection formats become styles",
+			swagger: `
+paths:
+  /pets:
+    get:
+      parameters:
+        - {in: query, name: csv, type: array, items: {type: string}}
+        - {in: query, name: multi, type: array, collectionFormat: multi, items: {type: string}}
+        - {in: query, name: pipes, type: array, collectionFormat: pipes, items: {type: string}}
+        - {in: query, name: ssv, type: array, collectionFormat: ssv, items: {type: string}}
+        - {in: header, name: X-Tags, type: array, items: {type: string, collectionFormat: csv}}
+      responses: {200: {description: pets}}`,
+			path: []string{"paths", "/pets", "get", "parameters"},
+			expected: `
+- {in: query, name: csv, schema: {type: array, items: {type: string}}, style: form, explode: false}
+- {in: query, name: multi, schema: {type: array, items: {type: string}}, style: form, explode: true}
+- {in: query, name: pipes, schema: {type: array, items: {type: string}}, style: pipeDelimited, explode: false}
+- {in: query, name: ssv, schema: {type: array, items: {type: string}}, style: spaceDelimited, explode: false}
+- {in: header, name: X-Tags, schema: {type: array, items: {type: string}}, style: simple, explode: false}`,
+		},
+		{
+			name: "shared parameters are inlined",
+			swagger: `
+parameters:
+  limit: {in: query, name: limit, type: integer, maximum: 100}
+paths:
+  /pets:
+    get:
+      parameters: [{$ref: '#/parameters/limit'}]
+      responses: {200: {description: pets}}`,
+			path:     []string{"paths", "/pets", "get", "parameters"},
+			expected: `[{in: query, name: limit, schema: {type: integer, maximum: 100}}]`,
+		},
+		{
+			name: "definitions become component schemas",
+			swagger: `
+definitions:
+  Pet:
+    type: object
+    discriminator: kind
+    properties:
+      kind: {type: string}
+      owner: {$ref: '#/definitions/Owner'}
+      nickname: {type: string, x-nullable: true}
+  Owner: {type: object}`,
+			path: []string{"components", "schemas"},
+			expected: `
+Pet:
+  type: object
+  discriminator: {propertyName: kind}
+  properties:
+    kind: {type: string}
+    owner: {$ref: '#/components/schemas/Owner'}
+    nickname: {type: string, nullable: true}
+Owner: {type: object}`,
+		},
+		{
+			name: "responses become component responses",
+			swagger: `
+produces: [application/json]
+responses:
+  NotFound: {description: not found, schema: {$ref: '#/definitions/Error'}}
+paths:
+  /pets/{id}:
+    get:
+      responses: {404: {$ref: '#/responses/NotFound'}}`,
+			path: []string{"components", "responses"},
+			expected: `
+NotFound:
+  description: not found
+  content: {application/json: {schema: {$ref: '#/components/schemas/Error'}}}`,
+		},
+		{
+			name: "response references point at the components",
+			swagger: `
+responses:
+  NotFound: {description: not found}
+paths:
+  /pets/{id}:
+    get:
+      responses: {404: {$ref: '#/responses/NotFound'}}`,
+			path:     []string{"paths", "/pets/{id}", "get", "responses"},
+			expected: `{'404': {$ref: '#/components/responses/NotFound'}}`,
+		},
+		{
+			name: "security definitions become security schemes",
+			swagger: `
+securityDefinitions:
+  basic: {type: basic}
+  key: {type: apiKey, name: X-API-Key, in: header, description: the key}
+  oauth:
+    type: oauth2
+    flow: accessCode
+    authorizationUrl: https://example.com/authorize
+    tokenUrl: https://example.com/token
+    scopes: {read: read pets}`,
+			path: []string{"components", "securitySchemes"},
+			expected: `
+basic: {type: http, scheme: basic}
+key: {type: apiKey, name: X-API-Key, in: header, description: the key}
+oauth:
+  type: oauth2
+  flows:
+    authorizationCode:
+      authorizationUrl: https://example.com/authorize
+      tokenUrl: https://example.com/token
+      scopes: {read: read pets}`,
+		},
+		{
+			name: "produces become response media types",
+			swagger: `
+produces: [application/json, application/xml]
+paths:
+  /pets:
+    get:
+      responses:
+        200:
+          description: pets
+          schema: {type: array, items: {$ref: '#/definitions/Pet'}}
+          examples: {application/json: [{name: beef}]}
+          headers: {X-Total: {type: integer}}`,
+			path: []string{"paths", "/pets", "get", "responses", "200"},
+			expected: `
+description: pets
+content:
+  application/json:
+    schema: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+    example: [{name: beef}]
+  application/xml:
+    schema: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+headers: {X-Total: {schema: {type: integer}}}`,
+		},
+		{
+			name: "operation consumes override the specification",
+			swagger: `
+consumes: [application/json]
+paths:
+  /pets:
+    put:
+      consumes: [application/xml, text/plain]
+      parameters: [{in: body, name: pet, schema: {type: string}}]
+      responses: {204: {description: stored}}`,
+			path: []string{"paths", "/pets", "put", "requestBody", "content"},
+			expected: `
+application/xml: {schema: {type: string}}
+text/plain: {schema: {type: string}}`,
+		},
+		{
+			name: "host, base path and schemes become servers",
+			swagger: `
+host: petstore.example.com
+basePath: /v2
+schemes: [http, https]`,
+			path:     []string{"servers"},
+			expected: `[{url: 'http://petstore.example.com/v2'}, {url: 'https://petstore.example.com/v2'}]`,
+		},
+		{
+			name: "a host without schemes is served over https",
+			swagger: `
+host: petstore.example.com`,
+			path:     []string{"servers"},
+			expected: `[{url: 'https://petstore.example.com'}]`,
+		},
+		{
+			name: "a base path without a host is a relative server",
+			swagger: `
+basePath: /v2`,
+			path:     []string{"servers"},
+			expected: `[{url: /v2}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			converted, err := convertSwagger([]byte("swagger: '2.0'\ninfo: {title: pets, version: '1'}\n" +
+				test.swagger))
+			require.NoError(t, err)
+			assert.Equal(t, ConvertedOpenAPIVersion, convertedValue(t, converted, "openapi"))
+			assert.Equal(t, yamlValue(t, test.expected), convertedValue(t, converted, test.path...))
+		})
+	}
+}
+
+func TestConvertSwagger_NotAnObject(t *testing.T) {
+	_, err := convertSwagger([]byte("- swagger"))
+	assert.ErrorContains(t, err, "the specification is not an object")
+}
@@ -112,6 +112,29 @@ func PathValidationAllowListed(path string, configuration *shared.WiretapConfigu
 	return false
 }
 
+// PathBlocked is true when the path matches a blocked path.
+func PathBlocked(path string, configuration *shared.WiretapConfiguration) bool {
+	for _, blockedPath := range configuration.CompiledBlockedPaths {
+		if blockedPath.CompiledPath.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// PathAllowed is true when there are no allowed paths, or the path matches one of them.
+func PathAllowed(path string, configuration *shared.WiretapConfiguration) bool {
+	if len(configuration.CompiledAllowedPaths) == 0 {
+		return true
+	}
+	for _, allowedPath := range configuration.CompiledAllowedPaths {
+		if allowedPath.CompiledPath.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
 func rewriteTaget(path string, pathConfig *shared.WiretapPathConfig, configuration *shared.WiretapConfiguration) *PathRewrite {
 	scheme := "http://"
 	if pathConfig.Secure {
@@ -678,3 +678,36 @@ func TestGetRewriteHeaderValues_MissingHeader(t *testing.T) {
 	}
 
 }
+
+func TestPathBlocked(t *testing.T) {
+
+	config := `blockedPaths:
+  - /admin/**
+  - /*/secret`
+
+	var c shared.WiretapConfiguration
+	_ = yaml.Unmarshal([]byte(config), &c)
+
+	c.CompileBlockedPaths()
+
+	assert.True(t, PathBlocked("/admin/users/1", &c))
+	assert.True(t, PathBlocked("/pb33f/secret", &c))
+	assert.False(t, PathBlocked("/pb33f/public", &c))
+}
+
+func TestPathAllowed(t *testing.T) {
+
+	var c shared.WiretapConfiguration
+	c.CompileAllowedPaths()
+
+	// with no allowed paths, every path is allowed.
+	assert.True(t, PathAllowed("/anything", &c))
+
+	config := `allowedPaths:
+  - /pb33f/**`
+	_ = yaml.Unmarshal([]byte(config), &c)
+	c.CompileAllowedPaths()
+
+	assert.True(t, PathAllowed("/pb33f/test/123", &c))
+	assert.False(t, PathAllowed("/roastbeef/test/123", &c))
+}
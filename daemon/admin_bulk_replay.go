@@ -0,0 +1,334 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+)
+
+const AdminBulkReplayPath = "/wiretap/replay"
+
+// TransactionFilter selects captured transactions. Every field that is set must match, an empty filter
+// matches every transaction.
+type TransactionFilter struct {
+	Ids         []string `json:"ids,omitempty"`
+	Method      string   `json:"method,omitempty"`
+	PathPrefix  string   `json:"pathPrefix,omitempty"`
+	StatusCodes []int    `json:"statusCodes,omitempty"`
+	HasErrors   *bool    `json:"hasErrors,omitempty"`
+}
+
+// Matches reports if the transaction matches every field of the filter.
+func (tf *TransactionFilter) Matches(transaction *HttpTransaction) bool {
+	if transaction.Request == nil {
+		return false
+	}
+	if len(tf.Ids) > 0 && !containsString(tf.Ids, transaction.Id) {
+		return false
+	}
+	if tf.Method != "" && !strings.EqualFold(tf.Method, transaction.Request.Method) {
+		return false
+	}
+	if tf.PathPrefix != "" && !strings.HasPrefix(replayPath(transaction.Request), tf.PathPrefix) {
+		return false
+	}
+	if len(tf.StatusCodes) > 0 {
+		if transaction.Response == nil || !containsInt(tf.StatusCodes, transaction.Response.StatusCode) {
+			return false
+		}
+	}
+	if tf.HasErrors != nil {
+		hasErrors := len(transaction.RequestValidation) > 0 || len(transaction.ResponseValidation) > 0
+		if hasErrors != *tf.HasErrors {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// BulkReplayRequest is the body of the bulk replay endpoint: a transaction filter, an optional upstream to send the
+// replayed requests to (instead of the redirect URL), and the number of requests to replay at the same time.
+type BulkReplayRequest struct {
+	TransactionFilter
+	TargetUpstream string `json:"targetUpstream,omitempty"`
+	Concurrency    int    `json:"concurrency,omitempty"`
+}
+
+// BulkReplayResult compares the validation results of a replayed transaction with those of the original.
+type BulkReplayResult struct {
+	OriginalId         string   `json:"originalId"`
+	TransactionId      string   `json:"transactionId,omitempty"`
+	Method             string   `json:"method"`
+	Path               string   `json:"path"`
+	OriginalStatusCode int      `json:"originalStatusCode,omitempty"`
+	StatusCode         int      `json:"statusCode,omitempty"`
+	OriginalErrors     int      `json:"originalErrors"`
+	Errors             int      `json:"errors"`
+	Regressions        []string `json:"regressions,omitempty"`  // errors reported by the replay, not by the original.
+	Improvements       []string `json:"improvements,omitempty"` // errors reported by the original, not by the replay.
+	Error              string   `json:"error,omitempty"`
+}
+
+// BulkReplayResponse summarizes a bulk replay.
+type BulkReplayResponse struct {
+	Matched      int                 `json:"matched"`
+	Failed       int                 `json:"failed"`       // replays that could not be sent, or reach the upstream.
+	Regressions  int                 `json:"regressions"`  // replays with new validation errors.
+	Improvements int                 `json:"improvements"` // replays no longer reporting an original error.
+	Results      []*BulkReplayResult `json:"results"`
+}
+
+func replayPath(request *HttpRequest) string {
+	if request.OriginalPath != "" {
+		return request.OriginalPath
+	}
+	return request.Path
+}
+
+// FindTransactions returns the captured transactions matching the filter, oldest first. With a Redis transaction
+// store, transactions captured by any instance are returned.
+func (ws *WiretapService) FindTransactions(filter *TransactionFilter) []*HttpTransaction {
+	var transactions []*HttpTransaction
+	for _, transaction := range ws.allTransactions() {
+		if filter.Matches(transaction) {
+			transactions = append(transactions, transaction)
+		}
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		if transactions[i].Request.Timestamp != transactions[j].Request.Timestamp {
+			return transactions[i].Request.Timestamp < transactions[j].Request.Timestamp
+		}
+		return transactions[i].Id < transactions[j].Id
+	})
+	return transactions
+}
+
+// allTransactions returns the transactions in the Redis transaction store if there is one, or the transactions
+// captured by this instance.
+func (ws *WiretapService) allTransactions() []*HttpTransaction {
+	if ws.redisTransactions != nil {
+		transactions, err := ws.redisTransactions.all()
+		if err == nil {
+			return transactions
+		}
+		ws.config.Logger.Warn("[wiretap] unable to read transactions from redis, using local transactions",
+			"error", err.Error())
+	}
+	var transactions []*HttpTransaction
+	for _, value := range ws.transactionStore.AllValues() {
+		if transaction, ok := value.(*HttpTransaction); ok {
+			transactions = append(transactions, transaction)
+		}
+	}
+	return transactions
+}
+
+// runBulkReplay replays every transaction, running up to concurrency replays at the same time (at least one).
+// Results are returned in the order of the transactions.
+func runBulkReplay(transactions []*HttpTransaction, concurrency int,
+	replay func(*HttpTransaction) *BulkReplayResult) []*BulkReplayResult {
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]*BulkReplayResult, len(transactions))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, transaction := range transactions {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = replay(transaction)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// replayTransaction sends a captured request to the upstream again, validating the replay synchronously and
+// capturing it as a new transaction. When a target upstream is given, the request is sent straight to it,
+// instead of the redirect URL.
+func (ws *WiretapService) replayTransaction(transaction *HttpTransaction, target *url.URL) *BulkReplayResult {
+	result := &BulkReplayResult{
+		OriginalId:     transaction.Id,
+		Method:         transaction.Request.Method,
+		Path:           replayPath(transaction.Request),
+		OriginalErrors: len(transaction.RequestValidation) + len(transaction.ResponseValidation),
+	}
+	if transaction.Response != nil {
+		result.OriginalStatusCode = transaction.Response.StatusCode
+	}
+	if reason := unreplayableBody(transaction.Request); reason != "" {
+		result.Error = reason
+		return result
+	}
+
+	configStore, _ := ws.controlsStore.Get(shared.ConfigKey)
+	config := configStore.(*shared.WiretapConfiguration)
+
+	replay, err := buildReplayRequest(transaction, "localhost", nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	protocol, host, port, basePath := config.RedirectProtocol, config.RedirectHost, config.RedirectPort, config.RedirectBasePath
+	if target != nil {
+		protocol, host, port, basePath = target.Scheme, target.Hostname(), target.Port(), strings.TrimSuffix(target.Path, "/")
+	}
+
+	// captured headers already had headers dropped and injected, they are sent as they were captured.
+	newReq := CloneExistingRequest(CloneRequest{Request: replay, Protocol: protocol, Host: host, Port: port})
+	apiRequest := CloneExistingRequest(CloneRequest{
+		Request:  replay,
+		Protocol: protocol,
+		Host:     host,
+		Port:     port,
+		BasePath: basePath,
+	})
+	if newReq == nil || apiRequest == nil {
+		result.Error = "unable to clone captured request"
+		return result
+	}
+
+	id, _ := uuid.NewUUID()
+	result.TransactionId = id.String()
+	modelRequest := &model.Request{Id: &id, HttpRequest: replay}
+
+	replayErrors := ws.ValidateRequest(modelRequest, newReq)
+
+	var resp *http.Response
+	if target != nil {
+		resp, err = (&http.Client{Transport: newWiretapTransport()}).Do(apiRequest)
+	} else {
+		resp, err = ws.callAPI(apiRequest)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+
+	for _, ve := range ws.ValidateResponse(modelRequest, CloneExistingResponse(resp)) {
+		if !ve.IsPathMissingError() {
+			replayErrors = append(replayErrors, ve)
+		}
+	}
+	result.Errors = len(replayErrors)
+
+	var originalErrors []*errors.ValidationError
+	originalErrors = append(originalErrors, transaction.RequestValidation...)
+	originalErrors = append(originalErrors, transaction.ResponseValidation...)
+	result.Regressions, result.Improvements = compareValidationErrors(originalErrors, replayErrors)
+	return result
+}
+
+func validationErrorKey(ve *errors.ValidationError) string {
+	if ve.Reason != "" && ve.Reason != ve.Message {
+		return ve.Message + ": " + ve.Reason
+	}
+	return ve.Message
+}
+
+// compareValidationErrors returns the errors only present in the replay, and those only present in the original.
+func compareValidationErrors(original, replay []*errors.ValidationError) ([]string, []string) {
+	originalKeys := make(map[string]bool, len(original))
+	for _, ve := range original {
+		originalKeys[validationErrorKey(ve)] = true
+	}
+	replayKeys := make(map[string]bool, len(replay))
+	var regressions, improvements []string
+	for _, ve := range replay {
+		key := validationErrorKey(ve)
+		if !originalKeys[key] && !replayKeys[key] {
+			regressions = append(regressions, key)
+		}
+		replayKeys[key] = true
+	}
+	for _, ve := range original {
+		key := validationErrorKey(ve)
+		if !replayKeys[key] {
+			improvements = append(improvements, key)
+			replayKeys[key] = true
+		}
+	}
+	return regressions, improvements
+}
+
+// handleBulkReplay replays every captured transaction matching the filter in the request body.
+func (ws *WiretapService) handleBulkReplay(w http.ResponseWriter, r *http.Request) {
+	var replayRequest BulkReplayRequest
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&replayRequest); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "Invalid replay request", err.Error(), r.URL.Path)
+			return
+		}
+	}
+
+	var target *url.URL
+	if replayRequest.TargetUpstream != "" {
+		var err error
+		target, err = url.Parse(replayRequest.TargetUpstream)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			writeAdminError(w, http.StatusBadRequest, "Invalid target upstream",
+				fmt.Sprintf("the target upstream '%s' is not an absolute URL", replayRequest.TargetUpstream), r.URL.Path)
+			return
+		}
+	}
+
+	transactions := ws.FindTransactions(&replayRequest.TransactionFilter)
+	results := runBulkReplay(transactions, replayRequest.Concurrency, func(transaction *HttpTransaction) *BulkReplayResult {
+		return ws.replayTransaction(transaction, target)
+	})
+
+	response := &BulkReplayResponse{Matched: len(transactions), Results: results}
+	for _, result := range results {
+		if result.Error != "" {
+			response.Failed++
+			continue
+		}
+		if len(result.Regressions) > 0 {
+			response.Regressions++
+		}
+		if len(result.Improvements) > 0 {
+			response.Improvements++
+		}
+	}
+
+	ws.config.Logger.Info("[wiretap] bulk replay complete", "matched", response.Matched,
+		"failed", response.Failed, "regressions", response.Regressions, "improvements", response.Improvements)
+	writeAdminResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionFilter_Matches(t *testing.T) {
+	transaction := &HttpTransaction{
+		Id:                 "abc",
+		Request:            &HttpRequest{Method: "POST", Path: "/v1/pets", OriginalPath: "/pets"},
+		Response:           &HttpResponse{StatusCode: 201},
+		ResponseValidation: []*errors.ValidationError{{Message: "bad"}},
+	}
+	yes, no := true, false
+
+	assert.True(t, (&TransactionFilter{}).Matches(transaction))
+	assert.True(t, (&TransactionFilter{Method: "post", PathPrefix: "/pets", StatusCodes: []int{200, 201},
+		HasErrors: &yes, Ids: []string{"abc"}}).Matches(transaction))
+	assert.False(t, (&TransactionFilter{Method: "GET"}).Matches(transaction))
+	assert.False(t, (&TransactionFilter{PathPrefix: "/v1"}).Matches(transaction))
+	assert.False(t, (&TransactionFilter{StatusCodes: []int{500}}).Matches(transaction))
+	assert.False(t, (&TransactionFilter{HasErrors: &no}).Matches(transaction))
+	assert.False(t, (&TransactionFilter{Ids: []string{"xyz"}}).Matches(transaction))
+}
+
+func TestRunBulkReplay(t *testing.T) {
+	var transactions []*HttpTransaction
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		transactions = append(transactions, &HttpTransaction{Id: id})
+	}
+
+	var running, maxRunning atomic.Int32
+	results := runBulkReplay(transactions, 2, func(transaction *HttpTransaction) *BulkReplayResult {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return &BulkReplayResult{OriginalId: transaction.Id}
+	})
+
+	assert.LessOrEqual(t, maxRunning.Load(), int32(2))
+	for i, result := range results {
+		assert.Equal(t, transactions[i].Id, result.OriginalId)
+	}
+}
+
+func TestCompareValidationErrors(t *testing.T) {
+	original := []*errors.ValidationError{{Message: "missing name"}, {Message: "bad type", Reason: "expected integer"}}
+	replay := []*errors.ValidationError{{Message: "bad type", Reason: "expected integer"}, {Message: "unknown field"}}
+
+	regressions, improvements := compareValidationErrors(original, replay)
+	assert.Equal(t, []string{"unknown field"}, regressions)
+	assert.Equal(t, []string{"missing name"}, improvements)
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+const AdminCacheInvalidatePath = "/wiretap/cache/invalidate"
+
+// CacheInvalidateResponse is returned by the cache invalidation endpoint.
+type CacheInvalidateResponse struct {
+	Evicted int `json:"evicted"`
+}
+
+// handleCacheInvalidate evicts the cached responses tagged with any of the surrogate keys in the request body,
+// which is a JSON array of tags.
+func (ws *WiretapService) handleCacheInvalidate(w http.ResponseWriter, r *http.Request) {
+	if ws.responseCache == nil {
+		writeAdminError(w, http.StatusConflict, "Response cache is not enabled",
+			"caching is not enabled in the wiretap configuration, there is nothing to invalidate", r.URL.Path)
+		return
+	}
+	var tags []string
+	if err := json.NewDecoder(r.Body).Decode(&tags); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Invalid cache invalidation request",
+			"the request body must be a JSON array of tag strings: "+err.Error(), r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, http.StatusOK, &CacheInvalidateResponse{Evicted: ws.responseCache.invalidateTags(tags)})
+}
+
+func writeAdminResponse(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, title, detail, instance string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_, _ = w.Write(shared.MarshalError(shared.GenerateError(title, status, detail, instance, nil)))
+}
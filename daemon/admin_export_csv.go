@@ -0,0 +1,85 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+)
+
+const AdminExportCSVPath = "/wiretap/export/csv"
+
+var validationCSVHeader = []string{
+	"timestamp", "request_id", "path", "method", "error_type", "error_message", "schema_path",
+}
+
+// validationErrorType names the kind of a validation error, as 'type/subtype'.
+func validationErrorType(ve *errors.ValidationError) string {
+	if ve.ValidationSubType == "" {
+		return ve.ValidationType
+	}
+	return ve.ValidationType + "/" + ve.ValidationSubType
+}
+
+// writeValidationCSV writes a row for every validation error of the transactions. Errors with schema failures
+// get a row per failure, with the location of the failure in the schema.
+func writeValidationCSV(w io.Writer, transactions []*HttpTransaction) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(validationCSVHeader); err != nil {
+		return err
+	}
+	for _, transaction := range transactions {
+		timestamp := time.UnixMilli(transaction.Request.Timestamp).UTC().Format(time.RFC3339)
+		var validationErrors []*errors.ValidationError
+		validationErrors = append(validationErrors, transaction.RequestValidation...)
+		validationErrors = append(validationErrors, transaction.ResponseValidation...)
+
+		for _, ve := range validationErrors {
+			row := []string{timestamp, transaction.Id, replayPath(transaction.Request), transaction.Request.Method,
+				validationErrorType(ve), validationErrorKey(ve), ""}
+			if len(ve.SchemaValidationErrors) == 0 {
+				if err := cw.Write(row); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, failure := range ve.SchemaValidationErrors {
+				if failure == nil {
+					continue
+				}
+				row[5] = ve.Message + ": " + failure.Reason
+				row[6] = failure.Location
+				if err := cw.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// handleExportCSV exports the validation errors of every captured transaction as a CSV file, oldest first.
+// A transaction filter can be posted, to export the errors of the matching transactions only.
+func (ws *WiretapService) handleExportCSV(w http.ResponseWriter, r *http.Request) {
+	var filter TransactionFilter
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "Invalid transaction filter", err.Error(), r.URL.Path)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="wiretap-validation-errors.csv"`)
+	w.WriteHeader(http.StatusOK)
+	if err := writeValidationCSV(w, ws.FindTransactions(&filter)); err != nil {
+		ws.config.Logger.Error("[wiretap] unable to export validation errors", "error", err.Error())
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteValidationCSV(t *testing.T) {
+	at := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	transactions := []*HttpTransaction{
+		{
+			Id:      "abc",
+			Request: &HttpRequest{Method: "POST", Path: "/v1/pets", OriginalPath: "/pets", Timestamp: at.UnixMilli()},
+			RequestValidation: []*errors.ValidationError{{
+				Message: "Query parameter 'limit' is missing", ValidationType: "parameter", ValidationSubType: "query",
+			}},
+			ResponseValidation: []*errors.ValidationError{{
+				Message: "200 response body for '/pets' failed to validate schema", Reason: "schema, \"name\"",
+				ValidationType: "response", ValidationSubType: "schema",
+				SchemaValidationErrors: []*errors.SchemaValidationFailure{
+					{Reason: "missing property 'name'", Location: "/required"},
+					{Reason: "expected string", Location: "/properties/id/type"},
+				},
+			}},
+		},
+		{Id: "clean", Request: &HttpRequest{Method: "GET", Path: "/pets", Timestamp: at.UnixMilli()}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeValidationCSV(&buf, transactions))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{
+		validationCSVHeader,
+		{"2024-05-01T12:00:00Z", "abc", "/pets", "POST", "parameter/query", "Query parameter 'limit' is missing", ""},
+		{"2024-05-01T12:00:00Z", "abc", "/pets", "POST", "response/schema",
+			"200 response body for '/pets' failed to validate schema: missing property 'name'", "/required"},
+		{"2024-05-01T12:00:00Z", "abc", "/pets", "POST", "response/schema",
+			"200 response body for '/pets' failed to validate schema: expected string", "/properties/id/type"},
+	}, rows)
+}
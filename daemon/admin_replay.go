@@ -0,0 +1,130 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/ranch/model"
+)
+
+const AdminTransactionReplayPath = "/wiretap/transactions/{id}/replay"
+
+// ReplayResponse is returned by the transaction replay endpoint. The replayed request is captured as a new
+// transaction, validation results are available from the new transaction once validation completes.
+type ReplayResponse struct {
+	OriginalId    string `json:"originalId"`
+	TransactionId string `json:"transactionId"`
+	StatusCode    int    `json:"statusCode"`
+	ResponseBody  string `json:"responseBody,omitempty"`
+}
+
+// parseModifyHeaders reads header overrides from 'modifyHeaders' query parameters, formatted as 'Name:Value'.
+// Multiple overrides can be given, comma separated or as repeated parameters. An empty value removes the header.
+func parseModifyHeaders(query url.Values) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, param := range query["modifyHeaders"] {
+		for _, pair := range strings.Split(param, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(pair, ":")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("header override '%s' must be formatted as 'Name:Value'", pair)
+			}
+			headers[name] = strings.TrimSpace(value)
+		}
+	}
+	return headers, nil
+}
+
+// unreplayableBody explains why a captured request body cannot be replayed, or is empty when it can. Bodies are
+// stored redacted and cut down to the report body limit, replaying those would send a different request upstream.
+func unreplayableBody(captured *HttpRequest) string {
+	switch {
+	case captured.BodyTruncated:
+		return fmt.Sprintf("the captured request body was truncated from %d bytes, raise maxBodyBytesInReport "+
+			"to replay it", captured.BodyLength)
+	case captured.BodyRedacted:
+		return "fields of the captured request body were redacted, remove them from redactFields to replay it"
+	}
+	return ""
+}
+
+// buildReplayRequest recreates the captured request, as it was received by wiretap.
+func buildReplayRequest(transaction *HttpTransaction, host string, modifyHeaders map[string]string) (*http.Request, error) {
+	captured := transaction.Request
+	path := captured.OriginalPath
+	if path == "" {
+		path = captured.Path
+	}
+	u := &url.URL{Scheme: "http", Host: host, Path: path, RawQuery: captured.Query}
+	req, err := http.NewRequest(captured.Method, u.String(), strings.NewReader(captured.Body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range captured.Headers {
+		req.Header.Set(k, fmt.Sprint(v))
+	}
+	for k, v := range modifyHeaders {
+		if v == "" {
+			req.Header.Del(k)
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+	return req, nil
+}
+
+// handleTransactionReplay re-sends a captured request to the upstream, through the regular request handling,
+// so the replay is validated and captured as a new transaction.
+func (ws *WiretapService) handleTransactionReplay(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	transaction := ws.GetTransaction(id)
+	if transaction == nil || transaction.Request == nil {
+		writeAdminError(w, http.StatusNotFound, "Transaction not found",
+			fmt.Sprintf("no transaction with the id '%s' has been captured", id), r.URL.Path)
+		return
+	}
+
+	if reason := unreplayableBody(transaction.Request); reason != "" {
+		writeAdminError(w, http.StatusConflict, "Transaction cannot be replayed", reason, r.URL.Path)
+		return
+	}
+
+	modifyHeaders, err := parseModifyHeaders(r.URL.Query())
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Invalid header override", err.Error(), r.URL.Path)
+		return
+	}
+
+	replay, err := buildReplayRequest(transaction, r.Host, modifyHeaders)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Unable to replay transaction", err.Error(), r.URL.Path)
+		return
+	}
+
+	replayId, _ := uuid.NewUUID()
+	recorder := httptest.NewRecorder()
+	ws.handleHttpRequest(&model.Request{
+		Id:                 &replayId,
+		HttpRequest:        replay,
+		HttpResponseWriter: recorder,
+	})
+
+	ws.config.Logger.Info("[wiretap] replayed transaction", "transaction", id, "replay", replayId.String(),
+		"code", recorder.Code)
+	writeAdminResponse(w, http.StatusOK, &ReplayResponse{
+		OriginalId:    id,
+		TransactionId: replayId.String(),
+		StatusCode:    recorder.Code,
+		ResponseBody:  recorder.Body.String(),
+	})
+}
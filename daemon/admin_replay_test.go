@@ -0,0 +1,103 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModifyHeaders(t *testing.T) {
+	headers, err := parseModifyHeaders(url.Values{
+		"modifyHeaders": {"Authorization: Bearer new, X-Debug:", "X-Trace:abc"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer new", "X-Debug": "", "X-Trace": "abc"}, headers)
+
+	_, err = parseModifyHeaders(url.Values{"modifyHeaders": {"no-colon"}})
+	assert.Error(t, err)
+}
+
+func TestBuildReplayRequest(t *testing.T) {
+	transaction := &HttpTransaction{
+		Id: "abc",
+		Request: &HttpRequest{
+			Method:       http.MethodPost,
+			URL:          "http://upstream/v1/pets?limit=1",
+			Path:         "/v1/pets",
+			OriginalPath: "/pets",
+			Query:        "limit=1",
+			Headers:      map[string]any{"Authorization": "Bearer old", "X-Debug": "1", "Content-Type": "application/json"},
+			Body:         `{"name": "fido"}`,
+		},
+	}
+	req, err := buildReplayRequest(transaction, "localhost:9090",
+		map[string]string{"Authorization": "Bearer new", "X-Debug": ""})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "http://localhost:9090/pets?limit=1", req.URL.String())
+	assert.Equal(t, "Bearer new", req.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("X-Debug"))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"name": "fido"}`, string(body))
+}
+
+func TestHandleTransactionReplay_CapturedBodyChanged(t *testing.T) {
+	ws := &WiretapService{
+		config:           &shared.WiretapConfiguration{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))},
+		transactionStore: bus.GetBus().GetStoreManager().CreateStore("replay-transactions"),
+	}
+	defer bus.GetBus().GetStoreManager().DestroyStore("replay-transactions")
+
+	capture := func(config *shared.WiretapConfiguration) string {
+		id, _ := uuid.NewUUID()
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost/pets",
+			strings.NewReader(`{"name": "fido", "password": "b33f"}`))
+		transaction := BuildHttpTransaction(HttpTransactionConfig{OriginalRequest: req, NewRequest: req, ID: &id,
+			TransactionConfig: config})
+		ws.transactionStore.Put(transaction.Id, transaction, nil)
+		return transaction.Id
+	}
+	replay := func(id string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/wiretap/transactions/"+id+"/replay", nil)
+		r.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		ws.handleTransactionReplay(w, r)
+		return w
+	}
+
+	// a truncated body would be replayed as broken JSON.
+	w := replay(capture(&shared.WiretapConfiguration{MaxBodyBytesInReport: 10}))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "truncated from 36 bytes")
+
+	// a redacted body would be replayed with the redacted values.
+	w = replay(capture(&shared.WiretapConfiguration{RedactFields: []string{"password"}}))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "were redacted")
+
+	// bulk replays report them as failed.
+	id := capture(&shared.WiretapConfiguration{MaxBodyBytesInReport: 10})
+	result := ws.replayTransaction(ws.GetTransaction(id), nil)
+	assert.Contains(t, result.Error, "truncated from 36 bytes")
+
+	// redacting fields the body does not have leaves it replayable.
+	id = capture(&shared.WiretapConfiguration{RedactFields: []string{"token"}})
+	transaction := ws.GetTransaction(id)
+	assert.False(t, transaction.Request.BodyRedacted)
+	assert.Empty(t, unreplayableBody(transaction.Request))
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"time"
+)
+
+const AdminStatusPath = "/wiretap/status"
+
+// AdminStatus is returned by the admin status endpoint.
+type AdminStatus struct {
+	Version           string                   `json:"version,omitempty"`
+	RedirectURL       string                   `json:"redirectURL,omitempty"`
+	FailoverUpstreams []*UpstreamStatus        `json:"failoverUpstreams,omitempty"`
+	Cache             *CacheStats              `json:"cache,omitempty"`
+	Validation        *ValidationSamplingStats `json:"validationSampling,omitempty"`
+	Stream            *StreamStats             `json:"stream,omitempty"`
+	ErrorRate         *ErrorRateStats          `json:"errorRate,omitempty"`
+	Connections       *ConnectionStats         `json:"connections,omitempty"`
+}
+
+// RegisterAdminRoutes adds the wiretap admin endpoints to the mux.
+func (ws *WiretapService) RegisterAdminRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+AdminStatusPath, ws.handleAdminStatus)
+	mux.HandleFunc("POST "+AdminCacheInvalidatePath, ws.handleCacheInvalidate)
+	mux.HandleFunc("GET "+AdminCoveragePath, ws.handleCoverage)
+	mux.HandleFunc("POST "+AdminMutationTestPath, ws.handleMutationTest)
+	mux.HandleFunc("POST "+AdminTransactionReplayPath, ws.handleTransactionReplay)
+	mux.HandleFunc("POST "+AdminBulkReplayPath, ws.handleBulkReplay)
+	mux.HandleFunc("POST "+AdminExportCSVPath, ws.handleExportCSV)
+	mux.HandleFunc("POST "+AdminJUnitReportPath, ws.handleJUnitReport)
+	mux.HandleFunc("POST "+AdminHTMLReportPath, ws.handleHTMLReport)
+	mux.HandleFunc("POST "+AdminSpecCompatibilityPath, ws.handleSpecCompatibility)
+}
+
+// Status returns the current status of the service.
+func (ws *WiretapService) Status() *AdminStatus {
+	return &AdminStatus{
+		Version:           ws.config.Version,
+		RedirectURL:       ws.config.RedirectURL,
+		FailoverUpstreams: ws.failoverStats.status(ws.config.FailoverUpstreams),
+		Cache:             ws.responseCache.stats(),
+		Validation:        ws.sampler.stats(),
+		Stream:            ws.streamStats(),
+		ErrorRate:         ws.errorRate.stats(time.Now()),
+		Connections:       ws.connections.stats(),
+	}
+}
+
+func (ws *WiretapService) handleAdminStatus(w http.ResponseWriter, _ *http.Request) {
+	writeAdminResponse(w, http.StatusOK, ws.Status())
+}
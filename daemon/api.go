@@ -4,7 +4,9 @@
 package daemon
 
 import (
+	"bytes"
 	"crypto/tls"
+	"io"
 	"net/http"
 	"net/url"
 
@@ -86,7 +88,29 @@ func (ws *WiretapService) callAPI(req *http.Request) (*http.Response, error) {
 			wiretapConfig.RedirectBasePath,
 			wiretapConfig.RedirectPort))
 	}
-	resp, err := client.Do(req)
+	// buffer the body, so it can be hashed for routing, and the request can be sent again to a failover upstream.
+	var body []byte
+	failover := len(wiretapConfig.FailoverUpstreams) > 0
+	if (failover || wiretapConfig.HashRouting != nil) && req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	// route the request to an upstream selected by the hash of a field in the body.
+	if upstream, key, ok := hashRoutingUpstream(body, wiretapConfig.HashRouting); ok {
+		ws.config.Logger.Info("[wiretap] hash routing request", "url", req.URL.String(),
+			"key", key, "upstream", upstream.URL)
+		req = upstreamRequest(req, body, upstream, wiretapConfig)
+	}
+
+	resp, err := ws.responseCache.do(req, func(req *http.Request) (*http.Response, error) {
+		resp, err := client.Do(req)
+		if failover && shouldFailover(resp, err) {
+			resp, err = ws.callFailoverUpstreams(client, req, body, resp, err, wiretapConfig)
+		}
+		return resp, err
+	})
 
 	if err != nil {
 		return nil, err
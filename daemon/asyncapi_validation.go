@@ -0,0 +1,88 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/asyncapi"
+)
+
+// validateWebsocketMessage validates a text or binary websocket message against the AsyncAPI specification, if one
+// is loaded. Violations are reported for the request that opened the websocket, as request validation errors.
+func (ws *WiretapService) validateWebsocketMessage(request *model.Request, direction asyncapi.Direction,
+	messageType int, message []byte) {
+
+	if ws.config.AsyncAPIDocument == nil ||
+		(messageType != websocket.TextMessage && messageType != websocket.BinaryMessage) {
+		return
+	}
+	validationErrors := ws.config.AsyncAPIDocument.Validate(request.HttpRequest, direction, "WebSocket message",
+		message)
+	if len(validationErrors) == 0 {
+		return
+	}
+	transaction := BuildHttpTransaction(HttpTransactionConfig{
+		OriginalRequest:   request.HttpRequest,
+		NewRequest:        request.HttpRequest,
+		ID:                request.Id,
+		TransactionConfig: ws.config,
+	})
+	ws.sendViolations(validationErrors)
+	ws.broadcastRequestValidationErrors(request, validationErrors, transaction)
+}
+
+// validateEventStream validates the data of each event of a server-sent event stream against the AsyncAPI
+// specification, if one is loaded and the response is an event stream.
+func (ws *WiretapService) validateEventStream(request *http.Request,
+	response *http.Response) []*errors.ValidationError {
+
+	if ws.config.AsyncAPIDocument == nil || request == nil || response == nil {
+		return nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		return nil
+	}
+	var validationErrors []*errors.ValidationError
+	for _, data := range eventStreamData(readBody(&response.Body)) {
+		validationErrors = append(validationErrors,
+			ws.config.AsyncAPIDocument.Validate(request, asyncapi.Sent, "SSE event", data)...)
+	}
+	return validationErrors
+}
+
+// eventStreamData returns the data of each event of an event stream. The data lines of an event are joined with
+// newlines, comments and events without data are skipped.
+func eventStreamData(body []byte) [][]byte {
+	var events [][]byte
+	var data []string
+	dispatch := func() {
+		if data != nil {
+			events = append(events, []byte(strings.Join(data, "\n")))
+		}
+		data = nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			dispatch()
+		case line == "data":
+			data = append(data, "")
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	dispatch()
+	return events
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/wiretap/asyncapi"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStreamData(t *testing.T) {
+	body := ": a comment\n\nevent: tick\ndata: {\"count\":\ndata: 1}\n\nid: 2\n\ndata\r\n\ndata:last"
+	var events []string
+	for _, data := range eventStreamData([]byte(body)) {
+		events = append(events, string(data))
+	}
+	assert.Equal(t, []string{"{\"count\":\n1}", "", "last"}, events)
+}
+
+func TestWiretapService_ValidateEventStream(t *testing.T) {
+	doc, err := asyncapi.LoadDocument([]byte(`asyncapi: 2.6.0
+channels:
+  /ticks:
+    subscribe:
+      message:
+        name: Tick
+        payload:
+          type: object
+          required: [count]
+`))
+	require.NoError(t, err)
+	ws := &WiretapService{config: &shared.WiretapConfiguration{AsyncAPIDocument: doc}}
+
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/ticks", nil)
+	response := &http.Response{
+		Header: http.Header{"Content-Type": []string{"text/event-stream; charset=utf-8"}},
+		Body:   io.NopCloser(strings.NewReader("data: {\"count\":1}\n\ndata: {\"total\":2}\n\n")),
+	}
+	errs := ws.validateEventStream(request, response)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "SSE event on channel '/ticks' failed to validate message 'Tick'", errs[0].Message)
+
+	// the body is left for the transaction.
+	body, _ := io.ReadAll(response.Body)
+	assert.Contains(t, string(body), "total")
+
+	// other responses are not events.
+	response.Header.Set("Content-Type", "application/json")
+	assert.Empty(t, ws.validateEventStream(request, response))
+}
@@ -0,0 +1,290 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+const (
+	// DefaultAuditLogFile is used when audit logging is enabled without a file.
+	DefaultAuditLogFile = "wiretap-audit.log"
+
+	// auditLogBufferSize is the number of records that can be queued before new records are dropped.
+	auditLogBufferSize = 1024
+)
+
+// AuditRecord is a single entry in the audit log, one is written for every request handled by wiretap.
+type AuditRecord struct {
+	Timestamp        time.Time `json:"timestamp"`
+	ClientIP         string    `json:"clientIp,omitempty"`
+	Method           string    `json:"method"`
+	Path             string    `json:"path"`
+	Status           int       `json:"status"`
+	LatencyMs        int64     `json:"latencyMs"`
+	User             string    `json:"user,omitempty"`
+	ValidationPassed *bool     `json:"validationPassed,omitempty"` // not set when the request was not validated.
+}
+
+// auditResponseWriter records the status code written to the client.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// auditTracker follows a single request through the service, so an audit record can be written once the response
+// has been sent and any validation has completed. A nil tracker is valid and does nothing, which is what is used
+// when audit logging is disabled.
+type auditTracker struct {
+	ws        *WiretapService
+	start     time.Time
+	request   *http.Request
+	writer    *auditResponseWriter
+	wg        sync.WaitGroup
+	validated atomic.Bool
+	failed    atomic.Bool
+}
+
+// startAuditLog opens the audit log and starts the goroutine that writes records to it.
+func (ws *WiretapService) startAuditLog() {
+	auditConfig := ws.config.AuditLog
+	if auditConfig == nil || !auditConfig.Enabled {
+		return
+	}
+
+	file := auditConfig.File
+	if file == "" {
+		file = DefaultAuditLogFile
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		pterm.Error.Println("cannot open audit log: " + err.Error())
+		return
+	}
+
+	ws.auditChan = make(chan *AuditRecord, auditLogBufferSize)
+	go func() {
+		defer f.Close()
+		for record := range ws.auditChan {
+			if _, e := f.WriteString(formatAuditRecord(record, auditConfig.Format, ws.config.Version) + "\n"); e != nil {
+				pterm.Error.Println("cannot write audit record: " + e.Error())
+			}
+		}
+	}()
+}
+
+// trackAudit starts tracking a request for the audit log, wrapping the response writer to capture the status.
+func (ws *WiretapService) trackAudit(request *model.Request) *auditTracker {
+	if ws.auditChan == nil {
+		return nil
+	}
+	at := &auditTracker{
+		ws:      ws,
+		start:   time.Now(),
+		request: request.HttpRequest,
+		writer:  &auditResponseWriter{ResponseWriter: request.HttpResponseWriter},
+	}
+	request.HttpResponseWriter = at.writer
+	return at
+}
+
+func (at *auditTracker) recordValidation(validationErrors []*errors.ValidationError) {
+	at.validated.Store(true)
+	if len(validationErrors) > 0 {
+		at.failed.Store(true)
+	}
+}
+
+// validate runs a validation synchronously and records the result.
+func (at *auditTracker) validate(validation func() []*errors.ValidationError) []*errors.ValidationError {
+	validationErrors := validation()
+	if at != nil {
+		at.recordValidation(validationErrors)
+	}
+	return validationErrors
+}
+
+// validateAsync runs a validation in the background and records the result.
+func (at *auditTracker) validateAsync(validation func() []*errors.ValidationError) {
+	at.begin()
+	go func() {
+		at.end(validation())
+	}()
+}
+
+// begin counts a background validation, the record waits for it to end.
+func (at *auditTracker) begin() {
+	if at != nil {
+		at.wg.Add(1)
+	}
+}
+
+// end records the result of a background validation.
+func (at *auditTracker) end(validationErrors []*errors.ValidationError) {
+	if at != nil {
+		at.recordValidation(validationErrors)
+		at.wg.Done()
+	}
+}
+
+// finish queues the audit record, once any background validation has completed. Records are dropped rather than
+// blocking if the audit log cannot keep up.
+func (at *auditTracker) finish() {
+	if at == nil {
+		return
+	}
+	latency := time.Since(at.start)
+	go func() {
+		at.wg.Wait()
+		record := &AuditRecord{
+			Timestamp: at.start.UTC(),
+			ClientIP:  clientIP(at.request),
+			Method:    at.request.Method,
+			Path:      at.request.URL.Path,
+			Status:    at.writer.status,
+			LatencyMs: latency.Milliseconds(),
+			User:      userIdentity(at.request),
+		}
+		if at.validated.Load() {
+			passed := !at.failed.Load()
+			record.ValidationPassed = &passed
+		}
+		select {
+		case at.ws.auditChan <- record:
+		default:
+			at.ws.config.Logger.Warn("[wiretap] audit log buffer is full, dropping record", "path", record.Path)
+		}
+	}()
+}
+
+// clientIP returns the address of the client, preferring the first address in X-Forwarded-For.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// userIdentity extracts who made the request. The JWT subject is used for bearer tokens, the user name for basic
+// auth, and a masked value for API keys. The token is not verified, the identity is for auditing only.
+func userIdentity(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		if subject := jwtSubject(token); subject != "" {
+			return subject
+		}
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		return "apikey:" + maskSecret(apiKey)
+	}
+	return ""
+}
+
+// jwtSubject returns the 'sub' claim of a JWT, without verifying the signature.
+func jwtSubject(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if json.Unmarshal(payload, &claims) != nil {
+		return ""
+	}
+	return claims.Subject
+}
+
+// maskSecret keeps only the last four characters of a secret.
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+// formatAuditRecord renders a record as a single line, in JSON, CEF or LEEF. JSON is used for unknown formats.
+func formatAuditRecord(record *AuditRecord, format, version string) string {
+	validation := "skipped"
+	if record.ValidationPassed != nil {
+		validation = "failed"
+		if *record.ValidationPassed {
+			validation = "passed"
+		}
+	}
+	if version == "" {
+		version = "dev"
+	}
+
+	switch strings.ToLower(format) {
+	case shared.AuditLogFormatCEF:
+		severity := 3
+		if record.ValidationPassed != nil && !*record.ValidationPassed {
+			severity = 6
+		}
+		return fmt.Sprintf("CEF:0|pb33f|wiretap|%s|http-transaction|HTTP Transaction|%d|"+
+			"rt=%d src=%s requestMethod=%s request=%s outcome=%d suser=%s "+
+			"cn1=%d cn1Label=latencyMs cs1=%s cs1Label=validation",
+			cefHeaderEscape(version), severity, record.Timestamp.UnixMilli(), cefEscape(record.ClientIP),
+			cefEscape(record.Method), cefEscape(record.Path), record.Status, cefEscape(record.User),
+			record.LatencyMs, validation)
+	case shared.AuditLogFormatLEEF:
+		return fmt.Sprintf("LEEF:1.0|pb33f|wiretap|%s|http-transaction|"+
+			"devTime=%s\tsrc=%s\trequestMethod=%s\turl=%s\tstatus=%d\tusrName=%s\tlatencyMs=%d\tvalidation=%s",
+			cefHeaderEscape(version), record.Timestamp.Format(time.RFC3339), leefEscape(record.ClientIP),
+			leefEscape(record.Method), leefEscape(record.Path), record.Status, leefEscape(record.User),
+			record.LatencyMs, validation)
+	default:
+		b, _ := json.Marshal(record)
+		return string(b)
+	}
+}
+
+var (
+	cefHeaderReplacer = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefReplacer       = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+	leefReplacer      = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+)
+
+func cefHeaderEscape(s string) string { return cefHeaderReplacer.Replace(s) }
+func cefEscape(s string) string       { return cefReplacer.Replace(s) }
+func leefEscape(s string) string      { return leefReplacer.Replace(s) }
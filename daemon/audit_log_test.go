@@ -0,0 +1,100 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserIdentity(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"dave@pb33f.io"}`))
+	r, _ := http.NewRequest("GET", "http://localhost/", nil)
+	r.Header.Set("Authorization", "Bearer header."+payload+".signature")
+	assert.Equal(t, "dave@pb33f.io", userIdentity(r))
+
+	r, _ = http.NewRequest("GET", "http://localhost/", nil)
+	r.SetBasicAuth("quobix", "secret")
+	assert.Equal(t, "quobix", userIdentity(r))
+
+	r, _ = http.NewRequest("GET", "http://localhost/", nil)
+	r.Header.Set("X-API-Key", "abcdef123456")
+	assert.Equal(t, "apikey:****3456", userIdentity(r))
+
+	r, _ = http.NewRequest("GET", "http://localhost/", nil)
+	assert.Equal(t, "", userIdentity(r))
+}
+
+func TestFormatAuditRecord(t *testing.T) {
+	passed := false
+	record := &AuditRecord{
+		Timestamp:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		ClientIP:         "10.0.0.1",
+		Method:           "POST",
+		Path:             "/pets=1",
+		Status:           201,
+		LatencyMs:        12,
+		User:             "dave",
+		ValidationPassed: &passed,
+	}
+
+	assert.JSONEq(t, `{"timestamp":"2024-01-02T03:04:05Z","clientIp":"10.0.0.1","method":"POST","path":"/pets=1",
+		"status":201,"latencyMs":12,"user":"dave","validationPassed":false}`, formatAuditRecord(record, "json", "1.0"))
+
+	cef := formatAuditRecord(record, "CEF", "1.0")
+	assert.True(t, strings.HasPrefix(cef, "CEF:0|pb33f|wiretap|1.0|http-transaction|HTTP Transaction|6|"))
+	assert.Contains(t, cef, `request=/pets\=1`)
+	assert.Contains(t, cef, "cs1=failed")
+
+	leef := formatAuditRecord(record, "leef", "1.0")
+	assert.True(t, strings.HasPrefix(leef, "LEEF:1.0|pb33f|wiretap|1.0|http-transaction|"))
+	assert.Contains(t, leef, "\tstatus=201\t")
+}
+
+func TestAuditTracker(t *testing.T) {
+	ws := &WiretapService{
+		config:    &shared.WiretapConfiguration{Logger: slog.New(slog.NewTextHandler(os.Stderr, nil))},
+		auditChan: make(chan *AuditRecord, 1),
+	}
+	id, _ := uuid.NewUUID()
+	request := &model.Request{
+		Id:                 &id,
+		HttpRequest:        httptest.NewRequest("GET", "http://localhost/pets", nil),
+		HttpResponseWriter: httptest.NewRecorder(),
+	}
+
+	audit := ws.trackAudit(request)
+	audit.validateAsync(func() []*errors.ValidationError {
+		return []*errors.ValidationError{{Message: "nope"}}
+	})
+	request.HttpResponseWriter.WriteHeader(http.StatusTeapot)
+	audit.finish()
+
+	select {
+	case record := <-ws.auditChan:
+		assert.Equal(t, http.StatusTeapot, record.Status)
+		assert.Equal(t, "/pets", record.Path)
+		assert.False(t, *record.ValidationPassed)
+	case <-time.After(time.Second):
+		t.Fatal("no audit record written")
+	}
+
+	// a nil tracker still runs validation.
+	var disabled *auditTracker
+	assert.Len(t, disabled.validate(func() []*errors.ValidationError {
+		return []*errors.ValidationError{{}}
+	}), 1)
+}
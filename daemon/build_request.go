@@ -4,6 +4,8 @@
 package daemon
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +13,7 @@ import (
 	"net/url"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/pb33f/wiretap/config"
@@ -72,6 +75,7 @@ func BuildHttpTransaction(build HttpTransactionConfig) *HttpTransaction {
 		DropHeaders:   dropHeaders,
 		Auth:          auth,
 		InjectHeaders: injectHeaders,
+		HopByHop:      cf.CustomHopByHopHeaders,
 	})
 
 	var requestBody []byte
@@ -148,8 +152,13 @@ func BuildHttpTransaction(build HttpTransactionConfig) *HttpTransaction {
 		}
 	}
 
+	body := shared.RedactJSON(string(requestBody), cf.RedactFields)
+	hash := HashBody(body)
+	captured, truncated := TruncateBody(body, cf.MaxBodyBytesInReport)
+
 	return &HttpTransaction{
-		Id: build.ID.String(),
+		Id:              build.ID.String(),
+		RequestBodyHash: hash,
 		Request: &HttpRequest{
 			URL:             newUrl.String(),
 			Method:          build.NewRequest.Method,
@@ -161,7 +170,10 @@ func BuildHttpTransaction(build HttpTransactionConfig) *HttpTransaction {
 			OriginalPath:    build.NewRequest.URL.Path,
 			Cookies:         cookies,
 			Headers:         headers,
-			Body:            string(requestBody),
+			Body:            captured,
+			BodyLength:      len(body),
+			BodyTruncated:   truncated,
+			BodyRedacted:    body != string(requestBody),
 			Timestamp:       time.Now().UnixMilli(),
 		},
 	}
@@ -206,3 +218,25 @@ func ReplaceWithVariables(variables map[string]*shared.CompiledVariable, input s
 	}
 	return input
 }
+
+// TruncateBody cuts a captured body down to the limit in bytes, without splitting a UTF-8 character, and returns
+// true if the body was cut. A limit of 0 (or less) keeps the whole body.
+func TruncateBody(body string, limit int) (string, bool) {
+	if limit <= 0 || len(body) <= limit {
+		return body, false
+	}
+	for limit > 0 && !utf8.RuneStart(body[limit]) {
+		limit--
+	}
+	return body[:limit], true
+}
+
+// HashBody returns the hex encoded SHA-256 hash of a captured body, or an empty string if there is no body.
+// The hash is taken over the body after any redaction, and before the body is truncated.
+func HashBody(body string) string {
+	if body == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
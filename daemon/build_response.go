@@ -6,6 +6,7 @@ package daemon
 import (
 	"bytes"
 	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
 	"io"
 	"net/http"
 	"time"
@@ -32,7 +33,16 @@ func CloneExistingResponse(r *http.Response) *http.Response {
 	return resp
 }
 
-func BuildResponse(r *model.Request, response *http.Response) *HttpTransaction {
+// BuildResponse captures a response as a transaction. Any fields in the configured redaction list are redacted
+// from the captured body, and the body is truncated to the configured limit. The response itself is left untouched.
+func BuildResponse(r *model.Request, response *http.Response, config *shared.WiretapConfiguration) *HttpTransaction {
+	var redactFields []string
+	limit := 0
+	if config != nil {
+		redactFields = config.RedactFields
+		limit = config.MaxBodyBytesInReport
+	}
+
 	code := 500
 	headers := make(map[string]any)
 	cookies := make(map[string]*HttpCookie)
@@ -63,14 +73,21 @@ func BuildResponse(r *model.Request, response *http.Response) *HttpTransaction {
 			response.Body = io.NopCloser(bytes.NewBuffer(respBody))
 		}
 	}
+	body := shared.RedactJSON(string(respBody), redactFields)
+	captured, truncated := TruncateBody(body, limit)
+
 	return &HttpTransaction{
-		Id: r.Id.String(),
+		Id:               r.Id.String(),
+		ResponseBodyHash: HashBody(body),
 		Response: &HttpResponse{
-			Timestamp:  time.Now().UnixMilli(),
-			Headers:    headers,
-			StatusCode: code,
-			Body:       string(respBody),
-			Cookies:    cookies,
+			Timestamp:     time.Now().UnixMilli(),
+			Headers:       headers,
+			StatusCode:    code,
+			Body:          captured,
+			BodyLength:    len(body),
+			BodyTruncated: truncated,
+			BodyRedacted:  body != string(respBody),
+			Cookies:       cookies,
 		},
 	}
 }
@@ -14,6 +14,19 @@ import (
 	"github.com/pb33f/wiretap/shared"
 )
 
+// hopByHopHeaders only apply to a single connection, and are never forwarded (RFC 2616, section 13.5.1).
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"TE",
+	"Trailers",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
 type CloneRequest struct {
 	Request       *http.Request
 	Protocol      string
@@ -25,6 +38,8 @@ type CloneRequest struct {
 	InjectHeaders map[string]string
 	Auth          string
 	Variables     map[string]*shared.CompiledVariable
+	Forwarding    *ClientForwarding
+	HopByHop      []string // additional hop-by-hop headers to strip.
 }
 
 func CloneExistingRequest(request CloneRequest) *http.Request {
@@ -46,9 +61,10 @@ func CloneExistingRequest(request CloneRequest) *http.Request {
 		return nil
 	}
 
-	// copy headers, drop those that are specified.
+	// copy headers, drop those that are specified, and the hop-by-hop headers.
+	hopByHop := stripHeaders(request.Request.Header, request.HopByHop)
 	for k, v := range request.Request.Header {
-		skip := false
+		skip := hopByHop[http.CanonicalHeaderKey(k)]
 		for h := range request.DropHeaders {
 			if strings.EqualFold(request.DropHeaders[h], k) {
 				skip = true
@@ -59,6 +75,11 @@ func CloneExistingRequest(request CloneRequest) *http.Request {
 		}
 	}
 
+	// tell the upstream who the client is.
+	if request.Forwarding != nil {
+		request.Forwarding.forward(request.Request, newReq)
+	}
+
 	// inject headers
 	for k, v := range request.InjectHeaders {
 		newReq.Header.Set(k, ReplaceWithVariables(request.Variables, v))
@@ -73,3 +94,23 @@ func CloneExistingRequest(request CloneRequest) *http.Request {
 
 	return newReq
 }
+
+// stripHeaders returns the canonical names of the hop-by-hop headers of a request: the standard ones, the
+// additional ones, and those the Connection header names.
+func stripHeaders(headers http.Header, additional []string) map[string]bool {
+	strip := make(map[string]bool, len(hopByHopHeaders)+len(additional))
+	for _, header := range hopByHopHeaders {
+		strip[http.CanonicalHeaderKey(header)] = true
+	}
+	for _, header := range additional {
+		strip[http.CanonicalHeaderKey(header)] = true
+	}
+	for _, value := range headers.Values("Connection") {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				strip[http.CanonicalHeaderKey(header)] = true
+			}
+		}
+	}
+	return strip
+}
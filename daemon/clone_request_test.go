@@ -0,0 +1,28 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneExistingRequest_HopByHop(t *testing.T) {
+	original := httptest.NewRequest(http.MethodGet, "http://pb33f.io/pets", nil)
+	original.Header.Set("Connection", "keep-alive, X-Session-Hop")
+	original.Header.Set("Keep-Alive", "timeout=5")
+	original.Header.Set("Proxy-Authorization", "Basic cGIzM2Y=")
+	original.Header.Set("Te", "trailers")
+	original.Header.Set("Upgrade", "h2c")
+	original.Header.Set("X-Session-Hop", "1")
+	original.Header.Set("X-Internal-Hop", "2")
+	original.Header.Set("Accept", "application/json")
+
+	clone := CloneExistingRequest(CloneRequest{Request: original, Protocol: "http", Host: "localhost",
+		HopByHop: []string{"x-internal-hop"}})
+	assert.Equal(t, http.Header{"Accept": []string{"application/json"}}, clone.Header)
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/netutil"
+)
+
+// ConnectionRetryAfterSeconds is sent in the Retry-After header, when a connection is rejected.
+const ConnectionRetryAfterSeconds = 1
+
+// ConnectionStats are the proxy connection counts, returned by the admin status endpoint.
+type ConnectionStats struct {
+	Current  int64 `json:"current"`
+	Peak     int64 `json:"peak"`
+	Max      int64 `json:"max"`
+	Rejected int64 `json:"rejected"`
+}
+
+// connectionLimiter counts the connections served by the proxy. A nil limiter is valid and limits nothing, which
+// is what is used when no limit is configured.
+type connectionLimiter struct {
+	max      int64
+	current  atomic.Int64
+	peak     atomic.Int64
+	rejected atomic.Int64
+}
+
+// newConnectionLimiter returns nil when the limit is 0 (not configured).
+func newConnectionLimiter(max int) *connectionLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &connectionLimiter{max: int64(max)}
+}
+
+// open counts a new connection, and returns false when it is over the limit and must be rejected.
+func (cl *connectionLimiter) open() bool {
+	for {
+		current := cl.current.Load()
+		if current >= cl.max {
+			cl.rejected.Add(1)
+			return false
+		}
+		if cl.current.CompareAndSwap(current, current+1) {
+			for peak := cl.peak.Load(); current+1 > peak; peak = cl.peak.Load() {
+				if cl.peak.CompareAndSwap(peak, current+1) {
+					break
+				}
+			}
+			return true
+		}
+	}
+}
+
+func (cl *connectionLimiter) stats() *ConnectionStats {
+	if cl == nil {
+		return nil
+	}
+	return &ConnectionStats{
+		Current:  cl.current.Load(),
+		Peak:     cl.peak.Load(),
+		Max:      cl.max,
+		Rejected: cl.rejected.Load(),
+	}
+}
+
+// limitedConn is a connection accepted by a limitedListener, rejected connections are only served a 503.
+type limitedConn struct {
+	net.Conn
+	limiter  *connectionLimiter
+	rejected bool
+	once     sync.Once
+}
+
+func (lc *limitedConn) Close() error {
+	lc.once.Do(func() {
+		if !lc.rejected {
+			lc.limiter.current.Add(-1)
+		}
+	})
+	return lc.Conn.Close()
+}
+
+// limitedListener accepts every connection, and marks the connections over the limit as rejected.
+type limitedListener struct {
+	net.Listener
+	limiter *connectionLimiter
+}
+
+func (ll *limitedListener) Accept() (net.Conn, error) {
+	c, err := ll.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &limitedConn{Conn: c, limiter: ll.limiter, rejected: !ll.limiter.open()}, nil
+}
+
+// rejectedConnectionKey marks the requests of a rejected connection, in their context.
+type rejectedConnectionKey struct{}
+
+// ConfigureConnectionLimit limits the number of connections the proxy serves at once. Connections over the limit
+// are still accepted, so they can be sent a 503 with a Retry-After header and closed, rather than left waiting.
+// Rejected connections are only open for as long as it takes to reply, and the listener is capped at twice the
+// limit, so the connections open at once are always bounded. The limit is shared by all the listeners. It must be
+// called before the server starts, and the server must serve the returned listeners.
+func (ws *WiretapService) ConfigureConnectionLimit(server *http.Server, listeners []net.Listener) []net.Listener {
+	limiter := ws.connections
+	if limiter == nil {
+		return listeners
+	}
+
+	connContext := server.ConnContext
+	server.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		if connContext != nil {
+			ctx = connContext(ctx, c)
+		}
+		if tc, ok := c.(*tls.Conn); ok {
+			c = tc.NetConn()
+		}
+		if lc, ok := c.(*limitedConn); ok && lc.rejected {
+			ctx = context.WithValue(ctx, rejectedConnectionKey{}, true)
+		}
+		return ctx
+	}
+	next := server.Handler
+	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rejected, _ := r.Context().Value(rejectedConnectionKey{}).(bool); rejected {
+			w.Header().Set("Retry-After", strconv.Itoa(ConnectionRetryAfterSeconds))
+			w.Header().Set("Connection", "close")
+			http.Error(w, "too many concurrent connections", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+	limited := make([]net.Listener, len(listeners))
+	for i, ln := range listeners {
+		limited[i] = &limitedListener{Listener: netutil.LimitListener(ln, int(limiter.max)*2), limiter: limiter}
+	}
+	return limited
+}
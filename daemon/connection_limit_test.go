@@ -0,0 +1,53 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionLimiter_Nil(t *testing.T) {
+	assert.Nil(t, newConnectionLimiter(0))
+	ws := &WiretapService{}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	assert.Equal(t, []net.Listener{ln}, ws.ConfigureConnectionLimit(&http.Server{}, []net.Listener{ln}))
+	assert.Nil(t, ws.connections.stats())
+}
+
+func TestWiretapService_ConfigureConnectionLimit(t *testing.T) {
+	ws := &WiretapService{connections: newConnectionLimiter(1)}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(ws.ConfigureConnectionLimit(server, []net.Listener{ln})[0]) }()
+	defer server.Close()
+	url := "http://" + ln.Addr().String()
+
+	// the first client keeps its connection open.
+	first := &http.Client{Transport: &http.Transport{}}
+	resp, err := first.Get(url)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	second := &http.Client{Transport: &http.Transport{}}
+	resp, err = second.Get(url)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	assert.True(t, resp.Close)
+
+	stats := ws.connections.stats()
+	assert.Equal(t, &ConnectionStats{Current: 1, Peak: 1, Max: 1, Rejected: 1}, stats)
+}
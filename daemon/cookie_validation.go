@@ -0,0 +1,133 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+func cookieError(request *http.Request, param *v3.Parameter, message, reason, howToFix string) *errors.ValidationError {
+	ve := &errors.ValidationError{
+		ValidationType:    helpers.ParameterValidation,
+		ValidationSubType: helpers.ParameterValidationCookie,
+		Message:           message,
+		Reason:            reason,
+		HowToFix:          howToFix,
+		RequestPath:       request.URL.Path,
+		RequestMethod:     request.Method,
+		Context:           param,
+	}
+	if low := param.GoLow(); low != nil && low.Name.KeyNode != nil {
+		ve.SpecLine = low.Name.KeyNode.Line
+		ve.SpecCol = low.Name.KeyNode.Column
+	}
+	return ve
+}
+
+// validateCookies checks the cookie parameters of the operation: required cookies must be sent, cookie names are
+// case-sensitive, and string values must match the length, pattern and format of their schema. Numbers, booleans
+// and enums are already checked by the validator. Requests that match no operation are not checked.
+func validateCookies(doc *v3.Document, request *http.Request) []*errors.ValidationError {
+	if doc == nil || request == nil {
+		return nil
+	}
+	pathItem, errs, _ := paths.FindPath(request, doc)
+	if len(errs) > 0 || pathItem == nil {
+		return nil
+	}
+	operation := pathItem.GetOperations().GetOrZero(strings.ToLower(request.Method))
+	if operation == nil {
+		return nil
+	}
+
+	cookies := request.Cookies()
+	var validationErrors []*errors.ValidationError
+	for _, param := range operationParameters(pathItem, operation, helpers.Cookie) {
+		var cookie, folded *http.Cookie
+		for _, c := range cookies {
+			if c.Name == param.Name {
+				cookie = c
+				break
+			}
+			if folded == nil && strings.EqualFold(c.Name, param.Name) {
+				folded = c
+			}
+		}
+
+		switch {
+		case cookie == nil && folded != nil:
+			validationErrors = append(validationErrors, cookieError(request, param,
+				fmt.Sprintf("Cookie parameter '%s' was sent as '%s'", param.Name, folded.Name),
+				fmt.Sprintf("The cookie '%s' is defined as '%s', cookie names are case-sensitive",
+					folded.Name, param.Name),
+				fmt.Sprintf("rename the cookie to '%s'", param.Name)))
+		case cookie == nil && param.Required != nil && *param.Required:
+			validationErrors = append(validationErrors, cookieError(request, param,
+				fmt.Sprintf("Cookie parameter '%s' is missing", param.Name),
+				fmt.Sprintf("The cookie parameter '%s' is defined as being required, "+
+					"however it's missing from the request", param.Name),
+				errors.HowToFixMissingValue))
+		case cookie != nil && param.Schema != nil:
+			if ve := validateCookieString(request, param, param.Schema.Schema(), cookie.Value); ve != nil {
+				validationErrors = append(validationErrors, ve)
+			}
+		}
+	}
+	return validationErrors
+}
+
+// validateCookieString checks a cookie value against the length, pattern and format of a string schema.
+func validateCookieString(request *http.Request, param *v3.Parameter, schema *base.Schema,
+	value string) *errors.ValidationError {
+
+	if schema == nil || len(schema.Type) != 1 || schema.Type[0] != helpers.String {
+		return nil
+	}
+	invalid := func(reason string) *errors.ValidationError {
+		return cookieError(request, param,
+			fmt.Sprintf("Cookie parameter '%s' is not a valid value", param.Name),
+			fmt.Sprintf("The cookie parameter '%s' has the value '%s', %s", param.Name, value, reason),
+			errors.HowToFixInvalidSchema)
+	}
+
+	length := int64(utf8.RuneCountInString(value))
+	if schema.MinLength != nil && length < *schema.MinLength {
+		return invalid(fmt.Sprintf("which is shorter than the minimum length of %d", *schema.MinLength))
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		return invalid(fmt.Sprintf("which is longer than the maximum length of %d", *schema.MaxLength))
+	}
+	if schema.Pattern != "" {
+		// patterns Go cannot compile are left to the schema, rather than failing every request.
+		if pattern, err := regexp.Compile(schema.Pattern); err == nil && !pattern.MatchString(value) {
+			return invalid(fmt.Sprintf("which does not match the pattern '%s'", schema.Pattern))
+		}
+	}
+
+	var formatErr error
+	switch schema.Format {
+	case "uuid":
+		_, formatErr = uuid.Parse(value)
+	case "date":
+		_, formatErr = time.Parse(time.DateOnly, value)
+	case "date-time":
+		_, formatErr = time.Parse(time.RFC3339, value)
+	}
+	if formatErr != nil {
+		return invalid(fmt.Sprintf("which is not a valid '%s'", schema.Format))
+	}
+	return nil
+}
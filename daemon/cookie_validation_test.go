@@ -0,0 +1,69 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var cookieSpec = []byte(`openapi: 3.1.0
+paths:
+  /pets:
+    parameters:
+      - name: sessionId
+        in: cookie
+        required: true
+        schema:
+          type: string
+          format: uuid
+    get:
+      parameters:
+        - name: theme
+          in: cookie
+          schema:
+            type: string
+            pattern: ^(light|dark)$
+        - name: nickname
+          in: cookie
+          schema:
+            type: string
+            maxLength: 5
+      responses:
+        "200":
+          description: ok
+`)
+
+func TestValidateCookies(t *testing.T) {
+	doc, err := libopenapi.NewDocument(cookieSpec)
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+
+	validate := func(cookie string) []string {
+		request, _ := http.NewRequest(http.MethodGet, "http://localhost/pets", nil)
+		if cookie != "" {
+			request.Header.Set("Cookie", cookie)
+		}
+		var messages []string
+		for _, ve := range validateCookies(&m.Model, request) {
+			messages = append(messages, ve.Message)
+		}
+		return messages
+	}
+
+	assert.Empty(t, validate("sessionId=6ba7b810-9dad-11d1-80b4-00c04fd430c8; theme=dark; nickname=beef"))
+	assert.Equal(t, []string{"Cookie parameter 'sessionId' is missing"}, validate(""))
+	assert.Equal(t, []string{"Cookie parameter 'sessionId' was sent as 'SessionId'"},
+		validate("SessionId=6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+	assert.Equal(t, []string{
+		"Cookie parameter 'theme' is not a valid value",
+		"Cookie parameter 'nickname' is not a valid value",
+		"Cookie parameter 'sessionId' is not a valid value",
+	}, validate("sessionId=pizza; theme=blue; nickname=princess"))
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+const (
+	AdminCoveragePath  = "/wiretap/coverage"
+	CoverageFormatJSON = "json"
+	CoverageFormatHTML = "html"
+)
+
+//go:embed templates/coverage-report.html
+var coverageTemplate string
+
+var coverageReportTemplate = template.Must(template.New("coverage").Parse(coverageTemplate))
+
+// OperationCoverage is a single operation of the specification, and the number of requests it received.
+type OperationCoverage struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	OperationId string `json:"operationId,omitempty"`
+	Requests    int64  `json:"requests"`
+}
+
+// CoverageReport describes which operations of the specification received at least one request this session.
+type CoverageReport struct {
+	Total        int                  `json:"total"`
+	CoveredCount int                  `json:"coveredCount"`
+	Percentage   float64              `json:"percentage"`
+	Covered      []*OperationCoverage `json:"covered"`
+	Uncovered    []*OperationCoverage `json:"uncovered"`
+}
+
+// coverageTracker counts requests per operation, keyed by method and path template.
+type coverageTracker struct {
+	lock   sync.Mutex
+	counts map[string]int64
+}
+
+func coverageKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// record counts a request against the operation of the specification it matches, if any.
+func (ct *coverageTracker) record(doc *v3.Document, request *http.Request) {
+	if doc == nil || request == nil {
+		return
+	}
+	pathItem, errs, path := paths.FindPath(request, doc)
+	if len(errs) > 0 || pathItem == nil {
+		return
+	}
+	if pathItem.GetOperations().GetOrZero(strings.ToLower(request.Method)) == nil {
+		return
+	}
+	ct.lock.Lock()
+	defer ct.lock.Unlock()
+	if ct.counts == nil {
+		ct.counts = make(map[string]int64)
+	}
+	ct.counts[coverageKey(request.Method, path)]++
+}
+
+// report builds the coverage of every operation in the specification, in the order they are defined.
+func (ct *coverageTracker) report(doc *v3.Document) *CoverageReport {
+	report := &CoverageReport{
+		Covered:   []*OperationCoverage{},
+		Uncovered: []*OperationCoverage{},
+	}
+	if doc == nil || doc.Paths == nil || doc.Paths.PathItems == nil {
+		return report
+	}
+
+	ct.lock.Lock()
+	defer ct.lock.Unlock()
+	for path, pathItem := range doc.Paths.PathItems.FromOldest() {
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			oc := &OperationCoverage{
+				Method:      strings.ToUpper(method),
+				Path:        path,
+				OperationId: operation.OperationId,
+				Requests:    ct.counts[coverageKey(method, path)],
+			}
+			report.Total++
+			if oc.Requests > 0 {
+				report.CoveredCount++
+				report.Covered = append(report.Covered, oc)
+			} else {
+				report.Uncovered = append(report.Uncovered, oc)
+			}
+		}
+	}
+	if report.Total > 0 {
+		report.Percentage = float64(report.CoveredCount) / float64(report.Total) * 100
+	}
+	return report
+}
+
+// Coverage returns the OpenAPI coverage of the traffic seen this session.
+func (ws *WiretapService) Coverage() *CoverageReport {
+	return ws.coverage.report(ws.loadedDocModel())
+}
+
+// handleCoverage serves the coverage report as JSON, or as an HTML page with ?format=html.
+func (ws *WiretapService) handleCoverage(w http.ResponseWriter, r *http.Request) {
+	report := ws.Coverage()
+	switch format := r.URL.Query().Get("format"); format {
+	case "", CoverageFormatJSON:
+		writeAdminResponse(w, http.StatusOK, report)
+	case CoverageFormatHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_ = coverageReportTemplate.Execute(w, report)
+	default:
+		writeAdminError(w, http.StatusBadRequest, "Unknown coverage format",
+			"the format must be '"+CoverageFormatJSON+"' or '"+CoverageFormatHTML+"', not '"+format+"'", r.URL.Path)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var coverageSpec = []byte(`openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        "200":
+          description: ok
+    post:
+      operationId: createPet
+      responses:
+        "201":
+          description: created
+  /pets/{id}:
+    get:
+      operationId: getPet
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+`)
+
+func TestCoverageReport(t *testing.T) {
+	doc, err := libopenapi.NewDocument(coverageSpec)
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+
+	ws := &WiretapService{}
+	ws.spec.Store(&loadedSpec{docModel: &m.Model})
+	ws.coverage.record(ws.loadedDocModel(), httptest.NewRequest(http.MethodGet, "/pets", nil))
+	ws.coverage.record(ws.loadedDocModel(), httptest.NewRequest(http.MethodGet, "/pets/1", nil))
+	ws.coverage.record(ws.loadedDocModel(), httptest.NewRequest(http.MethodGet, "/pets/2", nil))
+	// unknown paths and methods are not counted.
+	ws.coverage.record(ws.loadedDocModel(), httptest.NewRequest(http.MethodGet, "/owners", nil))
+	ws.coverage.record(ws.loadedDocModel(), httptest.NewRequest(http.MethodDelete, "/pets", nil))
+
+	report := ws.Coverage()
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 2, report.CoveredCount)
+	assert.InDelta(t, 66.6, report.Percentage, 0.1)
+	require.Len(t, report.Covered, 2)
+	assert.Equal(t, "listPets", report.Covered[0].OperationId)
+	assert.Equal(t, int64(2), report.Covered[1].Requests)
+	assert.Equal(t, "/pets/{id}", report.Covered[1].Path)
+	require.Len(t, report.Uncovered, 1)
+	assert.Equal(t, "POST", report.Uncovered[0].Method)
+
+	mux := http.NewServeMux()
+	ws.RegisterAdminRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, AdminCoveragePath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var decoded CoverageReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded))
+	assert.Equal(t, 2, decoded.CoveredCount)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, AdminCoveragePath+"?format=html", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "createPet")
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, AdminCoveragePath+"?format=xml", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
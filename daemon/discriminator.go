@@ -0,0 +1,214 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi-validator/schema_validation"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+// discriminatorValidator validates bodies against the subschema selected by a discriminator.
+var discriminatorValidator = schema_validation.NewSchemaValidator()
+
+// discriminated is the subschema a discriminator selected for a body.
+type discriminated struct {
+	property string
+	value    string
+	name     string // the name of the subschema, the last segment of its reference.
+	schema   *base.Schema
+}
+
+// errUnmappedDiscriminator is returned when the discriminator value of a body selects no subschema.
+type errUnmappedDiscriminator struct {
+	property string
+	value    string
+}
+
+func (e *errUnmappedDiscriminator) Error() string {
+	return fmt.Sprintf("discriminator '%s' value '%s' does not match any mapping", e.property, e.value)
+}
+
+// mediaTypeSchema returns the schema of the media type of the content type, falling back to JSON when the content type
+// is not defined.
+func mediaTypeSchema(content *orderedmap.Map[string, *v3.MediaType], contentType string) *base.Schema {
+	if content == nil {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media := content.GetOrZero(mediaType)
+	if media == nil {
+		media = content.GetOrZero("application/json")
+	}
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+	return media.Schema.Schema()
+}
+
+// requestBodySchema returns the schema of the request body of the operation the request matches.
+func requestBodySchema(doc *v3.Document, request *http.Request) *base.Schema {
+	pathItem, errs, _ := paths.FindPath(request, doc)
+	if len(errs) > 0 || pathItem == nil {
+		return nil
+	}
+	operation := pathItem.GetOperations().GetOrZero(strings.ToLower(request.Method))
+	if operation == nil || operation.RequestBody == nil {
+		return nil
+	}
+	return mediaTypeSchema(operation.RequestBody.Content, request.Header.Get("Content-Type"))
+}
+
+// responseBodySchema returns the schema of the response body for the status code of the response, its range, or the
+// default response of the operation the request matches.
+func responseBodySchema(doc *v3.Document, request *http.Request, response *http.Response) *base.Schema {
+	pathItem, errs, _ := paths.FindPath(request, doc)
+	if len(errs) > 0 || pathItem == nil {
+		return nil
+	}
+	operation := pathItem.GetOperations().GetOrZero(strings.ToLower(request.Method))
+	if operation == nil || operation.Responses == nil {
+		return nil
+	}
+	var defined *v3.Response
+	if operation.Responses.Codes != nil {
+		code := response.StatusCode
+		for _, key := range []string{strconv.Itoa(code), fmt.Sprintf("%dXX", code/100), fmt.Sprintf("%dxx", code/100)} {
+			if defined = operation.Responses.Codes.GetOrZero(key); defined != nil {
+				break
+			}
+		}
+	}
+	if defined == nil {
+		defined = operation.Responses.Default
+	}
+	if defined == nil {
+		return nil
+	}
+	return mediaTypeSchema(defined.Content, response.Header.Get("Content-Type"))
+}
+
+// referenceName is the last segment of a reference, '#/components/schemas/Cat' is 'Cat'.
+func referenceName(reference string) string {
+	return reference[strings.LastIndex(reference, "/")+1:]
+}
+
+// resolveDiscriminator returns the subschema the discriminator of the schema selects for a JSON body. The value of
+// the discriminator property is looked up in the mapping, or used as the name of a schema when it is not mapped, and
+// the subschema is looked for among the oneOf and anyOf schemas, then the component schemas. Nothing is returned
+// when the schema has no discriminator or the body is not an object with the property, the validator reports those.
+func resolveDiscriminator(doc *v3.Document, schema *base.Schema, body []byte) (*discriminated, error) {
+	if schema == nil || schema.Discriminator == nil || schema.Discriminator.PropertyName == "" {
+		return nil, nil
+	}
+	var decoded map[string]any
+	if json.Unmarshal(body, &decoded) != nil {
+		return nil, nil
+	}
+	property := schema.Discriminator.PropertyName
+	value, ok := decoded[property].(string)
+	if !ok {
+		return nil, nil
+	}
+
+	name := value
+	if schema.Discriminator.Mapping != nil {
+		if reference, mapped := schema.Discriminator.Mapping.Get(value); mapped {
+			name = referenceName(reference)
+		}
+	}
+	found := &discriminated{property: property, value: value, name: name}
+	for _, proxy := range append(append([]*base.SchemaProxy{}, schema.OneOf...), schema.AnyOf...) {
+		if proxy != nil && proxy.IsReference() && referenceName(proxy.GetReference()) == name {
+			found.schema = proxy.Schema()
+			return found, nil
+		}
+	}
+	if doc.Components != nil && doc.Components.Schemas != nil {
+		if proxy := doc.Components.Schemas.GetOrZero(name); proxy != nil {
+			found.schema = proxy.Schema()
+			return found, nil
+		}
+	}
+	return nil, &errUnmappedDiscriminator{property: property, value: value}
+}
+
+// readBody reads a body, and puts back a reader over it for the transaction and the next player in the chain.
+func readBody(body *io.ReadCloser) []byte {
+	if *body == nil || *body == http.NoBody {
+		return nil
+	}
+	data, err := io.ReadAll(*body)
+	_ = (*body).Close()
+	*body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// discriminate replaces the body schema errors of the validator with the errors of the subschema the discriminator
+// selects, the validator checks the body against every oneOf schema and reports the failures of them all. The
+// errors are left as they are when the schema has no discriminator, or a warning is logged when the value of the
+// discriminator selects no subschema.
+func (ws *WiretapService) discriminate(doc *v3.Document, schema *base.Schema, body []byte, validationType string,
+	request *http.Request, validationErrors []*errors.ValidationError) []*errors.ValidationError {
+
+	found, err := resolveDiscriminator(doc, schema, body)
+	if err != nil {
+		ws.config.Logger.Warn("[wiretap] "+err.Error(), "url", request.URL.String(), "method", request.Method)
+		return validationErrors
+	}
+	if found == nil || found.schema == nil {
+		return validationErrors
+	}
+
+	cleaned := make([]*errors.ValidationError, 0, len(validationErrors))
+	for _, ve := range validationErrors {
+		if ve.ValidationType != validationType || ve.ValidationSubType != helpers.Schema {
+			cleaned = append(cleaned, ve)
+		}
+	}
+	valid, schemaErrors := discriminatorValidator.ValidateSchemaBytes(found.schema, body)
+	if valid {
+		return cleaned
+	}
+
+	kind := "request"
+	if validationType == helpers.ResponseBodyValidation {
+		kind = "response"
+	}
+	ve := &errors.ValidationError{
+		ValidationType:    validationType,
+		ValidationSubType: helpers.Schema,
+		Message: fmt.Sprintf("%s %s body for '%s' failed to validate schema '%s', selected by discriminator '%s'",
+			request.Method, kind, request.URL.Path, found.name, found.property),
+		Reason: fmt.Sprintf("The %s body does not match the schema '%s', for the discriminator value '%s'",
+			kind, found.name, found.value),
+		HowToFix:      errors.HowToFixInvalidSchema,
+		RequestPath:   request.URL.Path,
+		RequestMethod: request.Method,
+		Context:       found.schema,
+	}
+	for _, schemaError := range schemaErrors {
+		ve.SchemaValidationErrors = append(ve.SchemaValidationErrors, schemaError.SchemaValidationErrors...)
+	}
+	if low := found.schema.GoLow(); low != nil && low.RootNode != nil {
+		ve.SpecLine, ve.SpecCol = low.RootNode.Line, low.RootNode.Column
+	}
+	return append(cleaned, ve)
+}
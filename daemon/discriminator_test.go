@@ -0,0 +1,99 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var discriminatorSpec = []byte(`openapi: 3.1.0
+paths:
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              oneOf:
+                - $ref: '#/components/schemas/Cat'
+                - $ref: '#/components/schemas/Dog'
+              discriminator:
+                propertyName: petType
+                mapping:
+                  kitty: '#/components/schemas/Cat'
+      responses:
+        "200":
+          description: ok
+components:
+  schemas:
+    Cat:
+      type: object
+      required: [petType, meows]
+      properties:
+        petType:
+          type: string
+        meows:
+          type: boolean
+    Dog:
+      type: object
+      required: [petType, barks]
+      properties:
+        petType:
+          type: string
+        barks:
+          type: boolean
+`)
+
+func TestWiretapService_Discriminate(t *testing.T) {
+	doc, err := libopenapi.NewDocument(discriminatorSpec)
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+
+	var logs bytes.Buffer
+	ws := &WiretapService{config: &shared.WiretapConfiguration{Logger: slog.New(slog.NewTextHandler(&logs, nil))}}
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost/pets", nil)
+	request.Header.Set("Content-Type", "application/json")
+	schema := requestBodySchema(&m.Model, request)
+	require.NotNil(t, schema)
+
+	// the validator reports the failures of every oneOf schema, other errors are kept.
+	other := &errors.ValidationError{ValidationType: helpers.ParameterValidation, Message: "other"}
+	validatorErrors := []*errors.ValidationError{other,
+		{ValidationType: helpers.RequestBodyValidation, ValidationSubType: helpers.Schema, Message: "oneOf"}}
+
+	discriminate := func(body string) []*errors.ValidationError {
+		return ws.discriminate(&m.Model, schema, []byte(body), helpers.RequestBodyValidation, request, validatorErrors)
+	}
+
+	// mapped to the cat, and matches it.
+	assert.Equal(t, []*errors.ValidationError{other}, discriminate(`{"petType":"kitty","meows":true}`))
+
+	// not mapped, the value is the name of the dog schema.
+	found := discriminate(`{"petType":"Dog","meows":true}`)
+	require.Len(t, found, 2)
+	assert.Same(t, other, found[0])
+	assert.Equal(t, "POST request body for '/pets' failed to validate schema 'Dog', selected by discriminator 'petType'",
+		found[1].Message)
+	assert.NotEmpty(t, found[1].SchemaValidationErrors)
+
+	// no subschema for the value, the errors of the validator are kept.
+	assert.Equal(t, validatorErrors, discriminate(`{"petType":"Fish"}`))
+	assert.True(t, strings.Contains(logs.String(), "discriminator 'petType' value 'Fish' does not match any mapping"))
+
+	// bodies without the property are left to the validator.
+	assert.Equal(t, validatorErrors, discriminate(`{"meows":true}`))
+	assert.Equal(t, validatorErrors, discriminate(`not json`))
+}
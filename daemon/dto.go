@@ -34,23 +34,31 @@ type HttpRequest struct {
 	Query           string                 `json:"query,omitempty"`
 	Headers         map[string]any         `json:"headers,omitempty"`
 	Body            string                 `json:"requestBody,omitempty"`
+	BodyLength      int                    `json:"requestBodyLength,omitempty"`
+	BodyTruncated   bool                   `json:"bodyTruncated,omitempty"`
+	BodyRedacted    bool                   `json:"bodyRedacted,omitempty"`
 	Cookies         map[string]*HttpCookie `json:"cookies,omitempty"`
 }
 
 type HttpResponse struct {
-	Timestamp  int64                  `json:"timestamp,omitempty"`
-	Headers    map[string]any         `json:"headers,omitempty"`
-	StatusCode int                    `json:"statusCode,omitempty"`
-	Body       string                 `json:"responseBody,omitempty"`
-	Cookies    map[string]*HttpCookie `json:"cookies,omitempty"`
-	Time       time.Time              `json:"-"`
+	Timestamp     int64                  `json:"timestamp,omitempty"`
+	Headers       map[string]any         `json:"headers,omitempty"`
+	StatusCode    int                    `json:"statusCode,omitempty"`
+	Body          string                 `json:"responseBody,omitempty"`
+	BodyLength    int                    `json:"responseBodyLength,omitempty"`
+	BodyTruncated bool                   `json:"bodyTruncated,omitempty"`
+	BodyRedacted  bool                   `json:"bodyRedacted,omitempty"`
+	Cookies       map[string]*HttpCookie `json:"cookies,omitempty"`
+	Time          time.Time              `json:"-"`
 }
 
 type HttpTransaction struct {
 	Request            *HttpRequest              `json:"httpRequest,omitempty"`
 	RequestValidation  []*errors.ValidationError `json:"requestValidation,omitempty"`
+	RequestBodyHash    string                    `json:"requestBodyHash,omitempty"`
 	Response           *HttpResponse             `json:"httpResponse,omitempty"`
 	ResponseValidation []*errors.ValidationError `json:"responseValidation,omitempty"`
+	ResponseBodyHash   string                    `json:"responseBodyHash,omitempty"`
 	Id                 string                    `json:"id,omitempty"`
 }
 
@@ -0,0 +1,90 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+)
+
+const (
+	// DefaultSMTPPort is the mail submission port, used when the email notification does not set one.
+	DefaultSMTPPort = 587
+
+	// DefaultEmailInterval is the minimum time between two emails, when the email notification does not set one.
+	DefaultEmailInterval = 15 * time.Minute
+
+	DefaultEmailSubject = "wiretap validation errors"
+)
+
+// newEmailNotifier creates a notifier emailing a summary, once more than the minimum number of errors are found
+// in a window. Emails are rate limited, so a sustained spike of errors does not flood the recipients.
+func newEmailNotifier(config *shared.SMTPConfig, dashboard string) *notifier {
+	window := DefaultNotificationWindow
+	if config.WindowSeconds > 0 {
+		window = time.Duration(config.WindowSeconds) * time.Second
+	}
+	interval := DefaultEmailInterval
+	if config.MinIntervalSeconds > 0 {
+		interval = time.Duration(config.MinIntervalSeconds) * time.Second
+	}
+	return &notifier{
+		name:     "email",
+		window:   window,
+		interval: interval,
+		since:    time.Now(),
+		ready:    func(count int) bool { return count > config.MinErrors },
+		send: func(violations []*errors.ValidationError, period time.Duration) error {
+			return sendEmail(config, emailMessage(config, violations, period, dashboard, time.Now()))
+		},
+	}
+}
+
+// emailMessage builds a plain text email summarizing the errors.
+func emailMessage(config *shared.SMTPConfig, violations []*errors.ValidationError, period time.Duration,
+	dashboard string, now time.Time) []byte {
+
+	subject := config.Subject
+	if subject == "" {
+		subject = DefaultEmailSubject
+	}
+	var msg strings.Builder
+	msg.WriteString("From: " + config.From + "\r\n")
+	msg.WriteString("To: " + strings.Join(config.To, ", ") + "\r\n")
+	msg.WriteString("Subject: " + subject + "\r\n")
+	msg.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(notificationTitle(violations, period) + "\r\n\r\n")
+	for _, line := range notificationLines(violations) {
+		msg.WriteString("- " + line + "\r\n")
+	}
+	msg.WriteString("\r\nOpen the wiretap dashboard: " + dashboard + "\r\n")
+	return []byte(msg.String())
+}
+
+// sendEmail sends a message through the SMTP server, authenticating when a username is set.
+func sendEmail(config *shared.SMTPConfig, message []byte) error {
+	port := config.Port
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(port))
+	if err := smtp.SendMail(addr, auth, config.From, config.To, message); err != nil {
+		return fmt.Errorf("unable to send email through %s: %w", addr, err)
+	}
+	return nil
+}
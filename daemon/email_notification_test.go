@@ -0,0 +1,56 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailNotifier_RateLimited(t *testing.T) {
+	nt := newEmailNotifier(&shared.SMTPConfig{MinErrors: 1, MinIntervalSeconds: 600}, "")
+	n := notifiers{nt}
+	start := nt.since
+	violations := []*errors.ValidationError{{Message: "one"}, {Message: "two"}}
+
+	// the minimum must be exceeded, not only reached.
+	n.record(violations[:1])
+	batch, _, _ := nt.flush(start.Add(time.Minute))
+	assert.Nil(t, batch)
+
+	n.record(violations)
+	batch, _, _ = nt.flush(start.Add(2 * time.Minute))
+	assert.Len(t, batch, 2)
+
+	// errors found while rate limited are kept, and sent once the interval has passed.
+	n.record(violations)
+	batch, _, _ = nt.flush(start.Add(3 * time.Minute))
+	assert.Nil(t, batch)
+	n.record(violations)
+	batch, period, _ := nt.flush(start.Add(12 * time.Minute))
+	assert.Len(t, batch, 4)
+	assert.Equal(t, 10*time.Minute, period)
+}
+
+func TestEmailMessage(t *testing.T) {
+	config := &shared.SMTPConfig{From: "wiretap@example.com", To: []string{"a@example.com", "b@example.com"}}
+	violations := []*errors.ValidationError{{RequestMethod: "GET", RequestPath: "/pets"}}
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	msg := string(emailMessage(config, violations, time.Minute, "http://localhost:9091", now))
+	assert.Equal(t, "From: wiretap@example.com\r\n"+
+		"To: a@example.com, b@example.com\r\n"+
+		"Subject: wiretap validation errors\r\n"+
+		"Date: Wed, 01 May 2024 12:00:00 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"\r\n"+
+		"wiretap found 1 validation error in the last 1m0s\r\n\r\n"+
+		"- GET /pets: 1 error\r\n"+
+		"\r\nOpen the wiretap dashboard: http://localhost:9091\r\n", msg)
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"sync"
+	"time"
+)
+
+// ErrorRateStats is the validation error rate over the sliding window, returned by the admin status endpoint.
+type ErrorRateStats struct {
+	WindowSeconds int     `json:"windowSeconds"`
+	Rate          float64 `json:"rate"` // errors per second.
+	Threshold     float64 `json:"threshold,omitempty"`
+	Exceeded      bool    `json:"exceeded"`
+}
+
+// errorRateTracker counts validation errors per second, over a sliding window of whole seconds. A nil tracker
+// is valid and tracks nothing, which is what is used when no window is configured.
+type errorRateTracker struct {
+	lock      sync.Mutex
+	threshold float64
+	buckets   []int   // errors per second, indexed by the unix second modulo the window.
+	seconds   []int64 // the unix second each bucket counts.
+	exceeded  bool
+}
+
+// newErrorRateTracker returns nil when the window is 0 (not configured).
+func newErrorRateTracker(windowSeconds int, threshold float64) *errorRateTracker {
+	if windowSeconds <= 0 {
+		return nil
+	}
+	return &errorRateTracker{
+		threshold: threshold,
+		buckets:   make([]int, windowSeconds),
+		seconds:   make([]int64, windowSeconds),
+	}
+}
+
+// rate returns the errors per second over the window ending now. The lock must be held.
+func (et *errorRateTracker) rate(now time.Time) float64 {
+	second, total := now.Unix(), 0
+	window := int64(len(et.buckets))
+	for i, s := range et.seconds {
+		if second-s < window {
+			total += et.buckets[i]
+		}
+	}
+	return float64(total) / float64(window)
+}
+
+// record counts errors, and returns true when the rate has just gone over the threshold. The rate has to drop
+// back under the threshold before it is reported again, so a sustained spike is only reported once.
+func (et *errorRateTracker) record(count int, now time.Time) (bool, float64) {
+	if et == nil {
+		return false, 0
+	}
+	et.lock.Lock()
+	defer et.lock.Unlock()
+	second := now.Unix()
+	i := int(second % int64(len(et.buckets)))
+	if et.seconds[i] != second {
+		et.seconds[i], et.buckets[i] = second, 0
+	}
+	et.buckets[i] += count
+
+	rate := et.rate(now)
+	if et.threshold <= 0 {
+		return false, rate
+	}
+	over := rate > et.threshold
+	crossed := over && !et.exceeded
+	et.exceeded = over
+	return crossed, rate
+}
+
+func (et *errorRateTracker) stats(now time.Time) *ErrorRateStats {
+	if et == nil {
+		return nil
+	}
+	et.lock.Lock()
+	defer et.lock.Unlock()
+	rate := et.rate(now)
+	return &ErrorRateStats{
+		WindowSeconds: len(et.buckets),
+		Rate:          rate,
+		Threshold:     et.threshold,
+		Exceeded:      et.threshold > 0 && rate > et.threshold,
+	}
+}
+
+// recordErrorRate tracks the rate of validation errors. When the rate goes over the threshold a warning is
+// logged, and every notifier is sent the errors of its current window straight away.
+func (ws *WiretapService) recordErrorRate(count int) {
+	now := time.Now()
+	crossed, rate := ws.errorRate.record(count, now)
+	if !crossed {
+		return
+	}
+	ws.config.Logger.Warn("[wiretap] validation error rate over the threshold", "rate", rate,
+		"threshold", ws.config.ErrorRateThreshold, "window", ws.config.ErrorRateWindow)
+	for _, nt := range ws.notifiers {
+		go nt.alert(now, ws.config.Logger)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorRateTracker(t *testing.T) {
+	et := newErrorRateTracker(10, 1)
+	start := time.Unix(1_700_000_000, 0)
+
+	crossed, rate := et.record(5, start)
+	assert.False(t, crossed)
+	assert.Equal(t, 0.5, rate)
+
+	// 11 errors in 10 seconds is over one per second.
+	crossed, rate = et.record(6, start.Add(3*time.Second))
+	assert.True(t, crossed)
+	assert.Equal(t, 1.1, rate)
+
+	// still over the threshold, it is not reported again.
+	crossed, _ = et.record(1, start.Add(4*time.Second))
+	assert.False(t, crossed)
+	assert.True(t, et.stats(start.Add(4*time.Second)).Exceeded)
+
+	// the first errors slide out of the window, the rate drops under the threshold.
+	stats := et.stats(start.Add(10 * time.Second))
+	assert.Equal(t, 0.7, stats.Rate)
+	assert.False(t, stats.Exceeded)
+	crossed, _ = et.record(0, start.Add(10*time.Second))
+	assert.False(t, crossed)
+
+	// and going over it again is reported.
+	crossed, _ = et.record(5, start.Add(11*time.Second))
+	assert.True(t, crossed)
+}
+
+func TestErrorRateTracker_Disabled(t *testing.T) {
+	et := newErrorRateTracker(0, 1)
+	assert.Nil(t, et)
+	crossed, _ := et.record(100, time.Now())
+	assert.False(t, crossed)
+	assert.Nil(t, et.stats(time.Now()))
+}
+
+func TestNotifier_Alert(t *testing.T) {
+	var sent []*errors.ValidationError
+	nt := newWebhookNotifier(&shared.NotificationConfig{Type: shared.NotificationSlack, MinimumErrorCount: 10}, "")
+	nt.send = func(violations []*errors.ValidationError, _ time.Duration) error {
+		sent = violations
+		return nil
+	}
+	notifiers{nt}.record([]*errors.ValidationError{{Message: "one"}})
+	// alerts do not wait for the minimum error count.
+	nt.alert(time.Now(), nil)
+	assert.Len(t, sent, 1)
+}
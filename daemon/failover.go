@@ -0,0 +1,119 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// UpstreamStatus holds the fallback event counts of a single failover upstream.
+type UpstreamStatus struct {
+	URL       string `json:"url"`
+	Attempts  int64  `json:"attempts"`  // the number of times a request fell back to this upstream.
+	Successes int64  `json:"successes"` // the number of those requests that were answered with a non-5xx response.
+}
+
+type upstreamCounters struct {
+	attempts  atomic.Int64
+	successes atomic.Int64
+}
+
+// failoverStats tracks fallback events per upstream URL.
+type failoverStats struct {
+	counters sync.Map
+}
+
+func (fs *failoverStats) get(upstream string) *upstreamCounters {
+	counters, _ := fs.counters.LoadOrStore(upstream, &upstreamCounters{})
+	return counters.(*upstreamCounters)
+}
+
+// status returns the counts for each configured upstream, in chain order.
+func (fs *failoverStats) status(upstreams []*shared.UpstreamConfig) []*UpstreamStatus {
+	statuses := make([]*UpstreamStatus, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		counters := fs.get(upstream.URL)
+		statuses = append(statuses, &UpstreamStatus{
+			URL:       upstream.URL,
+			Attempts:  counters.attempts.Load(),
+			Successes: counters.successes.Load(),
+		})
+	}
+	return statuses
+}
+
+// shouldFailover returns true if a response from an upstream means the next upstream in the chain should be tried.
+func shouldFailover(resp *http.Response, err error) bool {
+	return err != nil || resp == nil || resp.StatusCode >= 500
+}
+
+// upstreamRequest re-targets a request at a failover upstream. The scheme and host are replaced, if the upstream has
+// a path, it replaces the configured redirect base path.
+func upstreamRequest(req *http.Request, body []byte, upstream *shared.UpstreamConfig,
+	wiretapConfig *shared.WiretapConfiguration) *http.Request {
+
+	upReq := req.Clone(req.Context())
+	upReq.URL.Scheme = upstream.ParsedURL.Scheme
+	upReq.URL.Host = upstream.ParsedURL.Host
+	upReq.Host = ""
+	if upstream.ParsedURL.Path != "" {
+		path := strings.TrimPrefix(req.URL.Path, wiretapConfig.RedirectBasePath)
+		upReq.URL.Path = strings.TrimSuffix(upstream.ParsedURL.Path, "/") + path
+		upReq.URL.RawPath = ""
+	}
+	if body != nil {
+		upReq.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return upReq
+}
+
+// callFailoverUpstreams walks the failover chain in order, until an upstream returns a non-5xx response or the chain
+// is exhausted. The last response received is returned, so a 5xx from the end of the chain is still passed on.
+func (ws *WiretapService) callFailoverUpstreams(client *http.Client, req *http.Request, body []byte,
+	resp *http.Response, err error, wiretapConfig *shared.WiretapConfiguration) (*http.Response, error) {
+
+	for _, upstream := range wiretapConfig.FailoverUpstreams {
+		if upstream.ParsedURL == nil {
+			continue
+		}
+		failedWith := "error"
+		if resp != nil {
+			failedWith = resp.Status
+		}
+		ws.config.Logger.Warn("[wiretap] upstream failed, falling back", "url", req.URL.String(),
+			"failure", failedWith, "upstream", upstream.URL)
+
+		counters := ws.failoverStats.get(upstream.URL)
+		counters.attempts.Add(1)
+
+		upResp, upErr := client.Do(upstreamRequest(req, body, upstream, wiretapConfig))
+		if upResp != nil {
+			if resp != nil && resp.Body != nil {
+				_ = resp.Body.Close()
+			}
+			resp = upResp
+		}
+		err = upErr
+
+		if !shouldFailover(upResp, upErr) {
+			counters.successes.Add(1)
+			ws.config.Logger.Info("[wiretap] request served by failover upstream", "url", req.URL.String(),
+				"upstream", upstream.URL, "code", upResp.StatusCode)
+			return upResp, nil
+		}
+	}
+
+	ws.config.Logger.Error("[wiretap] failover chain exhausted", "url", req.URL.String())
+	if resp != nil {
+		return resp, nil
+	}
+	return nil, err
+}
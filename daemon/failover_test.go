@@ -0,0 +1,62 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWiretapService_CallFailoverUpstreams(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(r.URL.Path + ":" + string(body)))
+	}))
+	defer healthy.Close()
+
+	config := &shared.WiretapConfiguration{
+		Logger:           slog.New(slog.NewTextHandler(os.Stderr, nil)),
+		RedirectBasePath: "/v1",
+		FailoverUpstreams: []*shared.UpstreamConfig{
+			{URL: failing.URL},
+			{URL: healthy.URL + "/v2"},
+		},
+	}
+	assert.NoError(t, config.CompileFailoverUpstreams())
+	ws := &WiretapService{config: config}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:1/v1/pets", strings.NewReader("fido"))
+	resp, err := ws.callFailoverUpstreams(http.DefaultClient, req, []byte("fido"), nil, io.EOF, config)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "/v2/pets:fido", string(body))
+
+	status := ws.Status().FailoverUpstreams
+	assert.Len(t, status, 2)
+	assert.Equal(t, int64(1), status[0].Attempts)
+	assert.Equal(t, int64(0), status[0].Successes)
+	assert.Equal(t, int64(1), status[1].Attempts)
+	assert.Equal(t, int64(1), status[1].Successes)
+}
+
+func TestCompileFailoverUpstreams_Invalid(t *testing.T) {
+	config := &shared.WiretapConfiguration{
+		FailoverUpstreams: []*shared.UpstreamConfig{{URL: "not-a-url"}},
+	}
+	assert.Error(t, config.CompileFailoverUpstreams())
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientForwarding tells the upstream who the client is, when a request is cloned. The client is described with the
+// standardized Forwarded header (RFC 7239), or with the X-Forwarded-For, X-Forwarded-Host, X-Forwarded-Proto and
+// X-Real-IP headers.
+type ClientForwarding struct {
+	UseForwardedHeader bool
+	TrustedProxy       func(ip net.IP) bool // forwarding headers are only believed from trusted proxies.
+}
+
+// clientForwarding returns nil unless the client is forwarded upstream.
+func (ws *WiretapService) clientForwarding() *ClientForwarding {
+	if !ws.config.ForwardClientIP && !ws.config.UseForwardedHeader {
+		return nil
+	}
+	return &ClientForwarding{
+		UseForwardedHeader: ws.config.UseForwardedHeader,
+		TrustedProxy:       ws.config.TrustedProxy,
+	}
+}
+
+// forward sets the forwarding headers of the upstream request. The peer is recorded after the hops the original
+// request came through.
+func (cf *ClientForwarding) forward(original, upstream *http.Request) {
+	peer := original.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if peer == "" {
+		return
+	}
+	proto := "http"
+	if original.TLS != nil {
+		proto = "https"
+	}
+	if cf.UseForwardedHeader {
+		by := "_wiretap"
+		if local, ok := original.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+			by = local.String()
+		}
+		element := []forwardedPair{
+			{"for", forwardedNode(peer)}, {"host", original.Host}, {"proto", proto}, {"by", by},
+		}
+		elements := append(parseForwarded(original.Header.Values("Forwarded")), element)
+		upstream.Header.Set("Forwarded", formatForwarded(elements))
+		return
+	}
+
+	var forwarded []string
+	for _, value := range original.Header.Values("X-Forwarded-For") {
+		for _, address := range strings.Split(value, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				forwarded = append(forwarded, address)
+			}
+		}
+	}
+
+	// the client is the right-most forwarded address that is not a trusted proxy.
+	client := peer
+	trusted := cf.TrustedProxy != nil && cf.TrustedProxy(net.ParseIP(peer))
+	if trusted {
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			client = forwarded[i]
+			if !cf.TrustedProxy(net.ParseIP(client)) {
+				break
+			}
+		}
+	}
+
+	upstream.Header.Set("X-Forwarded-For", strings.Join(append(forwarded, peer), ", "))
+	for header, value := range map[string]string{"X-Real-IP": client, "X-Forwarded-Host": original.Host,
+		"X-Forwarded-Proto": proto} {
+		if original.Header.Get(header) == "" || !trusted {
+			upstream.Header.Set(header, value)
+		}
+	}
+}
+
+// forwardedPair is a parameter of a Forwarded element, such as for=192.0.2.60.
+type forwardedPair struct {
+	name, value string
+}
+
+// forwardedNode formats an address as a node, IPv6 addresses are bracketed.
+func forwardedNode(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "[" + ip + "]"
+	}
+	return ip
+}
+
+// parseForwarded parses the elements of Forwarded headers. Malformed elements are dropped.
+func parseForwarded(values []string) [][]forwardedPair {
+	var elements [][]forwardedPair
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			var pairs []forwardedPair
+			for _, pair := range splitQuoted(element, ';') {
+				if pair = strings.TrimSpace(pair); pair == "" {
+					continue
+				}
+				name, v, found := strings.Cut(pair, "=")
+				if !found || name == "" || !isToken(name) {
+					pairs = nil
+					break
+				}
+				if strings.HasPrefix(v, "\"") {
+					if len(v) < 2 || !strings.HasSuffix(v, "\"") {
+						pairs = nil
+						break
+					}
+					v = unquote(v[1 : len(v)-1])
+				} else if !isToken(v) {
+					pairs = nil
+					break
+				}
+				pairs = append(pairs, forwardedPair{strings.ToLower(name), v})
+			}
+			if len(pairs) > 0 {
+				elements = append(elements, pairs)
+			}
+		}
+	}
+	return elements
+}
+
+// formatForwarded formats the elements of a Forwarded header, quoting values that are not tokens.
+func formatForwarded(elements [][]forwardedPair) string {
+	formatted := make([]string, len(elements))
+	for i, element := range elements {
+		pairs := make([]string, len(element))
+		for j, pair := range element {
+			value := pair.value
+			if !isToken(value) {
+				value = "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(value) + "\""
+			}
+			pairs[j] = pair.name + "=" + value
+		}
+		formatted[i] = strings.Join(pairs, ";")
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// splitQuoted splits a header value on a separator outside of quoted strings.
+func splitQuoted(value string, separator byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == separator:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+func unquote(value string) string {
+	var unquoted strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		unquoted.WriteByte(value[i])
+	}
+	return unquoted.String()
+}
+
+// isToken is true when the value is an RFC 7230 token, which is written without quotes.
+func isToken(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, c := range value {
+		if c > 0x7e || c <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneExistingRequest_ForwardedFor(t *testing.T) {
+	config := &shared.WiretapConfiguration{ForwardClientIP: true, TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}
+	require.NoError(t, config.CompileTrustedProxies())
+	ws := &WiretapService{config: config}
+
+	forward := func(remote string, headers map[string]string) http.Header {
+		original := httptest.NewRequest(http.MethodGet, "http://pb33f.io/pets", nil)
+		original.RemoteAddr = remote + ":4321"
+		for k, v := range headers {
+			original.Header.Set(k, v)
+		}
+		upstream := CloneExistingRequest(CloneRequest{Request: original, Protocol: "http", Host: "localhost",
+			Forwarding: ws.clientForwarding()})
+		return upstream.Header
+	}
+
+	headers := forward("203.0.113.7", nil)
+	assert.Equal(t, "203.0.113.7", headers.Get("X-Forwarded-For"))
+	assert.Equal(t, "203.0.113.7", headers.Get("X-Real-IP"))
+	assert.Equal(t, "pb33f.io", headers.Get("X-Forwarded-Host"))
+	assert.Equal(t, "http", headers.Get("X-Forwarded-Proto"))
+	assert.Empty(t, headers.Get("Forwarded"))
+
+	// a trusted proxy forwards for the client, trusted hops are skipped.
+	headers = forward("10.1.2.3", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 192.168.1.1",
+		"X-Forwarded-Proto": "https"})
+	assert.Equal(t, "198.51.100.1, 203.0.113.7, 192.168.1.1, 10.1.2.3", headers.Get("X-Forwarded-For"))
+	assert.Equal(t, "203.0.113.7", headers.Get("X-Real-IP"))
+	assert.Equal(t, "https", headers.Get("X-Forwarded-Proto"))
+
+	// the headers of an untrusted peer are not believed.
+	headers = forward("203.0.113.7", map[string]string{"X-Forwarded-For": "198.51.100.1",
+		"X-Real-IP": "198.51.100.1", "X-Forwarded-Proto": "https"})
+	assert.Equal(t, "198.51.100.1, 203.0.113.7", headers.Get("X-Forwarded-For"))
+	assert.Equal(t, "203.0.113.7", headers.Get("X-Real-IP"))
+	assert.Equal(t, "http", headers.Get("X-Forwarded-Proto"))
+
+	// a trusted proxy may set the real address itself.
+	headers = forward("10.1.2.3", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.9"})
+	assert.Equal(t, "198.51.100.9", headers.Get("X-Real-IP"))
+
+	config.ForwardClientIP = false
+	assert.Nil(t, ws.clientForwarding())
+}
+
+func TestCloneExistingRequest_Forwarded(t *testing.T) {
+	ws := &WiretapService{config: &shared.WiretapConfiguration{UseForwardedHeader: true}}
+
+	original := httptest.NewRequest(http.MethodGet, "http://pb33f.io:9090/pets", nil)
+	original.RemoteAddr = "[2001:db8::17]:4321"
+	upstream := CloneExistingRequest(CloneRequest{Request: original, Protocol: "http", Host: "localhost",
+		Forwarding: ws.clientForwarding()})
+	assert.Equal(t, `for="[2001:db8::17]";host="pb33f.io:9090";proto=http;by=_wiretap`,
+		upstream.Header.Get("Forwarded"))
+	assert.Empty(t, upstream.Header.Get("X-Forwarded-For"))
+
+	// existing elements are kept, malformed elements are dropped.
+	original.RemoteAddr = "192.0.2.43:4321"
+	original.Header.Add("Forwarded", `for=192.0.2.60;PROTO=https;by="a;b, c", for=`)
+	original.Header.Add("Forwarded", `for="[2001:db8:cafe::17]:4711"`)
+	upstream = CloneExistingRequest(CloneRequest{Request: original, Protocol: "http", Host: "localhost",
+		Forwarding: ws.clientForwarding()})
+	assert.Equal(t, `for=192.0.2.60;proto=https;by="a;b, c", for="[2001:db8:cafe::17]:4711", `+
+		`for=192.0.2.43;host="pb33f.io:9090";proto=http;by=_wiretap`, upstream.Header.Get("Forwarded"))
+}
+
+func TestWiretapConfiguration_CompileTrustedProxies(t *testing.T) {
+	config := &shared.WiretapConfiguration{TrustedProxies: []string{"not-a-range"}}
+	assert.ErrorContains(t, config.CompileTrustedProxies(), "trusted proxy 'not-a-range' is not an address or a CIDR range")
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/pb33f/wiretap/shared"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// ConfigureH2C wraps the handler of the proxy server, so clients can speak HTTP/2 without TLS, either with prior
+// knowledge or by upgrading an HTTP/1.1 connection. Any other HTTP/1.1 request, including a websocket upgrade, is
+// passed to the handler as it is, and HTTP/2 responses can still be flushed for streaming. H2C is ignored when TLS
+// is configured, as HTTP/2 is then negotiated as part of the TLS handshake. It must be called before the server starts.
+func ConfigureH2C(server *http.Server, config *shared.WiretapConfiguration) {
+	if !config.H2CEnabled {
+		return
+	}
+	if config.GetHttpProtocol() == "https" {
+		if config.Logger != nil {
+			config.Logger.Warn("[wiretap] h2c and TLS are mutually exclusive, h2c is disabled")
+		}
+		return
+	}
+	server.Handler = h2c.NewHandler(server.Handler, &http2.Server{IdleTimeout: server.IdleTimeout})
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func TestConfigureH2C(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	ConfigureH2C(server.Config, &shared.WiretapConfiguration{H2CEnabled: true})
+	server.Start()
+	defer server.Close()
+
+	// prior knowledge, HTTP/2 straight away over a plain connection.
+	h2 := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := h2.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, 2, resp.ProtoMajor)
+
+	// HTTP/1.1 clients are still served.
+	resp, err = server.Client().Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, 1, resp.ProtoMajor)
+}
+
+func TestConfigureH2C_TLS(t *testing.T) {
+	handler := http.NewServeMux()
+	server := &http.Server{Handler: handler}
+	ConfigureH2C(server, &shared.WiretapConfiguration{H2CEnabled: true, Certificate: "cert.pem", CertificateKey: "key.pem"})
+	assert.Same(t, handler, server.Handler)
+}
@@ -11,13 +11,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/pb33f/libopenapi-validator/errors"
 	"github.com/pb33f/ranch/model"
 	configModel "github.com/pb33f/wiretap/config"
 	"github.com/pb33f/wiretap/shared"
 )
 
 func (ws *WiretapService) handleMockRequest(
-	request *model.Request, config *shared.WiretapConfiguration, newReq *http.Request) {
+	request *model.Request, config *shared.WiretapConfiguration, newReq *http.Request, audit *auditTracker) {
 	// dip out early if we're in mock mode.
 	delay := configModel.FindPathDelay(request.HttpRequest.URL.Path, config)
 	if delay > 0 {
@@ -28,19 +29,41 @@ func (ws *WiretapService) handleMockRequest(
 		}
 	}
 
-	// build a mock based on the request.
-	mock, mockStatus, mockErr := ws.mockEngine.GenerateResponse(request.HttpRequest)
+	// build a mock based on the request, dry-run mode serves the examples from the specification.
+	var mock []byte
+	var mockStatus int
+	var mockErr error
+	contentType := "application/json"
+	mockEngine := ws.currentSpec().mockEngine
+	if config.DryRun {
+		mock, mockStatus, contentType, mockErr = mockEngine.GenerateExampleResponse(request.HttpRequest)
+	} else {
+		mock, mockStatus, mockErr = mockEngine.GenerateResponse(request.HttpRequest)
+	}
 
 	// validate http request.
-	ws.ValidateRequest(request, newReq)
+	ws.validate(audit, func() []*errors.ValidationError {
+		return ws.ValidateRequest(request, newReq)
+	})
 
 	// sleep for a few ms, this prevents responses from being sent out of order.
 	time.Sleep(5 * time.Millisecond)
 
 	// wiretap needs to work from anywhere, so allow everything.
 	headers := make(map[string][]string)
+	if config.DryRun && mockErr == nil {
+		// the synthetic response carries the headers the specification defines for it.
+		specHeaders, missing := mockEngine.GenerateExampleHeaders(request.HttpRequest, mockStatus)
+		for name, value := range specHeaders {
+			headers[http.CanonicalHeaderKey(name)] = []string{value}
+		}
+		if len(missing) > 0 {
+			config.Logger.Warn("[wiretap] spec warning: required response headers have no example or default",
+				"url", newReq.URL.String(), "code", mockStatus, "headers", missing)
+		}
+	}
 	shared.SetCORSHeaders(headers)
-	headers["Content-Type"] = []string{"application/json"}
+	headers["Content-Type"] = []string{contentType}
 
 	buff := bytes.NewBuffer(mock)
 
@@ -55,7 +78,7 @@ func (ws *WiretapService) handleMockRequest(
 	for k, v := range headers {
 		for _, j := range v {
 			request.HttpResponseWriter.Header().Set(k, fmt.Sprint(j))
-			header.Add(k, fmt.Sprint(v))
+			header.Add(k, fmt.Sprint(j))
 		}
 	}
 
@@ -68,7 +91,7 @@ func (ws *WiretapService) handleMockRequest(
 
 		// validate response async
 		resp.StatusCode = mockStatus
-		go ws.broadcastResponse(request, resp)
+		ws.reportMockResponse(request, resp, config, audit)
 		return
 	}
 
@@ -81,13 +104,13 @@ func (ws *WiretapService) handleMockRequest(
 
 		// validate response async
 		resp.StatusCode = mockStatus
-		go ws.broadcastResponse(request, resp)
+		ws.reportMockResponse(request, resp, config, audit)
 		return
 	}
 
 	// validate response async
 	resp.StatusCode = mockStatus
-	go ws.broadcastResponse(request, resp)
+	ws.reportMockResponse(request, resp, config, audit)
 
 	// if the mock is empty
 	request.HttpResponseWriter.WriteHeader(mockStatus)
@@ -100,3 +123,18 @@ func (ws *WiretapService) handleMockRequest(
 		panic(errs)
 	}
 }
+
+// reportMockResponse sends a mocked response up to the monitor UI. In dry-run mode the synthetic response is
+// validated against the contract first, as if it had come from the API.
+func (ws *WiretapService) reportMockResponse(request *model.Request, resp *http.Response,
+	config *shared.WiretapConfiguration, audit *auditTracker) {
+	if config.DryRun {
+		// validate response async
+		clonedResponse := CloneExistingResponse(resp)
+		ws.validateAsync(audit, func() []*errors.ValidationError {
+			return ws.ValidateResponse(request, clonedResponse)
+		})
+		return
+	}
+	go ws.broadcastResponse(request, resp)
+}
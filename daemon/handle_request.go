@@ -19,6 +19,7 @@ import (
 
 	"github.com/pb33f/libopenapi-validator/errors"
 	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/asyncapi"
 	configModel "github.com/pb33f/wiretap/config"
 	"github.com/pb33f/wiretap/shared"
 )
@@ -32,6 +33,13 @@ type staticTemplateModel struct {
 }
 
 func (ws *WiretapService) handleHttpRequest(request *model.Request) {
+	audit := ws.trackAudit(request)
+	defer audit.finish()
+
+	// oversized headers, blocked paths and methods, and paths that are not allowed, are refused before anything else.
+	if ws.refuseRequest(request) {
+		return
+	}
 
 	// determine if this is a request for a file or not.
 	if ws.config.StaticDir != "" {
@@ -114,6 +122,7 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 		InjectHeaders: injectHeaders,
 		Auth:          auth,
 		Variables:     config.CompiledVariables,
+		HopByHop:      config.CustomHopByHopHeaders,
 	})
 
 	apiRequest := CloneExistingRequest(CloneRequest{
@@ -126,32 +135,53 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 		InjectHeaders: injectHeaders,
 		Auth:          auth,
 		Variables:     config.CompiledVariables,
+		Forwarding:    ws.clientForwarding(),
+		HopByHop:      config.CustomHopByHopHeaders,
 	})
 
 	if newReq == nil || apiRequest == nil {
 		ws.config.Logger.Error("[wiretap] unable to clone API request, failed", "url", request.HttpRequest.URL.String())
 		return
 	}
+	ws.injectVia(request.HttpRequest, apiRequest)
 
 	var requestErrors []*errors.ValidationError
 	var responseErrors []*errors.ValidationError
 
 	ws.config.Logger.Info("[wiretap] handling API request", "url", request.HttpRequest.URL.String())
+	if ws.specLoading.Load() {
+		ws.config.Logger.Warn("[wiretap] specification is still loading, request is not validated",
+			"url", request.HttpRequest.URL.String())
+	}
+	ws.coverage.record(ws.loadedDocModel(), newReq)
 
-	// short-circuit if we're using mock mode, there is no API call to make.
-	if ws.config.MockMode || configModel.IncludePathOnMockMode(apiRequest.URL.Path, ws.config) {
+	// when sampling, the request is only validated if it is picked, the request is proxied either way.
+	sampled := ws.sampler.sample(apiRequest.URL.Path)
+
+	// short-circuit if we're using mock mode or dry-run mode, there is no API call to make.
+	if ws.config.DryRun {
+		ws.config.Logger.Info("[wiretap] dry-run enabled; validating synthetic response", "url", request.HttpRequest.URL.String())
+		ws.handleMockRequest(request, config, newReq, audit)
+		return
+	} else if ws.config.MockMode || configModel.IncludePathOnMockMode(apiRequest.URL.Path, ws.config) {
 		ws.config.Logger.Info("MockMode enabled; skipping validation")
-		ws.handleMockRequest(request, config, newReq)
+		ws.handleMockRequest(request, config, newReq, audit)
 		return
 	} else if configModel.IgnoreValidationOnPath(apiRequest.URL.Path, ws.config) && !configModel.PathValidationAllowListed(apiRequest.URL.Path, ws.config) {
 		ws.config.Logger.Info(
 			fmt.Sprintf("Request on validation ignored path: %s ; skipping validation", apiRequest.URL.Path))
+	} else if !sampled {
+		ws.config.Logger.Debug("[wiretap] request not sampled; skipping validation", "url", apiRequest.URL.String())
 	} else if configModel.IsHardErrorsSet(apiRequest.URL.Path, ws.config) { // check if we're going to fail hard on validation errors. (default is to skip this)
 		// validate the request synchronously
-		requestErrors = ws.ValidateRequest(request, newReq)
+		requestErrors = ws.validate(audit, func() []*errors.ValidationError {
+			return ws.ValidateRequest(request, newReq)
+		})
 	} else {
 		// validate the request asynchronously
-		go ws.ValidateRequest(request, newReq)
+		ws.validateAsync(audit, func() []*errors.ValidationError {
+			return ws.ValidateRequest(request, newReq)
+		})
 	}
 
 	// call the API being requested.
@@ -165,16 +195,30 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 		wtError := shared.GenerateError("Unable to call API", 500, returnedError.Error(), "", returnedResponse)
 		_, _ = request.HttpResponseWriter.Write(shared.MarshalError(wtError))
 		return
+	}
 
-	} else {
+	// internal upstream headers never reach the client.
+	stripResponseHeaders(config, returnedResponse)
+
+	// replace the upstream status code before it is validated, so validation sees what the client sees.
+	if len(config.StatusCodeOverrides) > 0 {
+		returnedResponse = ws.overrideStatusCode(apiRequest, returnedResponse, config.StatusCodeOverrides)
+	}
+
+	if sampled {
 
 		// check if we're going to fail hard on validation errors. (default is to skip this)
 		if configModel.IsHardErrorsSet(apiRequest.URL.Path, ws.config) {
 			// validate response
-			responseErrors = ws.ValidateResponse(request, CloneExistingResponse(returnedResponse))
+			responseErrors = ws.validate(audit, func() []*errors.ValidationError {
+				return ws.ValidateResponse(request, CloneExistingResponse(returnedResponse))
+			})
 		} else {
 			// validate response async
-			go ws.ValidateResponse(request, CloneExistingResponse(returnedResponse))
+			clonedResponse := CloneExistingResponse(returnedResponse)
+			ws.validateAsync(audit, func() []*errors.ValidationError {
+				return ws.ValidateResponse(request, clonedResponse)
+			})
 		}
 	}
 
@@ -190,6 +234,7 @@ func (ws *WiretapService) handleHttpRequest(request *model.Request) {
 
 	body, _ := io.ReadAll(returnedResponse.Body)
 	headers := ExtractHeaders(returnedResponse)
+	stripInternalVia(config, headers)
 
 	// wiretap needs to work from anywhere, so allow everything.
 	shared.SetCORSHeaders(headers)
@@ -240,6 +285,9 @@ var gorillaDropHeaders = []string{
 }
 
 func (ws *WiretapService) handleWebsocketRequest(request *model.Request) {
+	if ws.refuseRequest(request) {
+		return
+	}
 
 	configStore, _ := ws.controlsStore.Get(shared.ConfigKey)
 	config := configStore.(*shared.WiretapConfiguration)
@@ -305,6 +353,8 @@ func (ws *WiretapService) handleWebsocketRequest(request *model.Request) {
 		InjectHeaders: injectHeaders,
 		Auth:          auth,
 		Variables:     config.CompiledVariables,
+		Forwarding:    ws.clientForwarding(),
+		HopByHop:      config.CustomHopByHopHeaders,
 	})
 
 	// Open a new websocket connection with the server
@@ -335,6 +385,7 @@ func (ws *WiretapService) handleWebsocketRequest(request *model.Request) {
 				_ = clientConn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 				return
 			}
+			ws.validateWebsocketMessage(request, asyncapi.Received, messageType, message)
 
 			err = serverConn.WriteMessage(messageType, message)
 			if err != nil {
@@ -357,6 +408,7 @@ func (ws *WiretapService) handleWebsocketRequest(request *model.Request) {
 				_ = clientConn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 				return
 			}
+			ws.validateWebsocketMessage(request, asyncapi.Sent, messageType, message)
 
 			err = clientConn.WriteMessage(messageType, message)
 			if err != nil {
@@ -0,0 +1,101 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var dryRunSpec = []byte(`openapi: 3.0.3
+info:
+  title: pets
+  version: "1.0"
+paths:
+  /pets/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+      responses:
+        "200":
+          description: a pet
+          headers:
+            X-Rate-Limit: {schema: {type: integer}, example: 100}
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [name]
+                properties:
+                  name: {type: string}
+              example: {name: 1}
+`)
+
+func TestWiretapService_HandleHttpRequest_DryRun(t *testing.T) {
+	var upstreamCalls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+
+	doc, err := libopenapi.NewDocument(dryRunSpec)
+	require.NoError(t, err)
+	config := &shared.WiretapConfiguration{
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		DryRun:           true,
+		RedirectProtocol: upstreamURL.Scheme,
+		RedirectHost:     upstreamURL.Hostname(),
+		RedirectPort:     upstreamURL.Port(),
+		ReportFile:       filepath.Join(t.TempDir(), "report.json"),
+	}
+	ws := NewWiretapService(doc, config)
+	ws.controlsStore.Put(shared.ConfigKey, config, nil)
+	require.NoError(t, service.GetServiceRegistry().RegisterService(ws, "dry-run"))
+	t.Cleanup(func() {
+		service.GetServiceRegistry().UnregisterService("dry-run")
+		ws.OnServerShutdown()
+	})
+
+	id := uuid.New()
+	recorder := httptest.NewRecorder()
+	ws.handleHttpRequest(&model.Request{Id: &id, HttpResponseWriter: recorder,
+		HttpRequest: httptest.NewRequest(http.MethodGet, "/pets/1", nil)})
+
+	// the response generated from the specification is returned, the API is not called.
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"name": 1}`, recorder.Body.String())
+	assert.Equal(t, "100", recorder.Header().Get("X-Rate-Limit"))
+	assert.Zero(t, upstreamCalls.Load())
+
+	// the request and the generated response are both validated.
+	var transaction *HttpTransaction
+	require.Eventually(t, func() bool {
+		transaction = ws.GetTransaction(id.String())
+		return transaction != nil && transaction.Request != nil && transaction.Response != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, transaction.RequestValidation)
+	assert.Equal(t, http.StatusOK, transaction.Response.StatusCode)
+	// the example in the specification does not match its schema.
+	require.NotEmpty(t, transaction.ResponseValidation)
+	assert.Equal(t, helpers.ResponseBodyValidation, transaction.ResponseValidation[0].ValidationType)
+	assert.NotEmpty(t, transaction.ResponseValidation[0].SchemaValidationErrors)
+}
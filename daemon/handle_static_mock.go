@@ -13,8 +13,12 @@ import (
 )
 
 func (ws *WiretapService) handleStaticMockResponse(request *model.Request, response *http.Response) {
-	// validate response async
-	go ws.broadcastResponse(request, response)
+	audit := ws.trackAudit(request)
+	defer audit.finish()
+	ws.coverage.record(ws.loadedDocModel(), request.HttpRequest)
+
+	// the response is reported in the background, from a copy, while its body is written to the client.
+	ws.reportMockResponse(request, CloneExistingResponse(response), ws.config, audit)
 
 	for k, v := range response.Header {
 		for _, j := range v {
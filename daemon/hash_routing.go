@@ -0,0 +1,52 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// hashRoutingUpstream selects the upstream for a request body, by hashing the value found at the configured JSONPath.
+// False is returned when the body is not JSON, or the path does not match anything, so the request is sent to the
+// redirect URL as usual.
+func hashRoutingUpstream(body []byte, routing *shared.HashRoutingConfig) (*shared.UpstreamConfig, string, bool) {
+	if routing == nil || routing.CompiledPath == nil || len(routing.Upstreams) == 0 || len(body) == 0 {
+		return nil, "", false
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, "", false
+	}
+	results := routing.CompiledPath.Get(decoded)
+	if len(results) == 0 || results[0] == nil {
+		return nil, "", false
+	}
+
+	key := hashRoutingKey(results[0])
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	upstream := routing.Upstreams[h.Sum32()%uint32(len(routing.Upstreams))]
+	if upstream.ParsedURL == nil {
+		return nil, "", false
+	}
+	return upstream, key, true
+}
+
+// hashRoutingKey converts the routed value into the string that is hashed. Strings are used as they are, so
+// "42" and 42 route to the same upstream.
+func hashRoutingKey(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, bool:
+		return fmt.Sprint(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
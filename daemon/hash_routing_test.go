@@ -0,0 +1,62 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRoutingUpstream(t *testing.T) {
+	config := &shared.WiretapConfiguration{
+		HashRouting: &shared.HashRoutingConfig{
+			JSONPath: "$.customer.id",
+			Upstreams: []*shared.UpstreamConfig{
+				{URL: "http://shard-0:8080"},
+				{URL: "http://shard-1:8080"},
+				{URL: "http://shard-2:8080"},
+			},
+		},
+	}
+	assert.NoError(t, config.CompileHashRouting())
+
+	seen := make(map[string]bool)
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		body := []byte(`{"customer": {"id": "` + id + `"}}`)
+		first, key, ok := hashRoutingUpstream(body, config.HashRouting)
+		assert.True(t, ok)
+		assert.Equal(t, id, key)
+		second, _, _ := hashRoutingUpstream(body, config.HashRouting)
+		assert.Same(t, first, second)
+		seen[first.URL] = true
+	}
+	assert.Greater(t, len(seen), 1)
+
+	// numbers and strings with the same value share an upstream.
+	numeric, _, _ := hashRoutingUpstream([]byte(`{"customer": {"id": 42}}`), config.HashRouting)
+	text, _, _ := hashRoutingUpstream([]byte(`{"customer": {"id": "42"}}`), config.HashRouting)
+	assert.Same(t, numeric, text)
+
+	_, _, ok := hashRoutingUpstream([]byte(`{"order": 1}`), config.HashRouting)
+	assert.False(t, ok)
+	_, _, ok = hashRoutingUpstream([]byte(`not json`), config.HashRouting)
+	assert.False(t, ok)
+	_, _, ok = hashRoutingUpstream(nil, config.HashRouting)
+	assert.False(t, ok)
+}
+
+func TestCompileHashRouting_Invalid(t *testing.T) {
+	config := &shared.WiretapConfiguration{
+		HashRouting: &shared.HashRoutingConfig{JSONPath: "$.id"},
+	}
+	assert.Error(t, config.CompileHashRouting())
+
+	config.HashRouting.Upstreams = []*shared.UpstreamConfig{{URL: "not-a-url"}}
+	assert.Error(t, config.CompileHashRouting())
+
+	config.HashRouting.JSONPath = "$[["
+	assert.Error(t, config.CompileHashRouting())
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// oversizedHeaders checks the request headers against the configured limits. The size of a header is the length of
+// its name and its value, each value of a repeated header is counted separately. An empty detail means the headers
+// are within the limits.
+func (ws *WiretapService) oversizedHeaders(headers http.Header) string {
+	if ws.config.MaxRequestHeaderSizeBytes <= 0 && ws.config.MaxSingleHeaderBytes <= 0 {
+		return ""
+	}
+	total := 0
+	for name, values := range headers {
+		for _, value := range values {
+			if ws.config.MaxSingleHeaderBytes > 0 && len(value) > ws.config.MaxSingleHeaderBytes {
+				return fmt.Sprintf("the value of header '%s' is %d bytes, the limit is %d bytes",
+					name, len(value), ws.config.MaxSingleHeaderBytes)
+			}
+			total += len(name) + len(value)
+		}
+	}
+	if ws.config.MaxRequestHeaderSizeBytes > 0 && total > ws.config.MaxRequestHeaderSizeBytes {
+		return fmt.Sprintf("the request headers are %d bytes, the limit is %d bytes",
+			total, ws.config.MaxRequestHeaderSizeBytes)
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWiretapService_OversizedHeaders(t *testing.T) {
+	ws := &WiretapService{config: &shared.WiretapConfiguration{}}
+	headers := http.Header{"X-Big": []string{strings.Repeat("a", 100)}, "X-Small": []string{"b", "c"}}
+
+	// no limits are configured.
+	assert.Empty(t, ws.oversizedHeaders(headers))
+
+	ws.config.MaxSingleHeaderBytes = 99
+	assert.Equal(t, "the value of header 'X-Big' is 100 bytes, the limit is 99 bytes", ws.oversizedHeaders(headers))
+
+	// 105 for the big header, 8 for each small value.
+	ws.config.MaxSingleHeaderBytes = 0
+	ws.config.MaxRequestHeaderSizeBytes = 121
+	assert.Empty(t, ws.oversizedHeaders(headers))
+	ws.config.MaxRequestHeaderSizeBytes = 120
+	assert.Equal(t, "the request headers are 121 bytes, the limit is 120 bytes", ws.oversizedHeaders(headers))
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+const (
+	HostValidationWarn  = "warn"
+	HostValidationError = "error"
+)
+
+var serverVariablePattern = regexp.MustCompile(`\{([^}]+)}`)
+
+// hostValidator checks the Host header of requests against the hosts of the servers in the specification.
+// A nil validator is disabled, and accepts every host.
+type hostValidator struct {
+	mode    string
+	servers []string         // the server URLs, for error messages.
+	hosts   []*regexp.Regexp // one pattern per server with a host, server variables match their enum, or anything.
+}
+
+// newHostValidator returns nil when host validation is not enabled, or when no server in the specification has a
+// host: relative server URLs are served by whatever host the API is on.
+func newHostValidator(mode string, docModel *v3.Document) *hostValidator {
+	if mode == "" || docModel == nil {
+		return nil
+	}
+	hv := &hostValidator{mode: mode}
+	for _, server := range docModel.Servers {
+		if server == nil {
+			continue
+		}
+		pattern, ok := serverHostPattern(server)
+		if !ok {
+			return nil // a relative server matches any host.
+		}
+		hv.servers = append(hv.servers, server.URL)
+		hv.hosts = append(hv.hosts, pattern)
+	}
+	if len(hv.hosts) == 0 {
+		return nil
+	}
+	return hv
+}
+
+// serverHostPattern builds a pattern matching the host of a server URL, or returns false if the URL has no host.
+// A host without a port matches any port.
+func serverHostPattern(server *v3.Server) (*regexp.Regexp, bool) {
+	var sb strings.Builder
+	last := 0
+	for _, match := range serverVariablePattern.FindAllStringSubmatchIndex(server.URL, -1) {
+		sb.WriteString(regexp.QuoteMeta(server.URL[last:match[0]]))
+		sb.WriteString(serverVariableExpression(server, server.URL[match[2]:match[3]]))
+		last = match[1]
+	}
+	sb.WriteString(regexp.QuoteMeta(server.URL[last:]))
+
+	// the host is cut out of the pattern source, which is still a URL with quoted characters.
+	source := sb.String()
+	scheme := strings.Index(source, "://")
+	if scheme < 0 {
+		return nil, false
+	}
+	host := source[scheme+3:]
+	if end := strings.Index(host, "/"); end >= 0 {
+		host = host[:end]
+	}
+	if host == "" {
+		return nil, false
+	}
+	if !strings.Contains(host, ":") {
+		host += `(:\d+)?`
+	}
+	pattern, err := regexp.Compile("(?i)^" + host + "$")
+	if err != nil {
+		return nil, false
+	}
+	return pattern, true
+}
+
+// serverVariableExpression matches the values of a server variable: its enum, if it has one, or any host segment.
+func serverVariableExpression(server *v3.Server, name string) string {
+	if server.Variables != nil {
+		if variable, ok := server.Variables.Get(name); ok && variable != nil && len(variable.Enum) > 0 {
+			values := make([]string, len(variable.Enum))
+			for i, value := range variable.Enum {
+				values[i] = regexp.QuoteMeta(value)
+			}
+			return "(" + strings.Join(values, "|") + ")"
+		}
+	}
+	return `[^/]*`
+}
+
+// validate returns an error if the host matches none of the servers, in error mode. In warn mode a mismatch is
+// only reported by the caller, and never returned as a validation error.
+func (hv *hostValidator) validate(host string) *errors.ValidationError {
+	if hv == nil || host == "" {
+		return nil
+	}
+	for _, pattern := range hv.hosts {
+		if pattern.MatchString(host) {
+			return nil
+		}
+	}
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	return &errors.ValidationError{
+		ValidationType:    "request",
+		ValidationSubType: "host",
+		Message:           fmt.Sprintf("Host '%s' does not match any server in the specification", hostname),
+		Reason: fmt.Sprintf("The request was sent to '%s', the specification defines the servers: %s",
+			host, strings.Join(hv.servers, ", ")),
+		HowToFix: "check the client is configured to call the right environment, or add the host to the servers",
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildHostValidator(t *testing.T, mode string, spec string) *hostValidator {
+	doc, err := libopenapi.NewDocument([]byte(spec))
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+	return newHostValidator(mode, &m.Model)
+}
+
+func TestHostValidator(t *testing.T) {
+	hv := buildHostValidator(t, HostValidationError, `openapi: 3.1.0
+servers:
+  - url: https://api.example.com/v1
+  - url: https://{env}.example.com:8443
+    variables:
+      env:
+        default: staging
+        enum: [staging, qa]
+paths: {}
+`)
+	require.NotNil(t, hv)
+	assert.Nil(t, hv.validate("api.example.com"))
+	assert.Nil(t, hv.validate("API.example.com:443"))
+	assert.Nil(t, hv.validate("qa.example.com:8443"))
+
+	for _, host := range []string{"prod.example.com:8443", "qa.example.com", "api.example.com.evil.io", "localhost:9090"} {
+		ve := hv.validate(host)
+		require.NotNil(t, ve, host)
+		assert.Equal(t, "host", ve.ValidationSubType)
+		assert.Contains(t, ve.Reason, "https://api.example.com/v1")
+	}
+}
+
+func TestHostValidator_Disabled(t *testing.T) {
+	spec := `openapi: 3.1.0
+servers:
+  - url: https://api.example.com
+paths: {}
+`
+	assert.Nil(t, buildHostValidator(t, "", spec))
+	assert.Nil(t, buildHostValidator(t, HostValidationWarn, `openapi: 3.1.0
+servers:
+  - url: https://api.example.com
+  - url: /api
+paths: {}
+`))
+	assert.Nil(t, buildHostValidator(t, HostValidationWarn, "openapi: 3.1.0\npaths: {}\n"))
+
+	var hv *hostValidator
+	assert.Nil(t, hv.validate("anything"))
+}
@@ -0,0 +1,225 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+const AdminHTMLReportPath = "/wiretap/reports/html"
+
+//go:embed templates/validation-report.html
+var validationTemplate string
+
+var validationReportTemplate = template.Must(template.New("validation").Parse(validationTemplate))
+
+// the colors of the pie chart slices, in order. Colors repeat when there are more error types than colors.
+var pieColors = []string{"#c01c28", "#e66100", "#f5c211", "#1c71d8", "#9141ac", "#26a269", "#865e3c", "#5e5c64"}
+
+// ValidationReport is the content of the HTML report, validation errors grouped by path.
+type ValidationReport struct {
+	Generated    string
+	Transactions int
+	Failed       int
+	Errors       int
+	Paths        []*PathValidation
+	Chart        []*PieSlice
+}
+
+// PathValidation groups the operations of a path, and the number of errors they had.
+type PathValidation struct {
+	Path       string
+	Errors     int
+	Operations []*OperationValidation
+}
+
+// OperationValidation lists the transactions of an operation that failed validation.
+type OperationValidation struct {
+	Method       string
+	Requests     int
+	Errors       int
+	Transactions []*TransactionValidation
+}
+
+// TransactionValidation is a single transaction that failed validation, with its bodies.
+type TransactionValidation struct {
+	Id           string
+	Path         string
+	StatusCode   int
+	RequestBody  string
+	ResponseBody string
+	Errors       []*errors.ValidationError
+}
+
+// PieSlice is a slice of the summary chart, the errors of a single validation type.
+type PieSlice struct {
+	Label      string
+	Count      int
+	Percentage float64
+	Color      string
+	Path       string // the SVG path of the slice, empty when the slice is the whole chart.
+}
+
+// pieSlices splits a chart of radius 50, centered on (50, 50), into slices for the counts, in the order of the labels.
+func pieSlices(labels []string, counts map[string]int) []*PieSlice {
+	total := 0
+	for _, label := range labels {
+		total += counts[label]
+	}
+	if total == 0 {
+		return nil
+	}
+	var slices []*PieSlice
+	angle := -math.Pi / 2 // start at the top.
+	for i, label := range labels {
+		fraction := float64(counts[label]) / float64(total)
+		slice := &PieSlice{
+			Label:      label,
+			Count:      counts[label],
+			Percentage: fraction * 100,
+			Color:      pieColors[i%len(pieColors)],
+		}
+		if fraction < 1 {
+			end := angle + fraction*2*math.Pi
+			largeArc := 0
+			if fraction > 0.5 {
+				largeArc = 1
+			}
+			slice.Path = fmt.Sprintf("M 50 50 L %.3f %.3f A 50 50 0 %d 1 %.3f %.3f Z",
+				50+50*math.Cos(angle), 50+50*math.Sin(angle), largeArc, 50+50*math.Cos(end), 50+50*math.Sin(end))
+			angle = end
+		}
+		slices = append(slices, slice)
+	}
+	return slices
+}
+
+// buildValidationReport groups the validation errors of the transactions by path and operation, both sorted.
+// Transactions without errors are counted against their operation, but not listed. The generated time should be
+// in the report time zone.
+func buildValidationReport(doc *v3.Document, transactions []*HttpTransaction, generated time.Time) *ValidationReport {
+	report := &ValidationReport{Generated: generated.Format(time.RFC3339)}
+	pathsByName := make(map[string]*PathValidation)
+	operations := make(map[string]*OperationValidation)
+	errorTypes := make(map[string]int)
+
+	for _, transaction := range transactions {
+		if transaction.Request == nil {
+			continue
+		}
+		report.Transactions++
+		path := transactionOperationPath(doc, transaction.Request)
+		pv := pathsByName[path]
+		if pv == nil {
+			pv = &PathValidation{Path: path}
+			pathsByName[path] = pv
+			report.Paths = append(report.Paths, pv)
+		}
+		key := coverageKey(transaction.Request.Method, path)
+		ov := operations[key]
+		if ov == nil {
+			ov = &OperationValidation{Method: transaction.Request.Method}
+			operations[key] = ov
+			pv.Operations = append(pv.Operations, ov)
+		}
+		ov.Requests++
+
+		var validationErrors []*errors.ValidationError
+		validationErrors = append(validationErrors, transaction.RequestValidation...)
+		validationErrors = append(validationErrors, transaction.ResponseValidation...)
+		if len(validationErrors) == 0 {
+			continue
+		}
+		tv := &TransactionValidation{
+			Id:          transaction.Id,
+			Path:        replayPath(transaction.Request),
+			RequestBody: transaction.Request.Body,
+			Errors:      validationErrors,
+		}
+		if transaction.Response != nil {
+			tv.StatusCode = transaction.Response.StatusCode
+			tv.ResponseBody = transaction.Response.Body
+		}
+		ov.Transactions = append(ov.Transactions, tv)
+		ov.Errors += len(validationErrors)
+		pv.Errors += len(validationErrors)
+		report.Errors += len(validationErrors)
+		report.Failed++
+		for _, ve := range validationErrors {
+			errorTypes[validationErrorType(ve)]++
+		}
+	}
+
+	sort.SliceStable(report.Paths, func(i, j int) bool {
+		return report.Paths[i].Path < report.Paths[j].Path
+	})
+	for _, pv := range report.Paths {
+		sort.SliceStable(pv.Operations, func(i, j int) bool {
+			return pv.Operations[i].Method < pv.Operations[j].Method
+		})
+	}
+
+	labels := make([]string, 0, len(errorTypes))
+	for label := range errorTypes {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if errorTypes[labels[i]] != errorTypes[labels[j]] {
+			return errorTypes[labels[i]] > errorTypes[labels[j]]
+		}
+		return labels[i] < labels[j]
+	})
+	report.Chart = pieSlices(labels, errorTypes)
+	return report
+}
+
+// HTMLReport renders the validation errors of every captured transaction as a self-contained HTML page.
+func (ws *WiretapService) HTMLReport() ([]byte, error) {
+	var buf bytes.Buffer
+	report := buildValidationReport(ws.loadedDocModel(), ws.FindTransactions(&TransactionFilter{}),
+		ws.config.ReportTime(time.Now()))
+	if err := validationReportTemplate.Execute(&buf, report); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteHTMLReport writes the HTML report to a file, replacing the previous report.
+func (ws *WiretapService) WriteHTMLReport(path string) error {
+	report, err := ws.HTMLReport()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, report, 0644)
+}
+
+// handleHTMLReport returns the HTML report of the captured transactions. When a report file is configured,
+// the report is written to it as well.
+func (ws *WiretapService) handleHTMLReport(w http.ResponseWriter, r *http.Request) {
+	report, err := ws.HTMLReport()
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, "Unable to build HTML report", err.Error(), r.URL.Path)
+		return
+	}
+	if ws.config.HTMLReport != "" {
+		if err = os.WriteFile(ws.config.HTMLReport, report, 0644); err != nil {
+			writeAdminError(w, http.StatusInternalServerError, "Unable to write HTML report", err.Error(), r.URL.Path)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(report)
+}
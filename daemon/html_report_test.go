@@ -0,0 +1,87 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildValidationReport(t *testing.T) {
+	doc, err := libopenapi.NewDocument(coverageSpec)
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+
+	transactions := []*HttpTransaction{
+		{
+			Id:       "one",
+			Request:  &HttpRequest{Method: "POST", Path: "/pets", Body: `{"name": "<script>"}`},
+			Response: &HttpResponse{StatusCode: 201, Body: `{"id": 1}`},
+			RequestValidation: []*errors.ValidationError{
+				{Message: "missing header", ValidationType: "parameter", ValidationSubType: "header"},
+				{Message: "bad body", ValidationType: "request", ValidationSubType: "schema"},
+			},
+		},
+		{
+			Id:      "two",
+			Request: &HttpRequest{Method: "GET", Path: "/pets/2"},
+			ResponseValidation: []*errors.ValidationError{
+				{Message: "bad response", ValidationType: "response", ValidationSubType: "schema"},
+				{Message: "bad response again", ValidationType: "response", ValidationSubType: "schema"},
+			},
+		},
+		{Id: "three", Request: &HttpRequest{Method: "GET", Path: "/pets"}},
+	}
+
+	tz, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	config := &shared.WiretapConfiguration{ReportLocation: tz}
+	report := buildValidationReport(&m.Model, transactions,
+		config.ReportTime(time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC)))
+	assert.Equal(t, "2024-01-02T10:04:05-05:00", report.Generated)
+	assert.Equal(t, 3, report.Transactions)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, 4, report.Errors)
+
+	require.Len(t, report.Paths, 2)
+	assert.Equal(t, "/pets", report.Paths[0].Path)
+	assert.Equal(t, 2, report.Paths[0].Errors)
+	require.Len(t, report.Paths[0].Operations, 2)
+	assert.Equal(t, "GET", report.Paths[0].Operations[0].Method)
+	assert.Empty(t, report.Paths[0].Operations[0].Transactions)
+	assert.Equal(t, "POST", report.Paths[0].Operations[1].Method)
+	assert.Len(t, report.Paths[0].Operations[1].Transactions, 1)
+	assert.Equal(t, "/pets/{id}", report.Paths[1].Path)
+
+	// slices are sorted by count, then by name.
+	require.Len(t, report.Chart, 3)
+	assert.Equal(t, "response/schema", report.Chart[0].Label)
+	assert.Equal(t, 50.0, report.Chart[0].Percentage)
+	assert.Equal(t, "parameter/header", report.Chart[1].Label)
+	assert.Equal(t, "request/schema", report.Chart[2].Label)
+
+	var buf bytes.Buffer
+	require.NoError(t, validationReportTemplate.Execute(&buf, report))
+	html := buf.String()
+	assert.Contains(t, html, "<h2>/pets/{id} <span")
+	assert.Contains(t, html, "&lt;script&gt;") // bodies are escaped.
+	assert.Contains(t, html, "<path d=\"M 50 50 L 50.000 0.000 A 50 50 0 0 1 50.000 100.000 Z\"")
+}
+
+func TestPieSlices_Single(t *testing.T) {
+	slices := pieSlices([]string{"response/schema"}, map[string]int{"response/schema": 3})
+	require.Len(t, slices, 1)
+	assert.Empty(t, slices[0].Path) // a whole chart is drawn as a circle.
+	assert.Equal(t, 100.0, slices[0].Percentage)
+
+	assert.Nil(t, pieSlices(nil, nil))
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/paths"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/shared"
+)
+
+const AdminJUnitReportPath = "/wiretap/reports/junit"
+
+// transactionOperationPath returns the path template of the operation a transaction was sent to. Requests that
+// match no operation of the specification return their own path.
+func transactionOperationPath(doc *v3.Document, request *HttpRequest) string {
+	path := replayPath(request)
+	if doc != nil {
+		req := &http.Request{Method: request.Method, URL: &url.URL{Path: path}}
+		if pathItem, errs, template := paths.FindPath(req, doc); len(errs) == 0 && pathItem != nil &&
+			pathItem.GetOperations().GetOrZero(strings.ToLower(request.Method)) != nil {
+			path = template
+		}
+	}
+	return path
+}
+
+// transactionOperation names the operation a transaction was sent to, as the method and path template.
+func transactionOperation(doc *v3.Document, request *HttpRequest) string {
+	return coverageKey(request.Method, transactionOperationPath(doc, request))
+}
+
+// junitFailureContent describes a validation error for the body of a failed test case.
+func junitFailureContent(ve *errors.ValidationError) string {
+	var lines []string
+	if ve.Reason != "" {
+		lines = append(lines, ve.Reason)
+	}
+	for _, failure := range ve.SchemaValidationErrors {
+		if failure != nil {
+			lines = append(lines, failure.Location+": "+failure.Reason)
+		}
+	}
+	if ve.HowToFix != "" {
+		lines = append(lines, "how to fix: "+ve.HowToFix)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// buildJUnitReport builds a JUnit report of the transactions, with a test suite per operation. Every validation
+// error is a failed test case, transactions without errors are a passing test case. Suites are sorted by name.
+func buildJUnitReport(doc *v3.Document, transactions []*HttpTransaction) *shared.JUnitTestSuites {
+	suites := make(map[string]*shared.JUnitTestSuite)
+	report := &shared.JUnitTestSuites{Name: "wiretap"}
+	for _, transaction := range transactions {
+		if transaction.Request == nil {
+			continue
+		}
+		operation := transactionOperation(doc, transaction.Request)
+		suite := suites[operation]
+		if suite == nil {
+			suite = &shared.JUnitTestSuite{Name: operation}
+			suites[operation] = suite
+			report.Suites = append(report.Suites, suite)
+		}
+
+		var validationErrors []*errors.ValidationError
+		validationErrors = append(validationErrors, transaction.RequestValidation...)
+		validationErrors = append(validationErrors, transaction.ResponseValidation...)
+		if len(validationErrors) == 0 {
+			suite.TestCases = append(suite.TestCases, &shared.JUnitTestCase{
+				Name:      transaction.Request.Method + " " + replayPath(transaction.Request),
+				ClassName: operation,
+				SystemOut: "transaction: " + transaction.Id,
+			})
+			continue
+		}
+		for _, ve := range validationErrors {
+			suite.TestCases = append(suite.TestCases, &shared.JUnitTestCase{
+				Name:      ve.Message,
+				ClassName: operation,
+				Failure: &shared.JUnitFailure{
+					Message: ve.Message,
+					Type:    validationErrorType(ve),
+					Content: junitFailureContent(ve),
+				},
+				SystemOut: "transaction: " + transaction.Id,
+			})
+			suite.Failures++
+		}
+	}
+	sort.SliceStable(report.Suites, func(i, j int) bool {
+		return report.Suites[i].Name < report.Suites[j].Name
+	})
+	for _, suite := range report.Suites {
+		suite.Tests = len(suite.TestCases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+	}
+	return report
+}
+
+// JUnitReport renders the validation errors of every captured transaction as JUnit XML.
+func (ws *WiretapService) JUnitReport() ([]byte, error) {
+	return buildJUnitReport(ws.loadedDocModel(), ws.FindTransactions(&TransactionFilter{})).Marshal()
+}
+
+// WriteJUnitReport writes the JUnit report to a file, replacing the previous report.
+func (ws *WiretapService) WriteJUnitReport(path string) error {
+	report, err := ws.JUnitReport()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, report, 0644)
+}
+
+// handleJUnitReport returns the JUnit report of the captured transactions. When a report file is configured,
+// the report is written to it as well.
+func (ws *WiretapService) handleJUnitReport(w http.ResponseWriter, r *http.Request) {
+	report, err := ws.JUnitReport()
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, "Unable to build JUnit report", err.Error(), r.URL.Path)
+		return
+	}
+	if ws.config.JUnitReport != "" {
+		if err = os.WriteFile(ws.config.JUnitReport, report, 0644); err != nil {
+			writeAdminError(w, http.StatusInternalServerError, "Unable to write JUnit report", err.Error(), r.URL.Path)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(report)
+}
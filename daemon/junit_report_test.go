@@ -0,0 +1,64 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildJUnitReport(t *testing.T) {
+	doc, err := libopenapi.NewDocument(coverageSpec)
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+
+	transactions := []*HttpTransaction{
+		{
+			Id:      "one",
+			Request: &HttpRequest{Method: "GET", Path: "/pets/1"},
+			ResponseValidation: []*errors.ValidationError{{
+				Message: "200 response body for '/pets/1' failed to validate schema", Reason: "schema",
+				ValidationType: "response", ValidationSubType: "schema",
+				SchemaValidationErrors: []*errors.SchemaValidationFailure{
+					{Reason: "missing property 'name'", Location: "/required"},
+				},
+			}},
+		},
+		{Id: "two", Request: &HttpRequest{Method: "GET", Path: "/pets/2"}},
+		{Id: "three", Request: &HttpRequest{Method: "GET", Path: "/owners"}},
+	}
+
+	report := buildJUnitReport(&m.Model, transactions)
+	assert.Equal(t, 3, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	require.Len(t, report.Suites, 2)
+
+	// requests that match no operation are a suite of their own, named by the path.
+	assert.Equal(t, "GET /owners", report.Suites[0].Name)
+	assert.Equal(t, 0, report.Suites[0].Failures)
+
+	pets := report.Suites[1]
+	assert.Equal(t, "GET /pets/{id}", pets.Name)
+	assert.Equal(t, 2, pets.Tests)
+	assert.Equal(t, 1, pets.Failures)
+	require.NotNil(t, pets.TestCases[0].Failure)
+	assert.Equal(t, "response/schema", pets.TestCases[0].Failure.Type)
+	assert.Equal(t, "schema\n/required: missing property 'name'", pets.TestCases[0].Failure.Content)
+	assert.Nil(t, pets.TestCases[1].Failure)
+	assert.Equal(t, "GET /pets/2", pets.TestCases[1].Name)
+
+	b, err := report.Marshal()
+	require.NoError(t, err)
+	var decoded shared.JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(b, &decoded))
+	assert.Equal(t, 3, decoded.Tests)
+	assert.Contains(t, string(b), `<testsuite name="GET /pets/{id}" tests="2" failures="1">`)
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// connectionRequestsKey holds the number of requests served on a connection, in the context of its requests.
+type connectionRequestsKey struct{}
+
+// ConfigureKeepAlive applies the keep-alive configuration to the proxy server, and wraps its handler to close
+// connections once they have served the maximum number of requests. It must be called before the server starts.
+func ConfigureKeepAlive(server *http.Server, config *shared.WiretapConfiguration) {
+	if config.KeepAliveEnabled != nil {
+		server.SetKeepAlivesEnabled(*config.KeepAliveEnabled)
+	}
+	if config.KeepAliveIdleSeconds > 0 {
+		server.IdleTimeout = time.Duration(config.KeepAliveIdleSeconds) * time.Second
+	}
+	if config.MaxRequestsPerConnection <= 0 {
+		return
+	}
+
+	connContext := server.ConnContext
+	server.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		if connContext != nil {
+			ctx = connContext(ctx, c)
+		}
+		return context.WithValue(ctx, connectionRequestsKey{}, &atomic.Int64{})
+	}
+	next, limit := server.Handler, int64(config.MaxRequestsPerConnection)
+	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server closes the connection once a response asking for it has been written.
+		if requests, ok := r.Context().Value(connectionRequestsKey{}).(*atomic.Int64); ok && requests.Add(1) >= limit {
+			w.Header().Set("Connection", "close")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
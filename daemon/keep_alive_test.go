@@ -0,0 +1,44 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureKeepAlive_IdleTimeout(t *testing.T) {
+	server := &http.Server{Handler: http.NotFoundHandler()}
+	ConfigureKeepAlive(server, &shared.WiretapConfiguration{KeepAliveIdleSeconds: 30})
+	assert.Equal(t, 30*time.Second, server.IdleTimeout)
+	assert.Nil(t, server.ConnContext)
+}
+
+func TestConfigureKeepAlive_MaxRequestsPerConnection(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	ConfigureKeepAlive(server.Config, &shared.WiretapConfiguration{MaxRequestsPerConnection: 2})
+	server.Start()
+	defer server.Close()
+
+	client := server.Client()
+	var closed []bool
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		closed = append(closed, resp.Close)
+	}
+	// the second request reaches the limit, the third is served on a new connection.
+	assert.Equal(t, []bool{false, true, false}, closed)
+}
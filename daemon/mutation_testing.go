@@ -0,0 +1,487 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+const AdminMutationTestPath = "/wiretap/mutation-test"
+
+const (
+	MutationRemoveRequiredField     = "remove-required-field"
+	MutationChangeType              = "change-type"
+	MutationUnknownField            = "unknown-field"
+	MutationRemoveRequiredParameter = "remove-required-parameter"
+	MutationChangeParameterType     = "change-parameter-type"
+)
+
+const (
+	mutationValue        = "wiretap-mutation"
+	mutationNumber       = 12345
+	mutationUnknownField = "wiretapMutationUnknownField"
+)
+
+// MutationTestRequest selects the captured transaction to run mutation testing against.
+type MutationTestRequest struct {
+	TransactionId string `json:"transactionId"`
+}
+
+// MutationResult is the outcome of validating a single mutation of the captured request.
+type MutationResult struct {
+	Kind        string   `json:"kind"`
+	Location    string   `json:"location"`
+	Description string   `json:"description"`
+	Caught      bool     `json:"caught"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// MutationTestReport lists which mutations of a captured request were caught by validation, and which were missed.
+// Missed mutations point at gaps in the specification, such as missing required fields or open objects.
+type MutationTestReport struct {
+	TransactionId  string                    `json:"transactionId"`
+	BaselineErrors []*errors.ValidationError `json:"baselineErrors,omitempty"`
+	Total          int                       `json:"total"`
+	Caught         int                       `json:"caught"`
+	Missed         int                       `json:"missed"`
+	Mutations      []*MutationResult         `json:"mutations"`
+}
+
+// mutation describes a single change to the captured request.
+type mutation struct {
+	kind        string
+	location    string
+	description string
+	apply       func(rt *requestTemplate)
+}
+
+// requestTemplate holds the parts of a captured request, so mutations can be applied to a copy of it.
+type requestTemplate struct {
+	method  string
+	host    string
+	path    string
+	query   url.Values
+	header  http.Header
+	rawBody string
+	body    any
+	isJSON  bool
+}
+
+func newRequestTemplate(req *HttpRequest) *requestTemplate {
+	rt := &requestTemplate{
+		method:  req.Method,
+		host:    req.Host,
+		path:    req.OriginalPath,
+		header:  make(http.Header),
+		rawBody: req.Body,
+	}
+	if rt.path == "" {
+		rt.path = req.Path
+	}
+	if rt.host == "" {
+		rt.host = "localhost"
+	}
+	rt.query, _ = url.ParseQuery(req.Query)
+	for k, v := range req.Headers {
+		rt.header.Set(k, fmt.Sprint(v))
+	}
+	if strings.Contains(rt.header.Get("Content-Type"), "json") && req.Body != "" {
+		if err := json.Unmarshal([]byte(req.Body), &rt.body); err == nil {
+			rt.isJSON = true
+		}
+	}
+	return rt
+}
+
+func (rt *requestTemplate) clone() *requestTemplate {
+	c := *rt
+	c.query = make(url.Values, len(rt.query))
+	for k, v := range rt.query {
+		c.query[k] = append([]string(nil), v...)
+	}
+	c.header = rt.header.Clone()
+	if rt.isJSON {
+		c.body = copyJSONValue(rt.body)
+	}
+	return &c
+}
+
+func (rt *requestTemplate) build() (*http.Request, error) {
+	u := &url.URL{Scheme: "http", Host: rt.host, Path: rt.path, RawQuery: rt.query.Encode()}
+	body := []byte(rt.rawBody)
+	if rt.isJSON {
+		body, _ = json.Marshal(rt.body)
+	}
+	req, err := http.NewRequest(rt.method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = rt.header.Clone()
+	return req, nil
+}
+
+func copyJSONValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, val := range v {
+			c[k] = copyJSONValue(val)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, val := range v {
+			c[i] = copyJSONValue(val)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// RunMutationTest mutates the request of a captured transaction, re-validates every mutation against the
+// specification and reports which mutations were caught by validation.
+func (ws *WiretapService) RunMutationTest(transaction *HttpTransaction) (*MutationTestReport, error) {
+	if transaction.Request == nil {
+		return nil, fmt.Errorf("transaction '%s' has no captured request", transaction.Id)
+	}
+	rt := newRequestTemplate(transaction.Request)
+	baseline, err := rt.build()
+	if err != nil {
+		return nil, err
+	}
+	spec := ws.currentSpec()
+	_, baselineErrors := spec.validator.ValidateHttpRequest(baseline)
+
+	report := &MutationTestReport{
+		TransactionId:  transaction.Id,
+		BaselineErrors: baselineErrors,
+		Mutations:      []*MutationResult{},
+	}
+
+	for _, m := range buildMutations(spec.docModel, rt, baseline) {
+		mutated := rt.clone()
+		m.apply(mutated)
+		req, err := mutated.build()
+		if err != nil {
+			continue
+		}
+		_, validationErrors := spec.validator.ValidateHttpRequest(req)
+		result := &MutationResult{
+			Kind:        m.kind,
+			Location:    m.location,
+			Description: m.description,
+			// a mutation is caught when it produces more errors than the unmodified request did.
+			Caught: len(validationErrors) > len(baselineErrors),
+		}
+		for _, ve := range validationErrors {
+			result.Errors = append(result.Errors, ve.Message)
+		}
+		report.Total++
+		if result.Caught {
+			report.Caught++
+		} else {
+			report.Missed++
+		}
+		report.Mutations = append(report.Mutations, result)
+	}
+	return report, nil
+}
+
+// buildMutations creates the mutations for a request, based on the operation it matches in the specification.
+func buildMutations(docModel *v3.Document, rt *requestTemplate, request *http.Request) []*mutation {
+	var mutations []*mutation
+
+	var operation *v3.Operation
+	var params []*v3.Parameter
+	if pathItem, errs, _ := paths.FindPath(request, docModel); len(errs) == 0 && pathItem != nil {
+		operation = pathItem.GetOperations().GetOrZero(strings.ToLower(request.Method))
+		params = append(params, pathItem.Parameters...)
+	}
+	if operation != nil {
+		params = append(params, operation.Parameters...)
+	}
+	mutations = append(mutations, parameterMutations(rt, params)...)
+
+	if rt.isJSON {
+		var schema *base.Schema
+		if operation != nil && operation.RequestBody != nil && operation.RequestBody.Content != nil {
+			mediaType, _, _ := mime.ParseMediaType(rt.header.Get("Content-Type"))
+			if mt := operation.RequestBody.Content.GetOrZero(mediaType); mt != nil && mt.Schema != nil {
+				schema = mt.Schema.Schema()
+			}
+		}
+		mutations = append(mutations, bodyMutations(rt.body, schema, nil)...)
+	}
+	return mutations
+}
+
+// parameterMutations removes required query parameters and headers, and sends values of the wrong type for
+// parameters that expect numbers or booleans.
+func parameterMutations(rt *requestTemplate, params []*v3.Parameter) []*mutation {
+	var mutations []*mutation
+	for _, param := range params {
+		if param == nil || (param.In != "query" && param.In != "header") {
+			continue
+		}
+		name := param.Name
+		var present bool
+		if param.In == "query" {
+			_, present = rt.query[name]
+		} else {
+			present = rt.header.Get(name) != ""
+		}
+		if !present {
+			continue
+		}
+		location := param.In + "." + name
+		isQuery := param.In == "query"
+
+		if param.Required != nil && *param.Required {
+			mutations = append(mutations, &mutation{
+				kind:        MutationRemoveRequiredParameter,
+				location:    location,
+				description: fmt.Sprintf("removed required %s parameter '%s'", param.In, name),
+				apply: func(rt *requestTemplate) {
+					if isQuery {
+						rt.query.Del(name)
+					} else {
+						rt.header.Del(name)
+					}
+				},
+			})
+		}
+
+		if param.Schema != nil {
+			if schema := param.Schema.Schema(); schema != nil && isScalarNonString(schema.Type) {
+				mutations = append(mutations, &mutation{
+					kind:     MutationChangeParameterType,
+					location: location,
+					description: fmt.Sprintf("sent a string for %s parameter '%s', which expects %s",
+						param.In, name, strings.Join(schema.Type, " or ")),
+					apply: func(rt *requestTemplate) {
+						if isQuery {
+							rt.query.Set(name, mutationValue)
+						} else {
+							rt.header.Set(name, mutationValue)
+						}
+					},
+				})
+			}
+		}
+	}
+	return mutations
+}
+
+func isScalarNonString(types []string) bool {
+	for _, t := range types {
+		if t == "integer" || t == "number" || t == "boolean" {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyMutations walks a JSON body alongside its schema, removing required fields, changing the type of every
+// field and adding an unknown field to every object.
+func bodyMutations(value any, schema *base.Schema, path []any) []*mutation {
+	var mutations []*mutation
+	switch v := value.(type) {
+	case map[string]any:
+		location := jsonLocation(path)
+		mutations = append(mutations, &mutation{
+			kind:        MutationUnknownField,
+			location:    location,
+			description: fmt.Sprintf("added unknown field '%s' to %s", mutationUnknownField, location),
+			apply: func(rt *requestTemplate) {
+				if obj, ok := jsonValueAt(rt.body, path).(map[string]any); ok {
+					obj[mutationUnknownField] = mutationValue
+				}
+			},
+		})
+
+		if schema != nil {
+			for _, required := range schema.Required {
+				if _, ok := v[required]; !ok {
+					continue
+				}
+				field := required
+				fieldLocation := jsonLocation(append(append([]any{}, path...), field))
+				mutations = append(mutations, &mutation{
+					kind:        MutationRemoveRequiredField,
+					location:    fieldLocation,
+					description: fmt.Sprintf("removed required field %s", fieldLocation),
+					apply: func(rt *requestTemplate) {
+						if obj, ok := jsonValueAt(rt.body, path).(map[string]any); ok {
+							delete(obj, field)
+						}
+					},
+				})
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			var child *base.Schema
+			if schema != nil && schema.Properties != nil {
+				if proxy := schema.Properties.GetOrZero(k); proxy != nil {
+					child = proxy.Schema()
+				}
+			}
+			childPath := append(append([]any{}, path...), k)
+			if m := changeTypeMutation(v[k], childPath); m != nil {
+				mutations = append(mutations, m)
+			}
+			mutations = append(mutations, bodyMutations(v[k], child, childPath)...)
+		}
+
+	case []any:
+		// every item shares a schema, mutating the first item is enough.
+		if len(v) == 0 {
+			return nil
+		}
+		var child *base.Schema
+		if schema != nil && schema.Items != nil && schema.Items.IsA() && schema.Items.A != nil {
+			child = schema.Items.A.Schema()
+		}
+		childPath := append(append([]any{}, path...), 0)
+		if m := changeTypeMutation(v[0], childPath); m != nil {
+			mutations = append(mutations, m)
+		}
+		mutations = append(mutations, bodyMutations(v[0], child, childPath)...)
+	}
+	return mutations
+}
+
+// changeTypeMutation replaces a value with a value of a different JSON type.
+func changeTypeMutation(value any, path []any) *mutation {
+	var replacement any
+	switch value.(type) {
+	case string:
+		replacement = mutationNumber
+	case float64, bool, map[string]any, []any:
+		replacement = mutationValue
+	default:
+		return nil
+	}
+	location := jsonLocation(path)
+	parent, key := path[:len(path)-1], path[len(path)-1]
+	return &mutation{
+		kind:        MutationChangeType,
+		location:    location,
+		description: fmt.Sprintf("changed the type of %s from %s to %s", location, jsonType(value), jsonType(replacement)),
+		apply: func(rt *requestTemplate) {
+			switch container := jsonValueAt(rt.body, parent).(type) {
+			case map[string]any:
+				container[key.(string)] = replacement
+			case []any:
+				container[key.(int)] = replacement
+			}
+		},
+	}
+}
+
+func jsonValueAt(value any, path []any) any {
+	for _, segment := range path {
+		switch s := segment.(type) {
+		case string:
+			obj, ok := value.(map[string]any)
+			if !ok {
+				return nil
+			}
+			value = obj[s]
+		case int:
+			arr, ok := value.([]any)
+			if !ok || s >= len(arr) {
+				return nil
+			}
+			value = arr[s]
+		}
+	}
+	return value
+}
+
+func jsonLocation(path []any) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, segment := range path {
+		switch s := segment.(type) {
+		case string:
+			sb.WriteString("." + s)
+		case int:
+			sb.WriteString("[" + strconv.Itoa(s) + "]")
+		}
+	}
+	return sb.String()
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64, int:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return "null"
+}
+
+// handleMutationTest runs mutation testing against the captured transaction named in the request body.
+func (ws *WiretapService) handleMutationTest(w http.ResponseWriter, r *http.Request) {
+	if !ws.config.MutationTest {
+		writeAdminError(w, http.StatusConflict, "Mutation testing is not enabled",
+			"mutation testing is not enabled in the wiretap configuration", r.URL.Path)
+		return
+	}
+	if spec := ws.currentSpec(); spec.docModel == nil || spec.validator == nil {
+		writeAdminError(w, http.StatusConflict, "No OpenAPI specification loaded",
+			"mutation testing validates requests against a specification, none has been loaded", r.URL.Path)
+		return
+	}
+	var mutationRequest MutationTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&mutationRequest); err != nil || mutationRequest.TransactionId == "" {
+		detail := "the request body must be a JSON object with a 'transactionId'"
+		if err != nil {
+			detail += ": " + err.Error()
+		}
+		writeAdminError(w, http.StatusBadRequest, "Invalid mutation test request", detail, r.URL.Path)
+		return
+	}
+	transaction := ws.GetTransaction(mutationRequest.TransactionId)
+	if transaction == nil {
+		writeAdminError(w, http.StatusNotFound, "Transaction not found",
+			fmt.Sprintf("no transaction with the id '%s' has been captured", mutationRequest.TransactionId), r.URL.Path)
+		return
+	}
+	report, err := ws.RunMutationTest(transaction)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Unable to run mutation test", err.Error(), r.URL.Path)
+		return
+	}
+	ws.config.Logger.Info("[wiretap] mutation test complete", "transaction", transaction.Id,
+		"caught", report.Caught, "missed", report.Missed)
+	writeAdminResponse(w, http.StatusOK, report)
+}
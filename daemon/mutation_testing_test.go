@@ -0,0 +1,91 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi-validator"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var mutationSpec = []byte(`openapi: 3.1.0
+paths:
+  /pets:
+    post:
+      parameters:
+        - name: limit
+          in: query
+          required: true
+          schema:
+            type: integer
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              additionalProperties: false
+              required: [name]
+              properties:
+                name:
+                  type: string
+                owner:
+                  type: object
+                  properties:
+                    email:
+                      type: string
+      responses:
+        "201":
+          description: created
+`)
+
+func TestRunMutationTest(t *testing.T) {
+	doc, err := libopenapi.NewDocument(mutationSpec)
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+
+	ws := &WiretapService{}
+	ws.spec.Store(&loadedSpec{docModel: &m.Model, validator: validator.NewValidatorFromV3Model(&m.Model)})
+	report, err := ws.RunMutationTest(&HttpTransaction{
+		Id: "abc",
+		Request: &HttpRequest{
+			Method:       http.MethodPost,
+			OriginalPath: "/pets",
+			Query:        "limit=10",
+			Headers:      map[string]any{"Content-Type": "application/json"},
+			Body:         `{"name": "fido", "owner": {"email": "a@b.c"}}`,
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, report.BaselineErrors)
+
+	results := make(map[string]*MutationResult)
+	for _, result := range report.Mutations {
+		results[result.Kind+" "+result.Location] = result
+	}
+	assert.True(t, results[MutationRemoveRequiredParameter+" query.limit"].Caught)
+	assert.True(t, results[MutationChangeParameterType+" query.limit"].Caught)
+	assert.True(t, results[MutationRemoveRequiredField+" $.name"].Caught)
+	assert.True(t, results[MutationChangeType+" $.name"].Caught)
+	assert.True(t, results[MutationUnknownField+" $"].Caught)
+	// the owner object is open, so an unknown field is a gap in the specification.
+	assert.False(t, results[MutationUnknownField+" $.owner"].Caught)
+	assert.Equal(t, report.Total, report.Caught+report.Missed)
+	assert.Positive(t, report.Missed)
+}
+
+func TestHandleMutationTest_Disabled(t *testing.T) {
+	ws := &WiretapService{config: &shared.WiretapConfiguration{}}
+	rec := httptest.NewRecorder()
+	ws.handleMutationTest(rec, httptest.NewRequest(http.MethodPost, AdminMutationTestPath,
+		strings.NewReader(`{"transactionId": "abc"}`)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+)
+
+const (
+	// DefaultNATSSubject is the subject validation errors are published to, when none is configured.
+	DefaultNATSSubject = "wiretap.validation"
+
+	natsPublishBuffer = 1024
+	natsTimeout       = 10 * time.Second
+	natsRetryInterval = 5 * time.Second
+)
+
+// ValidationErrorEvent is published to NATS for every request or response that fails validation.
+type ValidationErrorEvent struct {
+	TransactionId string                    `json:"transactionId,omitempty"`
+	Direction     string                    `json:"direction"` // request or response.
+	Method        string                    `json:"method,omitempty"`
+	Path          string                    `json:"path,omitempty"`
+	StatusCode    int                       `json:"statusCode,omitempty"`
+	Timestamp     int64                     `json:"timestamp"`
+	Errors        []*errors.ValidationError `json:"errors"`
+}
+
+// natsPublisher publishes events to a NATS subject. Events are queued and published in order by a single goroutine,
+// and dropped rather than blocking if the queue is full. The NATS client keeps reconnecting when the servers cannot
+// be reached, events published in the meantime are buffered by the client until it is connected again.
+type natsPublisher struct {
+	conn    *nats.Conn
+	subject string
+	logger  *slog.Logger
+	queue   chan []byte
+	done    chan struct{}
+	stopped chan struct{}
+	closed  chan struct{}
+}
+
+// startNATSPublisher starts publishing validation errors to NATS, if a server is configured.
+func (ws *WiretapService) startNATSPublisher() {
+	if ws.config.NATS == nil || ws.config.NATS.ServerURL == "" {
+		return
+	}
+	publisher, err := newNATSPublisher(ws.config.NATS, ws.config.Logger)
+	if err != nil {
+		ws.config.Logger.Error("[wiretap] unable to publish validation errors to NATS", "error", err.Error())
+		return
+	}
+	ws.natsPublisher = publisher
+	go publisher.run()
+}
+
+func newNATSPublisher(config *shared.NATSConfig, logger *slog.Logger) (*natsPublisher, error) {
+	np := &natsPublisher{
+		subject: config.Subject,
+		logger:  logger,
+		queue:   make(chan []byte, natsPublishBuffer),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	if np.subject == "" {
+		np.subject = DefaultNATSSubject
+	}
+	options, err := natsOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options,
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("[wiretap] lost the connection to NATS, reconnecting", "error", err.Error())
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logger.Info("[wiretap] reconnected to NATS", "server", conn.ConnectedUrlRedacted())
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			logger.Warn("[wiretap] NATS error", "error", err.Error())
+		}),
+		nats.ClosedHandler(func(*nats.Conn) { close(np.closed) }),
+	)
+	if np.conn, err = nats.Connect(config.ServerURL, options...); err != nil {
+		return nil, fmt.Errorf("unable to connect to NATS: %w", err)
+	}
+	return np, nil
+}
+
+// natsOptions configures the NATS client. The client keeps trying to connect, so wiretap starts even when the servers
+// are not up yet. Credentials in the server URLs are used by the client itself.
+func natsOptions(config *shared.NATSConfig) ([]nats.Option, error) {
+	options := []nats.Option{
+		nats.Name("wiretap"),
+		nats.Timeout(natsTimeout),
+		nats.DrainTimeout(natsTimeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(natsRetryInterval),
+	}
+	if config.CredentialsFile != "" {
+		options = append(options, nats.UserCredentials(config.CredentialsFile))
+	}
+	if config.NKeySeedFile != "" {
+		option, err := nats.NkeyOptionFromSeed(config.NKeySeedFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read NATS nkey seed '%s': %w", config.NKeySeedFile, err)
+		}
+		options = append(options, option)
+	}
+	if config.CACertFile != "" {
+		options = append(options, nats.RootCAs(config.CACertFile))
+	}
+	if config.CertFile != "" {
+		options = append(options, nats.ClientCert(config.CertFile, config.KeyFile))
+	}
+	return options, nil
+}
+
+// publishValidationErrors publishes the validation errors of a request or a response to NATS, if configured.
+func (ws *WiretapService) publishValidationErrors(direction string, request *model.Request, response *http.Response,
+	validationErrors []*errors.ValidationError) {
+
+	if ws.natsPublisher == nil || len(validationErrors) == 0 {
+		return
+	}
+	event := &ValidationErrorEvent{
+		Direction: direction,
+		Timestamp: time.Now().UnixMilli(),
+		Errors:    validationErrors,
+	}
+	if response != nil {
+		event.StatusCode = response.StatusCode
+	}
+	if request.Id != nil {
+		event.TransactionId = request.Id.String()
+	}
+	if request.HttpRequest != nil {
+		event.Method = request.HttpRequest.Method
+		event.Path = request.HttpRequest.URL.Path
+	}
+	ws.natsPublisher.publish(event)
+}
+
+// publish queues an event to be published.
+func (np *natsPublisher) publish(event *ValidationErrorEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		np.logger.Warn("[wiretap] unable to encode validation errors for NATS", "error", err.Error())
+		return
+	}
+	select {
+	case <-np.done:
+	case np.queue <- payload:
+	default:
+		np.logger.Warn("[wiretap] NATS publish buffer is full, dropping validation errors", "path", event.Path)
+	}
+}
+
+// run publishes queued events, until the publisher is stopped. Events still queued when the publisher is stopped
+// are published before it returns.
+func (np *natsPublisher) run() {
+	defer close(np.stopped)
+	for {
+		select {
+		case payload := <-np.queue:
+			np.send(payload)
+		case <-np.done:
+			for {
+				select {
+				case payload := <-np.queue:
+					np.send(payload)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (np *natsPublisher) send(payload []byte) {
+	if err := np.conn.Publish(np.subject, payload); err != nil {
+		np.logger.Warn("[wiretap] unable to publish validation errors to NATS", "error", err.Error())
+	}
+}
+
+// stop publishes the queued events, then drains the connection so they reach the server before it is closed. Events
+// are given up on if the server cannot be reached in time.
+func (np *natsPublisher) stop() {
+	close(np.done)
+	select {
+	case <-np.stopped:
+	case <-time.After(natsTimeout):
+	}
+	if err := np.conn.Drain(); err != nil {
+		np.conn.Close()
+	}
+	select {
+	case <-np.closed:
+	case <-time.After(natsTimeout):
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNATSServer accepts clients that authenticate with the token, and sends what they publish to the channel.
+type fakeNATSServer struct {
+	listener  net.Listener
+	token     string
+	published chan string
+	connects  chan map[string]any
+}
+
+func newFakeNATSServer(t *testing.T, token string) *fakeNATSServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fs := &fakeNATSServer{listener: listener, token: token, published: make(chan string, 10),
+		connects: make(chan map[string]any, 10)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fs.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return fs
+}
+
+func (fs *fakeNATSServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	io.WriteString(conn, `INFO {"server_id":"fake","auth_required":true,"max_payload":1048576}`+"\r\n")
+	for {
+		line, err := readNATSLine(reader)
+		if err != nil {
+			return
+		}
+		command, args, _ := strings.Cut(line, " ")
+		switch command {
+		case "CONNECT":
+			var options map[string]any
+			json.Unmarshal([]byte(args), &options)
+			fs.connects <- options
+			if options["auth_token"] != fs.token {
+				io.WriteString(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "PUB":
+			fields := strings.Fields(args)
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			io.ReadFull(reader, payload)
+			fs.published <- fields[0] + " " + string(payload[:size])
+		}
+	}
+}
+
+func readNATSLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func TestNewNATSPublisher(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// the client keeps trying to connect to servers that are not up yet.
+	np, err := newNATSPublisher(&shared.NATSConfig{ServerURL: "nats://127.0.0.1:1"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultNATSSubject, np.subject)
+	assert.True(t, np.conn.IsReconnecting())
+	go np.run()
+	np.stop()
+	assert.True(t, np.conn.IsClosed())
+
+	_, err = newNATSPublisher(&shared.NATSConfig{ServerURL: "nats://127.0.0.1:1", NKeySeedFile: "/missing/seed.nk"},
+		logger)
+	assert.ErrorContains(t, err, "unable to read NATS nkey seed '/missing/seed.nk'")
+
+	_, err = newNATSPublisher(&shared.NATSConfig{ServerURL: "nats://127.0.0.1:1", CACertFile: "/missing/ca.pem"},
+		logger)
+	assert.ErrorContains(t, err, "unable to connect to NATS")
+
+	options, err := natsOptions(&shared.NATSConfig{})
+	require.NoError(t, err)
+	natsOpts := nats.GetDefaultOptions()
+	for _, option := range options {
+		require.NoError(t, option(&natsOpts))
+	}
+	assert.Equal(t, "wiretap", natsOpts.Name)
+	assert.True(t, natsOpts.RetryOnFailedConnect)
+	assert.Equal(t, -1, natsOpts.MaxReconnect)
+}
+
+func TestWiretapService_PublishValidationErrors(t *testing.T) {
+	fs := newFakeNATSServer(t, "s3cr3t")
+	ws := &WiretapService{config: &shared.WiretapConfiguration{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		// the first server is down, the client moves on to the next one.
+		NATS: &shared.NATSConfig{ServerURL: fmt.Sprintf("nats://127.0.0.1:1,nats://s3cr3t@%s", fs.listener.Addr()),
+			Subject: "checks"},
+	}}
+	ws.startNATSPublisher()
+	require.NotNil(t, ws.natsPublisher)
+
+	id := uuid.New()
+	httpRequest, _ := http.NewRequest(http.MethodPost, "http://localhost/pets?limit=1", nil)
+	request := &model.Request{Id: &id, HttpRequest: httpRequest}
+	ws.publishValidationErrors("response", request, &http.Response{StatusCode: 201},
+		[]*errors.ValidationError{{Message: "response body is not valid"}})
+	// requests without errors are not published.
+	ws.publishValidationErrors("request", request, nil, nil)
+
+	select {
+	case options := <-fs.connects:
+		assert.Equal(t, "wiretap", options["name"])
+		assert.Equal(t, "s3cr3t", options["auth_token"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("wiretap did not connect to NATS")
+	}
+	select {
+	case published := <-fs.published:
+		subject, payload, _ := strings.Cut(published, " ")
+		assert.Equal(t, "checks", subject)
+		var event ValidationErrorEvent
+		require.NoError(t, json.Unmarshal([]byte(payload), &event))
+		assert.Equal(t, id.String(), event.TransactionId)
+		assert.Equal(t, "response", event.Direction)
+		assert.Equal(t, http.MethodPost, event.Method)
+		assert.Equal(t, "/pets", event.Path)
+		assert.Equal(t, 201, event.StatusCode)
+		require.Len(t, event.Errors, 1)
+		assert.Equal(t, "response body is not valid", event.Errors[0].Message)
+	case <-time.After(5 * time.Second):
+		t.Fatal("validation errors were not published")
+	}
+
+	// queued events are published when wiretap stops.
+	ws.publishValidationErrors("request", request, nil, []*errors.ValidationError{{Message: "missing header"}})
+	ws.natsPublisher.stop()
+	select {
+	case published := <-fs.published:
+		assert.Contains(t, published, "missing header")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queued event was not published")
+	}
+}
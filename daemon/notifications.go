@@ -0,0 +1,254 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+)
+
+const (
+	// DefaultNotificationWindow is the window errors are batched over, when a notification does not set one.
+	DefaultNotificationWindow = time.Minute
+
+	// notificationMaxPaths is the number of paths listed in a message, the paths with the most errors first.
+	notificationMaxPaths = 10
+)
+
+// notifier batches validation errors and sends a summary at the end of every window, if enough errors were found
+// in the window. Errors of a window that does not have enough errors are discarded. When a minimum interval is
+// set, summaries are not sent more often, errors found in between are kept for the next summary.
+type notifier struct {
+	name     string
+	window   time.Duration
+	interval time.Duration
+	ready    func(count int) bool
+	send     func(violations []*errors.ValidationError, period time.Duration) error
+	clear    func() error // called at the end of windows without errors, if set.
+	lock     sync.Mutex
+	sendLock sync.Mutex
+	errors   []*errors.ValidationError
+	since    time.Time
+	lastSent time.Time
+}
+
+// notifiers is the set of configured notifiers, an empty set records nothing.
+type notifiers []*notifier
+
+// pathErrorCount is the number of errors found for a single method and path.
+type pathErrorCount struct {
+	Operation string
+	Errors    int
+}
+
+// newWebhookNotifier creates a notifier posting to a Slack or Teams webhook.
+func newWebhookNotifier(config *shared.NotificationConfig, dashboard string) *notifier {
+	window := DefaultNotificationWindow
+	if config.WindowSeconds > 0 {
+		window = time.Duration(config.WindowSeconds) * time.Second
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &notifier{
+		name:   config.Type,
+		window: window,
+		since:  time.Now(),
+		ready:  func(count int) bool { return count >= config.MinimumErrorCount },
+		send: func(violations []*errors.ValidationError, period time.Duration) error {
+			return postWebhook(client, config, violations, period, dashboard)
+		},
+	}
+}
+
+// record adds violations to the current window of every notifier.
+func (n notifiers) record(violations []*errors.ValidationError) {
+	for _, nt := range n {
+		nt.lock.Lock()
+		nt.errors = append(nt.errors, violations...)
+		nt.lock.Unlock()
+	}
+}
+
+// flush ends the current window, returning its errors and the period they were found in, if there are enough of
+// them to notify. Nothing is returned while the notifier is rate limited. Clean is true when the window had no errors.
+func (nt *notifier) flush(now time.Time) (batch []*errors.ValidationError, period time.Duration, clean bool) {
+	return nt.take(now, false)
+}
+
+// alert sends the errors of the current window straight away, however many there are. Rate limiting still applies.
+func (nt *notifier) alert(now time.Time, logger *slog.Logger) {
+	batch, period, _ := nt.take(now, true)
+	if batch != nil {
+		nt.deliver(logger, batch, period, false)
+	}
+}
+
+// take ends the current window, force skips the minimum number of errors.
+func (nt *notifier) take(now time.Time, force bool) (batch []*errors.ValidationError, period time.Duration, clean bool) {
+	nt.lock.Lock()
+	defer nt.lock.Unlock()
+	if nt.interval > 0 && !nt.lastSent.IsZero() && now.Sub(nt.lastSent) < nt.interval {
+		return nil, 0, false
+	}
+	batch, period = nt.errors, now.Sub(nt.since)
+	nt.errors, nt.since = nil, now
+	if len(batch) == 0 {
+		return nil, 0, true
+	}
+	if !force && !nt.ready(len(batch)) {
+		return nil, 0, false
+	}
+	nt.lastSent = now
+	return batch, period, false
+}
+
+// run sends a notification at the end of every window that has enough errors, and clears it after a clean window.
+func (nt *notifier) run(logger *slog.Logger) {
+	ticker := time.NewTicker(nt.window)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		batch, period, clean := nt.flush(now)
+		nt.deliver(logger, batch, period, clean)
+	}
+}
+
+// deliver sends a batch of errors, or clears the notification after a clean window. Deliveries are one at a
+// time, windows and alerts can end together.
+func (nt *notifier) deliver(logger *slog.Logger, batch []*errors.ValidationError, period time.Duration, clean bool) {
+	nt.sendLock.Lock()
+	defer nt.sendLock.Unlock()
+	var err error
+	switch {
+	case batch != nil:
+		err = nt.send(batch, period)
+	case clean && nt.clear != nil:
+		err = nt.clear()
+	}
+	if err != nil {
+		logger.Error("[wiretap] unable to send validation error notification", "type", nt.name,
+			"error", err.Error())
+	}
+}
+
+// postWebhook sends a summary of the errors to the webhook.
+func postWebhook(client *http.Client, config *shared.NotificationConfig, violations []*errors.ValidationError,
+	period time.Duration, dashboard string) error {
+
+	payload, err := notificationPayload(config.Type, violations, period, dashboard)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(config.WebhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// summarizeErrors counts the errors per method and path, the most errors first.
+func summarizeErrors(violations []*errors.ValidationError) []*pathErrorCount {
+	counts := make(map[string]*pathErrorCount)
+	var summary []*pathErrorCount
+	for _, ve := range violations {
+		operation := coverageKey(ve.RequestMethod, ve.RequestPath)
+		count := counts[operation]
+		if count == nil {
+			count = &pathErrorCount{Operation: operation}
+			counts[operation] = count
+			summary = append(summary, count)
+		}
+		count.Errors++
+	}
+	sort.SliceStable(summary, func(i, j int) bool {
+		if summary[i].Errors != summary[j].Errors {
+			return summary[i].Errors > summary[j].Errors
+		}
+		return summary[i].Operation < summary[j].Operation
+	})
+	return summary
+}
+
+// notificationTitle is the headline of a notification.
+func notificationTitle(violations []*errors.ValidationError, period time.Duration) string {
+	return fmt.Sprintf("wiretap found %d validation %s in the last %s", len(violations),
+		shared.Pluralize(len(violations), "error", "errors"), period.Round(time.Second))
+}
+
+// notificationLines lists the error count of the paths with the most errors.
+func notificationLines(violations []*errors.ValidationError) []string {
+	summary := summarizeErrors(violations)
+	var lines []string
+	for i, count := range summary {
+		if i == notificationMaxPaths {
+			lines = append(lines, fmt.Sprintf("and %d more", len(summary)-notificationMaxPaths))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s: %d %s", count.Operation, count.Errors,
+			shared.Pluralize(count.Errors, "error", "errors")))
+	}
+	return lines
+}
+
+// notificationPayload builds the webhook message for the type of notification.
+func notificationPayload(notificationType string, violations []*errors.ValidationError, period time.Duration,
+	dashboard string) ([]byte, error) {
+
+	title := notificationTitle(violations, period)
+	lines := notificationLines(violations)
+
+	switch notificationType {
+	case shared.NotificationSlack:
+		var text strings.Builder
+		text.WriteString("*" + title + "*\n")
+		for _, line := range lines {
+			text.WriteString("• " + line + "\n")
+		}
+		text.WriteString("<" + dashboard + "|Open the wiretap dashboard>")
+		return json.Marshal(map[string]any{"text": text.String()})
+	case shared.NotificationTeams:
+		return json.Marshal(map[string]any{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  title,
+			"title":    title,
+			"text":     strings.Join(lines, "<br>"),
+			"potentialAction": []any{map[string]any{
+				"@type":   "OpenUri",
+				"name":    "Open the wiretap dashboard",
+				"targets": []any{map[string]string{"os": "default", "uri": dashboard}},
+			}},
+		})
+	}
+	return nil, fmt.Errorf("unknown notification type '%s'", notificationType)
+}
+
+// startNotifications starts a notifier for every configured webhook, for email and for PagerDuty.
+func (ws *WiretapService) startNotifications() {
+	dashboard := ws.config.GetMonitorUI()
+	for _, config := range ws.config.Notifications {
+		ws.notifiers = append(ws.notifiers, newWebhookNotifier(config, dashboard))
+	}
+	if ws.config.SMTPNotification != nil {
+		ws.notifiers = append(ws.notifiers, newEmailNotifier(ws.config.SMTPNotification, dashboard))
+	}
+	if ws.config.PagerDuty != nil && ws.config.PagerDuty.AlertOnValidationErrors {
+		ws.notifiers = append(ws.notifiers, newPagerDutyNotifier(ws.config.PagerDuty, dashboard))
+	}
+	for _, nt := range ws.notifiers {
+		go nt.run(ws.config.Logger)
+	}
+}
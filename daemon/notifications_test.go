@@ -0,0 +1,87 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier_Flush_MinimumErrorCount(t *testing.T) {
+	nt := newWebhookNotifier(&shared.NotificationConfig{Type: shared.NotificationSlack, MinimumErrorCount: 3}, "")
+	n := notifiers{nt}
+	start := nt.since
+
+	n.record([]*errors.ValidationError{{Message: "one"}, {Message: "two"}})
+	batch, _, _ := nt.flush(start.Add(time.Minute))
+	assert.Nil(t, batch) // not enough errors, the window is discarded.
+
+	n.record([]*errors.ValidationError{{Message: "one"}, {Message: "two"}})
+	n.record([]*errors.ValidationError{{Message: "three"}})
+	batch, period, _ := nt.flush(start.Add(2 * time.Minute))
+	assert.Len(t, batch, 3)
+	assert.Equal(t, time.Minute, period)
+	batch, _, _ = nt.flush(start.Add(3 * time.Minute))
+	assert.Nil(t, batch)
+}
+
+func TestNotificationPayload_Slack(t *testing.T) {
+	violations := []*errors.ValidationError{
+		{RequestMethod: "GET", RequestPath: "/pets"},
+		{RequestMethod: "POST", RequestPath: "/pets"},
+		{RequestMethod: "POST", RequestPath: "/pets"},
+	}
+	payload, err := notificationPayload(shared.NotificationSlack, violations, time.Minute, "http://localhost:9091")
+	require.NoError(t, err)
+
+	var message map[string]string
+	require.NoError(t, json.Unmarshal(payload, &message))
+	assert.Equal(t, "*wiretap found 3 validation errors in the last 1m0s*\n"+
+		"• POST /pets: 2 errors\n"+
+		"• GET /pets: 1 error\n"+
+		"<http://localhost:9091|Open the wiretap dashboard>", message["text"])
+}
+
+func TestNotificationPayload_Teams(t *testing.T) {
+	violations := []*errors.ValidationError{{RequestMethod: "GET", RequestPath: "/pets"}}
+	payload, err := notificationPayload(shared.NotificationTeams, violations, time.Minute, "http://localhost:9091")
+	require.NoError(t, err)
+
+	var message map[string]any
+	require.NoError(t, json.Unmarshal(payload, &message))
+	assert.Equal(t, "MessageCard", message["@type"])
+	assert.Equal(t, "wiretap found 1 validation error in the last 1m0s", message["title"])
+	assert.Equal(t, "GET /pets: 1 error", message["text"])
+	assert.Contains(t, string(payload), `"uri":"http://localhost:9091"`)
+}
+
+func TestPostWebhook(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := &shared.NotificationConfig{Type: shared.NotificationSlack, WebhookURL: server.URL}
+	violations := []*errors.ValidationError{{RequestMethod: "GET", RequestPath: "/pets"}}
+	require.NoError(t, postWebhook(http.DefaultClient, config, violations, time.Minute, ""))
+	assert.Contains(t, string(received), "GET /pets: 1 error")
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer failing.Close()
+	config.WebhookURL = failing.URL
+	assert.Error(t, postWebhook(http.DefaultClient, config, violations, time.Minute, ""))
+}
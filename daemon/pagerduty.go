@@ -0,0 +1,141 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+var pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+const (
+	pagerDutyTrigger = "trigger"
+	pagerDutyResolve = "resolve"
+)
+
+// pagerDutyEvent is an event of the PagerDuty Events API v2. Events with the same dedup key update the same incident.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Client      string            `json:"client,omitempty"`
+	ClientURL   string            `json:"client_url,omitempty"`
+	Links       []*pagerDutyLink  `json:"links,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity"`
+	Component     string         `json:"component,omitempty"`
+	CustomDetails map[string]any `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// pagerDutyInstance returns the name of this wiretap in incidents, the host name when none is configured.
+func pagerDutyInstance(config *shared.PagerDutyConfig) string {
+	if config.InstanceName != "" {
+		return config.InstanceName
+	}
+	if host, err := os.Hostname(); err == nil {
+		return host
+	}
+	return "wiretap"
+}
+
+// pagerDutyTriggerEvent builds the event opening (or updating) the incident of an instance.
+func pagerDutyTriggerEvent(config *shared.PagerDutyConfig, instance string, violations []*errors.ValidationError,
+	period time.Duration, dashboard string) *pagerDutyEvent {
+
+	return &pagerDutyEvent{
+		RoutingKey:  config.IntegrationKey,
+		EventAction: pagerDutyTrigger,
+		DedupKey:    "wiretap-" + instance,
+		Payload: &pagerDutyPayload{
+			Summary:   notificationTitle(violations, period) + " on " + instance,
+			Source:    instance,
+			Severity:  "error",
+			Component: "wiretap",
+			CustomDetails: map[string]any{
+				"instance":    instance,
+				"error_count": len(violations),
+				"paths":       notificationLines(violations),
+			},
+		},
+		Client:    "wiretap",
+		ClientURL: dashboard,
+		Links:     []*pagerDutyLink{{Href: dashboard, Text: "wiretap dashboard"}},
+	}
+}
+
+// sendPagerDutyEvent posts an event to the PagerDuty Events API.
+func sendPagerDutyEvent(client *http.Client, event *pagerDutyEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(pagerDutyEventsURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("PagerDuty responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// newPagerDutyNotifier creates a notifier triggering an incident for every window with errors. The incident is
+// resolved by the first window without errors that follows.
+func newPagerDutyNotifier(config *shared.PagerDutyConfig, dashboard string) *notifier {
+	window := DefaultNotificationWindow
+	if config.WindowSeconds > 0 {
+		window = time.Duration(config.WindowSeconds) * time.Second
+	}
+	instance := pagerDutyInstance(config)
+	client := &http.Client{Timeout: 10 * time.Second}
+	triggered := false
+	return &notifier{
+		name:   "pagerduty",
+		window: window,
+		since:  time.Now(),
+		ready:  func(count int) bool { return count > 0 },
+		send: func(violations []*errors.ValidationError, period time.Duration) error {
+			if err := sendPagerDutyEvent(client,
+				pagerDutyTriggerEvent(config, instance, violations, period, dashboard)); err != nil {
+				return err
+			}
+			triggered = true
+			return nil
+		},
+		clear: func() error {
+			if !triggered {
+				return nil
+			}
+			if err := sendPagerDutyEvent(client, &pagerDutyEvent{
+				RoutingKey:  config.IntegrationKey,
+				EventAction: pagerDutyResolve,
+				DedupKey:    "wiretap-" + instance,
+			}); err != nil {
+				return err
+			}
+			triggered = false
+			return nil
+		},
+	}
+}
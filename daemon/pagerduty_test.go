@@ -0,0 +1,67 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagerDutyNotifier_TriggerAndResolve(t *testing.T) {
+	var events []*pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, &event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	defer func(url string) { pagerDutyEventsURL = url }(pagerDutyEventsURL)
+	pagerDutyEventsURL = server.URL
+
+	config := &shared.PagerDutyConfig{IntegrationKey: "key", AlertOnValidationErrors: true, InstanceName: "staging"}
+	nt := newPagerDutyNotifier(config, "http://localhost:9091")
+
+	// a clean window before any incident resolves nothing.
+	require.NoError(t, nt.clear())
+	assert.Empty(t, events)
+
+	violations := []*errors.ValidationError{{RequestMethod: "GET", RequestPath: "/pets"}}
+	require.NoError(t, nt.send(violations, time.Minute))
+	require.NoError(t, nt.clear())
+	require.NoError(t, nt.clear())
+
+	require.Len(t, events, 2)
+	assert.Equal(t, pagerDutyTrigger, events[0].EventAction)
+	assert.Equal(t, "key", events[0].RoutingKey)
+	assert.Equal(t, "wiretap-staging", events[0].DedupKey)
+	assert.Equal(t, "wiretap found 1 validation error in the last 1m0s on staging", events[0].Payload.Summary)
+	assert.Equal(t, float64(1), events[0].Payload.CustomDetails["error_count"])
+	assert.Equal(t, []any{"GET /pets: 1 error"}, events[0].Payload.CustomDetails["paths"])
+	assert.Equal(t, "http://localhost:9091", events[0].Links[0].Href)
+
+	assert.Equal(t, pagerDutyResolve, events[1].EventAction)
+	assert.Equal(t, "wiretap-staging", events[1].DedupKey)
+	assert.Nil(t, events[1].Payload)
+}
+
+func TestNotifier_Flush_Clean(t *testing.T) {
+	nt := newPagerDutyNotifier(&shared.PagerDutyConfig{InstanceName: "staging"}, "")
+	batch, _, clean := nt.flush(nt.since.Add(time.Minute))
+	assert.Nil(t, batch)
+	assert.True(t, clean)
+
+	notifiers{nt}.record([]*errors.ValidationError{{Message: "one"}})
+	batch, _, clean = nt.flush(nt.since.Add(time.Minute))
+	assert.Len(t, batch, 1)
+	assert.False(t, clean)
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// the query parameter encodings that can be told apart from the query string.
+const (
+	encodingExploded = "repeated"        // the parameter is repeated, once per value (form, exploded).
+	encodingForm     = "comma separated" // values are comma separated (form, not exploded).
+	encodingSpace    = helpers.SpaceDelimited
+	encodingPipe     = helpers.PipeDelimited
+	encodingDeep     = helpers.DeepObject // properties are sent as name[property].
+)
+
+// operationParameters returns the parameters of the operation in a location (query, cookie, ...), operation
+// parameters replace the path item parameters with the same name.
+func operationParameters(pathItem *v3.PathItem, operation *v3.Operation, in string) []*v3.Parameter {
+	var params []*v3.Parameter
+	for _, param := range operation.Parameters {
+		if param != nil && param.In == in {
+			params = append(params, param)
+		}
+	}
+	for _, param := range pathItem.Parameters {
+		if param == nil || param.In != in {
+			continue
+		}
+		if !slices.ContainsFunc(params, func(p *v3.Parameter) bool { return p.Name == param.Name }) {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// parameterStyle returns the style of a query parameter and if it is exploded, with the defaults of the
+// specification: form, exploded unless set otherwise.
+func parameterStyle(param *v3.Parameter) (string, bool) {
+	style := param.Style
+	if style == "" {
+		style = helpers.Form
+	}
+	explode := style == helpers.Form
+	if param.Explode != nil {
+		explode = *param.Explode
+	}
+	return style, explode
+}
+
+// describeStyle describes the style of a parameter, as it is defined in the specification, defaults included.
+func describeStyle(param *v3.Parameter) string {
+	style, explode := parameterStyle(param)
+	return fmt.Sprintf("%s (explode: %t)", style, explode)
+}
+
+// expectedEncodings returns the encodings allowed by the style of a parameter, for an array or an object.
+// Exploded objects have no encoding of their own, each property is a separate query parameter.
+func expectedEncodings(param *v3.Parameter, schemaType string) []string {
+	style, explode := parameterStyle(param)
+
+	switch {
+	case style == helpers.DeepObject:
+		return []string{encodingDeep}
+	case schemaType == helpers.Object && explode:
+		return nil
+	case explode:
+		return []string{encodingExploded}
+	case style == helpers.SpaceDelimited:
+		return []string{encodingSpace}
+	case style == helpers.PipeDelimited:
+		return []string{encodingPipe}
+	}
+	return []string{encodingForm}
+}
+
+// delimitedEncoding returns the encoding suggested by the delimiter in a value, if there is one.
+func delimitedEncoding(value string) string {
+	switch {
+	case strings.Contains(value, helpers.Pipe):
+		return encodingPipe
+	case strings.Contains(value, helpers.Space):
+		return encodingSpace
+	case strings.Contains(value, helpers.Comma):
+		return encodingForm
+	}
+	return ""
+}
+
+// hasDelimitedItems checks if the items of an array can never contain a delimiter, so a delimiter in a value
+// can only be an encoding. String items can contain anything, so their delimiters are not checked.
+func hasDelimitedItems(schema *base.Schema) bool {
+	if schema.Items == nil || !schema.Items.IsA() || schema.Items.A == nil {
+		return false
+	}
+	items := schema.Items.A.Schema()
+	if items == nil || len(items.Type) == 0 {
+		return false
+	}
+	for _, t := range items.Type {
+		if t != helpers.Integer && t != helpers.Number && t != helpers.Boolean {
+			return false
+		}
+	}
+	return true
+}
+
+// detectEncodings returns the encodings a query parameter has been sent with.
+func detectEncodings(param *v3.Parameter, schema *base.Schema, schemaType string, query url.Values) []string {
+	var detected []string
+	for key := range query {
+		if strings.HasPrefix(key, param.Name+"[") && strings.HasSuffix(key, "]") {
+			detected = append(detected, encodingDeep)
+			break
+		}
+	}
+
+	values, sent := query[param.Name]
+	switch {
+	case !sent:
+	case schemaType == helpers.Object:
+		// objects sent under their own name, rather than as properties.
+		if encoding := delimitedEncoding(strings.Join(values, "")); encoding != "" {
+			detected = append(detected, encoding)
+		} else {
+			detected = append(detected, encodingForm)
+		}
+	default:
+		if len(values) > 1 {
+			detected = append(detected, encodingExploded)
+		}
+		if hasDelimitedItems(schema) {
+			for _, value := range values {
+				if encoding := delimitedEncoding(value); encoding != "" && !slices.Contains(detected, encoding) {
+					detected = append(detected, encoding)
+				}
+			}
+		}
+	}
+	return detected
+}
+
+// validateQueryEncoding checks that array and object query parameters are encoded with the style defined for them
+// in the specification. The validator decodes values with the specified style, so a mismatched encoding otherwise
+// shows up as a confusing type or schema error. Requests that match no operation are not checked.
+func validateQueryEncoding(doc *v3.Document, request *http.Request) []*errors.ValidationError {
+	if doc == nil || request == nil || request.URL.RawQuery == "" {
+		return nil
+	}
+	pathItem, errs, _ := paths.FindPath(request, doc)
+	if len(errs) > 0 || pathItem == nil {
+		return nil
+	}
+	operation := pathItem.GetOperations().GetOrZero(strings.ToLower(request.Method))
+	if operation == nil {
+		return nil
+	}
+
+	query := request.URL.Query()
+	var validationErrors []*errors.ValidationError
+	for _, param := range operationParameters(pathItem, operation, helpers.Query) {
+		if param.Schema == nil {
+			continue
+		}
+		schema := param.Schema.Schema()
+		if schema == nil {
+			continue
+		}
+		var schemaType string
+		switch {
+		case slices.Contains(schema.Type, helpers.Array):
+			schemaType = helpers.Array
+		case slices.Contains(schema.Type, helpers.Object):
+			schemaType = helpers.Object
+		default:
+			continue // primitive values are encoded the same way with every style.
+		}
+
+		expected := expectedEncodings(param, schemaType)
+		for _, encoding := range detectEncodings(param, schema, schemaType, query) {
+			if slices.Contains(expected, encoding) {
+				continue
+			}
+			ve := &errors.ValidationError{
+				ValidationType:    helpers.ParameterValidation,
+				ValidationSubType: helpers.ParameterValidationQuery,
+				Message: fmt.Sprintf("Query parameter '%s' uses the %s encoding, the specification defines %s",
+					param.Name, encoding, describeStyle(param)),
+				Reason: fmt.Sprintf("The %s query parameter '%s' is defined with the style %s, "+
+					"but the query string uses the %s encoding", schemaType, param.Name, describeStyle(param), encoding),
+				HowToFix:      fmt.Sprintf("encode the query parameter '%s' as %s", param.Name, describeStyle(param)),
+				RequestPath:   request.URL.Path,
+				RequestMethod: request.Method,
+				Context:       param,
+			}
+			if low := param.GoLow(); low != nil && low.Name.KeyNode != nil {
+				ve.SpecLine = low.Name.KeyNode.Line
+				ve.SpecCol = low.Name.KeyNode.Column
+			}
+			validationErrors = append(validationErrors, ve)
+			break // one error per parameter, the first mismatch explains the rest.
+		}
+	}
+	return validationErrors
+}
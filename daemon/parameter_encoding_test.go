@@ -0,0 +1,91 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var encodingSpec = []byte(`openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: ids
+          in: query
+          schema:
+            type: array
+            items:
+              type: integer
+        - name: tags
+          in: query
+          style: pipeDelimited
+          explode: false
+          schema:
+            type: array
+            items:
+              type: integer
+        - name: names
+          in: query
+          explode: false
+          schema:
+            type: array
+            items:
+              type: string
+        - name: filter
+          in: query
+          style: deepObject
+          schema:
+            type: object
+        - name: point
+          in: query
+          explode: false
+          schema:
+            type: object
+        - name: limit
+          in: query
+          schema:
+            type: integer
+      responses:
+        "200":
+          description: ok
+`)
+
+func TestValidateQueryEncoding(t *testing.T) {
+	doc, err := libopenapi.NewDocument(encodingSpec)
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+
+	validate := func(query string) []string {
+		request, _ := http.NewRequest(http.MethodGet, "http://localhost/pets?"+query, nil)
+		var messages []string
+		for _, ve := range validateQueryEncoding(&m.Model, request) {
+			messages = append(messages, ve.Message)
+		}
+		return messages
+	}
+
+	// encoded as specified.
+	assert.Empty(t, validate("ids=1&ids=2&tags=1|2&names=a,b&filter[color]=red&point=x,1,y,2&limit=10"))
+	assert.Empty(t, validate("ids=1&names=a|b&limit=1,2"))
+
+	assert.Equal(t, []string{"Query parameter 'ids' uses the comma separated encoding, the specification defines form (explode: true)"},
+		validate("ids=1,2"))
+	assert.Equal(t, []string{"Query parameter 'ids' uses the pipeDelimited encoding, the specification defines form (explode: true)"},
+		validate("ids=1|2"))
+	assert.Equal(t, []string{"Query parameter 'tags' uses the repeated encoding, the specification defines pipeDelimited (explode: false)"},
+		validate("tags=1&tags=2"))
+	assert.Equal(t, []string{"Query parameter 'tags' uses the comma separated encoding, the specification defines pipeDelimited (explode: false)"},
+		validate("tags=1,2"))
+	assert.Equal(t, []string{"Query parameter 'filter' uses the comma separated encoding, the specification defines deepObject (explode: false)"},
+		validate("filter=color,red"))
+	assert.Equal(t, []string{"Query parameter 'point' uses the deepObject encoding, the specification defines form (explode: false)"},
+		validate("point[x]=1"))
+}
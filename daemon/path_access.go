@@ -0,0 +1,86 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/ranch/model"
+	configModel "github.com/pb33f/wiretap/config"
+	"github.com/pb33f/wiretap/shared"
+)
+
+// proxiedMethods are the methods listed in the Allow header of a blocked method, unless they are blocked too.
+var proxiedMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodOptions, http.MethodTrace,
+}
+
+// refuseRequest responds to a request with oversized headers with a 431, to a request on a blocked path with a 403,
+// to a request on a path that is not allowed with a 404, and to a request with a blocked method with a 405. It
+// returns true when the request was refused, and must not be proxied.
+func (ws *WiretapService) refuseRequest(request *model.Request) bool {
+	path := request.HttpRequest.URL.Path
+	var status int
+	var title string
+	detail := ws.oversizedHeaders(request.HttpRequest.Header)
+	switch {
+	case detail != "":
+		status, title = http.StatusRequestHeaderFieldsTooLarge, "Request headers are too large"
+	case configModel.PathBlocked(path, ws.config):
+		status, title = http.StatusForbidden, "Path is blocked"
+		detail = "the path '" + path + "' is not served by wiretap"
+	case !configModel.PathAllowed(path, ws.config):
+		status, title = http.StatusNotFound, "Path is not allowed"
+		detail = "the path '" + path + "' is not served by wiretap"
+	case ws.methodBlocked(request.HttpRequest.Method):
+		status, title = http.StatusMethodNotAllowed, "Method is blocked"
+		detail = "the method '" + request.HttpRequest.Method + "' is not proxied by wiretap"
+		request.HttpResponseWriter.Header().Set("Allow", strings.Join(ws.allowedMethods(), ", "))
+		ws.config.Logger.Warn("[wiretap] security event: blocked method", "method", request.HttpRequest.Method,
+			"url", request.HttpRequest.URL.String(), "remote", request.HttpRequest.RemoteAddr, "code", status)
+	default:
+		return false
+	}
+	if status != http.StatusMethodNotAllowed {
+		ws.config.Logger.Info("[wiretap] request refused", "url", request.HttpRequest.URL.String(), "code", status)
+	}
+	request.HttpResponseWriter.Header().Set("Content-Type", "application/problem+json")
+	request.HttpResponseWriter.WriteHeader(status)
+	wtError := shared.GenerateError(title, status, detail, path, nil)
+	_, _ = request.HttpResponseWriter.Write(shared.MarshalError(wtError))
+	return true
+}
+
+// RefuseRequest refuses a request that must not be served, before it is matched against static mocks. Refused
+// requests are audited here, requests that are served are audited by the handler that serves them.
+func (ws *WiretapService) RefuseRequest(request *model.Request) bool {
+	writer := request.HttpResponseWriter
+	audit := ws.trackAudit(request)
+	if !ws.refuseRequest(request) {
+		request.HttpResponseWriter = writer
+		return false
+	}
+	audit.finish()
+	return true
+}
+
+func (ws *WiretapService) methodBlocked(method string) bool {
+	return slices.ContainsFunc(ws.config.BlockedMethods, func(blocked string) bool {
+		return strings.EqualFold(blocked, method)
+	})
+}
+
+// allowedMethods are the proxied methods that are not blocked.
+func (ws *WiretapService) allowedMethods() []string {
+	var allowed []string
+	for _, method := range proxiedMethods {
+		if !ws.methodBlocked(method) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
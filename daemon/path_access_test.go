@@ -0,0 +1,55 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWiretapService_RefuseRequest(t *testing.T) {
+	config := &shared.WiretapConfiguration{
+		AllowedPaths:   []string{"/pets/**"},
+		BlockedPaths:   []string{"/pets/private/**"},
+		BlockedMethods: []string{"delete", "PATCH"},
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	config.CompileAllowedPaths()
+	config.CompileBlockedPaths()
+	ws := &WiretapService{config: config}
+
+	var recorder *httptest.ResponseRecorder
+	refuse := func(method, path string) int {
+		recorder = httptest.NewRecorder()
+		request := &model.Request{
+			HttpRequest:        httptest.NewRequest(method, path, nil),
+			HttpResponseWriter: recorder,
+		}
+		request.HttpRequest.Header.Set("Accept", "application/json")
+		if !ws.refuseRequest(request) {
+			return 0
+		}
+		return recorder.Code
+	}
+
+	assert.Zero(t, refuse(http.MethodGet, "/pets/1"))
+	assert.Equal(t, http.StatusForbidden, refuse(http.MethodGet, "/pets/private/1"))
+	assert.Equal(t, http.StatusNotFound, refuse(http.MethodGet, "/owners/1"))
+
+	// blocked methods are matched regardless of case.
+	assert.Equal(t, http.StatusMethodNotAllowed, refuse(http.MethodDelete, "/pets/1"))
+	assert.Equal(t, "GET, HEAD, POST, PUT, OPTIONS, TRACE", recorder.Header().Get("Allow"))
+	assert.Equal(t, http.StatusMethodNotAllowed, refuse(http.MethodPatch, "/pets/1"))
+
+	// oversized headers are refused first.
+	ws.config.MaxRequestHeaderSizeBytes = 1
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, refuse(http.MethodDelete, "/owners/1"))
+}
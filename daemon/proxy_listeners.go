@@ -0,0 +1,36 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// ListenProxy opens the listeners the proxy serves. A single listener is opened on the listen address, unless
+// dual-stack is configured, in which case an IPv4 and an IPv6 listener are opened on every interface, on the same
+// port. Both listeners are served by the same server, so they share its handler and the transaction store.
+func ListenProxy(config *shared.WiretapConfiguration) ([]net.Listener, error) {
+	if !config.DualStack {
+		ln, err := net.Listen("tcp", config.GetListenAddress())
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{ln}, nil
+	}
+
+	ipv4, err := net.Listen("tcp4", net.JoinHostPort("0.0.0.0", config.Port))
+	if err != nil {
+		return nil, err
+	}
+	// use the port of the IPv4 listener, in case it was picked by the system.
+	_, port, _ := net.SplitHostPort(ipv4.Addr().String())
+	ipv6, err := net.Listen("tcp6", net.JoinHostPort("::", port))
+	if err != nil {
+		_ = ipv4.Close()
+		return nil, err
+	}
+	return []net.Listener{ipv4, ipv6}, nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenProxy(t *testing.T) {
+	listeners, err := ListenProxy(&shared.WiretapConfiguration{ListenAddress: "127.0.0.1", Port: "0"})
+	require.NoError(t, err)
+	require.Len(t, listeners, 1)
+	defer listeners[0].Close()
+	assert.Equal(t, "127.0.0.1", listeners[0].Addr().(*net.TCPAddr).IP.String())
+}
+
+func TestListenProxy_DualStack(t *testing.T) {
+	if ln, err := net.Listen("tcp6", "[::1]:0"); err != nil {
+		t.Skip("IPv6 is not available")
+	} else {
+		_ = ln.Close()
+	}
+	listeners, err := ListenProxy(&shared.WiretapConfiguration{DualStack: true, Port: "0"})
+	require.NoError(t, err)
+	require.Len(t, listeners, 2)
+	defer listeners[0].Close()
+	defer listeners[1].Close()
+
+	ipv4, ipv6 := listeners[0].Addr().(*net.TCPAddr), listeners[1].Addr().(*net.TCPAddr)
+	assert.NotNil(t, ipv4.IP.To4())
+	assert.Nil(t, ipv6.IP.To4())
+	assert.Equal(t, ipv4.Port, ipv6.Port)
+}
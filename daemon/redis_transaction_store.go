@@ -0,0 +1,177 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultRedisKeyPrefix      = "wiretap"
+	defaultRedisTransactionTTL = time.Hour
+	defaultRedisPoolSize       = 10
+
+	// redisFetchBatch is the number of transactions read with each MGET.
+	redisFetchBatch = 500
+)
+
+// redisTransactionStore shares captured transactions between wiretap instances. Each transaction is stored as JSON
+// under its own key, which expires after the TTL. A sorted set indexes the transactions by the timestamp of their
+// request, it is used to list them and to evict the entries of transactions that have expired.
+type redisTransactionStore struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// newRedisTransactionStore returns nil when no Redis address is configured.
+func newRedisTransactionStore(config *shared.RedisConfig) *redisTransactionStore {
+	if config == nil || config.Address == "" {
+		return nil
+	}
+	prefix := config.KeyPrefix
+	if prefix == "" {
+		prefix = defaultRedisKeyPrefix
+	}
+	ttl := defaultRedisTransactionTTL
+	if config.TransactionTTLSeconds > 0 {
+		ttl = time.Duration(config.TransactionTTLSeconds) * time.Second
+	}
+	return &redisTransactionStore{client: redis.NewClient(redisOptions(config)), prefix: prefix, ttl: ttl}
+}
+
+// redisOptions maps the configuration onto the options of the Redis client. Timeouts that are not configured use the
+// defaults of the client.
+func redisOptions(config *shared.RedisConfig) *redis.Options {
+	poolSize := config.PoolSize
+	if poolSize <= 0 {
+		poolSize = defaultRedisPoolSize
+	}
+	return &redis.Options{
+		Addr:         config.Address,
+		Username:     config.Username,
+		Password:     config.Password,
+		DB:           config.DB,
+		PoolSize:     poolSize,
+		MaxIdleConns: min(config.MaxIdleConns, poolSize),
+		DialTimeout:  time.Duration(config.DialTimeoutMs) * time.Millisecond,
+		ReadTimeout:  time.Duration(config.ReadTimeoutMs) * time.Millisecond,
+		WriteTimeout: time.Duration(config.WriteTimeoutMs) * time.Millisecond,
+		PoolTimeout:  time.Duration(config.PoolTimeoutMs) * time.Millisecond,
+	}
+}
+
+func (rs *redisTransactionStore) transactionKey(id string) string {
+	return rs.prefix + ":transaction:" + id
+}
+
+func (rs *redisTransactionStore) indexKey() string {
+	return rs.prefix + ":transactions"
+}
+
+// expiredBefore is the score of the oldest transaction that has not expired, as a ZREMRANGEBYSCORE bound.
+func (rs *redisTransactionStore) expiredBefore() string {
+	return "(" + strconv.FormatInt(time.Now().Add(-rs.ttl).UnixMilli(), 10)
+}
+
+// put stores a transaction, replacing any stored with the same ID, and evicts expired transactions from the index.
+func (rs *redisTransactionStore) put(transaction *HttpTransaction) error {
+	data, err := json.Marshal(transaction)
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().UnixMilli()
+	if transaction.Request != nil && transaction.Request.Timestamp != 0 {
+		timestamp = transaction.Request.Timestamp
+	} else if transaction.Response != nil && transaction.Response.Timestamp != 0 {
+		timestamp = transaction.Response.Timestamp
+	}
+	ctx := context.Background()
+	_, err = rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, rs.transactionKey(transaction.Id), data, rs.ttl)
+		pipe.ZAdd(ctx, rs.indexKey(), redis.Z{Score: float64(timestamp), Member: transaction.Id})
+		pipe.ZRemRangeByScore(ctx, rs.indexKey(), "-inf", rs.expiredBefore())
+		// the index goes when no instance has stored a transaction for as long as they are kept.
+		pipe.PExpire(ctx, rs.indexKey(), rs.ttl)
+		return nil
+	})
+	return err
+}
+
+// get returns a stored transaction, or nil if there is none with the ID.
+func (rs *redisTransactionStore) get(id string) (*HttpTransaction, error) {
+	data, err := rs.client.Get(context.Background(), rs.transactionKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRedisTransaction(data)
+}
+
+// all returns the stored transactions, oldest first.
+func (rs *redisTransactionStore) all() ([]*HttpTransaction, error) {
+	ctx := context.Background()
+	var ids *redis.StringSliceCmd
+	_, err := rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, rs.indexKey(), "-inf", rs.expiredBefore())
+		ids = pipe.ZRange(ctx, rs.indexKey(), 0, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []*HttpTransaction
+	members := ids.Val()
+	for start := 0; start < len(members); start += redisFetchBatch {
+		batch := members[start:min(start+redisFetchBatch, len(members))]
+		keys := make([]string, len(batch))
+		for i, id := range batch {
+			keys[i] = rs.transactionKey(id)
+		}
+		values, err := rs.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			// the transaction expired after it was listed.
+			if value == nil {
+				continue
+			}
+			transaction, err := decodeRedisTransaction(value)
+			if err != nil {
+				return nil, err
+			}
+			transactions = append(transactions, transaction)
+		}
+	}
+	return transactions, nil
+}
+
+// close closes the connections to Redis.
+func (rs *redisTransactionStore) close() error {
+	return rs.client.Close()
+}
+
+func decodeRedisTransaction(reply any) (*HttpTransaction, error) {
+	data, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected reply for a transaction: %v", reply)
+	}
+	var transaction HttpTransaction
+	if err := json.Unmarshal([]byte(data), &transaction); err != nil {
+		return nil, fmt.Errorf("redis: unable to decode a stored transaction: %w", err)
+	}
+	return &transaction, nil
+}
@@ -0,0 +1,274 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pb33f/ranch/bus"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis implements the commands used by the transaction store.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+	lock     sync.Mutex
+	values   map[string]string
+	scores   map[string]map[string]int64
+	conns    []net.Conn
+	dials    int
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fr := &fakeRedis{listener: listener, password: password, values: make(map[string]string),
+		scores: make(map[string]map[string]int64)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			fr.lock.Lock()
+			fr.conns = append(fr.conns, conn)
+			fr.dials++
+			fr.lock.Unlock()
+			go fr.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		fr.dropConnections()
+	})
+	return fr
+}
+
+func (fr *fakeRedis) dropConnections() {
+	fr.lock.Lock()
+	defer fr.lock.Unlock()
+	for _, conn := range fr.conns {
+		conn.Close()
+	}
+	fr.conns = nil
+}
+
+func (fr *fakeRedis) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	authenticated := fr.password == ""
+	for {
+		args, err := readRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		if args[0] == "AUTH" {
+			if args[len(args)-1] != fr.password {
+				io.WriteString(conn, "-WRONGPASS invalid username-password pair\r\n")
+				continue
+			}
+			authenticated = true
+		}
+		if !authenticated {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		io.WriteString(conn, fr.reply(args))
+	}
+}
+
+// readRedisCommand reads a command, sent as an array of bulk strings. Command names are upper-cased.
+func readRedisCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	args[0] = strings.ToUpper(args[0])
+	return args, nil
+}
+
+func (fr *fakeRedis) reply(args []string) string {
+	fr.lock.Lock()
+	defer fr.lock.Unlock()
+	bulk := func(value string, ok bool) string {
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	}
+	switch args[0] {
+	case "AUTH", "SELECT", "SET":
+		if args[0] == "SET" {
+			fr.values[args[1]] = args[2]
+		}
+		return "+OK\r\n"
+	case "GET":
+		value, ok := fr.values[args[1]]
+		return bulk(value, ok)
+	case "MGET":
+		reply := fmt.Sprintf("*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			value, ok := fr.values[key]
+			reply += bulk(value, ok)
+		}
+		return reply
+	case "ZADD":
+		if fr.scores[args[1]] == nil {
+			fr.scores[args[1]] = make(map[string]int64)
+		}
+		fr.scores[args[1]][args[3]], _ = strconv.ParseInt(args[2], 10, 64)
+		return ":1\r\n"
+	case "ZREMRANGEBYSCORE":
+		before, _ := strconv.ParseInt(strings.TrimPrefix(args[3], "("), 10, 64)
+		removed := 0
+		for member, score := range fr.scores[args[1]] {
+			if score < before {
+				delete(fr.scores[args[1]], member)
+				delete(fr.values, "wiretap:transaction:"+member)
+				removed++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", removed)
+	case "ZRANGE":
+		var members []string
+		for member := range fr.scores[args[1]] {
+			members = append(members, member)
+		}
+		sort.Slice(members, func(i, j int) bool { return fr.scores[args[1]][members[i]] < fr.scores[args[1]][members[j]] })
+		reply := fmt.Sprintf("*%d\r\n", len(members))
+		for _, member := range members {
+			reply += bulk(member, true)
+		}
+		return reply
+	case "PEXPIRE":
+		return ":1\r\n"
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func newRedisTestService(store string, config *shared.RedisConfig) *WiretapService {
+	return &WiretapService{
+		config:            &shared.WiretapConfiguration{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))},
+		transactionStore:  bus.GetBus().GetStoreManager().CreateStore(store),
+		redisTransactions: newRedisTransactionStore(config),
+	}
+}
+
+func TestRedisTransactionStore_Shared(t *testing.T) {
+	fr := newFakeRedis(t, "")
+	config := &shared.RedisConfig{Address: fr.listener.Addr().String(), DB: 2}
+	first := newRedisTestService("redis-first", config)
+	second := newRedisTestService("redis-second", config)
+	defer bus.GetBus().GetStoreManager().DestroyStore("redis-first")
+	defer bus.GetBus().GetStoreManager().DestroyStore("redis-second")
+
+	now := time.Now().UnixMilli()
+	first.storeTransaction(&HttpTransaction{Id: "b", Request: &HttpRequest{Path: "/pets/b", Timestamp: now}})
+	first.storeTransaction(&HttpTransaction{Id: "b", Response: &HttpResponse{StatusCode: 201}})
+	second.storeTransaction(&HttpTransaction{Id: "a", Request: &HttpRequest{Path: "/pets/a", Timestamp: now - 10}})
+
+	// both halves are merged before they are shared.
+	transaction := second.GetTransaction("b")
+	require.NotNil(t, transaction)
+	assert.Equal(t, "/pets/b", transaction.Request.Path)
+	assert.Equal(t, 201, transaction.Response.StatusCode)
+	assert.Nil(t, second.GetTransaction("c"))
+
+	var paths []string
+	for _, found := range first.FindTransactions(&TransactionFilter{}) {
+		paths = append(paths, found.Request.Path)
+	}
+	assert.Equal(t, []string{"/pets/a", "/pets/b"}, paths)
+
+	// expired transactions are evicted from the index.
+	first.storeTransaction(&HttpTransaction{Id: "old",
+		Request: &HttpRequest{Path: "/pets/old", Timestamp: now - 2*time.Hour.Milliseconds()}})
+	assert.Len(t, first.FindTransactions(&TransactionFilter{}), 2)
+}
+
+func TestRedisTransactionStore_Reconnects(t *testing.T) {
+	fr := newFakeRedis(t, "beef")
+	store := newRedisTransactionStore(&shared.RedisConfig{Address: fr.listener.Addr().String(), Password: "beef",
+		PoolSize: 2})
+	require.NoError(t, store.put(&HttpTransaction{Id: "a", Request: &HttpRequest{Timestamp: time.Now().UnixMilli()}}))
+
+	// an idle connection closed by the server is replaced.
+	fr.dropConnections()
+	transaction, err := store.get("a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", transaction.Id)
+	fr.lock.Lock()
+	assert.Equal(t, 2, fr.dials)
+	fr.lock.Unlock()
+
+	wrong := newRedisTransactionStore(&shared.RedisConfig{Address: fr.listener.Addr().String(), Password: "pork"})
+	_, err = wrong.get("a")
+	assert.ErrorContains(t, err, "WRONGPASS")
+}
+
+func TestRedisTransactionStore_Unavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	// transactions captured by the instance are used while Redis is down.
+	ws := newRedisTestService("redis-down", &shared.RedisConfig{Address: address, DialTimeoutMs: 100})
+	defer bus.GetBus().GetStoreManager().DestroyStore("redis-down")
+	ws.storeTransaction(&HttpTransaction{Id: "a", Request: &HttpRequest{Path: "/pets/a"}})
+	assert.NotNil(t, ws.GetTransaction("a"))
+	assert.Len(t, ws.FindTransactions(&TransactionFilter{}), 1)
+	assert.Nil(t, newRedisTransactionStore(&shared.RedisConfig{}))
+}
+
+func TestRedisOptions(t *testing.T) {
+	options := redisOptions(&shared.RedisConfig{Address: "redis.local:6380", Username: "dave", Password: "beef", DB: 3,
+		PoolSize: 4, MaxIdleConns: 8, DialTimeoutMs: 250, ReadTimeoutMs: 500, WriteTimeoutMs: 750, PoolTimeoutMs: 1000})
+	assert.Equal(t, "redis.local:6380", options.Addr)
+	assert.Equal(t, "dave", options.Username)
+	assert.Equal(t, "beef", options.Password)
+	assert.Equal(t, 3, options.DB)
+	assert.Equal(t, 4, options.PoolSize)
+	assert.Equal(t, 4, options.MaxIdleConns)
+	assert.Equal(t, 250*time.Millisecond, options.DialTimeout)
+	assert.Equal(t, 500*time.Millisecond, options.ReadTimeout)
+	assert.Equal(t, 750*time.Millisecond, options.WriteTimeout)
+	assert.Equal(t, time.Second, options.PoolTimeout)
+
+	// timeouts that are not configured use the defaults of the client.
+	options = redisOptions(&shared.RedisConfig{Address: "redis.local:6379"})
+	assert.Equal(t, defaultRedisPoolSize, options.PoolSize)
+	assert.Zero(t, options.DialTimeout)
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pterm/pterm"
+)
+
+// DefaultCompressedExtension is added to compressed report files, when no extension is configured.
+const DefaultCompressedExtension = ".gz"
+
+// reportArchiver compresses rotated report files, writes their checksums, uploads them and deletes the oldest ones, in
+// a background goroutine so streaming violations is never held up. A nil archiver is valid, and leaves rotated files as
+// they are.
+type reportArchiver struct {
+	compress    bool
+	extension   string
+	maxRetained int
+	checksums   bool
+	upload      *shared.UploadConfig
+	logger      *slog.Logger
+	uploader    reportUploader
+	queue       chan string
+	retained    []string
+	wg          sync.WaitGroup
+}
+
+// newReportArchiver returns an archiver for the rotation options, or nil if rotated files are kept as they are.
+func newReportArchiver(options reportOptions) *reportArchiver {
+	if !options.compress && options.maxRetained <= 0 && options.upload == nil {
+		return nil
+	}
+	ra := &reportArchiver{
+		compress:    options.compress,
+		extension:   options.extension,
+		maxRetained: options.maxRetained,
+		checksums:   options.checksums,
+		upload:      options.upload,
+		logger:      options.logger,
+		queue:       make(chan string, 16),
+	}
+	if ra.logger == nil {
+		ra.logger = slog.Default()
+	}
+	if ra.extension == "" {
+		ra.extension = DefaultCompressedExtension
+	}
+	ra.wg.Add(1)
+	go ra.run()
+	return ra
+}
+
+// archive queues a rotated report file, which must be closed.
+func (ra *reportArchiver) archive(path string) {
+	if ra == nil {
+		return
+	}
+	ra.queue <- path
+}
+
+// close waits for queued files to be archived.
+func (ra *reportArchiver) close() {
+	if ra == nil {
+		return
+	}
+	close(ra.queue)
+	ra.wg.Wait()
+}
+
+func (ra *reportArchiver) run() {
+	defer ra.wg.Done()
+	for path := range ra.queue {
+		if ra.compress {
+			compressed, err := compressReportFile(path, ra.extension)
+			if err != nil {
+				pterm.Error.Println("cannot compress rotated report: " + err.Error())
+			} else {
+				path = compressed
+			}
+		}
+		if ra.checksums {
+			if err := writeReportChecksum(path); err != nil {
+				pterm.Error.Println("cannot write report checksum: " + err.Error())
+			}
+		}
+		if ra.upload != nil {
+			key, err := ra.uploadReport(path)
+			if err != nil {
+				ra.logger.Error("[wiretap] unable to upload rotated report", "file", path, "key", key,
+					"error", err.Error())
+			} else {
+				ra.logger.Info("[wiretap] rotated report uploaded", "file", path, "bucket", ra.upload.Bucket,
+					"key", key)
+				if ra.upload.DeleteLocal {
+					if err = removeReport(path); err != nil {
+						pterm.Error.Println("cannot delete uploaded report: " + err.Error())
+					}
+					continue
+				}
+			}
+		}
+		ra.retained = append(ra.retained, path)
+		for ra.maxRetained > 0 && len(ra.retained) > ra.maxRetained {
+			if err := removeReport(ra.retained[0]); err != nil && !os.IsNotExist(err) {
+				pterm.Error.Println("cannot delete rotated report: " + err.Error())
+			}
+			ra.retained = ra.retained[1:]
+		}
+	}
+}
+
+// uploadReport uploads a rotated report, the client of the store is created by the first upload. It is created again
+// by the next upload if that fails, for instance when the credentials are not available yet.
+func (ra *reportArchiver) uploadReport(path string) (string, error) {
+	ctx := context.Background()
+	if ra.uploader == nil {
+		uploader, err := newReportUploader(ctx, ra.upload)
+		if err != nil {
+			return uploadObjectKey(ra.upload, path), err
+		}
+		ra.uploader = uploader
+	}
+	return uploadReportFile(ctx, ra.uploader, ra.upload, path)
+}
+
+// compressReportFile gzips a report file next to it, and deletes the uncompressed file once the copy is complete.
+func compressReportFile(path, extension string) (string, error) {
+	compressed := path + extension
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(compressed, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if cErr := dst.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		_ = os.Remove(compressed)
+		return "", err
+	}
+	_ = src.Close()
+	return compressed, os.Remove(path)
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportFile_CompressAndRetain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.ndjson")
+	rf, err := openReportFile(path, ReportFormatNDJSON, reportOptions{maxSize: 10, compress: true, maxRetained: 2})
+	require.NoError(t, err)
+
+	// every write is over the limit, so every write rotates.
+	for _, v := range []string{`{"n":"one"}`, `{"n":"two"}`, `{"n":"three"}`} {
+		require.NoError(t, rf.write([][]byte{[]byte(v)}))
+	}
+	require.NoError(t, rf.close())
+
+	// the oldest rotated file is deleted, the others are compressed.
+	assert.NoFileExists(t, path)
+	assert.NoFileExists(t, path+".gz")
+	assert.NoFileExists(t, filepath.Join(dir, "report_1.ndjson"))
+	assert.FileExists(t, filepath.Join(dir, "report_1.ndjson.gz"))
+	assert.FileExists(t, filepath.Join(dir, "report_2.ndjson.gz"))
+	assert.FileExists(t, filepath.Join(dir, "report_3.ndjson")) // the current report.
+
+	f, err := os.Open(filepath.Join(dir, "report_2.ndjson.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"n\":\"three\"}\n", string(data))
+}
+
+func TestNewReportArchiver_Disabled(t *testing.T) {
+	ra := newReportArchiver(reportOptions{maxSize: 10})
+	assert.Nil(t, ra)
+	ra.archive("report.json") // a nil archiver leaves files as they are.
+	ra.close()
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumExtension is added to the path of a report, for the companion file holding its SHA-256 checksum.
+const ChecksumExtension = ".sha256"
+
+// fileChecksum returns the hex encoded SHA-256 checksum of a file.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeReportChecksum writes the checksum of a finished report next to it, in the format read by 'sha256sum -c'.
+func writeReportChecksum(path string) error {
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+ChecksumExtension, []byte(fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))), 0644)
+}
+
+// removeReport deletes a report, and its checksum if it has one.
+func removeReport(path string) error {
+	if err := os.Remove(path + ChecksumExtension); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// VerifyReportChecksum checks a report file against its companion checksum file. The error wraps os.ErrNotExist
+// when the report has no checksum.
+func VerifyReportChecksum(path string) error {
+	data, err := os.ReadFile(path + ChecksumExtension)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("checksum file '%s' is empty", path+ChecksumExtension)
+	}
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, fields[0]) {
+		return fmt.Errorf("'%s' does not match its checksum", path)
+	}
+	return nil
+}
+
+// verifyExistingReports checks the reports left in the directory by a previous run against their checksums,
+// before they are replaced or archived. A corrupted report is logged and skipped, never trusted. Reports without a
+// checksum are left alone. It returns the paths of the corrupted reports.
+func verifyExistingReports(basePath, compressedExtension string, logger *slog.Logger) []string {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if compressedExtension == "" {
+		compressedExtension = DefaultCompressedExtension
+	}
+	dir := filepath.Dir(basePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var corrupted []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isReportFile(basePath, entry.Name(), compressedExtension) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err = VerifyReportChecksum(path); err != nil && !os.IsNotExist(err) {
+			logger.Warn("[wiretap] skipping corrupted report", "file", path, "error", err.Error())
+			corrupted = append(corrupted, path)
+		}
+	}
+	return corrupted
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReportChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report_1.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+	require.NoError(t, writeReportChecksum(path))
+
+	data, err := os.ReadFile(path + ChecksumExtension)
+	require.NoError(t, err)
+	assert.Equal(t, "4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945  report_1.json\n", string(data))
+	assert.NoError(t, VerifyReportChecksum(path))
+
+	require.NoError(t, os.WriteFile(path, []byte("[{}]"), 0644))
+	assert.ErrorContains(t, VerifyReportChecksum(path), "does not match its checksum")
+
+	require.NoError(t, removeReport(path))
+	assert.NoFileExists(t, path)
+	assert.NoFileExists(t, path+ChecksumExtension)
+}
+
+func TestVerifyExistingReports(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "report.json")
+	for _, name := range []string{"report.json", "report_1.json", "report_2.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("[]"), 0644))
+	}
+	require.NoError(t, writeReportChecksum(filepath.Join(dir, "report_1.json")))
+	require.NoError(t, writeReportChecksum(filepath.Join(dir, "report_2.json")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report_2.json"), []byte("[tampered]"), 0644))
+
+	// the report without a checksum is left alone.
+	assert.Equal(t, []string{filepath.Join(dir, "report_2.json")}, verifyExistingReports(base, "", nil))
+}
+
+func TestReportFile_RotateWritesChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	rf, err := openReportFile(path, ReportFormatNDJSON, reportOptions{maxSize: 1, checksums: true})
+	require.NoError(t, err)
+	require.NoError(t, rf.write([][]byte{[]byte(`{"message":"one"}`)}))
+	assert.NoError(t, VerifyReportChecksum(path))
+
+	require.NoError(t, rf.close())
+	assert.NoError(t, VerifyReportChecksum(rotatedReportPath(path, 1)))
+}
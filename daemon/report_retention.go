@@ -0,0 +1,92 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportSweepInterval is how often old report files are looked for.
+const reportSweepInterval = 10 * time.Minute
+
+// isReportFile is true when a file name belongs to a report: the report itself, or one of its rotated files,
+// compressed or not. Any other file in the directory is never touched.
+func isReportFile(basePath, name, compressedExtension string) bool {
+	name = strings.TrimSuffix(name, compressedExtension)
+	base := filepath.Base(basePath)
+	if name == base {
+		return true
+	}
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "_"
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+		return false
+	}
+	_, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+	return err == nil
+}
+
+// sweepReports deletes the report files in the directory of the report that were last modified before the maximum
+// age. The file currently being written is always kept.
+func (rf *reportFile) sweepReports(active string, maxAge time.Duration, now time.Time) {
+	extension := rf.options.extension
+	if extension == "" {
+		extension = DefaultCompressedExtension
+	}
+	logger := rf.options.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	dir := filepath.Dir(rf.basePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Error("[wiretap] unable to read report directory", "dir", dir, "error", err.Error())
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || path == active || !isReportFile(rf.basePath, entry.Name(), extension) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || now.Sub(info.ModTime()) < maxAge {
+			continue
+		}
+		if err = removeReport(path); err != nil {
+			logger.Error("[wiretap] unable to delete old report", "file", path, "error", err.Error())
+			continue
+		}
+		logger.Info("[wiretap] old report deleted", "file", path,
+			"age", now.Sub(info.ModTime()).Round(time.Second).String())
+	}
+}
+
+// sweepOldReports deletes report files older than the maximum report age, straight away and then periodically, in
+// a background goroutine.
+func (ws *WiretapService) sweepOldReports(rf *reportFile) {
+	if ws.config.MaxReportAgeHours <= 0 {
+		return
+	}
+	maxAge := time.Duration(ws.config.MaxReportAgeHours) * time.Hour
+	sweep := func() {
+		// the report may rotate while streaming.
+		ws.streamLock.RLock()
+		active := rf.path
+		ws.streamLock.RUnlock()
+		rf.sweepReports(active, maxAge, time.Now())
+	}
+	go func() {
+		sweep()
+		ticker := time.NewTicker(reportSweepInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			sweep()
+		}
+	}()
+}
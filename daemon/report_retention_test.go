@@ -0,0 +1,50 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReportFile(t *testing.T) {
+	assert.True(t, isReportFile("/tmp/report.json", "report.json", ".gz"))
+	assert.True(t, isReportFile("/tmp/report.json", "report_12.json", ".gz"))
+	assert.True(t, isReportFile("/tmp/report.json", "report_3.json.gz", ".gz"))
+	assert.False(t, isReportFile("/tmp/report.json", "report_final.json", ".gz"))
+	assert.False(t, isReportFile("/tmp/report.json", "report_1.ndjson", ".gz"))
+	assert.False(t, isReportFile("/tmp/report.json", "main.go", ".gz"))
+}
+
+func TestReportFile_SweepReports(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.Add(-3 * time.Hour)
+	files := map[string]time.Time{
+		"report.json":      old,
+		"report_1.json.gz": old,
+		"report_2.json":    now,
+		"report_3.json":    old, // being written.
+		"notes.json":       old,
+	}
+	for name, modified := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+		require.NoError(t, os.Chtimes(path, modified, modified))
+	}
+
+	rf := &reportFile{basePath: filepath.Join(dir, "report.json")}
+	rf.sweepReports(filepath.Join(dir, "report_3.json"), 2*time.Hour, now)
+
+	assert.NoFileExists(t, filepath.Join(dir, "report.json"))
+	assert.NoFileExists(t, filepath.Join(dir, "report_1.json.gz"))
+	assert.FileExists(t, filepath.Join(dir, "report_2.json"))
+	assert.FileExists(t, filepath.Join(dir, "report_3.json"))
+	assert.FileExists(t, filepath.Join(dir, "notes.json"))
+}
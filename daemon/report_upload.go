@@ -0,0 +1,145 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pb33f/wiretap/shared"
+	"google.golang.org/api/option"
+)
+
+const (
+	UploadS3  = "s3"
+	UploadGCS = "gcs"
+
+	// DefaultS3Region is used when neither the upload configuration nor the AWS configuration set a region.
+	DefaultS3Region = "us-east-1"
+)
+
+// reportUploader uploads rotated reports to a bucket.
+type reportUploader interface {
+	upload(ctx context.Context, key string, body io.Reader) error
+}
+
+// s3Uploader uploads to S3 with the upload manager of the AWS SDK, which splits large reports into parts.
+type s3Uploader struct {
+	bucket   string
+	uploader *manager.Uploader
+}
+
+// gcsUploader uploads to Cloud Storage with the Google Cloud client.
+type gcsUploader struct {
+	bucket *storage.BucketHandle
+}
+
+// uploadObjectKey returns the key a rotated report is stored under, the prefix followed by the file name.
+func uploadObjectKey(config *shared.UploadConfig, file string) string {
+	if config.Prefix == "" {
+		return filepath.Base(file)
+	}
+	return path.Join(config.Prefix, filepath.Base(file))
+}
+
+// newReportUploader creates the client of the configured store.
+func newReportUploader(ctx context.Context, config *shared.UploadConfig) (reportUploader, error) {
+	switch config.Type {
+	case UploadS3:
+		return newS3Uploader(ctx, config)
+	case UploadGCS:
+		return newGCSUploader(ctx, config)
+	}
+	return nil, fmt.Errorf("unknown upload type '%s', expected '%s' or '%s'", config.Type, UploadS3, UploadGCS)
+}
+
+// uploadReportFile streams a rotated report to the bucket, returning the object key.
+func uploadReportFile(ctx context.Context, uploader reportUploader, config *shared.UploadConfig,
+	file string) (string, error) {
+
+	key := uploadObjectKey(config, file)
+	f, err := os.Open(file)
+	if err != nil {
+		return key, err
+	}
+	defer f.Close()
+	return key, uploader.upload(ctx, key, f)
+}
+
+// newS3Uploader loads the AWS configuration the way the AWS CLI does: environment variables, the shared
+// configuration and credentials files (with the configured profile), then web identity (IRSA), container and
+// instance (IMDS) credentials. The configured region wins over the region of the AWS configuration.
+func newS3Uploader(ctx context.Context, config *shared.UploadConfig) (*s3Uploader, error) {
+	var options []func(*awsconfig.LoadOptions) error
+	if config.Region != "" {
+		options = append(options, awsconfig.WithRegion(config.Region))
+	}
+	if config.Profile != "" {
+		options = append(options, awsconfig.WithSharedConfigProfile(config.Profile))
+	}
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the AWS configuration: %w", err)
+	}
+	if awsConfig.Region == "" {
+		awsConfig.Region = DefaultS3Region
+	}
+	client := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		if config.Endpoint != "" {
+			// S3 compatible stores expect path style buckets, and do not all support the checksums the SDK adds.
+			o.BaseEndpoint = aws.String(config.Endpoint)
+			o.UsePathStyle = true
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		}
+	})
+	return &s3Uploader{bucket: config.Bucket, uploader: manager.NewUploader(client)}, nil
+}
+
+func (su *s3Uploader) upload(ctx context.Context, key string, body io.Reader) error {
+	_, err := su.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(su.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+	return err
+}
+
+// newGCSUploader authenticates with the configured service account key file, or with the application default
+// credentials otherwise: GOOGLE_APPLICATION_CREDENTIALS, the gcloud credentials, or the metadata server.
+func newGCSUploader(ctx context.Context, config *shared.UploadConfig) (*gcsUploader, error) {
+	var options []option.ClientOption
+	if config.CredentialsFile != "" {
+		options = append(options, option.WithCredentialsFile(config.CredentialsFile))
+	}
+	if config.Endpoint != "" {
+		options = append(options, option.WithEndpoint(config.Endpoint))
+	}
+	client, err := storage.NewClient(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the GCS client: %w", err)
+	}
+	return &gcsUploader{bucket: client.Bucket(config.Bucket)}, nil
+}
+
+// upload streams the body to the object, which is only created once the whole body has been written.
+func (gu *gcsUploader) upload(ctx context.Context, key string, body io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	writer := gu.bucket.Object(key).NewWriter(ctx)
+	if _, err := io.Copy(writer, body); err != nil {
+		cancel() // abandons the upload.
+		_ = writer.Close()
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 stores the objects put to it, whole or in parts, by path.
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+	parts   map[string]map[int][]byte
+	auth    []string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	// the AWS configuration of the machine running the tests is not used.
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	fs := &fakeS3{objects: make(map[string][]byte), parts: make(map[string]map[int][]byte)}
+	server := httptest.NewServer(fs)
+	t.Cleanup(server.Close)
+	return fs, server
+}
+
+func (fs *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	fs.auth = append(fs.auth, r.Header.Get("Authorization"))
+	body, _ := io.ReadAll(r.Body)
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		fs.parts[r.URL.Path] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>")
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		part, _ := strconv.Atoi(query.Get("partNumber"))
+		fs.parts[r.URL.Path][part] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, part))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var numbers []int
+		for number := range fs.parts[r.URL.Path] {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		var object []byte
+		for _, number := range numbers {
+			object = append(object, fs.parts[r.URL.Path][number]...)
+		}
+		fs.objects[r.URL.Path] = object
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><ETag>\"object\"</ETag></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodPut:
+		fs.objects[r.URL.Path] = body
+		w.Header().Set("ETag", `"object"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (fs *fakeS3) object(path string) []byte {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	return fs.objects[path]
+}
+
+func TestUploadReportFile_S3(t *testing.T) {
+	fs, server := newFakeS3(t)
+	config := &shared.UploadConfig{Type: UploadS3, Bucket: "reports", Prefix: "wiretap/", Endpoint: server.URL}
+	uploader, err := newReportUploader(context.Background(), config)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "report_1.json")
+	require.NoError(t, os.WriteFile(file, []byte("[]"), 0644))
+	key, err := uploadReportFile(context.Background(), uploader, config, file)
+	require.NoError(t, err)
+	assert.Equal(t, "wiretap/report_1.json", key)
+	assert.Equal(t, []byte("[]"), fs.object("/reports/wiretap/report_1.json"))
+	assert.True(t, strings.HasPrefix(fs.auth[0], "AWS4-HMAC-SHA256 Credential=key/"), fs.auth[0])
+
+	// reports above the part size are uploaded in parts.
+	large := bytes.Repeat([]byte(`{"violation": true},`), 400*1024)
+	file = filepath.Join(dir, "report_2.json")
+	require.NoError(t, os.WriteFile(file, large, 0644))
+	_, err = uploadReportFile(context.Background(), uploader, config, file)
+	require.NoError(t, err)
+	assert.Len(t, fs.parts["/reports/wiretap/report_2.json"], 2)
+	assert.Equal(t, large, fs.object("/reports/wiretap/report_2.json"))
+}
+
+func TestUploadReportFile_GCS(t *testing.T) {
+	var name string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name = r.URL.Query().Get("name")
+		// the object is uploaded in a single multipart request, its metadata first and then its content.
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		parts := multipart.NewReader(r.Body, params["boundary"])
+		if _, err := parts.NextPart(); err == nil {
+			if part, err := parts.NextPart(); err == nil {
+				body, _ = io.ReadAll(part)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"bucket": "reports", "name": name})
+	}))
+	defer server.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	config := &shared.UploadConfig{Type: UploadGCS, Bucket: "reports", Prefix: "wiretap"}
+	uploader, err := newReportUploader(context.Background(), config)
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "report_1.json")
+	require.NoError(t, os.WriteFile(file, []byte("[]"), 0644))
+	key, err := uploadReportFile(context.Background(), uploader, config, file)
+	require.NoError(t, err)
+	assert.Equal(t, "wiretap/report_1.json", key)
+	assert.Equal(t, "wiretap/report_1.json", name)
+	assert.Equal(t, []byte("[]"), body)
+}
+
+func TestNewReportUploader_UnknownType(t *testing.T) {
+	_, err := newReportUploader(context.Background(), &shared.UploadConfig{Type: "ftp"})
+	assert.ErrorContains(t, err, "unknown upload type 'ftp'")
+}
+
+func TestReportArchiver_UploadDeleteLocal(t *testing.T) {
+	fs, server := newFakeS3(t)
+
+	file := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, os.WriteFile(file, []byte("[]"), 0644))
+	ra := newReportArchiver(reportOptions{upload: &shared.UploadConfig{
+		Type: UploadS3, Bucket: "reports", Endpoint: server.URL, DeleteLocal: true,
+	}})
+	ra.archive(file)
+	ra.close()
+	assert.Equal(t, []byte("[]"), fs.object("/reports/report.json"))
+	assert.NoFileExists(t, file)
+}
@@ -0,0 +1,471 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// DefaultCacheMaxEntries is the number of responses held by the cache, when no maximum is configured.
+const DefaultCacheMaxEntries = 1000
+
+// CacheStats holds the counts of the response cache.
+type CacheStats struct {
+	Hits    int64 `json:"hits"`    // requests answered from the cache, without contacting the upstream.
+	Misses  int64 `json:"misses"`  // requests with no cached response.
+	Stale   int64 `json:"stale"`   // requests with a cached response that had to be revalidated with the upstream.
+	Entries int   `json:"entries"` // the number of responses currently cached.
+}
+
+// heuristically cacheable status codes, RFC 7231 section 6.1.
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+type cacheEntry struct {
+	status   int
+	header   http.Header
+	body     []byte
+	vary     map[string]string // the request header values selected by the Vary header of the response.
+	tags     []string          // the surrogate keys of the response, used for invalidation.
+	storedAt time.Time
+	expires  time.Time
+}
+
+// responseCache is a shared cache of upstream responses, keyed by method and URL. A nil cache is disabled,
+// and passes every request straight through.
+type responseCache struct {
+	lock       sync.Mutex
+	entries    map[string]*cacheEntry
+	tags       map[string]map[string]struct{} // surrogate key -> cache keys of the entries tagged with it.
+	maxEntries int
+	hits       atomic.Int64
+	misses     atomic.Int64
+	stale      atomic.Int64
+	now        func() time.Time
+}
+
+func newResponseCache(config *shared.CacheConfig) *responseCache {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	maxEntries := config.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheMaxEntries
+	}
+	return &responseCache{
+		entries:    make(map[string]*cacheEntry),
+		tags:       make(map[string]map[string]struct{}),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+func cacheKey(method string, u *url.URL) string {
+	return method + " " + u.String()
+}
+
+// do sends a request through the cache. Fresh responses are returned without calling send, stale responses are
+// revalidated with If-None-Match / If-Modified-Since, and served from the cache when the upstream returns a 304.
+func (rc *responseCache) do(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if rc == nil {
+		return send(req)
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp, err := send(req)
+		// unsafe methods invalidate the cached responses of the target, RFC 7234 section 4.4.
+		if err == nil && resp != nil && resp.StatusCode < 400 {
+			rc.invalidate(req.URL)
+		}
+		return resp, err
+	}
+
+	requestDirectives := parseCacheControl(req.Header.Get("Cache-Control"))
+	if _, ok := requestDirectives["no-store"]; ok {
+		rc.misses.Add(1)
+		return send(req)
+	}
+
+	key := cacheKey(req.Method, req.URL)
+	entry := rc.lookup(key, req)
+	if entry == nil {
+		rc.misses.Add(1)
+		resp, err := send(req)
+		if err == nil && resp != nil {
+			rc.store(key, req, resp)
+		}
+		return resp, err
+	}
+
+	if rc.isFresh(entry, req, requestDirectives) {
+		rc.hits.Add(1)
+		return entry.response(req, rc.now()), nil
+	}
+
+	rc.stale.Add(1)
+
+	// only revalidate on behalf of the client when it has not sent its own conditional headers,
+	// otherwise a 304 from the upstream is meant for the client.
+	conditional := req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == ""
+	if conditional {
+		if etag := entry.header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := send(req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		_ = resp.Body.Close()
+		return rc.revalidated(key, entry, resp).response(req, rc.now()), nil
+	}
+	rc.store(key, req, resp)
+	return resp, nil
+}
+
+func (rc *responseCache) lookup(key string, req *http.Request) *cacheEntry {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	entry := rc.entries[key]
+	if entry == nil {
+		return nil
+	}
+	for name, value := range entry.vary {
+		if req.Header.Get(name) != value {
+			return nil
+		}
+	}
+	return entry
+}
+
+// cached returns the cached response for the request, fresh or stale, without counting a hit or a miss.
+func (rc *responseCache) cached(req *http.Request) *http.Response {
+	if rc == nil {
+		return nil
+	}
+	entry := rc.lookup(cacheKey(req.Method, req.URL), req)
+	if entry == nil {
+		return nil
+	}
+	return entry.response(req, rc.now())
+}
+
+func (rc *responseCache) isFresh(entry *cacheEntry, req *http.Request, requestDirectives map[string]string) bool {
+	if _, ok := requestDirectives["no-cache"]; ok {
+		return false
+	}
+	if requestDirectives["max-age"] == "0" || req.Header.Get("Pragma") == "no-cache" {
+		return false
+	}
+	return rc.now().Before(entry.expires)
+}
+
+// store caches a response, if it is allowed to. The body is buffered, and the response body replaced, so the
+// response can still be read by the caller.
+func (rc *responseCache) store(key string, req *http.Request, resp *http.Response) {
+	if !cacheableStatusCodes[resp.StatusCode] {
+		return
+	}
+	directives := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return
+	}
+	if _, ok := directives["private"]; ok {
+		return // wiretap is a shared cache.
+	}
+	if resp.Header.Get("Set-Cookie") != "" {
+		return // cookies are for a single client, a shared cache must not hand them to others.
+	}
+	if req.Header.Get("Authorization") != "" && !allowsAuthorizedCaching(directives) {
+		return
+	}
+	varyNames := varyHeaderNames(resp.Header)
+	if len(varyNames) == 1 && varyNames[0] == "*" {
+		return
+	}
+	if !hasExplicitFreshness(directives, resp.Header) &&
+		resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return // no way to tell if the response is fresh, and no way to revalidate it.
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	vary := make(map[string]string, len(varyNames))
+	for _, name := range varyNames {
+		vary[name] = req.Header.Get(name)
+	}
+
+	now := rc.now()
+	entry := &cacheEntry{
+		status:   resp.StatusCode,
+		header:   resp.Header.Clone(),
+		body:     body,
+		vary:     vary,
+		tags:     surrogateKeys(resp.Header),
+		storedAt: now,
+		expires:  now.Add(freshnessLifetime(directives, resp.Header, now)),
+	}
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if _, exists := rc.entries[key]; !exists && len(rc.entries) >= rc.maxEntries {
+		rc.evictOldest()
+	}
+	rc.put(key, entry)
+}
+
+// revalidated refreshes a cached entry with the headers of a 304 response, RFC 7234 section 4.3.4.
+func (rc *responseCache) revalidated(key string, entry *cacheEntry, resp *http.Response) *cacheEntry {
+	now := rc.now()
+	header := entry.header.Clone()
+	for name, values := range resp.Header {
+		header[name] = values
+	}
+	refreshed := &cacheEntry{
+		status:   entry.status,
+		header:   header,
+		body:     entry.body,
+		vary:     entry.vary,
+		tags:     surrogateKeys(header),
+		storedAt: now,
+		expires:  now.Add(freshnessLifetime(parseCacheControl(header.Get("Cache-Control")), header, now)),
+	}
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if rc.entries[key] == entry {
+		rc.put(key, refreshed)
+	}
+	return refreshed
+}
+
+func (rc *responseCache) invalidate(u *url.URL) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	rc.remove(cacheKey(http.MethodGet, u))
+	rc.remove(cacheKey(http.MethodHead, u))
+}
+
+// invalidateTags evicts every entry tagged with any of the surrogate keys, and returns the number of entries evicted.
+func (rc *responseCache) invalidateTags(tags []string) int {
+	if rc == nil {
+		return 0
+	}
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	evicted := 0
+	for _, tag := range tags {
+		for key := range rc.tags[tag] {
+			rc.remove(key)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// put stores an entry and indexes its surrogate keys, replacing any existing entry. The lock must be held.
+func (rc *responseCache) put(key string, entry *cacheEntry) {
+	rc.remove(key)
+	rc.entries[key] = entry
+	for _, tag := range entry.tags {
+		if rc.tags[tag] == nil {
+			rc.tags[tag] = make(map[string]struct{})
+		}
+		rc.tags[tag][key] = struct{}{}
+	}
+}
+
+// remove deletes an entry and its surrogate keys from the index. The lock must be held.
+func (rc *responseCache) remove(key string) {
+	entry, ok := rc.entries[key]
+	if !ok {
+		return
+	}
+	delete(rc.entries, key)
+	for _, tag := range entry.tags {
+		delete(rc.tags[tag], key)
+		if len(rc.tags[tag]) == 0 {
+			delete(rc.tags, tag)
+		}
+	}
+}
+
+// evictOldest removes the entry that was stored first, the lock must be held.
+func (rc *responseCache) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range rc.entries {
+		if oldestKey == "" || entry.storedAt.Before(oldest) {
+			oldestKey, oldest = key, entry.storedAt
+		}
+	}
+	rc.remove(oldestKey)
+}
+
+func (rc *responseCache) stats() *CacheStats {
+	if rc == nil {
+		return nil
+	}
+	rc.lock.Lock()
+	entries := len(rc.entries)
+	rc.lock.Unlock()
+	return &CacheStats{
+		Hits:    rc.hits.Load(),
+		Misses:  rc.misses.Load(),
+		Stale:   rc.stale.Load(),
+		Entries: entries,
+	}
+}
+
+// response builds a new response from a cached entry, with an Age header.
+func (ce *cacheEntry) response(req *http.Request, now time.Time) *http.Response {
+	header := ce.header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(now.Sub(ce.storedAt)/time.Second), 10))
+	return &http.Response{
+		Status:        strconv.Itoa(ce.status) + " " + http.StatusText(ce.status),
+		StatusCode:    ce.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(ce.body)),
+		ContentLength: int64(len(ce.body)),
+		Request:       req,
+	}
+}
+
+// parseCacheControl splits a Cache-Control header into lower case directives and their (unquoted) values.
+func parseCacheControl(header string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return directives
+}
+
+// allowsAuthorizedCaching reports if a response to a request with an Authorization header may be stored by a
+// shared cache, RFC 7234 section 3.2.
+func allowsAuthorizedCaching(directives map[string]string) bool {
+	for _, directive := range []string{"public", "s-maxage", "must-revalidate"} {
+		if _, ok := directives[directive]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasExplicitFreshness(directives map[string]string, header http.Header) bool {
+	_, sMaxAge := directives["s-maxage"]
+	_, maxAge := directives["max-age"]
+	return sMaxAge || maxAge || header.Get("Expires") != ""
+}
+
+// freshnessLifetime returns how long a response stays fresh, less its current age. Responses without explicit
+// freshness information, and responses marked no-cache, are stale straight away and always revalidated.
+func freshnessLifetime(directives map[string]string, header http.Header, now time.Time) time.Duration {
+	if _, ok := directives["no-cache"]; ok {
+		return 0
+	}
+
+	var lifetime time.Duration
+	if seconds, ok := parseDeltaSeconds(directives, "s-maxage"); ok {
+		lifetime = seconds
+	} else if seconds, ok = parseDeltaSeconds(directives, "max-age"); ok {
+		lifetime = seconds
+	} else if expires := header.Get("Expires"); expires != "" {
+		// an invalid Expires date means the response is already expired.
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		date := now
+		if d, dErr := http.ParseTime(header.Get("Date")); dErr == nil {
+			date = d
+		}
+		lifetime = expiresAt.Sub(date)
+	}
+
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+		lifetime -= time.Duration(age) * time.Second
+	}
+	if lifetime < 0 {
+		return 0
+	}
+	return lifetime
+}
+
+func parseDeltaSeconds(directives map[string]string, name string) (time.Duration, bool) {
+	value, ok := directives[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, true
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func varyHeaderNames(header http.Header) []string {
+	var names []string
+	for _, vary := range header.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// surrogateKeys returns the tags of a response, from the space separated Surrogate-Key header (Fastly / Varnish)
+// and the comma separated Cache-Tag header.
+func surrogateKeys(header http.Header) []string {
+	var tags []string
+	for _, value := range header.Values("Surrogate-Key") {
+		tags = append(tags, strings.Fields(value)...)
+	}
+	for _, value := range header.Values("Cache-Tag") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,185 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache_FreshHitAndRevalidation(t *testing.T) {
+	calls := 0
+	var conditional []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		conditional = append(conditional, r.Header.Get("If-None-Match"))
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("pets"))
+	}))
+	defer upstream.Close()
+
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	rc := newResponseCache(&shared.CacheConfig{Enabled: true})
+	rc.now = func() time.Time { return now }
+
+	get := func() *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, upstream.URL+"/pets", nil)
+		resp, err := rc.do(req, http.DefaultClient.Do)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// miss, then a fresh hit that never reaches the upstream.
+	resp := get()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "pets", string(body))
+
+	now = now.Add(30 * time.Second)
+	resp = get()
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, "pets", string(body))
+	assert.Equal(t, "30", resp.Header.Get("Age"))
+	assert.Equal(t, 1, calls)
+
+	// once stale, the entry is revalidated and the 304 is answered from the cache.
+	now = now.Add(time.Minute)
+	resp = get()
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "pets", string(body))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, []string{"", `"v1"`}, conditional)
+
+	stats := rc.stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(1), stats.Stale)
+	assert.Equal(t, 1, stats.Entries)
+}
+
+func TestResponseCache_NotStored(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+	}{
+		{"no-store", http.Header{"Cache-Control": {"no-store"}}},
+		{"private", http.Header{"Cache-Control": {"private, max-age=60"}}},
+		{"a cookie is set", http.Header{"Cache-Control": {"max-age=60"}, "Set-Cookie": {"session=beef"}}},
+		{"no freshness or validators", http.Header{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				for name, values := range test.header {
+					w.Header()[name] = values
+				}
+				_, _ = w.Write([]byte("pets"))
+			}))
+			defer upstream.Close()
+
+			rc := newResponseCache(&shared.CacheConfig{Enabled: true})
+			for i := 0; i < 2; i++ {
+				req, _ := http.NewRequest(http.MethodGet, upstream.URL+"/pets", nil)
+				_, err := rc.do(req, http.DefaultClient.Do)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, 2, calls)
+			assert.Equal(t, 0, rc.stats().Entries)
+		})
+	}
+}
+
+func TestResponseCache_UnsafeMethodInvalidates(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte(r.Method))
+	}))
+	defer upstream.Close()
+
+	rc := newResponseCache(&shared.CacheConfig{Enabled: true})
+	req, _ := http.NewRequest(http.MethodGet, upstream.URL+"/pets", nil)
+	_, _ = rc.do(req, http.DefaultClient.Do)
+	assert.Equal(t, 1, rc.stats().Entries)
+
+	req, _ = http.NewRequest(http.MethodPost, upstream.URL+"/pets", nil)
+	_, _ = rc.do(req, http.DefaultClient.Do)
+	assert.Equal(t, 0, rc.stats().Entries)
+}
+
+func TestFreshnessLifetime(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	header := http.Header{}
+	header.Set("Date", now.Format(http.TimeFormat))
+	header.Set("Expires", now.Add(10*time.Minute).Format(http.TimeFormat))
+	assert.Equal(t, 10*time.Minute, freshnessLifetime(parseCacheControl(""), header, now))
+	assert.Equal(t, time.Minute, freshnessLifetime(parseCacheControl("max-age=60"), header, now))
+	assert.Equal(t, 2*time.Minute, freshnessLifetime(parseCacheControl("max-age=60, s-maxage=120"), header, now))
+	assert.Equal(t, time.Duration(0), freshnessLifetime(parseCacheControl("no-cache, max-age=60"), header, now))
+
+	header.Set("Age", "20")
+	assert.Equal(t, 40*time.Second, freshnessLifetime(parseCacheControl("max-age=60"), header, now))
+}
+
+func TestResponseCache_Disabled(t *testing.T) {
+	assert.Nil(t, newResponseCache(nil))
+	assert.Nil(t, newResponseCache(&shared.CacheConfig{}))
+	var rc *responseCache
+	assert.Nil(t, rc.stats())
+}
+
+func TestWiretapService_HandleCacheInvalidate(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		switch r.URL.Path {
+		case "/pets":
+			w.Header().Set("Surrogate-Key", "pets list")
+		case "/pets/1":
+			w.Header().Set("Cache-Tag", "pets, pet-1")
+		case "/owners":
+			w.Header().Set("Surrogate-Key", "owners")
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer upstream.Close()
+
+	ws := &WiretapService{
+		config:        &shared.WiretapConfiguration{},
+		responseCache: newResponseCache(&shared.CacheConfig{Enabled: true}),
+	}
+	for _, path := range []string{"/pets", "/pets/1", "/owners"} {
+		req, _ := http.NewRequest(http.MethodGet, upstream.URL+path, nil)
+		_, err := ws.responseCache.do(req, http.DefaultClient.Do)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, ws.responseCache.stats().Entries)
+
+	mux := http.NewServeMux()
+	ws.RegisterAdminRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, AdminCacheInvalidatePath, strings.NewReader(`["pets", "unknown"]`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"evicted": 2}`, rec.Body.String())
+	assert.Equal(t, 1, ws.responseCache.stats().Entries)
+	assert.Empty(t, ws.responseCache.tags["pet-1"])
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, AdminCacheInvalidatePath, strings.NewReader(`{"tags": "pets"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
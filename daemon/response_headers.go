@@ -0,0 +1,40 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// stripResponseHeaders removes internal upstream headers from a response, before it is validated, broadcast and
+// returned to the client. Headers are stripped when their name matches a configured header, or starts with a
+// configured prefix, regardless of case.
+func stripResponseHeaders(config *shared.WiretapConfiguration, response *http.Response) {
+	if response == nil || (len(config.StripResponseHeaders) == 0 && len(config.StripResponseHeaderPrefixes) == 0) {
+		return
+	}
+	for name := range response.Header {
+		if stripResponseHeader(config, name) {
+			response.Header.Del(name)
+		}
+	}
+}
+
+func stripResponseHeader(config *shared.WiretapConfiguration, name string) bool {
+	for _, header := range config.StripResponseHeaders {
+		if strings.EqualFold(header, name) {
+			return true
+		}
+	}
+	lower := strings.ToLower(name)
+	for _, prefix := range config.StripResponseHeaderPrefixes {
+		if strings.HasPrefix(lower, strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return false
+}
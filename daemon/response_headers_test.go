@@ -0,0 +1,31 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripResponseHeaders(t *testing.T) {
+	config := &shared.WiretapConfiguration{
+		StripResponseHeaders:        []string{"x-debug-trace"},
+		StripResponseHeaderPrefixes: []string{"X-Envoy-"},
+	}
+	response := &http.Response{Header: http.Header{
+		"X-Debug-Trace":                 []string{"abc"},
+		"X-Envoy-Upstream-Service-Time": []string{"12"},
+		"X-Envoy-Decorator-Operation":   []string{"pets"},
+		"X-Debug-Trace-Id":              []string{"kept, not an exact match"},
+		"Content-Type":                  []string{"application/json"},
+	}}
+	stripResponseHeaders(config, response)
+	assert.Equal(t, http.Header{
+		"X-Debug-Trace-Id": []string{"kept, not an exact match"},
+		"Content-Type":     []string{"application/json"},
+	}, response.Header)
+}
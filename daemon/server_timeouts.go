@@ -0,0 +1,75 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// DefaultShutdownTimeout is how long in-flight proxy requests are given to complete when wiretap is stopped, when
+// no shutdown timeout is configured.
+const DefaultShutdownTimeout = 10 * time.Second
+
+func millis(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// ConfigureTimeouts applies the configured read, write, idle and read header timeouts to the proxy server, a
+// timeout that is not configured is left as it is. The write timeout covers the whole response, including waiting
+// for the upstream API and streamed mock responses, websocket connections are not affected once upgraded. It must
+// be called before the server starts, and before ConfigureKeepAlive, as the keep-alive idle timeout takes precedence.
+func ConfigureTimeouts(server *http.Server, config *shared.WiretapConfiguration) {
+	if config.ReadTimeoutMs > 0 {
+		server.ReadTimeout = millis(config.ReadTimeoutMs)
+	}
+	if config.WriteTimeoutMs > 0 {
+		server.WriteTimeout = millis(config.WriteTimeoutMs)
+	}
+	if config.IdleTimeoutMs > 0 {
+		server.IdleTimeout = millis(config.IdleTimeoutMs)
+	}
+	if config.ReadHeaderTimeoutMs > 0 {
+		server.ReadHeaderTimeout = millis(config.ReadHeaderTimeoutMs)
+	}
+}
+
+// ShutdownTimeout returns the configured shutdown timeout, or the default.
+func ShutdownTimeout(config *shared.WiretapConfiguration) time.Duration {
+	if config.ShutdownTimeoutMs > 0 {
+		return millis(config.ShutdownTimeoutMs)
+	}
+	return DefaultShutdownTimeout
+}
+
+// SetProxyServer registers the server proxying requests, so it is shut down gracefully when wiretap is stopped.
+func (ws *WiretapService) SetProxyServer(server *http.Server) {
+	ws.proxyServer.Store(server)
+}
+
+// SetAdminServer registers the server of the admin API, when it is served separately from the proxy, so it is
+// shut down gracefully as well.
+func (ws *WiretapService) SetAdminServer(server *http.Server) {
+	ws.adminServer.Store(server)
+}
+
+// shutdownServers stops the proxy and admin servers accepting connections, and waits for in-flight requests to
+// complete, up to the shutdown timeout.
+func (ws *WiretapService) shutdownServers() {
+	timeout := ShutdownTimeout(ws.config)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for name, server := range map[string]*http.Server{"proxy": ws.proxyServer.Load(), "admin": ws.adminServer.Load()} {
+		if server == nil {
+			continue
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			ws.config.Logger.Warn("[wiretap] server did not shut down gracefully", "server", name,
+				"timeout", timeout.String(), "error", err.Error())
+		}
+	}
+}
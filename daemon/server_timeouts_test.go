@@ -0,0 +1,77 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureTimeouts(t *testing.T) {
+	server := &http.Server{}
+	config := &shared.WiretapConfiguration{ReadTimeoutMs: 1000, WriteTimeoutMs: 2000, IdleTimeoutMs: 3000,
+		ReadHeaderTimeoutMs: 500, KeepAliveIdleSeconds: 60}
+	ConfigureTimeouts(server, config)
+	assert.Equal(t, time.Second, server.ReadTimeout)
+	assert.Equal(t, 2*time.Second, server.WriteTimeout)
+	assert.Equal(t, 3*time.Second, server.IdleTimeout)
+	assert.Equal(t, 500*time.Millisecond, server.ReadHeaderTimeout)
+
+	// the keep-alive idle timeout takes precedence.
+	ConfigureKeepAlive(server, config)
+	assert.Equal(t, time.Minute, server.IdleTimeout)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	assert.Equal(t, DefaultShutdownTimeout, ShutdownTimeout(&shared.WiretapConfiguration{}))
+	assert.Equal(t, 250*time.Millisecond, ShutdownTimeout(&shared.WiretapConfiguration{ShutdownTimeoutMs: 250}))
+}
+
+func TestWiretapService_ShutdownProxyServer(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = w.Write([]byte("done"))
+	}))
+	defer server.Close()
+
+	ws := &WiretapService{config: &shared.WiretapConfiguration{ShutdownTimeoutMs: 5000}}
+	ws.SetProxyServer(server.Config)
+
+	body := make(chan string)
+	go func() {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		b, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		body <- string(b)
+	}()
+	<-started
+
+	shutdown := make(chan struct{})
+	go func() {
+		ws.OnServerShutdown()
+		close(shutdown)
+	}()
+	close(release)
+
+	// the in-flight request completes before the server shuts down.
+	assert.Equal(t, "done", <-body)
+	select {
+	case <-shutdown:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "proxy server did not shut down")
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel"
+	what_changed "github.com/pb33f/libopenapi/what-changed/model"
+)
+
+const AdminSpecCompatibilityPath = "/wiretap/spec/compatibility"
+
+// informationalProperties are the properties whose changes document the API, without changing its contract.
+var informationalProperties = map[string]bool{
+	"description":    true,
+	"summary":        true,
+	"title":          true,
+	"example":        true,
+	"examples":       true,
+	"externalDocs":   true,
+	"termsOfService": true,
+	"contact":        true,
+	"license":        true,
+	"email":          true,
+}
+
+// SpecCompatibilityRequest names the specifications to compare, as URLs or file paths. The loaded specification
+// is compared when either one is left out.
+type SpecCompatibilityRequest struct {
+	Original string `json:"original,omitempty"`
+	Updated  string `json:"updated,omitempty"`
+}
+
+// SpecChange is a single difference between two specifications.
+type SpecChange struct {
+	Change       string `json:"change"` // modified, added or removed.
+	Location     string `json:"location,omitempty"`
+	Property     string `json:"property"`
+	Original     string `json:"original,omitempty"`
+	New          string `json:"new,omitempty"`
+	OriginalLine int    `json:"originalLine,omitempty"`
+	NewLine      int    `json:"newLine,omitempty"`
+}
+
+// SpecCompatibilityReport is returned by the compatibility endpoint. The updated specification is compatible when
+// none of its changes break clients of the original. Breaking changes are what libopenapi considers breaking, such
+// as removed paths and operations, newly required fields and parameters, changed types and removed enum values.
+type SpecCompatibilityReport struct {
+	Original      string        `json:"original"`
+	Updated       string        `json:"updated"`
+	Compatible    bool          `json:"compatible"`
+	Breaking      []*SpecChange `json:"breaking"`
+	NonBreaking   []*SpecChange `json:"nonBreaking"`
+	Informational []*SpecChange `json:"informational"`
+}
+
+// handleSpecCompatibility compares two specifications, and reports their differences by category.
+func (ws *WiretapService) handleSpecCompatibility(w http.ResponseWriter, r *http.Request) {
+	var request SpecCompatibilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Invalid compatibility request",
+			"the request body must be a JSON object with 'original' and 'updated' specifications: "+err.Error(),
+			r.URL.Path)
+		return
+	}
+	if request.Original == "" && request.Updated == "" {
+		writeAdminError(w, http.StatusBadRequest, "Invalid compatibility request",
+			"at least one of the 'original' and 'updated' specifications is required", r.URL.Path)
+		return
+	}
+
+	original, err := ws.compatibilityDocument(request.Original)
+	if err == nil {
+		var updated libopenapi.Document
+		if updated, err = ws.compatibilityDocument(request.Updated); err == nil {
+			var report *SpecCompatibilityReport
+			if report, err = compareSpecs(original, updated); err == nil {
+				report.Original, report.Updated = specLabel(request.Original), specLabel(request.Updated)
+				writeAdminResponse(w, http.StatusOK, report)
+				return
+			}
+		}
+	}
+	writeAdminError(w, http.StatusUnprocessableEntity, "Unable to compare specifications", err.Error(), r.URL.Path)
+}
+
+// specLabel names a specification in the report.
+func specLabel(location string) string {
+	if location == "" {
+		return "loaded specification"
+	}
+	return location
+}
+
+// compatibilityDocument loads a specification from a URL or a file, or returns the loaded specification when no
+// location is given.
+func (ws *WiretapService) compatibilityDocument(location string) (libopenapi.Document, error) {
+	if location == "" {
+		spec := ws.currentSpec()
+		if spec.docModel == nil {
+			return nil, fmt.Errorf("no specification is loaded to compare against")
+		}
+		return spec.document, nil
+	}
+
+	docConfig := datamodel.NewDocumentConfiguration()
+	docConfig.AllowFileReferences = true
+	docConfig.AllowRemoteReferences = true
+	docConfig.Logger = ws.config.Logger
+
+	var specBytes []byte
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		client := &http.Client{Timeout: 30 * time.Second}
+		var resp *http.Response
+		if resp, err = client.Get(location); err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("specification '%s' returned status %d", location, resp.StatusCode)
+		}
+		specBytes, err = io.ReadAll(resp.Body)
+	} else {
+		docConfig.BasePath = filepath.Dir(location)
+		specBytes, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(specBytes) == 0 {
+		return nil, fmt.Errorf("specification '%s' is empty", location)
+	}
+	return libopenapi.NewDocumentWithConfiguration(specBytes, docConfig)
+}
+
+// compareSpecs compares two specifications with libopenapi, and sorts their changes into categories. Changes within
+// a path or a component schema are located at it.
+func compareSpecs(original, updated libopenapi.Document) (*SpecCompatibilityReport, error) {
+	changes, errs := libopenapi.CompareDocuments(original, updated)
+	if changes == nil && len(errs) > 0 {
+		return nil, errs[0]
+	}
+	report := &SpecCompatibilityReport{
+		Compatible:    true,
+		Breaking:      []*SpecChange{},
+		NonBreaking:   []*SpecChange{},
+		Informational: []*SpecChange{},
+	}
+	if changes == nil {
+		return report, nil
+	}
+
+	locations := make(map[*what_changed.Change]string)
+	if changes.PathsChanges != nil {
+		for path, pathChanges := range changes.PathsChanges.PathItemsChanges {
+			for _, change := range pathChanges.GetAllChanges() {
+				locations[change] = "paths." + path
+			}
+		}
+		for _, change := range changes.PathsChanges.PropertyChanges.Changes {
+			locations[change] = "paths"
+		}
+	}
+	if changes.ComponentsChanges != nil {
+		for name, schemaChanges := range changes.ComponentsChanges.SchemaChanges {
+			for _, change := range schemaChanges.GetAllChanges() {
+				locations[change] = "components.schemas." + name
+			}
+		}
+	}
+
+	for _, change := range changes.GetAllChanges() {
+		sc := &SpecChange{
+			Change:   changeName(change.ChangeType),
+			Location: locations[change],
+			Property: change.Property,
+			Original: change.Original,
+			New:      change.New,
+		}
+		if change.Context != nil {
+			if change.Context.OriginalLine != nil {
+				sc.OriginalLine = *change.Context.OriginalLine
+			}
+			if change.Context.NewLine != nil {
+				sc.NewLine = *change.Context.NewLine
+			}
+		}
+		switch {
+		case change.Breaking:
+			report.Breaking = append(report.Breaking, sc)
+		case informationalProperties[change.Property]:
+			report.Informational = append(report.Informational, sc)
+		default:
+			report.NonBreaking = append(report.NonBreaking, sc)
+		}
+	}
+	report.Compatible = len(report.Breaking) == 0
+	for _, category := range [][]*SpecChange{report.Breaking, report.NonBreaking, report.Informational} {
+		slices.SortStableFunc(category, func(a, b *SpecChange) int {
+			return cmp.Or(strings.Compare(a.Location, b.Location), strings.Compare(a.Property, b.Property))
+		})
+	}
+	return report, nil
+}
+
+func changeName(changeType int) string {
+	switch changeType {
+	case what_changed.PropertyAdded, what_changed.ObjectAdded:
+		return "added"
+	case what_changed.PropertyRemoved, what_changed.ObjectRemoved:
+		return "removed"
+	}
+	return "modified"
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var compatibilityOriginal = []byte(`openapi: 3.0.3
+info: {title: pets, version: "1"}
+paths:
+  /pets:
+    get:
+      description: list the pets.
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+  /owners:
+    get:
+      responses:
+        "200": {description: ok}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        kind: {type: string, enum: [cat, dog]}
+`)
+
+var compatibilityUpdated = []byte(`openapi: 3.0.3
+info: {title: pets, version: "1"}
+paths:
+  /pets:
+    get:
+      description: list all of the pets.
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        kind: {type: string, enum: [cat]}
+        age: {type: integer}
+`)
+
+func TestWiretapService_HandleSpecCompatibility(t *testing.T) {
+	dir := t.TempDir()
+	updated := filepath.Join(dir, "updated.yaml")
+	require.NoError(t, os.WriteFile(updated, compatibilityUpdated, 0o644))
+
+	doc, err := libopenapi.NewDocument(compatibilityOriginal)
+	require.NoError(t, err)
+	ws := &WiretapService{config: &shared.WiretapConfiguration{}}
+	ws.spec.Store(buildSpec(doc, ws.config))
+
+	// the loaded specification is the original.
+	recorder := httptest.NewRecorder()
+	ws.handleSpecCompatibility(recorder, httptest.NewRequest(http.MethodPost, AdminSpecCompatibilityPath,
+		strings.NewReader(`{"updated": "`+updated+`"}`)))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var report SpecCompatibilityReport
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, "loaded specification", report.Original)
+	assert.Equal(t, updated, report.Updated)
+	assert.False(t, report.Compatible)
+
+	breaking := make(map[string]string)
+	for _, change := range report.Breaking {
+		breaking[change.Location+" "+change.Property] = change.Original
+	}
+	assert.Equal(t, "/owners", breaking["paths path"])
+	assert.Equal(t, "dog", breaking["components.schemas.Pet enum"])
+
+	require.Len(t, report.NonBreaking, 1)
+	assert.Equal(t, "added", report.NonBreaking[0].Change)
+	assert.Equal(t, "properties", report.NonBreaking[0].Property)
+	assert.Equal(t, "age", report.NonBreaking[0].New)
+	assert.Equal(t, "components.schemas.Pet", report.NonBreaking[0].Location)
+
+	require.Len(t, report.Informational, 1)
+	assert.Equal(t, "description", report.Informational[0].Property)
+	assert.Equal(t, "paths./pets", report.Informational[0].Location)
+	assert.Equal(t, "list all of the pets.", report.Informational[0].New)
+
+	// a specification is compatible with itself.
+	recorder = httptest.NewRecorder()
+	ws.handleSpecCompatibility(recorder, httptest.NewRequest(http.MethodPost, AdminSpecCompatibilityPath,
+		strings.NewReader(`{"original": "`+updated+`", "updated": "`+updated+`"}`)))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.True(t, report.Compatible)
+	assert.Empty(t, report.Breaking)
+}
+
+func TestWiretapService_HandleSpecCompatibility_Errors(t *testing.T) {
+	ws := &WiretapService{config: &shared.WiretapConfiguration{}}
+
+	recorder := httptest.NewRecorder()
+	ws.handleSpecCompatibility(recorder, httptest.NewRequest(http.MethodPost, AdminSpecCompatibilityPath,
+		strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	ws.handleSpecCompatibility(recorder, httptest.NewRequest(http.MethodPost, AdminSpecCompatibilityPath,
+		strings.NewReader(`{"updated": "missing.yaml"}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "no specification is loaded")
+}
@@ -0,0 +1,35 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// overrideStatusCode replaces the status code of an upstream response, when there is an override configured for it.
+// If the response cache holds a response for the request with the new status code, even a stale one, the cached
+// response is returned instead, so a 503 can be replaced by the last good response (maintenance mode testing).
+// Otherwise only the status code changes, the body of the upstream response is kept.
+func (ws *WiretapService) overrideStatusCode(req *http.Request, resp *http.Response,
+	overrides map[int]int) *http.Response {
+
+	code, ok := overrides[resp.StatusCode]
+	if !ok || code == resp.StatusCode {
+		return resp
+	}
+
+	if cached := ws.responseCache.cached(req); cached != nil && cached.StatusCode == code {
+		_ = resp.Body.Close()
+		ws.config.Logger.Info("[wiretap] status code overridden, serving cached response", "url", req.URL.String(),
+			"code", resp.StatusCode, "override", code)
+		return cached
+	}
+
+	ws.config.Logger.Info("[wiretap] status code overridden", "url", req.URL.String(),
+		"code", resp.StatusCode, "override", code)
+	resp.StatusCode = code
+	resp.Status = strconv.Itoa(code) + " " + http.StatusText(code)
+	return resp
+}
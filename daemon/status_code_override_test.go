@@ -0,0 +1,69 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrideStatusCode(t *testing.T) {
+	maintenance := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if maintenance {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("down for maintenance"))
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("pets"))
+	}))
+	defer upstream.Close()
+
+	config := &shared.WiretapConfiguration{Logger: slog.New(slog.NewTextHandler(os.Stderr, nil))}
+	ws := &WiretapService{config: config}
+	overrides := map[int]int{http.StatusServiceUnavailable: http.StatusOK, http.StatusNotFound: http.StatusGone}
+
+	send := func(path string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, upstream.URL+path, nil)
+		resp, err := ws.responseCache.do(req, http.DefaultClient.Do)
+		require.NoError(t, err)
+		return ws.overrideStatusCode(req, resp, overrides)
+	}
+
+	// without a cache only the status code changes.
+	maintenance = true
+	resp := send("/pets")
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "200 OK", resp.Status)
+	assert.Equal(t, "down for maintenance", string(body))
+
+	// with a cache, the last good response is served.
+	ws.responseCache = newResponseCache(&shared.CacheConfig{Enabled: true})
+	maintenance = false
+	resp = send("/pets")
+	_, _ = io.ReadAll(resp.Body)
+	maintenance = true
+	req, _ := http.NewRequest(http.MethodGet, upstream.URL+"/pets", nil)
+	resp, _ = http.DefaultClient.Do(req)
+	resp = ws.overrideStatusCode(req, resp, overrides)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "pets", string(body))
+
+	// codes without an override are untouched.
+	resp = ws.overrideStatusCode(req, &http.Response{StatusCode: http.StatusTeapot,
+		Body: io.NopCloser(strings.NewReader(""))}, overrides)
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+}
@@ -4,59 +4,485 @@
 package daemon
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
 	"github.com/pterm/pterm"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"log/slog"
+	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 )
 
+const (
+	ReportFormatJSON   = "json"   // a JSON array of violations, rewritten as violations arrive.
+	ReportFormatNDJSON = "ndjson" // one violation per line, appended as violations arrive.
+)
+
+const (
+	ReportEncodingUTF8    = "utf-8"
+	ReportEncodingUTF16LE = "utf-16le"
+	ReportEncodingUTF16BE = "utf-16be"
+)
+
+// reportFile streams violations to a report file, in one of the report formats. When a maximum size is set, the
+// report continues in a new file once it grows past the size, named with an incrementing suffix (report_1.json).
+type reportFile struct {
+	file     *os.File
+	basePath string
+	path     string
+	format   string
+	count    int
+	rotation int
+	options  reportOptions
+	archiver *reportArchiver
+	encoder  *encoding.Encoder
+	bom      []byte
+	unitSize int64 // the size of a single ASCII character, in the encoding of the report.
+}
+
+// reportOptions configures the encoding of a report, when it is rotated, and what happens to the rotated files.
+type reportOptions struct {
+	encoding    string
+	pretty      bool
+	maxSize     int64
+	compress    bool
+	extension   string
+	maxRetained int
+	checksums   bool
+	upload      *shared.UploadConfig
+	logger      *slog.Logger
+}
+
+// reportOptionsConfig reads the rotation options from the configuration.
+func reportOptionsConfig(config *shared.WiretapConfiguration) reportOptions {
+	return reportOptions{
+		encoding:    config.ReportEncoding,
+		pretty:      config.ReportPrettyPrint,
+		maxSize:     config.MaxReportFileSizeBytes,
+		compress:    config.CompressRotatedFiles,
+		extension:   config.CompressedExtension,
+		maxRetained: config.MaxRetainedFiles,
+		checksums:   config.ReportChecksums,
+		upload:      config.RotationUpload,
+		logger:      config.Logger,
+	}
+}
+
+// reportEncoder returns the encoder of a report encoding and the byte order mark files start with. UTF-8 (and
+// unknown encodings) have no encoder, reports are written as is.
+func reportEncoder(name string) (*encoding.Encoder, []byte) {
+	switch strings.ToLower(name) {
+	case ReportEncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder(), []byte{0xFF, 0xFE}
+	case ReportEncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder(), []byte{0xFE, 0xFF}
+	}
+	return nil, nil
+}
+
+// openReportFile replaces any existing report with an empty report in the format. An unknown format is
+// written as JSON. A maximum size of zero never rotates the report.
+func openReportFile(path, format string, options reportOptions) (*reportFile, error) {
+	if format != ReportFormatNDJSON {
+		format = ReportFormatJSON
+	}
+	rf := &reportFile{basePath: path, format: format, options: options, unitSize: 1}
+	if rf.encoder, rf.bom = reportEncoder(options.encoding); rf.encoder != nil {
+		rf.unitSize = 2
+	}
+	if err := rf.open(path); err != nil {
+		return nil, err
+	}
+	rf.archiver = newReportArchiver(options)
+	return rf, nil
+}
+
+// rotatedReportPath returns the path of a rotated report, the suffix is added before the extension.
+func rotatedReportPath(path string, rotation int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(path, ext), rotation, ext)
+}
+
+// open starts an empty report at the path, replacing any existing file.
+func (rf *reportFile) open(path string) error {
+	_ = os.Remove(path)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	rf.file, rf.path, rf.count = f, path, 0
+	if len(rf.bom) > 0 {
+		if _, err = f.Write(rf.bom); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if rf.format == ReportFormatJSON {
+		if err = rf.put([]byte("[]")); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// put writes to the current file, in the encoding of the report.
+func (rf *reportFile) put(b []byte) error {
+	if rf.encoder != nil {
+		encoded, err := rf.encoder.Bytes(b)
+		if err != nil {
+			return err
+		}
+		b = encoded
+	}
+	_, err := rf.file.Write(b)
+	return err
+}
+
+// rotate continues the report in a new file, if the current file is over the maximum size.
+func (rf *reportFile) rotate() error {
+	if rf.options.maxSize <= 0 {
+		return nil
+	}
+	fi, err := rf.file.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() <= rf.options.maxSize {
+		return nil
+	}
+	if err = rf.file.Close(); err != nil {
+		return err
+	}
+	rf.finish(rf.path)
+	rf.rotation++
+	return rf.open(rotatedReportPath(rf.basePath, rf.rotation))
+}
+
+// maxPooledReportBuffer is the largest buffer returned to the pool, a rare huge batch should not pin its buffer.
+const maxPooledReportBuffer = 1 << 20
+
+// reportBufferPool holds the buffers violations are encoded into before they are written to the report.
+var reportBufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// encode appends a violation to the buffer. JSON reports are indented when pretty printed, NDJSON reports
+// always have one violation per line.
+func (rf *reportFile) encode(buf *bytes.Buffer, violation *errors.ValidationError) error {
+	encoder := jsoniter.ConfigCompatibleWithStandardLibrary.NewEncoder(buf)
+	if rf.options.pretty && rf.format != ReportFormatNDJSON {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(violation); err != nil {
+		return err
+	}
+	// the encoder ends every value with a newline, the report adds its own separators.
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// encodeAll encodes violations into a pooled buffer, and returns the encoded violations along with the buffer.
+// The encoded violations point into the buffer, which is returned to the pool with releaseReportBuffer once they
+// have been written. Violations that cannot be encoded are left out.
+func (rf *reportFile) encodeAll(violations []*errors.ValidationError, redactFields []string) ([][]byte, *bytes.Buffer) {
+	buf := reportBufferPool.Get().(*bytes.Buffer)
+	ends := make([]int, 0, len(violations))
+	for _, v := range violations {
+		start := buf.Len()
+		if err := rf.encode(buf, redactViolation(v, redactFields)); err != nil {
+			buf.Truncate(start)
+			pterm.Error.Println("cannot encode violation for stream: " + err.Error())
+			continue
+		}
+		ends = append(ends, buf.Len())
+	}
+
+	// the buffer may have grown while encoding, so the violations are only sliced out once it is complete.
+	encoded := make([][]byte, len(ends))
+	data, start := buf.Bytes(), 0
+	for i, end := range ends {
+		encoded[i] = data[start:end:end]
+		start = end
+	}
+	return encoded, buf
+}
+
+// releaseReportBuffer empties a buffer and returns it to the pool.
+func releaseReportBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledReportBuffer {
+		return
+	}
+	buf.Reset()
+	reportBufferPool.Put(buf)
+}
+
+// write adds encoded violations to the report, and rotates the report if it has grown past the maximum size.
+func (rf *reportFile) write(violations [][]byte) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	if err := rf.append(violations); err != nil {
+		return err
+	}
+	return rf.rotate()
+}
+
+// append adds encoded violations to the current file. JSON reports have their closing bracket cut off and written
+// again after the new violations, so the file is always a valid JSON array.
+func (rf *reportFile) append(violations [][]byte) error {
+	if rf.format == ReportFormatNDJSON {
+		for _, v := range violations {
+			if err := rf.put(v); err != nil {
+				return err
+			}
+			if err := rf.put([]byte("\n")); err != nil {
+				return err
+			}
+		}
+		rf.count += len(violations)
+		return nil
+	}
+
+	fi, err := rf.file.Stat()
+	if err != nil {
+		return err
+	}
+	if err = os.Truncate(rf.path, fi.Size()-rf.unitSize); err != nil {
+		return err
+	}
+	for _, v := range violations {
+		if rf.count > 0 {
+			if err = rf.put([]byte(",\n")); err != nil {
+				return err
+			}
+		}
+		if err = rf.put(v); err != nil {
+			return err
+		}
+		rf.count++
+	}
+	return rf.put([]byte("]"))
+}
+
+// finish archives a closed report file, or writes its checksum when it is not archived. The archiver writes the
+// checksum of the file it leaves behind, so a compressed report has the checksum of the compressed file.
+func (rf *reportFile) finish(path string) {
+	if rf.archiver != nil {
+		rf.archiver.archive(path)
+		return
+	}
+	if rf.options.checksums {
+		if err := writeReportChecksum(path); err != nil {
+			pterm.Error.Println("cannot write report checksum: " + err.Error())
+		}
+	}
+}
+
+// close closes the report, and waits for rotated files to be archived.
+func (rf *reportFile) close() error {
+	err := rf.file.Close()
+	if err == nil && rf.options.checksums {
+		err = writeReportChecksum(rf.path)
+	}
+	rf.archiver.close()
+	return err
+}
+
+// StreamStats counts the violations dropped because the stream buffer was full, and the repeated violations
+// when they are deduplicated.
+type StreamStats struct {
+	BufferSize int   `json:"bufferSize"`
+	Dropped    int64 `json:"dropped"`              // violations not streamed, reported or notified.
+	Unique     int   `json:"unique,omitempty"`     // distinct violations, when deduplicating or sampling.
+	Duplicates int   `json:"duplicates,omitempty"` // repeats of a violation already seen, when deduplicating or sampling.
+	Skipped    int64 `json:"skipped,omitempty"`    // repeats not written to the report, when deduplicating or sampling.
+}
+
+// streamSamplingRate returns the rate repeated violations are written to the report at, 1 when every
+// violation is written.
+func (ws *WiretapService) streamSamplingRate() float64 {
+	if ws.config == nil || ws.config.ErrorStreamSamplingRate <= 0 || ws.config.ErrorStreamSamplingRate >= 1 {
+		return 1
+	}
+	return ws.config.ErrorStreamSamplingRate
+}
+
+// countsViolations is true when violations are counted by signature, to deduplicate or sample them.
+func (ws *WiretapService) countsViolations() bool {
+	return ws.config != nil && (ws.config.DeduplicateErrors || ws.streamSamplingRate() < 1)
+}
+
+// sendViolations queues violations for the report and notifications. An unbuffered stream waits for the violations to
+// be taken or the stream to stop, a buffered stream drops them (and counts them) when the buffer is full, so validation
+// never holds up the request.
+func (ws *WiretapService) sendViolations(violations []*errors.ValidationError) {
+	if cap(ws.streamChan) == 0 {
+		select {
+		case ws.streamChan <- violations:
+		case <-ws.streamDone:
+		}
+		return
+	}
+	select {
+	case ws.streamChan <- violations:
+	default:
+		ws.streamDropped.Add(int64(len(violations)))
+	}
+}
+
+// streamStats returns the stream statistics, nil when the stream is not buffered and violations are not counted.
+func (ws *WiretapService) streamStats() *StreamStats {
+	counted := ws.countsViolations()
+	if cap(ws.streamChan) == 0 && !counted {
+		return nil
+	}
+	stats := &StreamStats{BufferSize: cap(ws.streamChan), Dropped: ws.streamDropped.Load()}
+	if counted {
+		ws.streamLock.RLock()
+		stats.Unique = len(ws.streamCounts)
+		for _, count := range ws.streamCounts {
+			stats.Duplicates += count - 1
+		}
+		stats.Skipped = ws.streamSkipped
+		ws.streamLock.RUnlock()
+	}
+	return stats
+}
+
+// violationKey identifies the repeats of a violation: the same request path and method, the same rule and
+// the same message.
+func violationKey(ve *errors.ValidationError) string {
+	h := sha256.New()
+	for _, field := range []string{ve.RequestMethod, ve.RequestPath, ve.ValidationType, ve.ValidationSubType,
+		strconv.Itoa(ve.SpecLine), strconv.Itoa(ve.SpecCol), ve.Message, ve.Reason} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// selectViolations counts every violation by signature, and returns those to write to the report. The first
+// occurrence of a violation is always written. Repeats are not written when deduplicating, and are written at the
+// sampling rate otherwise. The stream lock must be held.
+func (ws *WiretapService) selectViolations(violations []*errors.ValidationError) []*errors.ValidationError {
+	if !ws.countsViolations() {
+		return violations
+	}
+	if ws.streamCounts == nil {
+		ws.streamCounts = make(map[string]int)
+	}
+	rate := ws.streamSamplingRate()
+	var selected []*errors.ValidationError
+	for _, v := range violations {
+		key := violationKey(v)
+		first := ws.streamCounts[key] == 0
+		ws.streamCounts[key]++
+		if first || (!ws.config.DeduplicateErrors && rand.Float64() < rate) {
+			selected = append(selected, v)
+		} else {
+			ws.streamSkipped++
+		}
+	}
+	return selected
+}
+
 func (ws *WiretapService) listenForValidationErrors() {
 
 	ws.streamViolations = []*errors.ValidationError{}
-	var lock sync.RWMutex
-	json := jsoniter.ConfigCompatibleWithStandardLibrary
 
-	_ = os.Remove(ws.reportFile)
-	f, err := os.OpenFile(ws.reportFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if ws.config.VerifyOnOpen {
+		verifyExistingReports(ws.reportFile, ws.config.CompressedExtension, ws.config.Logger)
+	}
+	rf, err := openReportFile(ws.reportFile, ws.reportFormat, reportOptionsConfig(ws.config))
 	if err != nil {
 		pterm.Error.Println("cannot stream violations: " + err.Error())
 		return
 	}
+	ws.sweepOldReports(rf)
 
+	ws.streamDone = make(chan struct{})
+	ws.streamStopped = make(chan struct{})
 	go func() {
-		defer f.Close()
-		if _, e := f.WriteString("[]"); e != nil {
-			pterm.Error.Println("cannot write violation to stream: " + err.Error())
-		}
+		defer close(ws.streamStopped)
 		for {
 			select {
 			case violations := <-ws.streamChan:
-
-				if ws.stream {
-					lock.Lock()
-
-					fi, _ := f.Stat()
-					_ = os.Truncate(ws.reportFile, fi.Size()-1)
-					if fi.Size() > 2 {
-						_, _ = f.WriteString(",\n")
-					}
-					ws.streamViolations = append(ws.streamViolations, violations...)
-
-					for i, v := range violations {
-						bytes, _ := json.Marshal(v)
-						if _, e := f.WriteString(fmt.Sprintf("%s", bytes)); e != nil {
-							pterm.Error.Println("cannot write violation to stream: " + err.Error())
-						}
-						if i > len(violations)-1 {
-							_, _ = f.WriteString(",\n")
+				ws.streamViolation(rf, violations)
+			case <-ws.streamDone:
+				// violations still queued are written before the report is closed.
+				for {
+					select {
+					case violations := <-ws.streamChan:
+						ws.streamViolation(rf, violations)
+					default:
+						if e := rf.close(); e != nil {
+							pterm.Error.Println("cannot close violation stream: " + e.Error())
 						}
+						return
 					}
-					_, _ = f.WriteString("]")
-					lock.Unlock()
 				}
 			}
 		}
 	}()
 }
+
+// streamViolation records violations for notifications and the error rate, and writes them to the report.
+func (ws *WiretapService) streamViolation(rf *reportFile, violations []*errors.ValidationError) {
+	ws.notifiers.record(violations)
+	ws.recordErrorRate(len(violations))
+
+	if ws.stream {
+		ws.streamLock.Lock()
+		// repeats are counted, and may be left out of the report.
+		violations = ws.selectViolations(violations)
+		ws.streamViolations = append(ws.streamViolations, violations...)
+
+		encoded, buf := rf.encodeAll(violations, ws.config.RedactFields)
+		if e := rf.write(encoded); e != nil {
+			pterm.Error.Println("cannot write violation to stream: " + e.Error())
+		}
+		releaseReportBuffer(buf)
+		ws.streamLock.Unlock()
+	}
+}
+
+// stopStream writes the queued violations, closes the report and waits for rotated reports to be archived.
+func (ws *WiretapService) stopStream() {
+	if ws.streamDone == nil {
+		return
+	}
+	close(ws.streamDone)
+	<-ws.streamStopped
+}
+
+// redactViolation returns a copy of a violation with any redacted fields removed from the objects that failed schema
+// validation. The original violation is not modified, it may still be in use elsewhere.
+func redactViolation(violation *errors.ValidationError, fields []string) *errors.ValidationError {
+	if len(fields) == 0 || violation == nil || len(violation.SchemaValidationErrors) == 0 {
+		return violation
+	}
+	redacted := *violation
+	redacted.SchemaValidationErrors = make([]*errors.SchemaValidationFailure, len(violation.SchemaValidationErrors))
+	for i, failure := range violation.SchemaValidationErrors {
+		if failure == nil {
+			continue
+		}
+		f := *failure
+		f.ReferenceObject = shared.RedactJSON(f.ReferenceObject, fields)
+		redacted.SchemaValidationErrors[i] = &f
+	}
+	return &redacted
+}
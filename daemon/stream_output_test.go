@@ -0,0 +1,244 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportFile_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	rf, err := openReportFile(path, "", reportOptions{})
+	require.NoError(t, err)
+	defer rf.close()
+
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, "[]", string(data))
+
+	require.NoError(t, rf.write([][]byte{[]byte(`{"message":"one"}`), []byte(`{"message":"two"}`)}))
+	require.NoError(t, rf.write([][]byte{[]byte(`{"message":"three"}`)}))
+
+	data, _ = os.ReadFile(path)
+	var violations []map[string]string
+	require.NoError(t, json.Unmarshal(data, &violations))
+	assert.Equal(t, []map[string]string{{"message": "one"}, {"message": "two"}, {"message": "three"}}, violations)
+}
+
+func TestReportFile_PrettyPrint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	rf, err := openReportFile(path, ReportFormatJSON, reportOptions{pretty: true})
+	require.NoError(t, err)
+	defer rf.close()
+
+	violation := &errors.ValidationError{Message: "one", Reason: "bad"}
+	encoded, buf := rf.encodeAll([]*errors.ValidationError{violation, violation}, nil)
+	require.Len(t, encoded, 2)
+	assert.Contains(t, string(encoded[0]), "{\n  \"message\": \"one\",\n")
+	require.NoError(t, rf.write(encoded))
+	releaseReportBuffer(buf)
+
+	data, _ := os.ReadFile(path)
+	var violations []*errors.ValidationError
+	require.NoError(t, json.Unmarshal(data, &violations))
+	assert.Len(t, violations, 2)
+
+	// NDJSON reports keep one violation per line.
+	rf.format = ReportFormatNDJSON
+	encoded, buf = rf.encodeAll([]*errors.ValidationError{violation}, nil)
+	assert.NotContains(t, string(encoded[0]), "\n")
+	releaseReportBuffer(buf)
+}
+
+func TestReportFile_EncodeAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.ndjson")
+	rf, err := openReportFile(path, ReportFormatNDJSON, reportOptions{})
+	require.NoError(t, err)
+	defer rf.close()
+
+	violations := make([]*errors.ValidationError, 100)
+	for i := range violations {
+		violations[i] = &errors.ValidationError{Message: strings.Repeat("x", i)}
+	}
+	encoded, buf := rf.encodeAll(violations, nil)
+	require.Len(t, encoded, 100)
+	require.NoError(t, rf.write(encoded))
+	releaseReportBuffer(buf)
+	assert.Zero(t, buf.Len())
+
+	// every violation is on its own line, the encoded violations did not overwrite each other.
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 100)
+	for i, line := range lines {
+		var v errors.ValidationError
+		require.NoError(t, json.Unmarshal([]byte(line), &v))
+		assert.Len(t, v.Message, i)
+	}
+}
+
+func TestReportFile_NDJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte("stale"), 0644))
+	rf, err := openReportFile(path, ReportFormatNDJSON, reportOptions{})
+	require.NoError(t, err)
+	defer rf.close()
+
+	require.NoError(t, rf.write([][]byte{[]byte(`{"message":"one"}`), []byte(`{"message":"two"}`)}))
+	require.NoError(t, rf.write([][]byte{[]byte(`{"message":"three"}`)}))
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 3)
+	for _, line := range lines {
+		assert.True(t, json.Valid([]byte(line)), line)
+	}
+}
+
+func TestReportFile_RotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	rf, err := openReportFile(path, "", reportOptions{maxSize: 30})
+	require.NoError(t, err)
+	defer rf.close()
+
+	require.NoError(t, rf.write([][]byte{[]byte(`{"message":"one"}`)}))   // 19 bytes, under the limit.
+	require.NoError(t, rf.write([][]byte{[]byte(`{"message":"two"}`)}))   // over the limit, rotates.
+	require.NoError(t, rf.write([][]byte{[]byte(`{"message":"three"}`)})) // written to report_1.json.
+
+	read := func(p string) []map[string]string {
+		data, e := os.ReadFile(p)
+		require.NoError(t, e)
+		var violations []map[string]string
+		require.NoError(t, json.Unmarshal(data, &violations))
+		return violations
+	}
+	assert.Equal(t, []map[string]string{{"message": "one"}, {"message": "two"}}, read(path))
+	assert.Equal(t, []map[string]string{{"message": "three"}}, read(filepath.Join(filepath.Dir(path), "report_1.json")))
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "report_2.json"), rotatedReportPath(path, 2))
+}
+
+func TestReportFile_UTF16(t *testing.T) {
+	for _, encoding := range []string{ReportEncodingUTF16LE, ReportEncodingUTF16BE} {
+		t.Run(encoding, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "report.json")
+			rf, err := openReportFile(path, "", reportOptions{encoding: encoding})
+			require.NoError(t, err)
+			defer rf.close()
+
+			require.NoError(t, rf.write([][]byte{[]byte(`{"message":"one"}`)}))
+			require.NoError(t, rf.write([][]byte{[]byte(`{"message":"twö"}`)}))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			order := binary.ByteOrder(binary.LittleEndian)
+			bom := []byte{0xFF, 0xFE}
+			if encoding == ReportEncodingUTF16BE {
+				order, bom = binary.BigEndian, []byte{0xFE, 0xFF}
+			}
+			require.Equal(t, bom, data[:2])
+			units := make([]uint16, (len(data)-2)/2)
+			for i := range units {
+				units[i] = order.Uint16(data[2+i*2:])
+			}
+			var violations []map[string]string
+			require.NoError(t, json.Unmarshal([]byte(string(utf16.Decode(units))), &violations))
+			assert.Equal(t, []map[string]string{{"message": "one"}, {"message": "twö"}}, violations)
+		})
+	}
+}
+
+func TestSendViolations_Buffered(t *testing.T) {
+	ws := &WiretapService{streamChan: make(chan []*errors.ValidationError, 1)}
+	ws.sendViolations([]*errors.ValidationError{{Message: "one"}})
+	// the buffer is full, the violations are dropped rather than blocking.
+	ws.sendViolations([]*errors.ValidationError{{Message: "two"}, {Message: "three"}})
+
+	assert.Len(t, ws.streamChan, 1)
+	assert.Equal(t, &StreamStats{BufferSize: 1, Dropped: 2}, ws.streamStats())
+
+	unbuffered := &WiretapService{streamChan: make(chan []*errors.ValidationError)}
+	assert.Nil(t, unbuffered.streamStats())
+}
+
+func TestOnServerShutdown_ClosesReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	ws := &WiretapService{
+		config: &shared.WiretapConfiguration{Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			ReportChecksums: true},
+		stream:     true,
+		streamChan: make(chan []*errors.ValidationError, 10),
+		reportFile: path,
+	}
+	ws.listenForValidationErrors()
+	ws.sendViolations([]*errors.ValidationError{{Message: "one"}})
+	ws.sendViolations([]*errors.ValidationError{{Message: "two"}})
+	ws.OnServerShutdown()
+
+	// the queued violations are written, and the closed report has its checksum.
+	assert.FileExists(t, path+ChecksumExtension)
+	assert.NoError(t, VerifyReportChecksum(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var violations []map[string]any
+	require.NoError(t, json.Unmarshal(data, &violations))
+	require.Len(t, violations, 2)
+	assert.Equal(t, "two", violations[1]["message"])
+
+	// violations sent once the stream has stopped do not block.
+	ws.streamChan = make(chan []*errors.ValidationError)
+	ws.sendViolations([]*errors.ValidationError{{Message: "three"}})
+}
+
+func TestSelectViolations_Deduplicate(t *testing.T) {
+	ws := &WiretapService{config: &shared.WiretapConfiguration{DeduplicateErrors: true}}
+	missing := &errors.ValidationError{RequestMethod: "GET", RequestPath: "/pets", ValidationType: "parameter",
+		Message: "Query parameter 'limit' is missing"}
+	repeat := *missing
+	other := &errors.ValidationError{RequestMethod: "GET", RequestPath: "/pets/1", ValidationType: "parameter",
+		Message: "Query parameter 'limit' is missing"}
+
+	assert.Equal(t, []*errors.ValidationError{missing, other},
+		ws.selectViolations([]*errors.ValidationError{missing, &repeat, other}))
+	assert.Empty(t, ws.selectViolations([]*errors.ValidationError{&repeat}))
+
+	stats := ws.streamStats()
+	assert.Equal(t, 2, stats.Unique)
+	assert.Equal(t, 2, stats.Duplicates)
+	assert.Equal(t, int64(2), stats.Skipped)
+	assert.Equal(t, 3, ws.streamCounts[violationKey(missing)])
+}
+
+func TestSelectViolations_Sampling(t *testing.T) {
+	ws := &WiretapService{config: &shared.WiretapConfiguration{ErrorStreamSamplingRate: 0.5}}
+	violations := make([]*errors.ValidationError, 1000)
+	for i := range violations {
+		violations[i] = &errors.ValidationError{RequestPath: "/pets", Message: "bad body"}
+	}
+	violations = append(violations, &errors.ValidationError{RequestPath: "/pets", Message: "other"})
+
+	selected := ws.selectViolations(violations)
+	// the first occurrence of each signature is always written, repeats at the sampling rate.
+	assert.Same(t, violations[0], selected[0])
+	assert.Same(t, violations[1000], selected[len(selected)-1])
+	assert.InDelta(t, 500, len(selected), 100)
+	assert.Equal(t, 1000, ws.streamCounts[violationKey(violations[0])]) // every error is counted.
+	assert.Equal(t, int64(1001-len(selected)), ws.streamStats().Skipped)
+
+	// without sampling or deduplication, every violation is written.
+	all := &WiretapService{config: &shared.WiretapConfiguration{}}
+	assert.Len(t, all.selectViolations(violations), 1001)
+	assert.Nil(t, all.streamStats())
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi-validator/paths"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// checkStatusCode returns an error if the status code of a response is not defined by the operation it answers,
+// either as the exact code or as a range ('2XX'). A 'default' response does not count as defining the code, it
+// would otherwise let any status code through. Requests that match no operation are not checked here.
+func checkStatusCode(doc *v3.Document, request *http.Request, response *http.Response) *errors.ValidationError {
+	if doc == nil || request == nil || response == nil {
+		return nil
+	}
+	pathItem, errs, specPath := paths.FindPath(request, doc)
+	if len(errs) > 0 || pathItem == nil {
+		return nil
+	}
+	operation := pathItem.GetOperations().GetOrZero(strings.ToLower(request.Method))
+	if operation == nil || operation.Responses == nil {
+		return nil
+	}
+
+	code := response.StatusCode
+	if operation.Responses.Codes != nil {
+		for _, defined := range []string{strconv.Itoa(code), fmt.Sprintf("%dXX", code/100), fmt.Sprintf("%dxx", code/100)} {
+			if _, ok := operation.Responses.Codes.Get(defined); ok {
+				return nil
+			}
+		}
+	}
+
+	ve := &errors.ValidationError{
+		ValidationType:    helpers.ResponseBodyValidation,
+		ValidationSubType: helpers.ResponseBodyResponseCode,
+		Message: fmt.Sprintf("%s %s returned status code '%d', which is not defined in the specification",
+			request.Method, specPath, code),
+		Reason: fmt.Sprintf("The operation defines no response for the status code '%d', or its range '%dXX'",
+			code, code/100),
+		HowToFix:      errors.HowToFixInvalidResponseCode,
+		RequestPath:   request.URL.Path,
+		RequestMethod: request.Method,
+		SpecPath:      specPath,
+		Context:       operation,
+	}
+	if low := operation.GoLow(); low != nil && low.Responses.KeyNode != nil {
+		ve.SpecLine = low.Responses.KeyNode.Line
+		ve.SpecCol = low.Responses.KeyNode.Column
+	}
+	return ve
+}
+
+// isResponseCodeError checks if the validator already reported the status code as undefined.
+func isResponseCodeError(ve *errors.ValidationError) bool {
+	return ve.ValidationType == helpers.ResponseBodyValidation && ve.ValidationSubType == helpers.ResponseBodyResponseCode
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var statusCodeSpec = []byte(`openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: ok
+        4XX:
+          description: client error
+        default:
+          description: anything else
+`)
+
+func TestCheckStatusCode(t *testing.T) {
+	doc, err := libopenapi.NewDocument(statusCodeSpec)
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/pets", nil)
+	assert.Nil(t, checkStatusCode(&m.Model, request, &http.Response{StatusCode: 200}))
+	assert.Nil(t, checkStatusCode(&m.Model, request, &http.Response{StatusCode: 404}))
+
+	ve := checkStatusCode(&m.Model, request, &http.Response{StatusCode: 503})
+	require.NotNil(t, ve)
+	assert.True(t, isResponseCodeError(ve))
+	assert.Equal(t, "/pets", ve.SpecPath)
+	assert.Contains(t, ve.Message, "'503'")
+
+	// requests without an operation are reported by the validator, not here.
+	request, _ = http.NewRequest(http.MethodPost, "http://localhost/pets", nil)
+	assert.Nil(t, checkStatusCode(&m.Model, request, &http.Response{StatusCode: 503}))
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// transactionShards stripes the lock the two halves of a transaction are merged under, so transactions with
+// different IDs are stored without waiting on each other. A transaction always maps to the same shard, by the low
+// bits of the hash of its ID. A nil set of shards is valid, every transaction then shares the service lock.
+type transactionShards struct {
+	locks []sync.Mutex
+	mask  uint64
+}
+
+// newTransactionShards returns nil for a single shard (not configured). The number of shards must be a power of 2.
+func newTransactionShards(shards int) *transactionShards {
+	if shards <= 1 {
+		return nil
+	}
+	return &transactionShards{locks: make([]sync.Mutex, shards), mask: uint64(shards - 1)}
+}
+
+// IsPowerOfTwo is true for 1, 2, 4, 8 and so on.
+func IsPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// shard returns the position of the shard of a transaction ID.
+func (ts *transactionShards) shard(id string) int {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return int(h.Sum64() & ts.mask)
+}
+
+// transactionLockFor returns the lock a transaction is stored under.
+func (ws *WiretapService) transactionLockFor(id string) *sync.Mutex {
+	if ws.transactionShards == nil {
+		return &ws.transactionLock
+	}
+	return &ws.transactionShards.locks[ws.transactionShards.shard(id)]
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pb33f/ranch/bus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionShards(t *testing.T) {
+	assert.Nil(t, newTransactionShards(0))
+	assert.Nil(t, newTransactionShards(1))
+	assert.True(t, IsPowerOfTwo(1))
+	assert.True(t, IsPowerOfTwo(16))
+	assert.False(t, IsPowerOfTwo(0))
+	assert.False(t, IsPowerOfTwo(12))
+
+	ws := &WiretapService{}
+	assert.Same(t, &ws.transactionLock, ws.transactionLockFor("one"))
+
+	ws.transactionShards = newTransactionShards(8)
+	assert.Same(t, ws.transactionLockFor("one"), ws.transactionLockFor("one"))
+	used := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		shard := ws.transactionShards.shard(fmt.Sprint(i))
+		assert.Less(t, shard, 8)
+		used[shard] = true
+	}
+	assert.Len(t, used, 8)
+}
+
+func TestStoreTransaction_Sharded(t *testing.T) {
+	ws := &WiretapService{
+		transactionStore:  bus.GetBus().GetStoreManager().CreateStore("sharded-transactions"),
+		transactionShards: newTransactionShards(4),
+	}
+	defer bus.GetBus().GetStoreManager().DestroyStore("sharded-transactions")
+
+	// both halves of every transaction are merged, whichever is stored first.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprint(i)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ws.storeTransaction(&HttpTransaction{Id: id, Request: &HttpRequest{Path: "/pets/" + id}})
+		}()
+		go func() {
+			defer wg.Done()
+			ws.storeTransaction(&HttpTransaction{Id: id, Response: &HttpResponse{StatusCode: 200}})
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 50; i++ {
+		transaction := ws.GetTransaction(fmt.Sprint(i))
+		if assert.NotNil(t, transaction) {
+			assert.Equal(t, "/pets/"+fmt.Sprint(i), transaction.Request.Path)
+			assert.Equal(t, 200, transaction.Response.StatusCode)
+		}
+	}
+}
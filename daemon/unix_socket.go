@@ -0,0 +1,46 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// ListenUnixSocket listens on a unix socket, creating its parent directory if needed. A socket left behind by a
+// previous run is replaced, a socket still in use or any other file is not. Only the owner may connect, unless group
+// access is allowed. The socket file is removed when the listener is closed.
+func ListenUnixSocket(path string, groupAccess bool) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("'%s' already exists and is not a socket", path)
+		}
+		if conn, dErr := net.Dial("unix", path); dErr == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("'%s' is already in use", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	mode := os.FileMode(0600)
+	if groupAccess {
+		mode = 0660
+	}
+	if err = os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
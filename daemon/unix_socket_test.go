@@ -0,0 +1,41 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "admin.sock")
+	ln, err := ListenUnixSocket(path, false)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// closing the listener removes the socket.
+	require.NoError(t, ln.Close())
+	assert.NoFileExists(t, path)
+
+	ln, err = ListenUnixSocket(path, true)
+	require.NoError(t, err)
+	defer ln.Close()
+	info, _ = os.Stat(path)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+}
+
+func TestListenUnixSocket_NotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	require.NoError(t, os.WriteFile(path, []byte("keep"), 0644))
+	_, err := ListenUnixSocket(path, false)
+	assert.ErrorContains(t, err, "is not a socket")
+	assert.FileExists(t, path)
+}
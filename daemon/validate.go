@@ -5,8 +5,11 @@ package daemon
 
 import (
 	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
 	"github.com/pb33f/ranch/model"
 	"net/http"
+	"slices"
+	"strings"
 )
 
 func (ws *WiretapService) ValidateResponse(
@@ -15,26 +18,49 @@ func (ws *WiretapService) ValidateResponse(
 
 	var validationErrors []*errors.ValidationError
 
-	if ws.document != nil && ws.docModel != nil {
-		_, validationErrors = ws.validator.ValidateHttpResponse(request.HttpRequest, returnedResponse)
+	spec := ws.currentSpec()
+	docModel := spec.docModel
+	if docModel != nil {
+		_, validationErrors = spec.validator.ValidateHttpResponse(request.HttpRequest, returnedResponse)
+		if schema := responseBodySchema(docModel, request.HttpRequest, returnedResponse); schema != nil &&
+			schema.Discriminator != nil {
+			validationErrors = ws.discriminate(docModel, schema, readBody(&returnedResponse.Body),
+				helpers.ResponseBodyValidation, request.HttpRequest, validationErrors)
+		}
 	}
 
 	// wipe out any path not found errors, they are not relevant to the response.
-	var cleanedErrors []*errors.ValidationError
+	scratch := acquireValidationErrors()
+	defer releaseValidationErrors(scratch)
 	for x := range validationErrors {
 		if !validationErrors[x].IsPathMissingError() {
-			cleanedErrors = append(cleanedErrors, validationErrors[x])
+			*scratch = append(*scratch, validationErrors[x])
+		}
+	}
+
+	// the validator accepts any status code when the operation has a default response, strict mode does not.
+	if ws.config.StrictStatusCodes && docModel != nil && !slices.ContainsFunc(*scratch, isResponseCodeError) {
+		if codeError := checkStatusCode(docModel, request.HttpRequest, returnedResponse); codeError != nil {
+			*scratch = append(*scratch, codeError)
+			validationErrors = append(validationErrors, codeError)
 		}
 	}
 
-	transaction := BuildResponse(request, returnedResponse)
+	// server-sent events are validated against the AsyncAPI specification.
+	if eventErrors := ws.validateEventStream(request.HttpRequest, returnedResponse); len(eventErrors) > 0 {
+		*scratch = append(*scratch, eventErrors...)
+		validationErrors = append(validationErrors, eventErrors...)
+	}
+	cleanedErrors := collectValidationErrors(*scratch)
+
+	transaction := BuildResponse(request, returnedResponse, ws.config)
 	if len(cleanedErrors) > 0 {
 		transaction.ResponseValidation = cleanedErrors
 	}
-	ws.transactionStore.Put(request.Id.String(), transaction, nil)
+	ws.storeTransaction(transaction)
 
 	if len(cleanedErrors) > 0 {
-		ws.streamChan <- cleanedErrors
+		ws.sendViolations(cleanedErrors)
 		ws.broadcastResponseValidationErrors(request, returnedResponse, cleanedErrors)
 	} else {
 		ws.broadcastResponse(request, returnedResponse)
@@ -46,16 +72,39 @@ func (ws *WiretapService) ValidateRequest(
 	modelRequest *model.Request,
 	httpRequest *http.Request) []*errors.ValidationError {
 
-	var validationErrors, cleanedErrors []*errors.ValidationError
+	scratch := acquireValidationErrors()
+	defer releaseValidationErrors(scratch)
 
-	if ws.document != nil && ws.docModel != nil {
-		validator := ws.validator
-		_, validationErrors = validator.ValidateHttpRequest(httpRequest)
+	spec := ws.currentSpec()
+	docModel := spec.docModel
+	if docModel != nil {
+		// encoding mismatches come first, they explain the errors the validator reports for the same parameters.
+		*scratch = append(*scratch, validateQueryEncoding(docModel, httpRequest)...)
+		*scratch = append(*scratch, validateCookies(docModel, httpRequest)...)
+		_, requestErrors := spec.validator.ValidateHttpRequest(httpRequest)
+		if schema := requestBodySchema(docModel, httpRequest); schema != nil && schema.Discriminator != nil {
+			requestErrors = ws.discriminate(docModel, schema, readBody(&httpRequest.Body),
+				helpers.RequestBodyValidation, httpRequest, requestErrors)
+		}
+		*scratch = append(*scratch, requestErrors...)
 	}
 
-	for _, validationError := range validationErrors {
-		cleanedErrors = append(cleanedErrors, validationError)
+	// the host is checked on the request wiretap received, the validated request is addressed to the redirect host.
+	// there is no host validator while the specification is loading.
+	var hostError *errors.ValidationError
+	if docModel != nil {
+		hostError = spec.hostValidator.validate(modelRequest.HttpRequest.Host)
+	}
+	if hostError != nil {
+		if spec.hostValidator.mode == HostValidationError {
+			*scratch = append(*scratch, hostError)
+		} else {
+			ws.config.Logger.Warn("[wiretap] "+hostError.Message, "url", modelRequest.HttpRequest.URL.String(),
+				"servers", strings.Join(spec.hostValidator.servers, ", "))
+		}
 	}
+	cleanedErrors := collectValidationErrors(*scratch)
+
 	// record results
 	buildTransConfig := HttpTransactionConfig{
 		OriginalRequest:   modelRequest.HttpRequest,
@@ -68,14 +117,64 @@ func (ws *WiretapService) ValidateRequest(
 	if len(cleanedErrors) > 0 {
 		transaction.RequestValidation = cleanedErrors
 	}
-	ws.transactionStore.Put(modelRequest.Id.String(), modelRequest, nil)
+	ws.storeTransaction(transaction)
 
 	// broadcast what we found.
 	if len(cleanedErrors) > 0 {
-		ws.streamChan <- cleanedErrors
+		ws.sendViolations(cleanedErrors)
 		ws.broadcastRequestValidationErrors(modelRequest, cleanedErrors, transaction)
 	} else {
 		ws.broadcastRequest(modelRequest, transaction)
 	}
 	return cleanedErrors
 }
+
+// storeTransaction records a transaction in the transaction store. Requests and responses are validated separately,
+// and possibly at the same time, so each half is merged with the half that may already be stored.
+func (ws *WiretapService) storeTransaction(transaction *HttpTransaction) {
+	lock := ws.transactionLockFor(transaction.Id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	stored := *transaction
+	if existing, ok := ws.transactionStore.Get(transaction.Id); ok {
+		if previous, ok := existing.(*HttpTransaction); ok {
+			if stored.Request == nil {
+				stored.Request = previous.Request
+				stored.RequestValidation = previous.RequestValidation
+				stored.RequestBodyHash = previous.RequestBodyHash
+			}
+			if stored.Response == nil {
+				stored.Response = previous.Response
+				stored.ResponseValidation = previous.ResponseValidation
+				stored.ResponseBodyHash = previous.ResponseBodyHash
+			}
+		}
+	}
+	ws.transactionStore.Put(transaction.Id, &stored, nil)
+	if ws.redisTransactions != nil {
+		if err := ws.redisTransactions.put(&stored); err != nil {
+			ws.config.Logger.Warn("[wiretap] unable to store transaction in redis", "id", stored.Id,
+				"error", err.Error())
+		}
+	}
+}
+
+// GetTransaction returns a captured transaction by ID, or nil if no transaction with the ID has been captured. With
+// a Redis transaction store, transactions captured by any instance are returned.
+func (ws *WiretapService) GetTransaction(id string) *HttpTransaction {
+	if ws.redisTransactions != nil {
+		transaction, err := ws.redisTransactions.get(id)
+		if err == nil {
+			return transaction
+		}
+		ws.config.Logger.Warn("[wiretap] unable to read transaction from redis, using local transactions",
+			"id", id, "error", err.Error())
+	}
+	if existing, ok := ws.transactionStore.Get(id); ok {
+		if transaction, ok := existing.(*HttpTransaction); ok {
+			return transaction
+		}
+	}
+	return nil
+}
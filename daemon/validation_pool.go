@@ -0,0 +1,50 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"sync"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+)
+
+// maxPooledValidationErrors is the largest capacity returned to the pool, a rare request with a huge number of
+// violations should not pin its slice in memory.
+const maxPooledValidationErrors = 256
+
+// validationErrorPool holds the scratch slices validation errors are collected in. Collected errors are copied
+// into an exactly sized slice before they are stored, streamed or broadcast, so the scratch slice never escapes
+// and can be reused by the next validation.
+var validationErrorPool = sync.Pool{
+	New: func() any {
+		s := make([]*errors.ValidationError, 0, 16)
+		return &s
+	},
+}
+
+// acquireValidationErrors returns an empty scratch slice from the pool.
+func acquireValidationErrors() *[]*errors.ValidationError {
+	return validationErrorPool.Get().(*[]*errors.ValidationError)
+}
+
+// releaseValidationErrors empties a scratch slice and returns it to the pool. The errors it points to are
+// cleared, so the pool does not keep them alive.
+func releaseValidationErrors(s *[]*errors.ValidationError) {
+	if cap(*s) > maxPooledValidationErrors {
+		return
+	}
+	clear(*s)
+	*s = (*s)[:0]
+	validationErrorPool.Put(s)
+}
+
+// collectValidationErrors copies the errors of a scratch slice, returning nil when there are none.
+func collectValidationErrors(s []*errors.ValidationError) []*errors.ValidationError {
+	if len(s) == 0 {
+		return nil
+	}
+	collected := make([]*errors.ValidationError, len(s))
+	copy(collected, s)
+	return collected
+}
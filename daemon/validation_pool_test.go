@@ -0,0 +1,33 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationErrorPool(t *testing.T) {
+	scratch := acquireValidationErrors()
+	assert.Empty(t, *scratch)
+	assert.Nil(t, collectValidationErrors(*scratch))
+
+	one, two := &errors.ValidationError{Message: "one"}, &errors.ValidationError{Message: "two"}
+	*scratch = append(*scratch, one, two)
+	collected := collectValidationErrors(*scratch)
+	assert.Equal(t, []*errors.ValidationError{one, two}, collected)
+	assert.Equal(t, 2, cap(collected))
+
+	// released slices are emptied, and do not keep the errors alive.
+	backing := (*scratch)[:2]
+	releaseValidationErrors(scratch)
+	assert.Empty(t, *scratch)
+	assert.Nil(t, backing[0])
+	assert.Nil(t, backing[1])
+
+	// the collected copy is not touched by the release.
+	assert.Same(t, one, collected[0])
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	crand "crypto/rand"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ValidationSamplingStats reports how many requests were validated when validation sampling is enabled.
+type ValidationSamplingStats struct {
+	Rate          float64            `json:"rate"`                // the global sampling rate.
+	Overrides     map[string]float64 `json:"overrides,omitempty"` // sampling rates by path prefix.
+	Sampled       int64              `json:"sampled"`             // requests that were validated.
+	Skipped       int64              `json:"skipped"`             // requests that were proxied without validation.
+	EffectiveRate float64            `json:"effectiveRate"`       // the fraction of requests that were actually validated.
+}
+
+// samplingOverride is the sampling rate for requests with a path starting with the prefix.
+type samplingOverride struct {
+	prefix string
+	rate   float64
+}
+
+// validationSampler decides which requests are validated. Each goroutine borrows its own PRNG from a pool,
+// so sampling does not contend on a shared source. Every PRNG is seeded from crypto/rand.
+type validationSampler struct {
+	rate      float64
+	overrides []samplingOverride
+	sampled   atomic.Int64
+	skipped   atomic.Int64
+	sources   sync.Pool
+}
+
+// newValidationSampler returns nil when every request is validated: there are no path overrides and the global
+// rate is 0 (not configured) or 1 or more. Overrides are checked longest prefix first.
+func newValidationSampler(rate float64, overrides map[string]float64) *validationSampler {
+	if len(overrides) == 0 && (rate <= 0 || rate >= 1) {
+		return nil
+	}
+	if rate <= 0 {
+		rate = 1
+	}
+	vs := &validationSampler{
+		rate: rate,
+		sources: sync.Pool{
+			New: func() any {
+				var seed [32]byte
+				_, _ = crand.Read(seed[:])
+				return rand.New(rand.NewChaCha8(seed))
+			},
+		},
+	}
+	for prefix, r := range overrides {
+		vs.overrides = append(vs.overrides, samplingOverride{prefix: prefix, rate: r})
+	}
+	sort.Slice(vs.overrides, func(i, j int) bool {
+		return len(vs.overrides[i].prefix) > len(vs.overrides[j].prefix)
+	})
+	return vs
+}
+
+// rateFor returns the sampling rate of the longest override matching the path, or the global rate.
+func (vs *validationSampler) rateFor(path string) float64 {
+	for _, o := range vs.overrides {
+		if strings.HasPrefix(path, o.prefix) {
+			return o.rate
+		}
+	}
+	return vs.rate
+}
+
+// sample reports if a request on the path should be validated.
+func (vs *validationSampler) sample(path string) bool {
+	if vs == nil {
+		return true
+	}
+	var sampled bool
+	switch rate := vs.rateFor(path); {
+	case rate >= 1:
+		sampled = true
+	case rate <= 0:
+		sampled = false
+	default:
+		r := vs.sources.Get().(*rand.Rand)
+		sampled = r.Float64() < rate
+		vs.sources.Put(r)
+	}
+
+	if sampled {
+		vs.sampled.Add(1)
+	} else {
+		vs.skipped.Add(1)
+	}
+	return sampled
+}
+
+func (vs *validationSampler) stats() *ValidationSamplingStats {
+	if vs == nil {
+		return nil
+	}
+	stats := &ValidationSamplingStats{
+		Rate:    vs.rate,
+		Sampled: vs.sampled.Load(),
+		Skipped: vs.skipped.Load(),
+	}
+	if len(vs.overrides) > 0 {
+		stats.Overrides = make(map[string]float64, len(vs.overrides))
+		for _, o := range vs.overrides {
+			stats.Overrides[o.prefix] = o.rate
+		}
+	}
+	if total := stats.Sampled + stats.Skipped; total > 0 {
+		stats.EffectiveRate = float64(stats.Sampled) / float64(total)
+	}
+	return stats
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationSampler(t *testing.T) {
+	assert.Nil(t, newValidationSampler(0, nil))
+	assert.Nil(t, newValidationSampler(1, nil))
+
+	// without a sampler, every request is validated.
+	var none *validationSampler
+	assert.True(t, none.sample("/pets"))
+	assert.Nil(t, none.stats())
+
+	vs := newValidationSampler(0.25, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				vs.sample("/pets")
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := vs.stats()
+	assert.Equal(t, 0.25, stats.Rate)
+	assert.Equal(t, int64(8000), stats.Sampled+stats.Skipped)
+	assert.InDelta(t, 0.25, stats.EffectiveRate, 0.05)
+}
+
+func TestValidationSampler_Overrides(t *testing.T) {
+	vs := newValidationSampler(0, map[string]float64{
+		"/payments/":        1,
+		"/payments/refunds": 0.5,
+		"/health":           0,
+	})
+	assert.NotNil(t, vs)
+	assert.Equal(t, 1.0, vs.rateFor("/pets"))
+	assert.Equal(t, 1.0, vs.rateFor("/payments/123"))
+	assert.Equal(t, 0.5, vs.rateFor("/payments/refunds/1"))
+	assert.Equal(t, 0.0, vs.rateFor("/health"))
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, vs.sample("/payments/123"))
+		assert.False(t, vs.sample("/health"))
+	}
+	stats := vs.stats()
+	assert.Equal(t, int64(100), stats.Sampled)
+	assert.Equal(t, int64(100), stats.Skipped)
+	assert.Len(t, stats.Overrides, 3)
+}
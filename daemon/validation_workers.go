@@ -0,0 +1,85 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"github.com/pb33f/libopenapi-validator/errors"
+)
+
+// validationQueueSize is the number of validations queued per worker, before submitting a validation waits.
+const validationQueueSize = 64
+
+// validationJob is a validation queued for a worker. The result is sent to the result channel when the validation
+// is synchronous, or passed to done when it is not.
+type validationJob struct {
+	validation func() []*errors.ValidationError
+	result     chan []*errors.ValidationError
+	done       func([]*errors.ValidationError)
+}
+
+// validationWorkers runs validations on a fixed number of worker goroutines, so a burst of traffic queues
+// validations instead of starting a goroutine for each of them. A nil pool is valid, validations then run on their
+// own goroutines as they always have.
+type validationWorkers struct {
+	jobs chan validationJob
+}
+
+// newValidationWorkers starts the workers, it returns nil when no workers are configured.
+func newValidationWorkers(workers int) *validationWorkers {
+	if workers <= 0 {
+		return nil
+	}
+	vw := &validationWorkers{jobs: make(chan validationJob, workers*validationQueueSize)}
+	for i := 0; i < workers; i++ {
+		go vw.work()
+	}
+	return vw
+}
+
+func (vw *validationWorkers) work() {
+	for job := range vw.jobs {
+		validationErrors := job.validation()
+		if job.result != nil {
+			job.result <- validationErrors
+		} else if job.done != nil {
+			job.done(validationErrors)
+		}
+	}
+}
+
+// run queues a validation and waits for its result.
+func (vw *validationWorkers) run(validation func() []*errors.ValidationError) []*errors.ValidationError {
+	if vw == nil {
+		return validation()
+	}
+	result := make(chan []*errors.ValidationError, 1)
+	vw.jobs <- validationJob{validation: validation, result: result}
+	return <-result
+}
+
+// submit queues a validation, done is called with its result once it has run.
+func (vw *validationWorkers) submit(validation func() []*errors.ValidationError, done func([]*errors.ValidationError)) {
+	if vw == nil {
+		go func() {
+			done(validation())
+		}()
+		return
+	}
+	vw.jobs <- validationJob{validation: validation, done: done}
+}
+
+// validate runs a validation, on a validation worker if they are configured, and records the result for the audit log.
+func (ws *WiretapService) validate(audit *auditTracker,
+	validation func() []*errors.ValidationError) []*errors.ValidationError {
+	return audit.validate(func() []*errors.ValidationError {
+		return ws.validationWorkers.run(validation)
+	})
+}
+
+// validateAsync runs a validation in the background, on a validation worker if they are configured, and records the
+// result for the audit log.
+func (ws *WiretapService) validateAsync(audit *auditTracker, validation func() []*errors.ValidationError) {
+	audit.begin()
+	ws.validationWorkers.submit(validation, audit.end)
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationWorkers(t *testing.T) {
+	assert.Nil(t, newValidationWorkers(0))
+
+	vw := newValidationWorkers(2)
+	var running, peak atomic.Int32
+	validation := func() []*errors.ValidationError {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return []*errors.ValidationError{{Message: "nope"}}
+	}
+
+	var wg sync.WaitGroup
+	var async atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Len(t, vw.run(validation), 1)
+		}()
+		vw.submit(validation, func(validationErrors []*errors.ValidationError) {
+			async.Add(int32(len(validationErrors)))
+			wg.Done()
+		})
+	}
+	wg.Wait()
+
+	// no more validations run at the same time than there are workers.
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+	assert.Equal(t, int32(10), async.Load())
+}
+
+func TestWiretapService_ValidateAsync_Audit(t *testing.T) {
+	ws := &WiretapService{validationWorkers: newValidationWorkers(1)}
+	audit := &auditTracker{}
+	ws.validateAsync(audit, func() []*errors.ValidationError {
+		return []*errors.ValidationError{{Message: "nope"}}
+	})
+	audit.wg.Wait()
+	assert.True(t, audit.validated.Load())
+	assert.True(t, audit.failed.Load())
+
+	// without an audit tracker or a pool, validations still run.
+	done := make(chan bool)
+	(&WiretapService{}).validateAsync(nil, func() []*errors.ValidationError {
+		close(done)
+		return nil
+	})
+	<-done
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// internalHostSuffixes are the domains of hosts that are only reachable from inside a network.
+var internalHostSuffixes = []string{".local", ".internal", ".localdomain", ".lan", ".localhost"}
+
+// viaEnabled is true unless Via handling is turned off, it is on by default.
+func viaEnabled(config *shared.WiretapConfiguration) bool {
+	return config.InjectViaHeader == nil || *config.InjectViaHeader
+}
+
+// viaEntry identifies wiretap as a hop, with the version it was built with.
+func viaEntry(config *shared.WiretapConfiguration) string {
+	if config.Version == "" {
+		return "1.1 wiretap"
+	}
+	return "1.1 wiretap/" + config.Version
+}
+
+// injectVia appends wiretap to the Via header of a request forwarded upstream, after the hops the request came
+// through.
+func (ws *WiretapService) injectVia(original, upstream *http.Request) {
+	if !viaEnabled(ws.config) {
+		return
+	}
+	upstream.Header.Set("Via", strings.Join(append(original.Header.Values("Via"), viaEntry(ws.config)), ", "))
+}
+
+// stripInternalVia removes the hops of a response Via header that name internal hosts, so the network behind the
+// upstream is not revealed to the client. The header is dropped when every hop is internal.
+func stripInternalVia(config *shared.WiretapConfiguration, headers map[string][]string) {
+	values, ok := headers["Via"]
+	if !ok || !viaEnabled(config) {
+		return
+	}
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" && !internalHop(config, hop) {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		delete(headers, "Via")
+		return
+	}
+	headers["Via"] = []string{strings.Join(hops, ", ")}
+}
+
+// internalHop is true when the received-by part of a Via hop is a private, loopback or link-local address, an
+// internal host name, or the upstream host itself. Pseudonyms are not hosts, and are kept.
+func internalHop(config *shared.WiretapConfiguration, hop string) bool {
+	fields := strings.Fields(hop)
+	if len(fields) < 2 {
+		return false
+	}
+	receivedBy := fields[1]
+	if host, _, err := net.SplitHostPort(receivedBy); err == nil {
+		receivedBy = host
+	}
+	receivedBy = strings.ToLower(strings.Trim(receivedBy, "[]"))
+	if ip := net.ParseIP(receivedBy); ip != nil {
+		return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+	}
+	if receivedBy == "localhost" || (config.RedirectHost != "" && strings.EqualFold(receivedBy, config.RedirectHost)) {
+		return true
+	}
+	for _, suffix := range internalHostSuffixes {
+		if strings.HasSuffix(receivedBy, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWiretapService_InjectVia(t *testing.T) {
+	ws := &WiretapService{config: &shared.WiretapConfiguration{Version: "v0.3.0"}}
+	original := httptest.NewRequest(http.MethodGet, "/pets", nil)
+	upstream := httptest.NewRequest(http.MethodGet, "/pets", nil)
+
+	ws.injectVia(original, upstream)
+	assert.Equal(t, "1.1 wiretap/v0.3.0", upstream.Header.Get("Via"))
+
+	// hops the request came through are kept.
+	original.Header.Add("Via", "1.0 fred")
+	original.Header.Add("Via", "1.1 p.example.net")
+	ws.injectVia(original, upstream)
+	assert.Equal(t, "1.0 fred, 1.1 p.example.net, 1.1 wiretap/v0.3.0", upstream.Header.Get("Via"))
+
+	disabled := false
+	ws.config.InjectViaHeader = &disabled
+	upstream.Header.Del("Via")
+	ws.injectVia(original, upstream)
+	assert.Empty(t, upstream.Header.Get("Via"))
+}
+
+func TestStripInternalVia(t *testing.T) {
+	config := &shared.WiretapConfiguration{RedirectHost: "api.pb33f.io"}
+	headers := map[string][]string{"Via": {
+		"1.1 10.0.0.12:8080, 1.1 backend.svc.cluster.local", "1.1 cdn.example.com (cache), 2 envoy, 1.1 api.pb33f.io",
+		"1.1 [::1]:9090",
+	}}
+	stripInternalVia(config, headers)
+	assert.Equal(t, []string{"1.1 cdn.example.com (cache), 2 envoy"}, headers["Via"])
+
+	headers = map[string][]string{"Via": {"1.1 localhost"}}
+	stripInternalVia(config, headers)
+	assert.NotContains(t, headers, "Via")
+
+	// responses pass through untouched when Via handling is off.
+	disabled := false
+	config.InjectViaHeader = &disabled
+	headers = map[string][]string{"Via": {"1.1 localhost"}}
+	stripInternalVia(config, headers)
+	assert.Equal(t, []string{"1.1 localhost"}, headers["Via"])
+}
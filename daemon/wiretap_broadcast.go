@@ -27,6 +27,7 @@ func (ws *WiretapService) broadcastRequestValidationErrors(request *model.Reques
 		Payload:       ht,
 		Direction:     model.ResponseDir,
 	})
+	ws.publishValidationErrors("request", request, nil, errors)
 }
 
 func (ws *WiretapService) broadcastRequest(request *model.Request, transaction *HttpTransaction) {
@@ -48,7 +49,7 @@ func (ws *WiretapService) broadcastResponse(request *model.Request, response *ht
 		DestinationId: request.Id,
 		Channel:       WiretapBroadcastChan,
 		Destination:   WiretapBroadcastChan,
-		Payload:       BuildResponse(request, response),
+		Payload:       BuildResponse(request, response, ws.config),
 		Direction:     model.ResponseDir,
 	})
 }
@@ -68,8 +69,9 @@ func (ws *WiretapService) broadcastResponseError(request *model.Request, respons
 		Detail: err.Error(),
 	})
 
-	resp := BuildResponse(request, response)
+	resp := BuildResponse(request, response, ws.config)
 	resp.Response.Body = string(respBodyString)
+	resp.ResponseBodyHash = HashBody(resp.Response.Body)
 
 	ws.broadcastChan.Send(&model.Message{
 		Id:            &id,
@@ -85,7 +87,7 @@ func (ws *WiretapService) broadcastResponseError(request *model.Request, respons
 func (ws *WiretapService) broadcastResponseValidationErrors(request *model.Request, response *http.Response, errors []*errors.ValidationError) {
 	id, _ := uuid.NewUUID()
 
-	ht := BuildResponse(request, response)
+	ht := BuildResponse(request, response, ws.config)
 	ht.ResponseValidation = errors
 
 	ws.broadcastChan.Send(&model.Message{
@@ -96,4 +98,5 @@ func (ws *WiretapService) broadcastResponseValidationErrors(request *model.Reque
 		Payload:       ht,
 		Direction:     model.ResponseDir,
 	})
+	ws.publishValidationErrors("response", request, response, errors)
 }
@@ -5,6 +5,8 @@ package daemon
 
 import (
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pb33f/libopenapi"
@@ -27,23 +29,43 @@ const (
 )
 
 type WiretapService struct {
-	transport        *http.Transport
-	document         libopenapi.Document
-	docModel         *v3.Document
-	serviceCore      service.FabricServiceCore
-	broadcastChan    *bus.Channel
-	bus              bus.EventBus
-	controlsStore    bus.BusStore
-	transactionStore bus.BusStore
-	config           *shared.WiretapConfiguration
-	fs               http.Handler
-	mockEngine       *mock.ResponseMockEngine
-	validator        validation.HttpValidator
-	stream           bool
-	streamChan       chan []*errors.ValidationError
-	streamViolations []*errors.ValidationError
-	reportFile       string
-	StaticMockDir    string
+	transport         *http.Transport
+	spec              atomic.Pointer[loadedSpec]
+	serviceCore       service.FabricServiceCore
+	broadcastChan     *bus.Channel
+	bus               bus.EventBus
+	controlsStore     bus.BusStore
+	transactionStore  bus.BusStore
+	transactionLock   sync.Mutex
+	transactionShards *transactionShards
+	redisTransactions *redisTransactionStore
+	specLoading       atomic.Bool
+	proxyServer       atomic.Pointer[http.Server]
+	adminServer       atomic.Pointer[http.Server]
+	validationWorkers *validationWorkers
+	config            *shared.WiretapConfiguration
+	fs                http.Handler
+	stream            bool
+	streamChan        chan []*errors.ValidationError
+	streamDropped     atomic.Int64
+	streamViolations  []*errors.ValidationError
+	streamCounts      map[string]int
+	streamSkipped     int64
+	streamLock        sync.RWMutex
+	streamDone        chan struct{}
+	streamStopped     chan struct{}
+	auditChan         chan *AuditRecord
+	failoverStats     failoverStats
+	responseCache     *responseCache
+	sampler           *validationSampler
+	coverage          coverageTracker
+	notifiers         notifiers
+	natsPublisher     *natsPublisher
+	errorRate         *errorRateTracker
+	connections       *connectionLimiter
+	reportFile        string
+	reportFormat      string
+	StaticMockDir     string
 }
 
 func NewWiretapService(document libopenapi.Document, config *shared.WiretapConfiguration) *WiretapService {
@@ -57,36 +79,110 @@ func NewWiretapService(document libopenapi.Document, config *shared.WiretapConfi
 	}
 
 	wts := &WiretapService{
-		stream:           config.StreamReport,
-		reportFile:       config.ReportFile,
-		streamChan:       make(chan []*errors.ValidationError),
-		transport:        tr,
-		controlsStore:    controlsStore,
-		transactionStore: transactionStore,
-		StaticMockDir:    config.StaticMockDir,
+		stream:            config.StreamReport,
+		reportFile:        config.ReportFile,
+		reportFormat:      config.ReportFormat,
+		streamChan:        make(chan []*errors.ValidationError, max(config.StreamChannelBufferSize, 0)),
+		transport:         tr,
+		controlsStore:     controlsStore,
+		transactionStore:  transactionStore,
+		transactionShards: newTransactionShards(config.TransactionStoreShards),
+		redisTransactions: newRedisTransactionStore(config.RedisTransactionStore),
+		StaticMockDir:     config.StaticMockDir,
+		responseCache:     newResponseCache(config.Cache),
+		sampler:           newValidationSampler(config.ValidationSamplingRate, config.ValidationSamplingOverrides),
+		errorRate:         newErrorRateTracker(config.ErrorRateWindow, config.ErrorRateThreshold),
+		connections:       newConnectionLimiter(config.MaxConcurrentConnections),
+		validationWorkers: newValidationWorkers(config.ValidationWorkers),
 	}
+	wts.spec.Store(buildSpec(document, config))
+
+	// the specification is loaded in the background, requests are not validated until it has loaded.
+	if document == nil && config.LazySpecLoading && config.Contract != "" {
+		wts.specLoading.Store(true)
+	}
+
+	// hard-wire the config, change this later if needed.
+	wts.config = config
+
+	// post validation errors to webhooks, if configured
+	wts.startNotifications()
+
+	// listen for violations
+	wts.listenForValidationErrors()
+
+	// write audit records, if enabled
+	wts.startAuditLog()
+
+	// publish validation errors to NATS, if configured
+	wts.startNATSPublisher()
+	return wts
+
+}
+
+// loadedSpec is the specification requests are validated and mocked against, with the model, validators and mock
+// engine built from it. A specification loaded in the background replaces the one wiretap started with, it is only
+// published once it has been built, so every request sees one specification or the other.
+type loadedSpec struct {
+	document      libopenapi.Document
+	docModel      *v3.Document
+	validator     validation.HttpValidator
+	hostValidator *hostValidator
+	mockEngine    *mock.ResponseMockEngine
+}
+
+// buildSpec builds the model, validators and mock engine of a specification. A nil document leaves them
+// without a specification.
+func buildSpec(document libopenapi.Document, config *shared.WiretapConfiguration) *loadedSpec {
+	spec := &loadedSpec{document: document}
 	if document != nil {
 		m, _ := document.BuildV3Model()
-		docModel := &m.Model
-		wts.document = document
-		wts.docModel = docModel
+		spec.docModel = &m.Model
 
 		// create a new validator
-		wts.validator = validation.NewHttpValidator(docModel)
+		spec.validator = validation.NewHttpValidator(spec.docModel)
+		spec.hostValidator = newHostValidator(config.HostValidation, spec.docModel)
 	}
 
 	// create a new mock engine
-	wts.mockEngine = mock.NewMockEngine(wts.docModel, config.MockModePretty,
+	spec.mockEngine = mock.NewMockEngine(spec.docModel, config.MockModePretty,
 		config.UseAllMockResponseFields)
+	spec.mockEngine.SetFakerEnabled(config.FakerEnabled)
+	return spec
+}
 
-	// hard-wire the config, change this later if needed.
-	wts.config = config
+// SetDocument enables validation against a specification loaded in the background. A nil document (the
+// specification failed to load) stops waiting for one, requests carry on without validation.
+func (ws *WiretapService) SetDocument(document libopenapi.Document) {
+	if !ws.specLoading.Load() {
+		return
+	}
+	ws.spec.Store(buildSpec(document, ws.config))
+	ws.specLoading.Store(false)
+}
 
-	// listen for violations
-	wts.listenForValidationErrors()
+// SpecLoading is true while the specification is loading in the background.
+func (ws *WiretapService) SpecLoading() bool {
+	return ws.specLoading.Load()
+}
 
-	return wts
+// DocModel returns the model of the loaded specification, nil when there is none or it is still loading.
+func (ws *WiretapService) DocModel() *v3.Document {
+	return ws.loadedDocModel()
+}
+
+// currentSpec returns the specification requests are handled with. A request reads it once, and uses it throughout,
+// rather than reading the specification again while it may be replaced.
+func (ws *WiretapService) currentSpec() *loadedSpec {
+	if spec := ws.spec.Load(); spec != nil {
+		return spec
+	}
+	return &loadedSpec{}
+}
 
+// loadedDocModel returns the model of the specification, nil when there is none or it is still loading.
+func (ws *WiretapService) loadedDocModel() *v3.Document {
+	return ws.currentSpec().docModel
 }
 
 func (ws *WiretapService) HandleServiceRequest(request *model.Request, core service.FabricServiceCore) {
@@ -109,3 +205,35 @@ func (ws *WiretapService) HandleStaticMockResponse(request *model.Request, respo
 func (ws *WiretapService) HandleWebsocketRequest(request *model.Request) {
 	ws.handleWebsocketRequest(request)
 }
+
+// OnServerShutdown waits for in-flight proxy and admin requests, closes the violation report, writes the configured
+// reports, closes the Redis connections and publishes the queued NATS events, when wiretap is stopped.
+func (ws *WiretapService) OnServerShutdown() {
+	if ws.config == nil {
+		return
+	}
+	ws.shutdownServers()
+	ws.stopStream()
+	if ws.config.JUnitReport != "" {
+		if err := ws.WriteJUnitReport(ws.config.JUnitReport); err != nil {
+			ws.config.Logger.Error("[wiretap] unable to write JUnit report", "file", ws.config.JUnitReport,
+				"error", err.Error())
+		} else {
+			ws.config.Logger.Info("[wiretap] JUnit report written", "file", ws.config.JUnitReport)
+		}
+	}
+	if ws.config.HTMLReport != "" {
+		if err := ws.WriteHTMLReport(ws.config.HTMLReport); err != nil {
+			ws.config.Logger.Error("[wiretap] unable to write HTML report", "file", ws.config.HTMLReport,
+				"error", err.Error())
+		} else {
+			ws.config.Logger.Info("[wiretap] HTML report written", "file", ws.config.HTMLReport)
+		}
+	}
+	if ws.redisTransactions != nil {
+		ws.redisTransactions.close()
+	}
+	if ws.natsPublisher != nil {
+		ws.natsPublisher.stop()
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWiretapService_SetDocument(t *testing.T) {
+	ws := &WiretapService{config: &shared.WiretapConfiguration{}}
+	ws.specLoading.Store(true)
+	assert.True(t, ws.SpecLoading())
+	assert.Nil(t, ws.loadedDocModel())
+	assert.Equal(t, 0, ws.Coverage().Total)
+
+	doc, err := libopenapi.NewDocument(coverageSpec)
+	require.NoError(t, err)
+	ws.SetDocument(doc)
+	assert.False(t, ws.SpecLoading())
+	require.NotNil(t, ws.loadedDocModel())
+	assert.Equal(t, doc, ws.currentSpec().document)
+	assert.NotNil(t, ws.currentSpec().validator)
+	assert.Equal(t, 3, ws.Coverage().Total)
+
+	// a specification that is not loading in the background is never replaced.
+	ws.SetDocument(nil)
+	assert.NotNil(t, ws.loadedDocModel())
+}
+
+func TestWiretapService_SetDocument_Failed(t *testing.T) {
+	ws := &WiretapService{config: &shared.WiretapConfiguration{}}
+	ws.specLoading.Store(true)
+
+	// the specification failed to load, requests carry on without one.
+	ws.SetDocument(nil)
+	assert.False(t, ws.SpecLoading())
+	assert.Nil(t, ws.loadedDocModel())
+	ws.coverage.record(ws.loadedDocModel(), httptest.NewRequest(http.MethodGet, "/pets", nil))
+}
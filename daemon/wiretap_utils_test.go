@@ -4,9 +4,14 @@
 package daemon
 
 import (
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/google/uuid"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/shared"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,3 +46,44 @@ func TestReconstructURL(t *testing.T) {
 	r, _ = http.NewRequest("GET", "http://localhost:1337?doctor=who", nil)
 	assert.Equal(t, "http://localhost?doctor=who", ReconstructURL(r, protocol, host, "", ""))
 }
+
+func TestHashBody(t *testing.T) {
+	assert.Equal(t, "", HashBody(""))
+	assert.Equal(t, "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c", HashBody("foo\n"))
+}
+
+func TestTruncateBody(t *testing.T) {
+	body, truncated := TruncateBody("hello", 0)
+	assert.Equal(t, "hello", body)
+	assert.False(t, truncated)
+
+	body, truncated = TruncateBody("hello", 5)
+	assert.Equal(t, "hello", body)
+	assert.False(t, truncated)
+
+	body, truncated = TruncateBody("hello", 3)
+	assert.Equal(t, "hel", body)
+	assert.True(t, truncated)
+
+	// a multibyte character is never split.
+	body, truncated = TruncateBody("hé", 2)
+	assert.Equal(t, "h", body)
+	assert.True(t, truncated)
+}
+
+func TestBuildResponse_MaxBodyBytesInReport(t *testing.T) {
+	id, _ := uuid.NewUUID()
+	response := &http.Response{StatusCode: 200, Header: http.Header{},
+		Body: io.NopCloser(strings.NewReader(`{"pets":["one","two","three"]}`))}
+
+	transaction := BuildResponse(&model.Request{Id: &id}, response,
+		&shared.WiretapConfiguration{MaxBodyBytesInReport: 8})
+	assert.Equal(t, `{"pets":`, transaction.Response.Body)
+	assert.Equal(t, 30, transaction.Response.BodyLength)
+	assert.True(t, transaction.Response.BodyTruncated)
+	assert.Equal(t, HashBody(`{"pets":["one","two","three"]}`), transaction.ResponseBodyHash)
+
+	// the response itself keeps the whole body.
+	b, _ := io.ReadAll(response.Body)
+	assert.Len(t, b, 30)
+}
@@ -0,0 +1,223 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package har
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pb33f/harhar"
+	"github.com/pb33f/libopenapi-validator/errors"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/validation"
+)
+
+const (
+	ContractErrorRequest  = "request"
+	ContractErrorResponse = "response"
+)
+
+// ContractTestError is a single validation error, reduced to the parts that can be compared between two
+// specifications (line numbers in each specification will differ).
+type ContractTestError struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	Reason   string `json:"reason,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+func (e *ContractTestError) key() string {
+	return e.Kind + "|" + e.Message + "|" + e.Reason + "|" + e.Location
+}
+
+func (e *ContractTestError) String() string {
+	s := fmt.Sprintf("%s: %s", e.Kind, e.Message)
+	if e.Reason != "" && e.Reason != e.Message {
+		s += ": " + e.Reason
+	}
+	if e.Location != "" {
+		s += " (" + e.Location + ")"
+	}
+	return s
+}
+
+// ContractTestResult compares the validation of a single recorded request and response against both specifications.
+type ContractTestResult struct {
+	Method      string               `json:"method"`
+	Path        string               `json:"path"`
+	Regressions []*ContractTestError `json:"regressions,omitempty"`
+	Fixed       []*ContractTestError `json:"fixed,omitempty"`
+	Unchanged   int                  `json:"unchanged"`
+}
+
+// ContractTestReport is the outcome of validating a recorded session against a baseline and a candidate
+// specification. Regressions are errors only reported by the candidate, fixed errors are only reported by the baseline.
+type ContractTestReport struct {
+	BaselineSpec  string                `json:"baselineSpec"`
+	CandidateSpec string                `json:"candidateSpec"`
+	Entries       int                   `json:"entries"`
+	Regressions   int                   `json:"regressions"`
+	Fixed         int                   `json:"fixed"`
+	Results       []*ContractTestResult `json:"results"`
+}
+
+// RunContractTest validates every entry of a HAR file against the baseline and candidate specifications, and
+// reports the differences in validation results. When a path allow list is configured, only matching entries
+// are validated, with the allowed prefix removed (the same as HAR validation).
+func RunContractTest(har *harhar.HAR, baseline, candidate *v3.Document,
+	config *shared.WiretapConfiguration) (*ContractTestReport, error) {
+
+	report := &ContractTestReport{Results: []*ContractTestResult{}}
+	if config.ContractTest != nil {
+		report.BaselineSpec = config.ContractTest.BaselineSpec
+		report.CandidateSpec = config.ContractTest.CandidateSpec
+	}
+
+	baselineValidator := validation.NewHttpValidator(baseline)
+	candidateValidator := validation.NewHttpValidator(candidate)
+
+	for _, entry := range har.Log.Entries {
+		if entry.Request.Method == "" {
+			continue
+		}
+		baselineErrors, path, ok, err := validateEntry(baselineValidator, entry, config.HARPathAllowList)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		candidateErrors, _, _, err := validateEntry(candidateValidator, entry, config.HARPathAllowList)
+		if err != nil {
+			return nil, err
+		}
+
+		result := compareContractErrors(baselineErrors, candidateErrors)
+		result.Method = entry.Request.Method
+		result.Path = path
+
+		report.Entries++
+		report.Regressions += len(result.Regressions)
+		report.Fixed += len(result.Fixed)
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// validateEntry validates the request and response of a HAR entry. The entry is converted for every validation,
+// as the converted request body can only be read once.
+func validateEntry(validator validation.HttpValidator, entry harhar.Entry,
+	allowList []string) ([]*ContractTestError, string, bool, error) {
+
+	httpRequest, err := harhar.ConvertRequestIntoHttpRequest(entry.Request)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("error converting request: %w", err)
+	}
+	if len(allowList) > 0 {
+		allowed := false
+		for _, allow := range allowList {
+			if strings.HasPrefix(httpRequest.URL.Path, allow) {
+				httpRequest.URL.Path = strings.Replace(httpRequest.URL.Path, allow, "", 1)
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, "", false, nil
+		}
+	}
+
+	var contractErrors []*ContractTestError
+	_, requestErrors := validator.ValidateHttpRequest(httpRequest)
+	contractErrors = append(contractErrors, toContractErrors(ContractErrorRequest, requestErrors)...)
+
+	if entry.Response.StatusCode > 0 {
+		httpResponse := harhar.ConvertResponseIntoHttpResponse(entry.Response)
+		_, responseErrors := validator.ValidateHttpResponse(httpRequest, httpResponse)
+		contractErrors = append(contractErrors, toContractErrors(ContractErrorResponse, responseErrors)...)
+	}
+	return contractErrors, httpRequest.URL.Path, true, nil
+}
+
+// toContractErrors flattens validation errors, schema violations become an error each, so that a changed schema
+// is reported by the violations that changed.
+func toContractErrors(kind string, validationErrors []*errors.ValidationError) []*ContractTestError {
+	var contractErrors []*ContractTestError
+	for _, ve := range validationErrors {
+		if len(ve.SchemaValidationErrors) == 0 {
+			contractErrors = append(contractErrors, &ContractTestError{Kind: kind, Message: ve.Message, Reason: ve.Reason})
+			continue
+		}
+		for _, sve := range ve.SchemaValidationErrors {
+			contractErrors = append(contractErrors, &ContractTestError{
+				Kind:     kind,
+				Message:  ve.Message,
+				Reason:   sve.Reason,
+				Location: sve.Location,
+			})
+		}
+	}
+	return contractErrors
+}
+
+func compareContractErrors(baseline, candidate []*ContractTestError) *ContractTestResult {
+	result := &ContractTestResult{}
+	inBaseline := make(map[string]bool, len(baseline))
+	for _, e := range baseline {
+		inBaseline[e.key()] = true
+	}
+	inCandidate := make(map[string]bool, len(candidate))
+	for _, e := range candidate {
+		inCandidate[e.key()] = true
+		if inBaseline[e.key()] {
+			result.Unchanged++
+		} else {
+			result.Regressions = append(result.Regressions, e)
+		}
+	}
+	for _, e := range baseline {
+		if !inCandidate[e.key()] {
+			result.Fixed = append(result.Fixed, e)
+		}
+	}
+	return result
+}
+
+// JUnit renders the report as JUnit XML, each recorded entry is a test case that fails when the candidate
+// specification introduces regressions. Fixed errors are listed in the test case output.
+func (r *ContractTestReport) JUnit() ([]byte, error) {
+	suite := &shared.JUnitTestSuite{Name: r.CandidateSpec, Tests: len(r.Results)}
+	for _, result := range r.Results {
+		tc := &shared.JUnitTestCase{Name: result.Method + " " + result.Path, ClassName: "wiretap.contract"}
+		if len(result.Regressions) > 0 {
+			suite.Failures++
+			var lines []string
+			for _, e := range result.Regressions {
+				lines = append(lines, e.String())
+			}
+			tc.Failure = &shared.JUnitFailure{
+				Message: fmt.Sprintf("%d %s compared to %s", len(result.Regressions),
+					shared.Pluralize(len(result.Regressions), "regression", "regressions"), r.BaselineSpec),
+				Type:    "regression",
+				Content: strings.Join(lines, "\n"),
+			}
+		}
+		if len(result.Fixed) > 0 {
+			var lines []string
+			for _, e := range result.Fixed {
+				lines = append(lines, "fixed: "+e.String())
+			}
+			tc.SystemOut = strings.Join(lines, "\n")
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suites := &shared.JUnitTestSuites{
+		Name:     "wiretap contract test",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []*shared.JUnitTestSuite{suite},
+	}
+	return suites.Marshal()
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package har
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const contractSpec = `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: MAXIMUM
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [REQUIRED]
+                properties:
+                  id:
+                    type: integer
+                  name:
+                    type: string
+`
+
+const contractHAR = `{"log": {"entries": [{
+  "request": {"method": "GET", "url": "http://localhost/pets", "queryString": [{"name": "limit", "value": "10"}],
+    "headers": [], "cookies": []},
+  "response": {"status": 200, "statusText": "OK", "headers": [], "cookies": [],
+    "content": {"mimeType": "application/json", "text": "{\"name\": \"fido\"}"}}
+}]}}`
+
+func buildContractModel(t *testing.T, maximum, required string) *v3.Document {
+	spec := strings.NewReplacer("MAXIMUM", maximum, "REQUIRED", required).Replace(contractSpec)
+	doc, err := libopenapi.NewDocument([]byte(spec))
+	require.NoError(t, err)
+	m, _ := doc.BuildV3Model()
+	require.NotNil(t, m)
+	return &m.Model
+}
+
+func TestRunContractTest(t *testing.T) {
+	harFile, err := BuildHAR([]byte(contractHAR))
+	require.NoError(t, err)
+
+	// the baseline requires an id the recorded response does not have, the candidate lowers the limit maximum.
+	baseline := buildContractModel(t, "100", "id")
+	candidate := buildContractModel(t, "5", "name")
+
+	config := &shared.WiretapConfiguration{
+		ContractTest: &shared.ContractTestConfig{BaselineSpec: "baseline.yaml", CandidateSpec: "candidate.yaml"},
+	}
+	report, err := RunContractTest(harFile, baseline, candidate, config)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Entries)
+	assert.Equal(t, 1, report.Regressions)
+	assert.Equal(t, 1, report.Fixed)
+	require.Len(t, report.Results, 1)
+	result := report.Results[0]
+	assert.Equal(t, "/pets", result.Path)
+	assert.Equal(t, ContractErrorRequest, result.Regressions[0].Kind)
+	assert.Equal(t, ContractErrorResponse, result.Fixed[0].Kind)
+
+	junit, err := report.JUnit()
+	require.NoError(t, err)
+	assert.Contains(t, string(junit), `<testsuites name="wiretap contract test" tests="1" failures="1">`)
+	assert.Contains(t, string(junit), `<testcase name="GET /pets" classname="wiretap.contract">`)
+	assert.Contains(t, string(junit), "fixed: response:")
+}
@@ -17,6 +17,8 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type ResponseMockEngine struct {
@@ -24,6 +26,13 @@ type ResponseMockEngine struct {
 	validator  validation.HttpValidator
 	mockEngine *renderer.MockGenerator
 	pretty     bool
+
+	// generates fake data from schemas when there are no examples, nil when disabled.
+	faker *schemaFaker
+
+	// round-robin position of the next example to serve in dry-run mode, for each media type.
+	exampleIndexes map[*v3.MediaType]int
+	exampleLock    sync.Mutex
 }
 
 func NewMockEngine(document *v3.Document, pretty, useAllPropertyExamples bool) *ResponseMockEngine {
@@ -37,10 +46,20 @@ func NewMockEngine(document *v3.Document, pretty, useAllPropertyExamples bool) *
 	}
 
 	return &ResponseMockEngine{
-		doc:        document,
-		validator:  validation.NewHttpValidator(document),
-		mockEngine: me,
-		pretty:     pretty,
+		doc:            document,
+		validator:      validation.NewHttpValidator(document),
+		mockEngine:     me,
+		pretty:         pretty,
+		exampleIndexes: make(map[*v3.MediaType]int),
+	}
+}
+
+// SetFakerEnabled switches on the generation of fake data from the schema, for responses that define no examples.
+func (rme *ResponseMockEngine) SetFakerEnabled(enabled bool) {
+	if enabled {
+		rme.faker = newSchemaFaker(time.Now().UnixNano())
+	} else {
+		rme.faker = nil
 	}
 }
 
@@ -343,7 +362,7 @@ func (rme *ResponseMockEngine) runWorkflow(request *http.Request) ([]byte, int,
 		), 415, nil
 	}
 
-	mock, mockErr := rme.mockEngine.GenerateMock(mt, preferred)
+	mock, mockErr := rme.generateMock(mt, preferred)
 	if mockErr != nil {
 		return rme.buildError(
 			422,
@@ -403,6 +422,17 @@ func (rme *ResponseMockEngine) findMediaTypeContainingNamedExample(
 	return nil, "", true
 }
 
+// generateMock renders a mock for a media type. When the faker is enabled and the specification has no examples
+// for the media type, fake data is generated from the schema instead.
+func (rme *ResponseMockEngine) generateMock(mt *v3.MediaType, preferred string) ([]byte, error) {
+	if rme.faker != nil && mt != nil && mt.Schema != nil && len(collectExamples(mt)) == 0 {
+		if fake := rme.faker.Generate(mt.Schema.Schema()); fake != nil {
+			return rme.render(fake), nil
+		}
+	}
+	return rme.mockEngine.GenerateMock(mt, preferred)
+}
+
 func (rme *ResponseMockEngine) findLowestSuccessCode(operation *v3.Operation) string {
 	var lowestCode = 299
 	if operation.Responses == nil {
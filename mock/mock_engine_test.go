@@ -1528,3 +1528,77 @@ components:
 	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, `{"id":123,"name":"John Doe"}`, string(b))
 }
+
+func TestNewMockEngine_GenerateExampleResponse_RoundRobin(t *testing.T) {
+
+	spec := `openapi: 3.1.0
+paths:
+  /test:
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                type: object
+              examples:
+                first:
+                  $ref: '#/components/examples/First'
+                second:
+                  value:
+                    name: second
+components:
+  examples:
+    First:
+      value:
+        name: first
+`
+
+	d, _ := libopenapi.NewDocument([]byte(spec))
+	doc, _ := d.BuildV3Model()
+
+	me := NewMockEngine(&doc.Model, false, true)
+
+	var names []any
+	for i := 0; i < 3; i++ {
+		request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/test", nil)
+		b, status, contentType, err := me.GenerateExampleResponse(request)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, "application/json", contentType)
+
+		var decoded map[string]any
+		_ = json.Unmarshal(b, &decoded)
+		names = append(names, decoded["name"])
+	}
+	assert.Equal(t, []any{"first", "second", "first"}, names)
+}
+
+func TestNewMockEngine_GenerateExampleResponse_ContentType(t *testing.T) {
+
+	spec := `openapi: 3.1.0
+paths:
+  /test:
+    get:
+      responses:
+        '200':
+          content:
+            text/plain:
+              schema:
+                type: string
+              example: hello there
+`
+
+	d, _ := libopenapi.NewDocument([]byte(spec))
+	doc, _ := d.BuildV3Model()
+
+	me := NewMockEngine(&doc.Model, false, true)
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/test", nil)
+	b, status, contentType, err := me.GenerateExampleResponse(request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "text/plain", contentType)
+	assert.Equal(t, "hello there", string(b))
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/helpers"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateExampleResponse builds a response for dry-run mode from the examples defined for the operation in the
+// specification. When a media type defines multiple examples, each call returns the next one (round-robin).
+// The media type of the example is returned along with the body, so it can be used as the Content-Type.
+// If the request is invalid, or the operation has no examples, the regular mock workflow is used instead.
+func (rme *ResponseMockEngine) GenerateExampleResponse(request *http.Request) ([]byte, int, string, error) {
+	if mock, code, mediaType, ok := rme.findExampleResponse(request); ok {
+		return mock, code, mediaType, nil
+	}
+	mock, code, err := rme.runWorkflow(request)
+	return mock, code, helpers.JSONContentType, err
+}
+
+func (rme *ResponseMockEngine) findExampleResponse(request *http.Request) ([]byte, int, string, bool) {
+	// a preferred example is explicitly requested by name, the regular workflow already handles that.
+	if rme.extractPreferred(request) != "" {
+		return nil, 0, "", false
+	}
+
+	path, err := rme.findPath(request)
+	if err != nil {
+		return nil, 0, "", false
+	}
+	operation := rme.findOperation(request, path)
+	if operation == nil || operation.Responses == nil {
+		return nil, 0, "", false
+	}
+
+	// security and request validation failures are answered by the regular workflow.
+	if rme.ValidateSecurity(request, operation) != nil {
+		return nil, 0, "", false
+	}
+	if _, validationErrors := rme.validator.ValidateHttpRequest(request); len(validationErrors) > 0 {
+		return nil, 0, "", false
+	}
+
+	lo := rme.findLowestSuccessCode(operation)
+	resp := operation.Responses.Codes.GetOrZero(lo)
+	if resp == nil || resp.Content == nil {
+		return nil, 0, "", false
+	}
+
+	mediaType, mt := rme.findExampleMediaType(resp.Content, request)
+	if mt == nil {
+		for pairs := resp.Content.First(); pairs != nil; pairs = pairs.Next() {
+			mediaType, mt = pairs.Key(), pairs.Value()
+			break
+		}
+	}
+	if mt == nil {
+		return nil, 0, "", false
+	}
+
+	examples := collectExamples(mt)
+	if len(examples) == 0 {
+		return nil, 0, "", false
+	}
+
+	example := examples[rme.nextExampleIndex(mt, len(examples))]
+	mock, ok := rme.renderExample(example, mediaType)
+	if !ok {
+		return nil, 0, "", false
+	}
+
+	c, _ := strconv.Atoi(lo)
+	// check for wiretap-status-code in header and override the code, regardless of what was found in the spec.
+	if statusCode := request.Header.Get("wiretap-status-code"); statusCode != "" {
+		c, _ = strconv.Atoi(statusCode)
+	}
+	return mock, c, mediaType, true
+}
+
+// findExampleMediaType looks up the media type requested by the client, falling back to JSON.
+func (rme *ResponseMockEngine) findExampleMediaType(content *orderedmap.Map[string, *v3.MediaType],
+	request *http.Request) (string, *v3.MediaType) {
+	mediaTypeString := rme.extractMediaTypeHeader(request)
+	if mt := content.GetOrZero(mediaTypeString); mt != nil {
+		return mediaTypeString, mt
+	}
+	if mt := content.GetOrZero(helpers.JSONContentType); mt != nil {
+		return helpers.JSONContentType, mt
+	}
+	return "", nil
+}
+
+// collectExamples gathers the examples for a media type, in the order they are defined. Named examples are
+// preferred over a single example, which is preferred over examples defined on the schema.
+// References to component examples are already resolved by the document model.
+func collectExamples(mt *v3.MediaType) []*yaml.Node {
+	var examples []*yaml.Node
+	if mt.Examples != nil {
+		for ex := range mt.Examples.ValuesFromOldest() {
+			if ex != nil && ex.Value != nil {
+				examples = append(examples, ex.Value)
+			}
+		}
+	}
+	if len(examples) > 0 {
+		return examples
+	}
+	if mt.Example != nil {
+		return []*yaml.Node{mt.Example}
+	}
+	if mt.Schema != nil {
+		if schema := mt.Schema.Schema(); schema != nil {
+			if len(schema.Examples) > 0 {
+				return schema.Examples
+			}
+			if schema.Example != nil {
+				return []*yaml.Node{schema.Example}
+			}
+		}
+	}
+	return nil
+}
+
+// nextExampleIndex returns the index of the next example to serve for a media type, cycling through all of them.
+func (rme *ResponseMockEngine) nextExampleIndex(mt *v3.MediaType, count int) int {
+	rme.exampleLock.Lock()
+	defer rme.exampleLock.Unlock()
+	idx := rme.exampleIndexes[mt] % count
+	rme.exampleIndexes[mt] = idx + 1
+	return idx
+}
+
+// renderExample renders an example as JSON, unless the example is a plain string for a non-JSON media type,
+// in which case the string is returned as is.
+func (rme *ResponseMockEngine) renderExample(example *yaml.Node, mediaType string) ([]byte, bool) {
+	var value any
+	if err := example.Decode(&value); err != nil {
+		return nil, false
+	}
+	if s, ok := value.(string); ok && !strings.Contains(mediaType, "json") {
+		return []byte(s), true
+	}
+	mock := rme.render(value)
+	return mock, len(mock) > 0
+}
@@ -0,0 +1,429 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lucasjones/reggen"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"gopkg.in/yaml.v3"
+)
+
+// maxFakeDepth stops the faker from walking circular schemas forever.
+const maxFakeDepth = 8
+
+// maxFakePatternRepeat limits how many times an unbounded repetition (*, +) is repeated when generating a
+// string from a pattern.
+const maxFakePatternRepeat = 10
+
+// maxFakePatternAttempts limits how many strings are generated from a pattern looking for one that satisfies the
+// minLength and maxLength of the schema.
+const maxFakePatternAttempts = 20
+
+const defaultFakeArrayItems = 2
+
+var fakeWords = []string{
+	"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india", "juliet",
+	"kilo", "lima", "mike", "november", "oscar", "papa", "quebec", "romeo", "sierra", "tango",
+}
+
+var fakeNames = []string{"Ada", "Grace", "Alan", "Linus", "Margaret", "Dennis", "Barbara", "Ken"}
+
+var fakeDomains = []string{"example.com", "example.org", "example.net"}
+
+// fakeValue produces a new random value for a schema every time it is called.
+type fakeValue func(r *rand.Rand) any
+
+// schemaFaker generates plausible fake data from a schema, for operations that define no examples.
+// Schemas are compiled into generators once, and the generators are cached by schema.
+type schemaFaker struct {
+	rand  *rand.Rand
+	cache map[*base.Schema]fakeValue
+	lock  sync.Mutex
+}
+
+func newSchemaFaker(seed int64) *schemaFaker {
+	return &schemaFaker{
+		rand:  rand.New(rand.NewSource(seed)),
+		cache: make(map[*base.Schema]fakeValue),
+	}
+}
+
+// Generate returns a fake value for the schema.
+func (sf *schemaFaker) Generate(schema *base.Schema) any {
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+	gen, ok := sf.cache[schema]
+	if !ok {
+		gen = sf.compile(schema, 0)
+		sf.cache[schema] = gen
+	}
+	return gen(sf.rand)
+}
+
+func (sf *schemaFaker) compileProxy(proxy *base.SchemaProxy, depth int) fakeValue {
+	if proxy == nil {
+		return fakeNil
+	}
+	return sf.compile(proxy.Schema(), depth)
+}
+
+func (sf *schemaFaker) compile(schema *base.Schema, depth int) fakeValue {
+	if schema == nil || depth > maxFakeDepth {
+		return fakeNil
+	}
+
+	if schema.Const != nil {
+		return fakeConstant(decodeNode(schema.Const))
+	}
+	// examples defined in the specification always win over fake data.
+	if len(schema.Examples) > 0 {
+		return fakeConstant(decodeNode(schema.Examples[0]))
+	}
+	if schema.Example != nil {
+		return fakeConstant(decodeNode(schema.Example))
+	}
+	if len(schema.Enum) > 0 {
+		values := make([]any, len(schema.Enum))
+		for i := range schema.Enum {
+			values[i] = decodeNode(schema.Enum[i])
+		}
+		return func(r *rand.Rand) any {
+			return values[r.Intn(len(values))]
+		}
+	}
+
+	if len(schema.AllOf) > 0 {
+		return sf.compileAllOf(schema, depth)
+	}
+	if len(schema.OneOf) > 0 {
+		return sf.compileProxy(schema.OneOf[0], depth+1)
+	}
+	if len(schema.AnyOf) > 0 {
+		return sf.compileProxy(schema.AnyOf[0], depth+1)
+	}
+
+	switch schemaType(schema) {
+	case "string":
+		return compileString(schema)
+	case "integer":
+		return compileInteger(schema)
+	case "number":
+		return compileNumber(schema)
+	case "boolean":
+		return func(r *rand.Rand) any {
+			return r.Intn(2) == 1
+		}
+	case "array":
+		return sf.compileArray(schema, depth)
+	case "object":
+		return sf.compileObject(schema, depth)
+	}
+	return fakeNil
+}
+
+// schemaType returns the first non-null type of a schema, inferring objects and arrays when no type is set.
+func schemaType(schema *base.Schema) string {
+	for _, t := range schema.Type {
+		if t != "null" {
+			return t
+		}
+	}
+	if schema.Properties != nil && schema.Properties.Len() > 0 {
+		return "object"
+	}
+	if schema.Items != nil {
+		return "array"
+	}
+	return ""
+}
+
+func (sf *schemaFaker) compileAllOf(schema *base.Schema, depth int) fakeValue {
+	var parts []fakeValue
+	for _, proxy := range schema.AllOf {
+		parts = append(parts, sf.compileProxy(proxy, depth+1))
+	}
+	if schema.Properties != nil {
+		parts = append(parts, sf.compileObject(schema, depth))
+	}
+	return func(r *rand.Rand) any {
+		merged := make(map[string]any)
+		var last any
+		for _, part := range parts {
+			last = part(r)
+			if m, ok := last.(map[string]any); ok {
+				for k, v := range m {
+					merged[k] = v
+				}
+			}
+		}
+		if len(merged) == 0 {
+			return last
+		}
+		return merged
+	}
+}
+
+func (sf *schemaFaker) compileObject(schema *base.Schema, depth int) fakeValue {
+	type fakeProperty struct {
+		name  string
+		value fakeValue
+	}
+	var properties []fakeProperty
+	if schema.Properties != nil {
+		for name, proxy := range schema.Properties.FromOldest() {
+			if s := proxy.Schema(); s != nil && s.WriteOnly != nil && *s.WriteOnly {
+				continue // write only properties are never part of a response.
+			}
+			properties = append(properties, fakeProperty{name: name, value: sf.compileProxy(proxy, depth+1)})
+		}
+	}
+	return func(r *rand.Rand) any {
+		obj := make(map[string]any, len(properties))
+		for _, p := range properties {
+			obj[p.name] = p.value(r)
+		}
+		return obj
+	}
+}
+
+func (sf *schemaFaker) compileArray(schema *base.Schema, depth int) fakeValue {
+	item := fakeNil
+	if schema.Items != nil && schema.Items.IsA() {
+		item = sf.compileProxy(schema.Items.A, depth+1)
+	}
+	minItems, maxItems := int64(1), int64(defaultFakeArrayItems)
+	if schema.MinItems != nil {
+		minItems = *schema.MinItems
+	}
+	if schema.MaxItems != nil {
+		maxItems = *schema.MaxItems
+	}
+	if maxItems < minItems {
+		maxItems = minItems
+	}
+	return func(r *rand.Rand) any {
+		count := minItems + r.Int63n(maxItems-minItems+1)
+		items := make([]any, count)
+		for i := range items {
+			items[i] = item(r)
+		}
+		return items
+	}
+}
+
+func compileString(schema *base.Schema) fakeValue {
+	minLength, maxLength := int64(0), int64(0)
+	if schema.MinLength != nil {
+		minLength = *schema.MinLength
+	}
+	if schema.MaxLength != nil {
+		maxLength = *schema.MaxLength
+	}
+
+	if schema.Pattern != "" {
+		if gen, err := reggen.NewGenerator(schema.Pattern); err == nil {
+			return func(r *rand.Rand) any {
+				return fakePattern(r, gen, minLength, maxLength)
+			}
+		}
+	}
+
+	switch schema.Format {
+	case "date-time":
+		return func(r *rand.Rand) any {
+			return fakeTime(r).Format(time.RFC3339)
+		}
+	case "date":
+		return func(r *rand.Rand) any {
+			return fakeTime(r).Format(time.DateOnly)
+		}
+	case "time":
+		return func(r *rand.Rand) any {
+			return fakeTime(r).Format(time.TimeOnly)
+		}
+	case "email":
+		return func(r *rand.Rand) any {
+			return fmt.Sprintf("%s@%s", strings.ToLower(pick(r, fakeNames)), pick(r, fakeDomains))
+		}
+	case "uuid":
+		return func(r *rand.Rand) any {
+			id, _ := uuid.NewRandomFromReader(r)
+			return id.String()
+		}
+	case "uri", "url":
+		return func(r *rand.Rand) any {
+			return fmt.Sprintf("https://%s/%s", pick(r, fakeDomains), pick(r, fakeWords))
+		}
+	case "hostname":
+		return func(r *rand.Rand) any {
+			return fmt.Sprintf("%s.%s", pick(r, fakeWords), pick(r, fakeDomains))
+		}
+	case "ipv4":
+		return func(r *rand.Rand) any {
+			return fmt.Sprintf("%d.%d.%d.%d", r.Intn(256), r.Intn(256), r.Intn(256), r.Intn(256))
+		}
+	case "ipv6":
+		return func(r *rand.Rand) any {
+			groups := make([]string, 8)
+			for i := range groups {
+				groups[i] = fmt.Sprintf("%x", r.Intn(0x10000))
+			}
+			return strings.Join(groups, ":")
+		}
+	}
+
+	return func(r *rand.Rand) any {
+		return fakeText(r, minLength, maxLength)
+	}
+}
+
+// fakePattern generates a string from a pattern that satisfies the length constraints, a maxLength of zero means
+// unbounded. Unbounded repetitions may repeat up to minLength times at first, and more or less often after each value
+// that is too short or too long. When no attempt fits, the last value is cut down to maxLength, which may no longer
+// match the pattern.
+func fakePattern(r *rand.Rand, gen *reggen.Generator, minLength, maxLength int64) string {
+	limit := max(maxFakePatternRepeat, int(minLength))
+	var value string
+	for attempt := 0; attempt < maxFakePatternAttempts; attempt++ {
+		gen.SetSeed(r.Int63())
+		value = gen.Generate(limit)
+		length := int64(utf8.RuneCountInString(value))
+		if length >= minLength && (maxLength == 0 || length <= maxLength) {
+			return value
+		}
+		// change how often unbounded repetitions may repeat, so the next attempt is more likely to fit.
+		if length < minLength {
+			limit *= 2
+		} else {
+			limit = max(1, limit/2)
+		}
+	}
+	if runes := []rune(value); maxLength > 0 && int64(len(runes)) > maxLength {
+		value = string(runes[:maxLength])
+	}
+	return value
+}
+
+// fakeText builds a string of words that satisfies the length constraints, a maxLength of zero means unbounded.
+func fakeText(r *rand.Rand, minLength, maxLength int64) string {
+	var sb strings.Builder
+	sb.WriteString(pick(r, fakeWords))
+	for int64(sb.Len()) < minLength {
+		sb.WriteString(" ")
+		sb.WriteString(pick(r, fakeWords))
+	}
+	text := sb.String()
+	if maxLength > 0 && int64(len(text)) > maxLength {
+		text = text[:maxLength]
+	}
+	return text
+}
+
+func compileInteger(schema *base.Schema) fakeValue {
+	low, high := numberRange(schema, 0, 1000)
+	lowInt, highInt := int64(math.Ceil(low)), int64(math.Floor(high))
+	if exclusiveMinimum(schema) && float64(lowInt) == low {
+		lowInt++
+	}
+	if exclusiveMaximum(schema) && float64(highInt) == high {
+		highInt--
+	}
+	if highInt < lowInt {
+		highInt = lowInt
+	}
+	return func(r *rand.Rand) any {
+		return lowInt + r.Int63n(highInt-lowInt+1)
+	}
+}
+
+func compileNumber(schema *base.Schema) fakeValue {
+	low, high := numberRange(schema, 0, 1000)
+	exclusive := exclusiveMinimum(schema) || exclusiveMaximum(schema)
+	return func(r *rand.Rand) any {
+		// round to two decimal places so the values look like something a person would write.
+		v := math.Round((low+r.Float64()*(high-low))*100) / 100
+		if v < low || v > high || (exclusive && (v == low || v == high)) {
+			v = low + (high-low)/2
+		}
+		return v
+	}
+}
+
+// numberRange returns the bounds of a numeric schema, using the defaults when no bounds are defined.
+func numberRange(schema *base.Schema, defaultLow, defaultHigh float64) (float64, float64) {
+	low, high := defaultLow, defaultHigh
+	if schema.Minimum != nil {
+		low = *schema.Minimum
+	} else if schema.ExclusiveMinimum != nil && schema.ExclusiveMinimum.IsB() {
+		low = schema.ExclusiveMinimum.B
+	}
+	if schema.Maximum != nil {
+		high = *schema.Maximum
+	} else if schema.ExclusiveMaximum != nil && schema.ExclusiveMaximum.IsB() {
+		high = schema.ExclusiveMaximum.B
+	}
+	if schema.Minimum == nil && schema.ExclusiveMinimum == nil && high < low {
+		low = high - (defaultHigh - defaultLow)
+	}
+	if schema.Maximum == nil && schema.ExclusiveMaximum == nil && high < low {
+		high = low + (defaultHigh - defaultLow)
+	}
+	if high < low {
+		high = low
+	}
+	return low, high
+}
+
+// exclusiveMinimum reports if the lower bound is exclusive, either as a 3.0 boolean or a 3.1 value.
+func exclusiveMinimum(schema *base.Schema) bool {
+	if schema.ExclusiveMinimum == nil {
+		return false
+	}
+	return (schema.ExclusiveMinimum.IsA() && schema.ExclusiveMinimum.A) ||
+		(schema.ExclusiveMinimum.IsB() && schema.Minimum == nil)
+}
+
+// exclusiveMaximum reports if the upper bound is exclusive, either as a 3.0 boolean or a 3.1 value.
+func exclusiveMaximum(schema *base.Schema) bool {
+	if schema.ExclusiveMaximum == nil {
+		return false
+	}
+	return (schema.ExclusiveMaximum.IsA() && schema.ExclusiveMaximum.A) ||
+		(schema.ExclusiveMaximum.IsB() && schema.Maximum == nil)
+}
+
+func fakeTime(r *rand.Rand) time.Time {
+	// somewhere during 2024, with a fixed origin so seeded output is repeatable.
+	origin := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return origin.Add(time.Duration(r.Int63n(int64(365 * 24 * time.Hour)))).Truncate(time.Second)
+}
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.Intn(len(values))]
+}
+
+func fakeConstant(value any) fakeValue {
+	return func(*rand.Rand) any {
+		return value
+	}
+}
+
+func fakeNil(*rand.Rand) any {
+	return nil
+}
+
+func decodeNode(node *yaml.Node) any {
+	var value any
+	_ = node.Decode(&value)
+	return value
+}
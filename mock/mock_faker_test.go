@@ -0,0 +1,205 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMockEngine_Faker_Constraints(t *testing.T) {
+
+	spec := `openapi: 3.1.0
+paths:
+  /test:
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Thing'
+components:
+  schemas:
+    Thing:
+      type: object
+      properties:
+        id:
+          type: string
+          format: uuid
+        created:
+          type: string
+          format: date-time
+        code:
+          type: string
+          pattern: '^[A-Z]{3}-[0-9]{4}$'
+        name:
+          type: string
+          minLength: 12
+          maxLength: 16
+        count:
+          type: integer
+          minimum: 5
+          maximum: 10
+        price:
+          type: number
+          minimum: 1.5
+          maximum: 2.5
+        status:
+          type: string
+          enum: [active, retired]
+        label:
+          type: string
+          example: fixed
+        tags:
+          type: array
+          minItems: 3
+          maxItems: 3
+          items:
+            type: boolean
+`
+
+	d, _ := libopenapi.NewDocument([]byte(spec))
+	doc, _ := d.BuildV3Model()
+
+	me := NewMockEngine(&doc.Model, false, true)
+	me.SetFakerEnabled(true)
+
+	for i := 0; i < 20; i++ {
+		request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/test", nil)
+		b, status, err := me.GenerateResponse(request)
+		require.NoError(t, err)
+		assert.Equal(t, 200, status)
+
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(b, &decoded))
+
+		_, err = uuid.Parse(decoded["id"].(string))
+		assert.NoError(t, err)
+		_, err = time.Parse(time.RFC3339, decoded["created"].(string))
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^[A-Z]{3}-[0-9]{4}$`), decoded["code"])
+
+		name := decoded["name"].(string)
+		assert.GreaterOrEqual(t, len(name), 12)
+		assert.LessOrEqual(t, len(name), 16)
+
+		count := decoded["count"].(float64)
+		assert.GreaterOrEqual(t, count, 5.0)
+		assert.LessOrEqual(t, count, 10.0)
+		assert.Equal(t, count, float64(int(count)))
+
+		price := decoded["price"].(float64)
+		assert.GreaterOrEqual(t, price, 1.5)
+		assert.LessOrEqual(t, price, 2.5)
+
+		assert.Contains(t, []any{"active", "retired"}, decoded["status"])
+		assert.Equal(t, "fixed", decoded["label"])
+		assert.Len(t, decoded["tags"], 3)
+	}
+}
+
+func TestNewMockEngine_Faker_ExamplesWin(t *testing.T) {
+
+	spec := `openapi: 3.1.0
+paths:
+  /test:
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  name:
+                    type: string
+              example:
+                name: from the spec
+`
+
+	d, _ := libopenapi.NewDocument([]byte(spec))
+	doc, _ := d.BuildV3Model()
+
+	me := NewMockEngine(&doc.Model, false, true)
+	me.SetFakerEnabled(true)
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.pb33f.io/test", nil)
+	b, _, err := me.GenerateResponse(request)
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	_ = json.Unmarshal(b, &decoded)
+	assert.Equal(t, "from the spec", decoded["name"])
+}
+
+func TestSchemaFaker_CachesCompiledSchemas(t *testing.T) {
+
+	spec := `openapi: 3.1.0
+components:
+  schemas:
+    Node:
+      type: object
+      properties:
+        child:
+          $ref: '#/components/schemas/Node'
+`
+
+	d, _ := libopenapi.NewDocument([]byte(spec))
+	doc, _ := d.BuildV3Model()
+	schema := doc.Model.Components.Schemas.GetOrZero("Node").Schema()
+
+	faker := newSchemaFaker(1)
+	assert.NotNil(t, faker.Generate(schema))
+	assert.NotNil(t, faker.Generate(schema))
+	assert.Len(t, faker.cache, 1)
+}
+
+func TestSchemaFaker_PatternLength(t *testing.T) {
+
+	spec := `openapi: 3.1.0
+components:
+  schemas:
+    Long:
+      type: string
+      pattern: '^[a-z]+$'
+      minLength: 30
+    Short:
+      type: string
+      pattern: '^[a-z]+$'
+      minLength: 2
+      maxLength: 4
+    Impossible:
+      type: string
+      pattern: '^[a-z]{10}$'
+      maxLength: 5
+`
+
+	d, _ := libopenapi.NewDocument([]byte(spec))
+	doc, _ := d.BuildV3Model()
+	schemas := doc.Model.Components.Schemas
+
+	faker := newSchemaFaker(1)
+	for i := 0; i < 50; i++ {
+		long := faker.Generate(schemas.GetOrZero("Long").Schema()).(string)
+		assert.Regexp(t, `^[a-z]+$`, long)
+		assert.GreaterOrEqual(t, len(long), 30)
+
+		short := faker.Generate(schemas.GetOrZero("Short").Schema()).(string)
+		assert.Regexp(t, `^[a-z]+$`, short)
+		assert.GreaterOrEqual(t, len(short), 2)
+		assert.LessOrEqual(t, len(short), 4)
+
+		// the length constraints win over a pattern that cannot satisfy them.
+		assert.Len(t, faker.Generate(schemas.GetOrZero("Impossible").Schema()), 5)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// GenerateExampleHeaders returns the headers the specification defines for the response with the status code, of
+// the operation matching the request, for dry-run mode. Values come from the header's example, its first named
+// example, or the example or default of its schema. The names of required headers that have no value are returned
+// as warnings, so the gap in the specification can be reported. A Content-Type header is ignored, as required by
+// the OpenAPI specification, the media type of the body is used instead.
+func (rme *ResponseMockEngine) GenerateExampleHeaders(request *http.Request, status int) (map[string]string, []string) {
+	path, err := rme.findPath(request)
+	if err != nil {
+		return nil, nil
+	}
+	operation := rme.findOperation(request, path)
+	if operation == nil || operation.Responses == nil {
+		return nil, nil
+	}
+	response := operation.Responses.Codes.GetOrZero(strconv.Itoa(status))
+	if response == nil {
+		response = operation.Responses.Default
+	}
+	if response == nil || response.Headers == nil {
+		return nil, nil
+	}
+
+	headers := make(map[string]string)
+	var missing []string
+	for name, header := range response.Headers.FromOldest() {
+		if header == nil || strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		if value, ok := exampleHeaderValue(header); ok {
+			headers[name] = value
+		} else if header.Required {
+			missing = append(missing, name)
+		}
+	}
+	return headers, missing
+}
+
+// exampleHeaderValue renders the example value of a header, in the simple style: arrays are comma separated, and
+// objects are comma separated keys and values.
+func exampleHeaderValue(header *v3.Header) (string, bool) {
+	node := header.Example
+	if node == nil && header.Examples != nil {
+		for ex := range header.Examples.ValuesFromOldest() {
+			if ex != nil && ex.Value != nil {
+				node = ex.Value
+				break
+			}
+		}
+	}
+	if node == nil && header.Schema != nil {
+		if schema := header.Schema.Schema(); schema != nil {
+			switch {
+			case schema.Example != nil:
+				node = schema.Example
+			case len(schema.Examples) > 0:
+				node = schema.Examples[0]
+			case schema.Default != nil:
+				node = schema.Default
+			}
+		}
+	}
+	if node == nil {
+		return "", false
+	}
+
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return "", false
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case []any:
+		values := make([]string, len(v))
+		for i := range v {
+			values[i] = fmt.Sprint(v[i])
+		}
+		return strings.Join(values, ","), true
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]string, 0, len(v)*2)
+		for _, k := range keys {
+			values = append(values, k, fmt.Sprint(v[k]))
+		}
+		return strings.Join(values, ","), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package mock
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var headersSpec = []byte(`openapi: 3.1.0
+info:
+  title: headers
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: pets
+          headers:
+            X-Rate-Limit:
+              schema:
+                type: integer
+                example: 100
+            X-Tags:
+              example: [a, b]
+            X-Region:
+              schema:
+                type: string
+                default: eu-west-1
+            X-Request-Id:
+              required: true
+              schema:
+                type: string
+            Content-Type:
+              example: text/plain
+          content:
+            application/json:
+              example: []
+`)
+
+func TestResponseMockEngine_GenerateExampleHeaders(t *testing.T) {
+	d, err := libopenapi.NewDocument(headersSpec)
+	require.NoError(t, err)
+	m, _ := d.BuildV3Model()
+	me := NewMockEngine(&m.Model, false, false)
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.example.com/pets", nil)
+	headers, missing := me.GenerateExampleHeaders(request, http.StatusOK)
+	assert.Equal(t, map[string]string{"X-Rate-Limit": "100", "X-Tags": "a,b", "X-Region": "eu-west-1"}, headers)
+	assert.Equal(t, []string{"X-Request-Id"}, missing)
+
+	headers, missing = me.GenerateExampleHeaders(request, http.StatusNotFound)
+	assert.Empty(t, headers)
+	assert.Empty(t, missing)
+}
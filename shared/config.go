@@ -8,74 +8,174 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/url"
 	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/pb33f/libopenapi/orderedmap"
 	"gopkg.in/yaml.v3"
 
 	"github.com/gobwas/glob"
+	"github.com/ohler55/ojg/jp"
 	"github.com/pb33f/harhar"
+	"github.com/pb33f/wiretap/asyncapi"
 )
 
 type WiretapConfiguration struct {
-	Contract                    string                                      `json:"-" yaml:"-"`
-	RedirectHost                string                                      `json:"redirectHost,omitempty" yaml:"redirectHost,omitempty"`
-	RedirectPort                string                                      `json:"redirectPort,omitempty" yaml:"redirectPort,omitempty"`
-	RedirectBasePath            string                                      `json:"redirectBasePath,omitempty" yaml:"redirectBasePath,omitempty"`
-	RedirectProtocol            string                                      `json:"redirectProtocol,omitempty" yaml:"redirectProtocol,omitempty"`
-	RedirectURL                 string                                      `json:"redirectURL,omitempty" yaml:"redirectURL,omitempty"`
-	Port                        string                                      `json:"port,omitempty" yaml:"port,omitempty"`
-	MonitorPort                 string                                      `json:"monitorPort,omitempty" yaml:"monitorPort,omitempty"`
-	WebSocketHost               string                                      `json:"webSocketHost,omitempty" yaml:"webSocketHost,omitempty"`
-	WebSocketPort               string                                      `json:"webSocketPort,omitempty" yaml:"webSocketPort,omitempty"`
-	GlobalAPIDelay              int                                         `json:"globalAPIDelay,omitempty" yaml:"globalAPIDelay,omitempty"`
-	StaticDir                   string                                      `json:"staticDir,omitempty" yaml:"staticDir,omitempty"`
-	StaticIndex                 string                                      `json:"staticIndex,omitempty" yaml:"staticIndex,omitempty"`
-	PathConfigurations          *orderedmap.Map[string, *WiretapPathConfig] `json:"paths,omitempty" yaml:"paths,omitempty"`
-	Headers                     *WiretapHeaderConfig                        `json:"headers,omitempty" yaml:"headers,omitempty"`
-	StaticPaths                 []string                                    `json:"staticPaths,omitempty" yaml:"staticPaths,omitempty"`
-	Variables                   map[string]string                           `json:"variables,omitempty" yaml:"variables,omitempty"`
-	Spec                        string                                      `json:"contract,omitempty" yaml:"contract,omitempty"`
-	Certificate                 string                                      `json:"certificate,omitempty" yaml:"certificate,omitempty"`
-	CertificateKey              string                                      `json:"certificateKey,omitempty" yaml:"certificateKey,omitempty"`
-	HardErrors                  bool                                        `json:"hardValidation,omitempty" yaml:"hardValidation,omitempty"`
-	HardErrorCode               int                                         `json:"hardValidationCode,omitempty" yaml:"hardValidationCode,omitempty"`
-	HardErrorReturnCode         int                                         `json:"hardValidationReturnCode,omitempty" yaml:"hardValidationReturnCode,omitempty"`
-	HardErrorsList              []string                                    `json:"hardValidationList,omitempty" yaml:"hardValidationList,omitempty"`
-	PathDelays                  map[string]int                              `json:"pathDelays,omitempty" yaml:"pathDelays,omitempty"`
-	MockMode                    bool                                        `json:"mockMode,omitempty" yaml:"mockMode,omitempty"`
-	MockModeList                []string                                    `json:"mockModeList,omitempty" yaml:"mockModeList,omitempty"`
-	StaticMockDir               string                                      `json:"staticMockDir,omitempty" yaml:"staticMockDir,omitempty"`
-	UseAllMockResponseFields    bool                                        `json:"useAllMockResponseFields,omitempty" yaml:"useAllMockResponseFields,omitempty"`
-	MockModePretty              bool                                        `json:"mockModePretty,omitempty" yaml:"mockModePretty,omitempty"`
-	Base                        string                                      `json:"base,omitempty" yaml:"base,omitempty"`
-	HAR                         string                                      `json:"har,omitempty" yaml:"har,omitempty"`
-	HARValidate                 bool                                        `json:"harValidate,omitempty" yaml:"harValidate,omitempty"`
-	HARPathAllowList            []string                                    `json:"harPathAllowList,omitempty" yaml:"harPathAllowList,omitempty"`
-	StreamReport                bool                                        `json:"streamReport,omitempty" yaml:"streamReport,omitempty"`
-	ReportFile                  string                                      `json:"reportFilename,omitempty" yaml:"reportFilename,omitempty"`
-	IgnoreRedirects             []string                                    `json:"ignoreRedirects,omitempty" yaml:"ignoreRedirects,omitempty"`
-	RedirectAllowList           []string                                    `json:"redirectAllowList,omitempty" yaml:"redirectAllowList,omitempty"`
-	WebsocketConfigs            map[string]*WiretapWebsocketConfig          `json:"websockets" yaml:"websockets"`
-	IgnoreValidation            []string                                    `json:"ignoreValidation,omitempty" yaml:"ignoreValidation,omitempty"`
-	ValidationAllowList         []string                                    `json:"validationAllowList,omitempty" yaml:"validationAllowList,omitempty"`
-	StrictRedirectLocation      bool                                        `json:"strictRedirectLocation,omitempty" yaml:"strictRedirectLocation,omitempty"`
-	IgnorePathRewrite           []*IgnoreRewriteConfig                      `json:"ignorePathRewrite,omitempty" yaml:"ignorePathRewrite,omitempty"`
-	HARFile                     *harhar.HAR                                 `json:"-" yaml:"-"`
-	CompiledMockModeList        []glob.Glob                                 `json:"-" yaml:"-"`
-	CompiledPathDelays          map[string]*CompiledPathDelay               `json:"-" yaml:"-"`
-	CompiledVariables           map[string]*CompiledVariable                `json:"-" yaml:"-"`
-	Version                     string                                      `json:"-" yaml:"-"`
-	StaticPathsCompiled         []glob.Glob                                 `json:"-" yaml:"-"`
-	CompiledHardErrorList       []glob.Glob                                 `json:"-" yaml:"-"`
-	CompiledPaths               *orderedmap.Map[string, *CompiledPath]      `json:"-"`
-	CompiledIgnoreRedirects     []*CompiledRedirect                         `json:"-" yaml:"-"`
-	CompiledRedirectAllowList   []*CompiledRedirect                         `json:"-" yaml:"-"`
-	CompiledIgnoreValidations   []*CompiledRedirect                         `json:"-" yaml:"-"`
-	CompiledValidationAllowList []*CompiledRedirect                         `json:"-" yaml:"-"`
-	CompiledIgnorePathRewrite   []*CompiledIgnoreRewrite                    `json:"-" yaml:"-"`
-	FS                          embed.FS                                    `json:"-"`
-	Logger                      *slog.Logger
+	Contract                          string                                      `json:"-" yaml:"-"`
+	AsyncAPISpec                      string                                      `json:"asyncAPISpec,omitempty" yaml:"asyncAPISpec,omitempty"`
+	RAMLSpec                          string                                      `json:"ramlSpec,omitempty" yaml:"ramlSpec,omitempty"`
+	RedirectHost                      string                                      `json:"redirectHost,omitempty" yaml:"redirectHost,omitempty"`
+	RedirectPort                      string                                      `json:"redirectPort,omitempty" yaml:"redirectPort,omitempty"`
+	RedirectBasePath                  string                                      `json:"redirectBasePath,omitempty" yaml:"redirectBasePath,omitempty"`
+	RedirectProtocol                  string                                      `json:"redirectProtocol,omitempty" yaml:"redirectProtocol,omitempty"`
+	RedirectURL                       string                                      `json:"redirectURL,omitempty" yaml:"redirectURL,omitempty"`
+	Port                              string                                      `json:"port,omitempty" yaml:"port,omitempty"`
+	ListenAddress                     string                                      `json:"listenAddress,omitempty" yaml:"listenAddress,omitempty"`
+	ListenPort                        int                                         `json:"listenPort,omitempty" yaml:"listenPort,omitempty"`
+	DualStack                         bool                                        `json:"dualStack,omitempty" yaml:"dualStack,omitempty"`
+	AdminListenAddress                string                                      `json:"adminListenAddress,omitempty" yaml:"adminListenAddress,omitempty"`
+	AdminListenPort                   int                                         `json:"adminListenPort,omitempty" yaml:"adminListenPort,omitempty"`
+	AdminUnixSocket                   string                                      `json:"adminUnixSocket,omitempty" yaml:"adminUnixSocket,omitempty"`
+	AdminUnixSocketGroupAccess        bool                                        `json:"adminUnixSocketGroupAccess,omitempty" yaml:"adminUnixSocketGroupAccess,omitempty"`
+	KeepAliveEnabled                  *bool                                       `json:"keepAliveEnabled,omitempty" yaml:"keepAliveEnabled,omitempty"`
+	KeepAliveIdleSeconds              int                                         `json:"keepAliveIdleSeconds,omitempty" yaml:"keepAliveIdleSeconds,omitempty"`
+	MaxRequestsPerConnection          int                                         `json:"maxRequestsPerConnection,omitempty" yaml:"maxRequestsPerConnection,omitempty"`
+	H2CEnabled                        bool                                        `json:"h2cEnabled,omitempty" yaml:"h2cEnabled,omitempty"`
+	ReadTimeoutMs                     int                                         `json:"readTimeoutMs,omitempty" yaml:"readTimeoutMs,omitempty"`
+	WriteTimeoutMs                    int                                         `json:"writeTimeoutMs,omitempty" yaml:"writeTimeoutMs,omitempty"`
+	IdleTimeoutMs                     int                                         `json:"idleTimeoutMs,omitempty" yaml:"idleTimeoutMs,omitempty"`
+	ReadHeaderTimeoutMs               int                                         `json:"readHeaderTimeoutMs,omitempty" yaml:"readHeaderTimeoutMs,omitempty"`
+	ShutdownTimeoutMs                 int                                         `json:"shutdownTimeoutMs,omitempty" yaml:"shutdownTimeoutMs,omitempty"`
+	MaxConcurrentConnections          int                                         `json:"maxConcurrentConnections,omitempty" yaml:"maxConcurrentConnections,omitempty"`
+	MonitorPort                       string                                      `json:"monitorPort,omitempty" yaml:"monitorPort,omitempty"`
+	WebSocketHost                     string                                      `json:"webSocketHost,omitempty" yaml:"webSocketHost,omitempty"`
+	WebSocketPort                     string                                      `json:"webSocketPort,omitempty" yaml:"webSocketPort,omitempty"`
+	GlobalAPIDelay                    int                                         `json:"globalAPIDelay,omitempty" yaml:"globalAPIDelay,omitempty"`
+	StaticDir                         string                                      `json:"staticDir,omitempty" yaml:"staticDir,omitempty"`
+	StaticIndex                       string                                      `json:"staticIndex,omitempty" yaml:"staticIndex,omitempty"`
+	PathConfigurations                *orderedmap.Map[string, *WiretapPathConfig] `json:"paths,omitempty" yaml:"paths,omitempty"`
+	Headers                           *WiretapHeaderConfig                        `json:"headers,omitempty" yaml:"headers,omitempty"`
+	StaticPaths                       []string                                    `json:"staticPaths,omitempty" yaml:"staticPaths,omitempty"`
+	Variables                         map[string]string                           `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Spec                              string                                      `json:"contract,omitempty" yaml:"contract,omitempty"`
+	Certificate                       string                                      `json:"certificate,omitempty" yaml:"certificate,omitempty"`
+	CertificateKey                    string                                      `json:"certificateKey,omitempty" yaml:"certificateKey,omitempty"`
+	HardErrors                        bool                                        `json:"hardValidation,omitempty" yaml:"hardValidation,omitempty"`
+	HardErrorCode                     int                                         `json:"hardValidationCode,omitempty" yaml:"hardValidationCode,omitempty"`
+	HardErrorReturnCode               int                                         `json:"hardValidationReturnCode,omitempty" yaml:"hardValidationReturnCode,omitempty"`
+	StrictStatusCodes                 bool                                        `json:"strictStatusCodes,omitempty" yaml:"strictStatusCodes,omitempty"`
+	HostValidation                    string                                      `json:"hostValidation,omitempty" yaml:"hostValidation,omitempty"`
+	StatusCodeOverrides               map[int]int                                 `json:"statusCodeOverrides,omitempty" yaml:"statusCodeOverrides,omitempty"`
+	HardErrorsList                    []string                                    `json:"hardValidationList,omitempty" yaml:"hardValidationList,omitempty"`
+	PathDelays                        map[string]int                              `json:"pathDelays,omitempty" yaml:"pathDelays,omitempty"`
+	MockMode                          bool                                        `json:"mockMode,omitempty" yaml:"mockMode,omitempty"`
+	MockModeList                      []string                                    `json:"mockModeList,omitempty" yaml:"mockModeList,omitempty"`
+	DryRun                            bool                                        `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+	StaticMockDir                     string                                      `json:"staticMockDir,omitempty" yaml:"staticMockDir,omitempty"`
+	MockDefinitionsDir                string                                      `json:"mockDefinitionsDir,omitempty" yaml:"mockDefinitionsDir,omitempty"`
+	MockDefinitionFormat              string                                      `json:"mockDefinitionFormat,omitempty" yaml:"mockDefinitionFormat,omitempty"`
+	MockDefinitionsURL                string                                      `json:"mockDefinitionsURL,omitempty" yaml:"mockDefinitionsURL,omitempty"`
+	MockDefinitionsHeaders            map[string]string                           `json:"mockDefinitionsHeaders,omitempty" yaml:"mockDefinitionsHeaders,omitempty"`
+	MockDefinitionsRefreshIntervalSec int                                         `json:"mockDefinitionsRefreshIntervalSec,omitempty" yaml:"mockDefinitionsRefreshIntervalSec,omitempty"`
+	MockDefinitionsGitRepo            string                                      `json:"mockDefinitionsGitRepo,omitempty" yaml:"mockDefinitionsGitRepo,omitempty"`
+	MockDefinitionsGitBranch          string                                      `json:"mockDefinitionsGitBranch,omitempty" yaml:"mockDefinitionsGitBranch,omitempty"`
+	GitSSHKeyFile                     string                                      `json:"gitSSHKeyFile,omitempty" yaml:"gitSSHKeyFile,omitempty"`
+	GitUsername                       string                                      `json:"gitUsername,omitempty" yaml:"gitUsername,omitempty"`
+	GitPassword                       string                                      `json:"gitPassword,omitempty" yaml:"gitPassword,omitempty"`
+	Etcd                              *EtcdConfig                                 `json:"etcd,omitempty" yaml:"etcd,omitempty"`
+	ActiveTags                        []string                                    `json:"activeTags,omitempty" yaml:"activeTags,omitempty"`
+	NormalizeRequestPath              bool                                        `json:"normalizeRequestPath,omitempty" yaml:"normalizeRequestPath,omitempty"`
+	MockCheckWorkers                  int                                         `json:"mockCheckWorkers,omitempty" yaml:"mockCheckWorkers,omitempty"`
+	MockLoadWorkers                   int                                         `json:"mockLoadWorkers,omitempty" yaml:"mockLoadWorkers,omitempty"`
+	UseAllMockResponseFields          bool                                        `json:"useAllMockResponseFields,omitempty" yaml:"useAllMockResponseFields,omitempty"`
+	MockModePretty                    bool                                        `json:"mockModePretty,omitempty" yaml:"mockModePretty,omitempty"`
+	FakerEnabled                      bool                                        `json:"fakerEnabled,omitempty" yaml:"fakerEnabled,omitempty"`
+	MutationTest                      bool                                        `json:"mutationTest,omitempty" yaml:"mutationTest,omitempty"`
+	ValidationSamplingRate            float64                                     `json:"validationSamplingRate,omitempty" yaml:"validationSamplingRate,omitempty"`
+	ValidationWorkers                 int                                         `json:"validationWorkers,omitempty" yaml:"validationWorkers,omitempty"`
+	ValidationSamplingOverrides       map[string]float64                          `json:"validationSamplingOverrides,omitempty" yaml:"validationSamplingOverrides,omitempty"`
+	Base                              string                                      `json:"base,omitempty" yaml:"base,omitempty"`
+	LazySpecLoading                   bool                                        `json:"lazySpecLoading,omitempty" yaml:"lazySpecLoading,omitempty"`
+	HAR                               string                                      `json:"har,omitempty" yaml:"har,omitempty"`
+	HARValidate                       bool                                        `json:"harValidate,omitempty" yaml:"harValidate,omitempty"`
+	HARPathAllowList                  []string                                    `json:"harPathAllowList,omitempty" yaml:"harPathAllowList,omitempty"`
+	StreamReport                      bool                                        `json:"streamReport,omitempty" yaml:"streamReport,omitempty"`
+	ReportFile                        string                                      `json:"reportFilename,omitempty" yaml:"reportFilename,omitempty"`
+	ReportFormat                      string                                      `json:"reportFormat,omitempty" yaml:"reportFormat,omitempty"`
+	ReportEncoding                    string                                      `json:"reportEncoding,omitempty" yaml:"reportEncoding,omitempty"`
+	ReportPrettyPrint                 bool                                        `json:"reportPrettyPrint,omitempty" yaml:"reportPrettyPrint,omitempty"`
+	StreamChannelBufferSize           int                                         `json:"streamChannelBufferSize,omitempty" yaml:"streamChannelBufferSize,omitempty"`
+	TransactionStoreShards            int                                         `json:"transactionStoreShards,omitempty" yaml:"transactionStoreShards,omitempty"`
+	RedisTransactionStore             *RedisConfig                                `json:"redisTransactionStore,omitempty" yaml:"redisTransactionStore,omitempty"`
+	DeduplicateErrors                 bool                                        `json:"deduplicateErrors,omitempty" yaml:"deduplicateErrors,omitempty"`
+	ErrorStreamSamplingRate           float64                                     `json:"errorStreamSamplingRate,omitempty" yaml:"errorStreamSamplingRate,omitempty"`
+	ErrorRateWindow                   int                                         `json:"errorRateWindow,omitempty" yaml:"errorRateWindow,omitempty"`
+	ErrorRateThreshold                float64                                     `json:"errorRateThreshold,omitempty" yaml:"errorRateThreshold,omitempty"`
+	MaxReportFileSizeBytes            int64                                       `json:"maxReportFileSizeBytes,omitempty" yaml:"maxReportFileSizeBytes,omitempty"`
+	CompressRotatedFiles              bool                                        `json:"compressRotatedFiles,omitempty" yaml:"compressRotatedFiles,omitempty"`
+	CompressedExtension               string                                      `json:"compressedExtension,omitempty" yaml:"compressedExtension,omitempty"`
+	MaxRetainedFiles                  int                                         `json:"maxRetainedFiles,omitempty" yaml:"maxRetainedFiles,omitempty"`
+	MaxReportAgeHours                 int                                         `json:"maxReportAgeHours,omitempty" yaml:"maxReportAgeHours,omitempty"`
+	ReportChecksums                   bool                                        `json:"reportChecksums,omitempty" yaml:"reportChecksums,omitempty"`
+	VerifyOnOpen                      bool                                        `json:"verifyOnOpen,omitempty" yaml:"verifyOnOpen,omitempty"`
+	ReportTimezone                    string                                      `json:"reportTimezone,omitempty" yaml:"reportTimezone,omitempty"`
+	RotationUpload                    *UploadConfig                               `json:"rotationUpload,omitempty" yaml:"rotationUpload,omitempty"`
+	JUnitReport                       string                                      `json:"junitReport,omitempty" yaml:"junitReport,omitempty"`
+	HTMLReport                        string                                      `json:"htmlReport,omitempty" yaml:"htmlReport,omitempty"`
+	Notifications                     []*NotificationConfig                       `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+	SMTPNotification                  *SMTPConfig                                 `json:"smtpNotification,omitempty" yaml:"smtpNotification,omitempty"`
+	PagerDuty                         *PagerDutyConfig                            `json:"pagerDuty,omitempty" yaml:"pagerDuty,omitempty"`
+	NATS                              *NATSConfig                                 `json:"nats,omitempty" yaml:"nats,omitempty"`
+	IgnoreRedirects                   []string                                    `json:"ignoreRedirects,omitempty" yaml:"ignoreRedirects,omitempty"`
+	RedirectAllowList                 []string                                    `json:"redirectAllowList,omitempty" yaml:"redirectAllowList,omitempty"`
+	WebsocketConfigs                  map[string]*WiretapWebsocketConfig          `json:"websockets" yaml:"websockets"`
+	IgnoreValidation                  []string                                    `json:"ignoreValidation,omitempty" yaml:"ignoreValidation,omitempty"`
+	ValidationAllowList               []string                                    `json:"validationAllowList,omitempty" yaml:"validationAllowList,omitempty"`
+	AllowedPaths                      []string                                    `json:"allowedPaths,omitempty" yaml:"allowedPaths,omitempty"`
+	BlockedPaths                      []string                                    `json:"blockedPaths,omitempty" yaml:"blockedPaths,omitempty"`
+	BlockedMethods                    []string                                    `json:"blockedMethods,omitempty" yaml:"blockedMethods,omitempty"`
+	MaxRequestHeaderSizeBytes         int                                         `json:"maxRequestHeaderSizeBytes,omitempty" yaml:"maxRequestHeaderSizeBytes,omitempty"`
+	MaxSingleHeaderBytes              int                                         `json:"maxSingleHeaderBytes,omitempty" yaml:"maxSingleHeaderBytes,omitempty"`
+	ForwardClientIP                   bool                                        `json:"forwardClientIP,omitempty" yaml:"forwardClientIP,omitempty"`
+	TrustedProxies                    []string                                    `json:"trustedProxies,omitempty" yaml:"trustedProxies,omitempty"`
+	UseForwardedHeader                bool                                        `json:"useForwardedHeader,omitempty" yaml:"useForwardedHeader,omitempty"`
+	InjectViaHeader                   *bool                                       `json:"injectViaHeader,omitempty" yaml:"injectViaHeader,omitempty"`
+	CustomHopByHopHeaders             []string                                    `json:"customHopByHopHeaders,omitempty" yaml:"customHopByHopHeaders,omitempty"`
+	StripResponseHeaders              []string                                    `json:"stripResponseHeaders,omitempty" yaml:"stripResponseHeaders,omitempty"`
+	StripResponseHeaderPrefixes       []string                                    `json:"stripResponseHeaderPrefixes,omitempty" yaml:"stripResponseHeaderPrefixes,omitempty"`
+	StrictRedirectLocation            bool                                        `json:"strictRedirectLocation,omitempty" yaml:"strictRedirectLocation,omitempty"`
+	IgnorePathRewrite                 []*IgnoreRewriteConfig                      `json:"ignorePathRewrite,omitempty" yaml:"ignorePathRewrite,omitempty"`
+	RedactFields                      []string                                    `json:"redactFields,omitempty" yaml:"redactFields,omitempty"`
+	MaxBodyBytesInReport              int                                         `json:"maxBodyBytesInReport,omitempty" yaml:"maxBodyBytesInReport,omitempty"`
+	AuditLog                          *AuditLogConfig                             `json:"auditLog,omitempty" yaml:"auditLog,omitempty"`
+	FailoverUpstreams                 []*UpstreamConfig                           `json:"failoverUpstreams,omitempty" yaml:"failoverUpstreams,omitempty"`
+	Cache                             *CacheConfig                                `json:"cache,omitempty" yaml:"cache,omitempty"`
+	HashRouting                       *HashRoutingConfig                          `json:"hashRouting,omitempty" yaml:"hashRouting,omitempty"`
+	ContractTest                      *ContractTestConfig                         `json:"contractTest,omitempty" yaml:"contractTest,omitempty"`
+	MaskFields                        []*MaskRule                                 `json:"maskFields,omitempty" yaml:"maskFields,omitempty"`
+	HARFile                           *harhar.HAR                                 `json:"-" yaml:"-"`
+	CompiledMockModeList              []glob.Glob                                 `json:"-" yaml:"-"`
+	CompiledPathDelays                map[string]*CompiledPathDelay               `json:"-" yaml:"-"`
+	CompiledVariables                 map[string]*CompiledVariable                `json:"-" yaml:"-"`
+	Version                           string                                      `json:"-" yaml:"-"`
+	StaticPathsCompiled               []glob.Glob                                 `json:"-" yaml:"-"`
+	CompiledHardErrorList             []glob.Glob                                 `json:"-" yaml:"-"`
+	CompiledPaths                     *orderedmap.Map[string, *CompiledPath]      `json:"-"`
+	CompiledIgnoreRedirects           []*CompiledRedirect                         `json:"-" yaml:"-"`
+	CompiledRedirectAllowList         []*CompiledRedirect                         `json:"-" yaml:"-"`
+	CompiledIgnoreValidations         []*CompiledRedirect                         `json:"-" yaml:"-"`
+	CompiledValidationAllowList       []*CompiledRedirect                         `json:"-" yaml:"-"`
+	CompiledAllowedPaths              []*CompiledRedirect                         `json:"-" yaml:"-"`
+	CompiledBlockedPaths              []*CompiledRedirect                         `json:"-" yaml:"-"`
+	CompiledTrustedProxies            []*net.IPNet                                `json:"-" yaml:"-"`
+	CompiledIgnorePathRewrite         []*CompiledIgnoreRewrite                    `json:"-" yaml:"-"`
+	ReportLocation                    *time.Location                              `json:"-" yaml:"-"`
+	AsyncAPIDocument                  *asyncapi.Document                          `json:"-" yaml:"-"`
+	FS                                embed.FS                                    `json:"-"`
+	Logger                            *slog.Logger
 }
 
 // UnmarshalJSON In order to initialize our ordered maps, we need to create custom un-marshallers.
@@ -206,6 +306,173 @@ func (wtc *WiretapConfiguration) CompileValidationAllowList() {
 	}
 }
 
+func (wtc *WiretapConfiguration) CompileAllowedPaths() {
+	wtc.CompiledAllowedPaths = make([]*CompiledRedirect, 0)
+	for _, x := range wtc.AllowedPaths {
+		compiled := &CompiledRedirect{
+			CompiledPath: glob.MustCompile(wtc.ReplaceWithVariables(x)),
+		}
+		wtc.CompiledAllowedPaths = append(wtc.CompiledAllowedPaths, compiled)
+	}
+}
+
+func (wtc *WiretapConfiguration) CompileBlockedPaths() {
+	wtc.CompiledBlockedPaths = make([]*CompiledRedirect, 0)
+	for _, x := range wtc.BlockedPaths {
+		compiled := &CompiledRedirect{
+			CompiledPath: glob.MustCompile(wtc.ReplaceWithVariables(x)),
+		}
+		wtc.CompiledBlockedPaths = append(wtc.CompiledBlockedPaths, compiled)
+	}
+}
+
+// CompileFailoverUpstreams parses the URL of every failover upstream. An upstream URL must have a scheme and a host.
+func (wtc *WiretapConfiguration) CompileFailoverUpstreams() error {
+	for _, upstream := range wtc.FailoverUpstreams {
+		if err := wtc.compileUpstream("failover", upstream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompileTrustedProxies parses the CIDR range of every trusted proxy. A plain address is trusted on its own.
+func (wtc *WiretapConfiguration) CompileTrustedProxies() error {
+	wtc.CompiledTrustedProxies = make([]*net.IPNet, 0, len(wtc.TrustedProxies))
+	for _, proxy := range wtc.TrustedProxies {
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			wtc.CompiledTrustedProxies = append(wtc.CompiledTrustedProxies,
+				&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("trusted proxy '%s' is not an address or a CIDR range", proxy)
+		}
+		wtc.CompiledTrustedProxies = append(wtc.CompiledTrustedProxies, network)
+	}
+	return nil
+}
+
+// TrustedProxy is true when the address is within a trusted proxy range.
+func (wtc *WiretapConfiguration) TrustedProxy(ip net.IP) bool {
+	for _, network := range wtc.CompiledTrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateNotifications checks that every notification has a known type and an absolute webhook URL.
+func (wtc *WiretapConfiguration) ValidateNotifications() error {
+	for _, notification := range wtc.Notifications {
+		switch notification.Type {
+		case NotificationSlack, NotificationTeams:
+		default:
+			return fmt.Errorf("notification type must be '%s' or '%s', not '%s'",
+				NotificationSlack, NotificationTeams, notification.Type)
+		}
+		u, err := url.Parse(notification.WebhookURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s notification webhook URL must be an absolute URL", notification.Type)
+		}
+		if notification.MinimumErrorCount < 0 || notification.WindowSeconds < 0 {
+			return fmt.Errorf("%s notification minimum error count and window cannot be negative", notification.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateSMTPNotification checks that the email notification has a server, a sender and at least one recipient.
+func (wtc *WiretapConfiguration) ValidateSMTPNotification() error {
+	smtp := wtc.SMTPNotification
+	if smtp == nil {
+		return nil
+	}
+	if smtp.Host == "" {
+		return fmt.Errorf("email notification requires an SMTP host")
+	}
+	if smtp.From == "" || len(smtp.To) == 0 {
+		return fmt.Errorf("email notification requires a sender and at least one recipient")
+	}
+	if smtp.Port < 0 || smtp.MinErrors < 0 || smtp.WindowSeconds < 0 || smtp.MinIntervalSeconds < 0 {
+		return fmt.Errorf("email notification port, minimum errors, window and interval cannot be negative")
+	}
+	return nil
+}
+
+// CompileHashRouting parses the JSONPath expression and the URL of every upstream of the hash routing configuration.
+func (wtc *WiretapConfiguration) CompileHashRouting() error {
+	if wtc.HashRouting == nil {
+		return nil
+	}
+	expr, err := jp.ParseString(wtc.HashRouting.JSONPath)
+	if err != nil {
+		return fmt.Errorf("hash routing JSONPath '%s' cannot be parsed: %w", wtc.HashRouting.JSONPath, err)
+	}
+	if len(wtc.HashRouting.Upstreams) == 0 {
+		return fmt.Errorf("hash routing requires at least one upstream")
+	}
+	for _, upstream := range wtc.HashRouting.Upstreams {
+		if err = wtc.compileUpstream("hash routing", upstream); err != nil {
+			return err
+		}
+	}
+	wtc.HashRouting.CompiledPath = expr
+	return nil
+}
+
+// CompileMaskFields parses the JSONPath expression of every mask rule.
+func (wtc *WiretapConfiguration) CompileMaskFields() error {
+	for _, rule := range wtc.MaskFields {
+		expr, err := jp.ParseString(rule.JSONPath)
+		if err != nil {
+			return fmt.Errorf("mask field JSONPath '%s' cannot be parsed: %w", rule.JSONPath, err)
+		}
+		rule.CompiledPath = expr
+	}
+	return nil
+}
+
+// CompileReportTimezone loads the IANA time zone report timestamps are written in, UTC when none is configured.
+func (wtc *WiretapConfiguration) CompileReportTimezone() error {
+	if wtc.ReportTimezone == "" {
+		wtc.ReportLocation = time.UTC
+		return nil
+	}
+	loc, err := time.LoadLocation(wtc.ReportTimezone)
+	if err != nil {
+		return fmt.Errorf("report timezone '%s' cannot be loaded: %w", wtc.ReportTimezone, err)
+	}
+	wtc.ReportLocation = loc
+	return nil
+}
+
+// ReportTime returns a time in the report time zone, UTC if it has not been loaded.
+func (wtc *WiretapConfiguration) ReportTime(t time.Time) time.Time {
+	if wtc == nil || wtc.ReportLocation == nil {
+		return t.UTC()
+	}
+	return t.In(wtc.ReportLocation)
+}
+
+func (wtc *WiretapConfiguration) compileUpstream(kind string, upstream *UpstreamConfig) error {
+	parsed, err := url.Parse(wtc.ReplaceWithVariables(upstream.URL))
+	if err != nil {
+		return fmt.Errorf("%s upstream '%s' cannot be parsed: %w", kind, upstream.URL, err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("%s upstream '%s' requires a scheme and a host", kind, upstream.URL)
+	}
+	upstream.ParsedURL = parsed
+	return nil
+}
+
 func (wtc *WiretapConfiguration) ReplaceWithVariables(input string) string {
 	for x := range wtc.Variables {
 		if wtc.Variables[x] != "" && wtc.CompiledVariables[x] != nil {
@@ -216,6 +483,13 @@ func (wtc *WiretapConfiguration) ReplaceWithVariables(input string) string {
 	return input
 }
 
+// StaticMocksEnabled returns true if static mock definitions are configured, either through the static mock
+// directory, a dedicated mock definitions directory, a remote mock definitions URL, a git repository or etcd.
+func (wtc *WiretapConfiguration) StaticMocksEnabled() bool {
+	return len(wtc.StaticMockDir) != 0 || len(wtc.MockDefinitionsDir) != 0 || len(wtc.MockDefinitionsURL) != 0 ||
+		len(wtc.MockDefinitionsGitRepo) != 0 || (wtc.Etcd != nil && len(wtc.Etcd.Endpoints) != 0)
+}
+
 func (wtc *WiretapConfiguration) GetHttpProtocol() string {
 	protocol := "http"
 
@@ -230,8 +504,28 @@ func (wtc *WiretapConfiguration) GetApiGateway() string {
 	return fmt.Sprintf("%s://%s", wtc.GetHttpProtocol(), wtc.GetApiGatewayHost())
 }
 
+// GetListenAddress returns the address the proxy listens on, every interface unless a listen address is configured.
+func (wtc *WiretapConfiguration) GetListenAddress() string {
+	return net.JoinHostPort(wtc.ListenAddress, wtc.Port)
+}
+
+// AdminServedSeparately returns true if the admin API is served on its own port or unix socket, rather than
+// alongside the proxy.
+func (wtc *WiretapConfiguration) AdminServedSeparately() bool {
+	return wtc.AdminListenPort > 0 || wtc.AdminUnixSocket != ""
+}
+
+// GetAdminListenAddress returns the address the admin API listens on, when it is served separately.
+func (wtc *WiretapConfiguration) GetAdminListenAddress() string {
+	return net.JoinHostPort(wtc.AdminListenAddress, strconv.Itoa(wtc.AdminListenPort))
+}
+
 func (wtc *WiretapConfiguration) GetApiGatewayHost() string {
-	return fmt.Sprintf("localhost:%s", wtc.Port)
+	host := "localhost"
+	if ip := net.ParseIP(wtc.ListenAddress); wtc.ListenAddress != "" && (ip == nil || !ip.IsUnspecified()) {
+		host = wtc.ListenAddress
+	}
+	return net.JoinHostPort(host, wtc.Port)
 }
 
 func (wtc *WiretapConfiguration) GetMonitorUI() string {
@@ -294,6 +588,156 @@ type CompiledRedirect struct {
 	CompiledPath glob.Glob
 }
 
+const (
+	AuditLogFormatJSON = "json"
+	AuditLogFormatCEF  = "cef"
+	AuditLogFormatLEEF = "leef"
+)
+
+type AuditLogConfig struct {
+	Enabled bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	File    string `json:"file,omitempty" yaml:"file,omitempty"`
+	Format  string `json:"format,omitempty" yaml:"format,omitempty"`
+}
+
+// EtcdConfig synchronizes mock definitions between wiretap instances through etcd. Definition sets changed with the
+// admin API are written under the prefix, and every instance watches the prefix for changes. TLS is used for https
+// endpoints, or when a certificate is configured. When LeaseTTLSeconds is set, the sets an instance published are
+// removed from etcd when the instance stops, or once it has been unreachable for that long.
+type EtcdConfig struct {
+	Endpoints       []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	Prefix          string   `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Username        string   `json:"username,omitempty" yaml:"username,omitempty"`
+	Password        string   `json:"password,omitempty" yaml:"password,omitempty"`
+	CACertFile      string   `json:"caCertFile,omitempty" yaml:"caCertFile,omitempty"`
+	CertFile        string   `json:"certFile,omitempty" yaml:"certFile,omitempty"`
+	KeyFile         string   `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	DialTimeoutMs   int      `json:"dialTimeoutMs,omitempty" yaml:"dialTimeoutMs,omitempty"`
+	LeaseTTLSeconds int      `json:"leaseTTLSeconds,omitempty" yaml:"leaseTTLSeconds,omitempty"`
+}
+
+// RedisConfig stores captured transactions in Redis, so wiretap instances sharing the same Redis share a single view
+// of their transactions. Transactions expire after TransactionTTLSeconds, one hour by default. The pool keeps up to
+// PoolSize connections open (10 by default), of which up to MaxIdleConns are kept when idle.
+type RedisConfig struct {
+	Address               string `json:"address,omitempty" yaml:"address,omitempty"`
+	Username              string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password              string `json:"password,omitempty" yaml:"password,omitempty"`
+	DB                    int    `json:"db,omitempty" yaml:"db,omitempty"`
+	KeyPrefix             string `json:"keyPrefix,omitempty" yaml:"keyPrefix,omitempty"`
+	TransactionTTLSeconds int    `json:"transactionTTLSeconds,omitempty" yaml:"transactionTTLSeconds,omitempty"`
+	PoolSize              int    `json:"poolSize,omitempty" yaml:"poolSize,omitempty"`
+	MaxIdleConns          int    `json:"maxIdleConns,omitempty" yaml:"maxIdleConns,omitempty"`
+	DialTimeoutMs         int    `json:"dialTimeoutMs,omitempty" yaml:"dialTimeoutMs,omitempty"`
+	ReadTimeoutMs         int    `json:"readTimeoutMs,omitempty" yaml:"readTimeoutMs,omitempty"`
+	WriteTimeoutMs        int    `json:"writeTimeoutMs,omitempty" yaml:"writeTimeoutMs,omitempty"`
+	PoolTimeoutMs         int    `json:"poolTimeoutMs,omitempty" yaml:"poolTimeoutMs,omitempty"`
+}
+
+// CacheConfig switches on caching of upstream responses, following the caching rules of RFC 7234.
+type CacheConfig struct {
+	Enabled    bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	MaxEntries int  `json:"maxEntries,omitempty" yaml:"maxEntries,omitempty"`
+}
+
+// HashRoutingConfig sends requests to one of a set of upstreams, chosen by hashing the value of a field in the JSON
+// request body. The same value is always routed to the same upstream, so upstreams can be used as shards.
+type HashRoutingConfig struct {
+	JSONPath     string            `json:"jsonPath,omitempty" yaml:"jsonPath,omitempty"`
+	Upstreams    []*UpstreamConfig `json:"upstreams,omitempty" yaml:"upstreams,omitempty"`
+	CompiledPath jp.Expr           `json:"-" yaml:"-"`
+}
+
+// MaskRule masks the values matched by a JSONPath expression in static mock response bodies. Values are replaced
+// with MaskWith, or with a format preserving fake value of the same shape when MaskWith is empty.
+type MaskRule struct {
+	JSONPath     string  `json:"jsonPath,omitempty" yaml:"jsonPath,omitempty"`
+	MaskWith     string  `json:"maskWith,omitempty" yaml:"maskWith,omitempty"`
+	CompiledPath jp.Expr `json:"-" yaml:"-"`
+}
+
+// ContractTestConfig compares two versions of a specification, by validating a recorded session (HAR file) against
+// both and reporting the differences. Reports are written as JUnit XML and JSON.
+type ContractTestConfig struct {
+	BaselineSpec  string `json:"baselineSpec,omitempty" yaml:"baselineSpec,omitempty"`
+	CandidateSpec string `json:"candidateSpec,omitempty" yaml:"candidateSpec,omitempty"`
+	JUnitReport   string `json:"junitReport,omitempty" yaml:"junitReport,omitempty"`
+	JSONReport    string `json:"jsonReport,omitempty" yaml:"jsonReport,omitempty"`
+}
+
+const (
+	NotificationSlack = "slack"
+	NotificationTeams = "teams"
+)
+
+// NotificationConfig posts a summary of validation errors to a Slack or Teams webhook. Errors are batched over a
+// window, a message is only posted when at least MinimumErrorCount errors were found in the window.
+type NotificationConfig struct {
+	Type              string `json:"type,omitempty" yaml:"type,omitempty"`
+	WebhookURL        string `json:"webhookURL,omitempty" yaml:"webhookURL,omitempty"`
+	MinimumErrorCount int    `json:"minimumErrorCount,omitempty" yaml:"minimumErrorCount,omitempty"`
+	WindowSeconds     int    `json:"windowSeconds,omitempty" yaml:"windowSeconds,omitempty"`
+}
+
+// SMTPConfig emails a summary of validation errors, when more than MinErrors errors are found within WindowSeconds.
+// Emails are sent at most once every MinIntervalSeconds, errors found in between are included in the next email.
+type SMTPConfig struct {
+	Host               string   `json:"host,omitempty" yaml:"host,omitempty"`
+	Port               int      `json:"port,omitempty" yaml:"port,omitempty"`
+	Username           string   `json:"username,omitempty" yaml:"username,omitempty"`
+	Password           string   `json:"password,omitempty" yaml:"password,omitempty"`
+	From               string   `json:"from,omitempty" yaml:"from,omitempty"`
+	To                 []string `json:"to,omitempty" yaml:"to,omitempty"`
+	Subject            string   `json:"subject,omitempty" yaml:"subject,omitempty"`
+	MinErrors          int      `json:"minErrors,omitempty" yaml:"minErrors,omitempty"`
+	WindowSeconds      int      `json:"windowSeconds,omitempty" yaml:"windowSeconds,omitempty"`
+	MinIntervalSeconds int      `json:"minIntervalSeconds,omitempty" yaml:"minIntervalSeconds,omitempty"`
+}
+
+// PagerDutyConfig triggers a PagerDuty incident when a window has validation errors, the incident is resolved by the
+// next window without errors. The instance name identifies this wiretap in the incident, the host name by default.
+type PagerDutyConfig struct {
+	IntegrationKey          string `json:"integrationKey,omitempty" yaml:"integrationKey,omitempty"`
+	AlertOnValidationErrors bool   `json:"alertOnValidationErrors,omitempty" yaml:"alertOnValidationErrors,omitempty"`
+	InstanceName            string `json:"instanceName,omitempty" yaml:"instanceName,omitempty"`
+	WindowSeconds           int    `json:"windowSeconds,omitempty" yaml:"windowSeconds,omitempty"`
+}
+
+// NATSConfig publishes every validation error found to a NATS subject, as well as broadcasting it to the monitor UI.
+// The server URL may list several servers, separated by commas, and may carry a user and password, or a token, to
+// authenticate with. A credentials file or an nkey seed file authenticate with NATS decentralized auth instead.
+// tls:// URLs, or a configured certificate, use TLS. The subject is 'wiretap.validation' by default.
+type NATSConfig struct {
+	ServerURL       string `json:"serverURL,omitempty" yaml:"serverURL,omitempty"`
+	Subject         string `json:"subject,omitempty" yaml:"subject,omitempty"`
+	CredentialsFile string `json:"credentialsFile,omitempty" yaml:"credentialsFile,omitempty"`
+	NKeySeedFile    string `json:"nkeySeedFile,omitempty" yaml:"nkeySeedFile,omitempty"`
+	CACertFile      string `json:"caCertFile,omitempty" yaml:"caCertFile,omitempty"`
+	CertFile        string `json:"certFile,omitempty" yaml:"certFile,omitempty"`
+	KeyFile         string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+}
+
+// UploadConfig uploads rotated report files to an S3 or GCS bucket. Endpoint replaces the default endpoint of the
+// store, for S3 compatible stores. The local file is deleted after a successful upload when DeleteLocal is set.
+// S3 credentials are found the way the AWS CLI finds them, Profile selects a profile of the shared configuration.
+// GCS uses the application default credentials, unless CredentialsFile names a service account key file.
+type UploadConfig struct {
+	Type            string `json:"type,omitempty" yaml:"type,omitempty"`
+	Bucket          string `json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	Prefix          string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	DeleteLocal     bool   `json:"deleteLocal,omitempty" yaml:"deleteLocal,omitempty"`
+	Region          string `json:"region,omitempty" yaml:"region,omitempty"`
+	Endpoint        string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Profile         string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	CredentialsFile string `json:"credentialsFile,omitempty" yaml:"credentialsFile,omitempty"`
+}
+
+// UpstreamConfig is an API that requests can be sent to instead of the redirect URL, by failover or hash routing.
+type UpstreamConfig struct {
+	URL       string   `json:"url,omitempty" yaml:"url,omitempty"`
+	ParsedURL *url.URL `json:"-" yaml:"-"`
+}
+
 type WiretapHeaderConfig struct {
 	DropHeaders    []string          `json:"drop,omitempty" yaml:"drop,omitempty"`
 	InjectHeaders  map[string]string `json:"inject,omitempty" yaml:"inject,omitempty"`
@@ -0,0 +1,42 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWiretapConfiguration_ListenAddresses(t *testing.T) {
+	tests := []struct {
+		listenAddress string
+		listen        string
+		gateway       string
+	}{
+		// every interface, the gateway is reached on localhost.
+		{"", ":9090", "localhost:9090"},
+		{"0.0.0.0", "0.0.0.0:9090", "localhost:9090"},
+		{"::", "[::]:9090", "localhost:9090"},
+		{"127.0.0.1", "127.0.0.1:9090", "127.0.0.1:9090"},
+		{"::1", "[::1]:9090", "[::1]:9090"},
+		{"wiretap.local", "wiretap.local:9090", "wiretap.local:9090"},
+	}
+	for _, test := range tests {
+		config := &WiretapConfiguration{ListenAddress: test.listenAddress, Port: "9090"}
+		assert.Equal(t, test.listen, config.GetListenAddress(), test.listenAddress)
+		assert.Equal(t, test.gateway, config.GetApiGatewayHost(), test.listenAddress)
+	}
+}
+
+func TestWiretapConfiguration_AdminServedSeparately(t *testing.T) {
+	assert.False(t, (&WiretapConfiguration{AdminListenAddress: "127.0.0.1"}).AdminServedSeparately())
+	assert.True(t, (&WiretapConfiguration{AdminUnixSocket: "/tmp/wiretap.sock"}).AdminServedSeparately())
+
+	config := &WiretapConfiguration{AdminListenPort: 9091}
+	assert.True(t, config.AdminServedSeparately())
+	assert.Equal(t, ":9091", config.GetAdminListenAddress())
+	config.AdminListenAddress = "::1"
+	assert.Equal(t, "[::1]:9091", config.GetAdminListenAddress())
+}
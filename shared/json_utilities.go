@@ -5,15 +5,43 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pterm/pterm"
 )
 
+// RegexSigil is the prefix that marks a string value in a mock definition as an explicit regular expression.
+// For example, `{"email": "~.*@example\\.com"}` will match any email address at example.com. The sigil is
+// stripped before the pattern is compiled. Values without the sigil are still checked for regex characters
+// and compared as a regex when they compile, so existing mock definitions continue to work.
+const RegexSigil = "~"
+
 var (
 	rePathSplit    = regexp.MustCompile(`\.`)
 	reTemplateVars = regexp.MustCompile(`\$\{([a-zA-Z0-9._\[\]]+)\}`)
+
+	// regexCache holds compiled patterns, keyed by the pattern string, so mock matching does not
+	// recompile the same expression on every request.
+	regexCache sync.Map
 )
 
+type cachedRegex struct {
+	rex *regexp.Regexp
+	err error
+}
+
+// compileRegex compiles a pattern, or returns the previously compiled result from the cache.
+// Failed compilations are cached as well, so an invalid pattern is only parsed once.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexCache.Load(pattern); ok {
+		c := cached.(*cachedRegex)
+		return c.rex, c.err
+	}
+	rex, err := regexp.Compile(pattern)
+	regexCache.Store(pattern, &cachedRegex{rex: rex, err: err})
+	return rex, err
+}
+
 // IsSubset method to check if json (interface{}) is a subset of a superSet
 func IsSubset(json, superSet interface{}) bool {
 	// Check if json is an object
@@ -42,6 +70,14 @@ func IsSubset(json, superSet interface{}) bool {
 			return StringCompare(str, ssStr)
 		}
 
+		// an explicit regex can also be matched against numbers and booleans, e.g. "~^[0-9]{3}$"
+		if strings.HasPrefix(str, RegexSigil) {
+			switch superSet.(type) {
+			case float64, bool:
+				return StringCompare(str, fmt.Sprintf("%v", superSet))
+			}
+		}
+
 		// if json is a string and superSet is not, then it can't be a subset
 		return false
 	}
@@ -99,27 +135,73 @@ func isPotentialRegex(s string) bool {
 	return false
 }
 
+// StringCompareOptions changes how StringCompareWithOptions compares strings.
+type StringCompareOptions struct {
+	// CaseInsensitive ignores case, for both literal strings and regular expressions.
+	CaseInsensitive bool
+}
+
 // StringCompare Helper function to compare strings. Since mock definitions support
 // regex, we need to check if the string is a regex and if so, compare it
 func StringCompare(patternOrStr, str string) bool {
-	// Try to compile the pattern to check if it's a valid regex
-	if isPotentialRegex(patternOrStr) {
-		if _, err := regexp.Compile(patternOrStr); err != nil {
-			// If it's not a valid regex, do a normal string comparison
-			return patternOrStr == str
+	return StringCompareWithOptions(patternOrStr, str, StringCompareOptions{})
+}
+
+// StringCompareWithOptions compares strings as StringCompare does, with the comparison changed by the options.
+func StringCompareWithOptions(patternOrStr, str string, opts StringCompareOptions) bool {
+	equal := func() bool {
+		if opts.CaseInsensitive {
+			return strings.EqualFold(patternOrStr, str)
 		}
+		return patternOrStr == str
+	}
+	compile := func(pattern string) (*regexp.Regexp, error) {
+		if opts.CaseInsensitive {
+			pattern = "(?i)" + pattern
+		}
+		return compileRegex(pattern)
+	}
 
-		// If it's a valid regex, check if it matches the string
-		matched, err := regexp.MatchString(patternOrStr, str)
+	// An explicit regex, marked with the sigil, must compile. An invalid pattern never matches.
+	if strings.HasPrefix(patternOrStr, RegexSigil) {
+		rex, err := compile(strings.TrimPrefix(patternOrStr, RegexSigil))
 		if err != nil {
 			return false
 		}
+		return rex.MatchString(str)
+	}
 
-		return matched
+	// Try to compile the pattern to check if it's a valid regex
+	if isPotentialRegex(patternOrStr) {
+		rex, err := compile(patternOrStr)
+		if err != nil {
+			// If it's not a valid regex, do a normal string comparison
+			return equal()
+		}
+
+		// If it's a valid regex, check if it matches the string
+		return rex.MatchString(str)
 	}
 
 	// If it's not a regex, do a normal string comparison
-	return patternOrStr == str
+	return equal()
+}
+
+// CheckPattern reports how StringCompare treats a value from a mock definition. isRegex is true when the value is
+// matched as a regular expression. An error is returned when the value is marked as a regex, or looks like one,
+// but does not compile; a marked regex then never matches, an unmarked one is compared as a literal string.
+func CheckPattern(patternOrStr string) (isRegex bool, err error) {
+	if strings.HasPrefix(patternOrStr, RegexSigil) {
+		_, err = compileRegex(strings.TrimPrefix(patternOrStr, RegexSigil))
+		return true, err
+	}
+	if isPotentialRegex(patternOrStr) {
+		if _, err = compileRegex(patternOrStr); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
 }
 
 // getValueByPath Helper function to get the value based on a JSON path (dot notation or array index).
@@ -0,0 +1,56 @@
+// Copyright 2024 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package shared
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringCompare_RegexSigil(t *testing.T) {
+	assert.True(t, StringCompare(`~.*@example\.com`, "dave@example.com"))
+	assert.False(t, StringCompare(`~.*@example\.com`, "dave@example.org"))
+
+	// the sigil forces regex comparison, an invalid pattern never matches.
+	assert.False(t, StringCompare(`~[unclosed`, "[unclosed"))
+
+	// without the sigil, an invalid pattern falls back to a plain comparison.
+	assert.True(t, StringCompare(`[unclosed`, "[unclosed"))
+	assert.True(t, StringCompare("plain", "plain"))
+}
+
+func TestIsSubset_RegexSigil(t *testing.T) {
+	var mock, incoming interface{}
+	_ = json.Unmarshal([]byte(`{"email": "~.*@example\\.com", "age": "~^[0-9]{2}$"}`), &mock)
+
+	_ = json.Unmarshal([]byte(`{"email": "dave@example.com", "age": 42, "name": "dave"}`), &incoming)
+	assert.True(t, IsSubset(mock, incoming))
+
+	_ = json.Unmarshal([]byte(`{"email": "dave@example.com", "age": 142}`), &incoming)
+	assert.False(t, IsSubset(mock, incoming))
+}
+
+func TestCompileRegex_Cached(t *testing.T) {
+	a, err := compileRegex("^cached$")
+	assert.NoError(t, err)
+	b, _ := compileRegex("^cached$")
+	assert.Same(t, a, b)
+
+	_, err = compileRegex("(")
+	assert.Error(t, err)
+	_, err = compileRegex("(")
+	assert.Error(t, err)
+}
+
+func TestStringCompareWithOptions_CaseInsensitive(t *testing.T) {
+	insensitive := StringCompareOptions{CaseInsensitive: true}
+	assert.False(t, StringCompare("/Pets", "/pets"))
+	assert.True(t, StringCompareWithOptions("/Pets", "/pets", insensitive))
+	assert.True(t, StringCompareWithOptions("~^/PETS/[0-9]+$", "/pets/42", insensitive))
+	assert.True(t, StringCompareWithOptions("/pets/[a-z]+", "/pets/DOG", insensitive))
+	assert.True(t, StringCompareWithOptions("[Unclosed", "[unclosed", insensitive))
+	assert.False(t, StringCompareWithOptions("/pets", "/dogs", insensitive))
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// RedactedValue replaces the value of every redacted field.
+const RedactedValue = "[REDACTED]"
+
+// RedactJSON replaces the values of any JSON object keys listed in fields with RedactedValue, at any depth. Keys are
+// matched case-insensitively. If the body is not JSON, or nothing was redacted, the body is returned untouched.
+func RedactJSON(body string, fields []string) string {
+	if len(fields) == 0 || body == "" {
+		return body
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var parsed interface{}
+	if err := decoder.Decode(&parsed); err != nil {
+		return body
+	}
+
+	if !redactValue(parsed, fields) {
+		return body
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(parsed); err != nil {
+		return body
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactValue walks a decoded JSON value and redacts matching keys in place, returning true if anything changed.
+func redactValue(value interface{}, fields []string) bool {
+	redacted := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key := range v {
+			if isRedactedField(key, fields) {
+				v[key] = RedactedValue
+				redacted = true
+				continue
+			}
+			redacted = redactValue(v[key], fields) || redacted
+		}
+	case []interface{}:
+		for i := range v {
+			redacted = redactValue(v[i], fields) || redacted
+		}
+	}
+	return redacted
+}
+
+func isRedactedField(key string, fields []string) bool {
+	for _, field := range fields {
+		if strings.EqualFold(key, field) {
+			return true
+		}
+	}
+	return false
+}
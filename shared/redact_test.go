@@ -0,0 +1,28 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: AGPL
+
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactJSON(t *testing.T) {
+	fields := []string{"password", "SSN"}
+
+	body := `{"user":"dave","password":"hunter2","profile":{"ssn":"123-45-6789","age":40},"items":[{"Password":"x"}]}`
+	assert.JSONEq(t,
+		`{"user":"dave","password":"[REDACTED]","profile":{"ssn":"[REDACTED]","age":40},"items":[{"Password":"[REDACTED]"}]}`,
+		RedactJSON(body, fields))
+
+	// untouched bodies are returned as is.
+	assert.Equal(t, `{ "user": "dave" }`, RedactJSON(`{ "user": "dave" }`, fields))
+	assert.Equal(t, "password=hunter2", RedactJSON("password=hunter2", fields))
+	assert.Equal(t, body, RedactJSON(body, nil))
+
+	// large numbers keep their precision.
+	assert.Equal(t, `{"id":12345678901234567890,"password":"[REDACTED]"}`,
+		RedactJSON(`{"id":12345678901234567890,"password":"a"}`, fields))
+}
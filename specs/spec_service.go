@@ -4,6 +4,8 @@
 package specs
 
 import (
+	"sync"
+
 	"github.com/pb33f/libopenapi"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 	"github.com/pb33f/ranch/model"
@@ -16,6 +18,7 @@ const (
 )
 
 type SpecService struct {
+	lock        sync.RWMutex
 	document    libopenapi.Document
 	docModel    *v3.Document
 	serviceCore service.FabricServiceCore
@@ -23,14 +26,22 @@ type SpecService struct {
 
 func NewSpecService(document libopenapi.Document) *SpecService {
 	ss := &SpecService{}
-	if document != nil {
-		m, _ := document.BuildV3Model()
-		ss.document = document
-		ss.docModel = &m.Model
-	}
+	ss.SetDocument(document)
 	return ss
 }
 
+// SetDocument sets the specification served, used when the specification is loaded in the background.
+func (ss *SpecService) SetDocument(document libopenapi.Document) {
+	if document == nil {
+		return
+	}
+	m, _ := document.BuildV3Model()
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	ss.document = document
+	ss.docModel = &m.Model
+}
+
 func (ss *SpecService) HandleServiceRequest(request *model.Request, core service.FabricServiceCore) {
 	switch request.RequestCommand {
 	case GetCurrentSpecRequest:
@@ -41,8 +52,11 @@ func (ss *SpecService) HandleServiceRequest(request *model.Request, core service
 }
 
 func (ss *SpecService) handleGetCurrentSpec(request *model.Request, core service.FabricServiceCore) {
-	if ss.document != nil {
-		core.SendResponse(request, ss.document.GetSpecInfo().SpecBytes)
+	ss.lock.RLock()
+	document := ss.document
+	ss.lock.RUnlock()
+	if document != nil {
+		core.SendResponse(request, document.GetSpecInfo().SpecBytes)
 	} else {
 		core.SendResponse(request, []byte("no-spec"))
 	}
@@ -0,0 +1,138 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+const (
+	AdminApiBasePath             = "/wiretap/mocks"
+	AdminApiTransactionsBasePath = "/wiretap/transactions"
+)
+
+type MockResetResponse struct {
+	Reset int `json:"reset"`
+}
+
+// MockDefinitionResponse is a mock definition listed by the admin API, along with the number of requests it served.
+type MockDefinitionResponse struct {
+	StaticMockDefinition
+	HitCount int64 `json:"hitCount"`
+}
+
+// RegisterAdminRoutes adds the static mock admin endpoints to the mux.
+func (sms *StaticMockService) RegisterAdminRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(fmt.Sprintf("GET %s", AdminApiBasePath), sms.handleListMocks)
+	mux.HandleFunc(fmt.Sprintf("POST %s", AdminApiBasePath), sms.handleAddMock)
+	mux.HandleFunc(fmt.Sprintf("PUT %s/{id}", AdminApiBasePath), sms.handleReplaceMock)
+	mux.HandleFunc(fmt.Sprintf("DELETE %s/{id}", AdminApiBasePath), sms.handleDeleteMock)
+	mux.HandleFunc(fmt.Sprintf("POST %s/reset", AdminApiBasePath), sms.handleResetMocks)
+	mux.HandleFunc(fmt.Sprintf("POST %s", AdminImportFromSpecPath), sms.handleImportFromSpec)
+	mux.HandleFunc(fmt.Sprintf("POST %s/{id}/reset", AdminApiBasePath), sms.handleResetMock)
+	mux.HandleFunc(fmt.Sprintf("POST %s/{id}/export-mock", AdminApiTransactionsBasePath), sms.handleExportMock)
+}
+
+// handleListMocks returns every active mock definition, and how many requests each has served.
+func (sms *StaticMockService) handleListMocks(w http.ResponseWriter, _ *http.Request) {
+	definitions := sms.ListMockDefinitions()
+	listed := make([]MockDefinitionResponse, len(definitions))
+	for i := range definitions {
+		listed[i] = MockDefinitionResponse{StaticMockDefinition: definitions[i], HitCount: definitions[i].HitCount()}
+	}
+	writeAdminResponse(w, http.StatusOK, listed)
+}
+
+// handleAddMock adds a new mock definition.
+func (sms *StaticMockService) handleAddMock(w http.ResponseWriter, r *http.Request) {
+	definition, ok := decodeMockDefinition(w, r)
+	if !ok {
+		return
+	}
+	if err := sms.AddMockDefinition(definition, isPersistRequested(r)); err != nil {
+		writeAdminError(w, mockManagementErrorStatus(err), "Unable to add mock definition", err.Error(), r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, http.StatusCreated, definition)
+}
+
+// handleReplaceMock replaces an existing mock definition.
+func (sms *StaticMockService) handleReplaceMock(w http.ResponseWriter, r *http.Request) {
+	definition, ok := decodeMockDefinition(w, r)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	if err := sms.ReplaceMockDefinition(id, definition, isPersistRequested(r)); err != nil {
+		writeAdminError(w, mockManagementErrorStatus(err), "Unable to replace mock definition", err.Error(), r.URL.Path)
+		return
+	}
+	if definition.Id == "" {
+		definition.Id = id
+	}
+	writeAdminResponse(w, http.StatusOK, definition)
+}
+
+// handleDeleteMock removes a mock definition.
+func (sms *StaticMockService) handleDeleteMock(w http.ResponseWriter, r *http.Request) {
+	if err := sms.DeleteMockDefinition(r.PathValue("id"), isPersistRequested(r)); err != nil {
+		writeAdminError(w, mockManagementErrorStatus(err), "Unable to delete mock definition", err.Error(), r.URL.Path)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeMockDefinition reads a mock definition from the request body, writing an error response if it is invalid.
+func decodeMockDefinition(w http.ResponseWriter, r *http.Request) (StaticMockDefinition, bool) {
+	var definition StaticMockDefinition
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&definition); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "Invalid mock definition", err.Error(), r.URL.Path)
+		return definition, false
+	}
+	return definition, true
+}
+
+// isPersistRequested checks if the admin request asks for changes to be written back to the mock definition files.
+func isPersistRequested(r *http.Request) bool {
+	persist, _ := strconv.ParseBool(r.URL.Query().Get("persist"))
+	return persist
+}
+
+// handleResetMocks resets the state of every mock definition.
+func (sms *StaticMockService) handleResetMocks(w http.ResponseWriter, _ *http.Request) {
+	writeAdminResponse(w, http.StatusOK, &MockResetResponse{Reset: sms.ResetMocks()})
+}
+
+// handleResetMock resets the state of a single mock definition, looked up by ID.
+func (sms *StaticMockService) handleResetMock(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	reset := sms.ResetMock(id)
+	if reset == 0 {
+		writeAdminError(w, http.StatusNotFound, "Mock definition not found",
+			fmt.Sprintf("no mock definition with the id '%s' exists", id), r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, http.StatusOK, &MockResetResponse{Reset: reset})
+}
+
+func writeAdminResponse(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, title, detail, instance string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_, _ = w.Write(shared.MarshalError(shared.GenerateError(title, status, detail, instance, nil)))
+}
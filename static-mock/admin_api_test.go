@@ -0,0 +1,128 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStaticMockService(definitions ...StaticMockDefinition) *StaticMockService {
+	for i := range definitions {
+		definitions[i].hits = &atomic.Int64{}
+	}
+	return &StaticMockService{
+		logger:            slog.New(slog.NewTextHandler(os.Stderr, nil)),
+		config:            &shared.WiretapConfiguration{},
+		definitionsByFile: map[string][]StaticMockDefinition{"mocks.json": definitions},
+		mockDefinitions:   definitions,
+		mockIndex:         buildMockIndex(definitions),
+	}
+}
+
+func TestStaticMockService_ResetEndpoints(t *testing.T) {
+	sms := newTestStaticMockService(StaticMockDefinition{Id: "one"}, StaticMockDefinition{Id: "two"})
+	for i := range sms.mockDefinitions {
+		sms.mockDefinitions[i].recordHit()
+		sms.mockDefinitions[i].recordHit()
+	}
+
+	mux := http.NewServeMux()
+	sms.RegisterAdminRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/wiretap/mocks/one/reset", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(0), sms.mockDefinitions[0].HitCount())
+	assert.Equal(t, int64(2), sms.mockDefinitions[1].HitCount())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/wiretap/mocks/missing/reset", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/wiretap/mocks/reset", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"reset": 2}`, rec.Body.String())
+	assert.Equal(t, int64(0), sms.mockDefinitions[1].HitCount())
+}
+
+func TestStaticMockService_ListMocksHitCount(t *testing.T) {
+	sms := newTestStaticMockService(StaticMockDefinition{Id: "one"}, StaticMockDefinition{Id: "two"})
+	sms.mockDefinitions[1].recordHit()
+
+	mux := http.NewServeMux()
+	sms.RegisterAdminRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/wiretap/mocks", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var listed []MockDefinitionResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	assert.Len(t, listed, 2)
+	assert.Equal(t, "one", listed[0].Id)
+	assert.Equal(t, int64(0), listed[0].HitCount)
+	assert.Equal(t, "two", listed[1].Id)
+	assert.Equal(t, int64(1), listed[1].HitCount)
+}
+
+func TestStaticMockService_CrudEndpoints(t *testing.T) {
+	sms := newTestStaticMockService()
+	sms.config.MockDefinitionsDir = t.TempDir()
+
+	mux := http.NewServeMux()
+	sms.RegisterAdminRoutes(mux)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "/wiretap/mocks", `{"id": "pets", "request": {"method": "GET", "urlPath": "/pets"}}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = serve(http.MethodPost, "/wiretap/mocks", `{"id": "pets", "request": {"method": "GET"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodPost, "/wiretap/mocks", `{"id": "bad", "request": {"method": "GET"}, "nope": true}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodPut, "/wiretap/mocks/pets?persist=true",
+		`{"request": {"method": "GET", "urlPath": "/cats"}, "response": {"statusCode": 204}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(http.MethodGet, "/wiretap/mocks", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id": "pets", "request": {"method": "GET", "urlPath": "/cats"}, "response": {"statusCode": 204},
+		"hitCount": 0}]`, rec.Body.String())
+
+	// runtime definitions are not written to disk, even when persist is requested.
+	_, err := os.Stat(filepath.Join(sms.config.MockDefinitionsDir, DefaultPersistFile))
+	assert.True(t, os.IsNotExist(err))
+
+	rec = serve(http.MethodDelete, "/wiretap/mocks/pets", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = serve(http.MethodDelete, "/wiretap/mocks/pets", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(http.MethodPost, "/wiretap/mocks?persist=true", `{"id": "dogs", "request": {"method": "GET"}}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	definitions, err := sms.loadMockDefinitionFile(filepath.Join(sms.config.MockDefinitionsDir, DefaultPersistFile))
+	assert.NoError(t, err)
+	assert.Len(t, definitions, 1)
+}
@@ -0,0 +1,165 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pb33f/wiretap/daemon"
+)
+
+const (
+	// ExportAllHeaders includes every captured request header in an exported mock definition.
+	ExportAllHeaders = "*"
+
+	// DefaultExportFile is the file exported mock definitions are appended to when no file name is given.
+	DefaultExportFile = "exported-mocks.json"
+)
+
+// ExportMockRequest controls how a captured transaction is converted into a mock definition.
+type ExportMockRequest struct {
+	Headers  []string `json:"headers,omitempty"`  // request headers to match on, '*' includes all of them.
+	Append   bool     `json:"append,omitempty"`   // append the definition to a file in the mock definitions directory.
+	FileName string   `json:"fileName,omitempty"` // the JSON file to append to, defaults to DefaultExportFile.
+}
+
+type ExportMockResponse struct {
+	Mock StaticMockDefinition `json:"mock"`
+	File string               `json:"file,omitempty"`
+}
+
+// buildMockFromTransaction converts a captured transaction into a mock definition. Only the request headers
+// listed in includeHeaders are added to the request definition. A response body that was truncated or redacted
+// when it was captured is not what the upstream sent, so those transactions are refused rather than exported.
+func buildMockFromTransaction(transaction *daemon.HttpTransaction, includeHeaders []string) (StaticMockDefinition, error) {
+	var definition StaticMockDefinition
+
+	if transaction.Response != nil {
+		if transaction.Response.BodyTruncated {
+			return definition, fmt.Errorf("the captured response body was truncated from %d bytes, "+
+				"raise maxBodyBytesInReport to export it", transaction.Response.BodyLength)
+		}
+		if transaction.Response.BodyRedacted {
+			return definition, fmt.Errorf("fields of the captured response body were redacted, " +
+				"remove them from redactFields to export it")
+		}
+	}
+
+	if transaction.Request != nil {
+		definition.Request.Method = transaction.Request.Method
+		definition.Request.UrlPath = transaction.Request.OriginalPath
+		if definition.Request.UrlPath == "" {
+			definition.Request.UrlPath = transaction.Request.Path
+		}
+
+		headers := make(map[string]any)
+		for key, value := range transaction.Request.Headers {
+			for _, include := range includeHeaders {
+				if include == ExportAllHeaders || strings.EqualFold(include, key) {
+					headers[key] = value
+					break
+				}
+			}
+		}
+		if len(headers) > 0 {
+			definition.Request.Header = &headers
+		}
+	}
+
+	if transaction.Response != nil {
+		definition.Response.StatusCode = transaction.Response.StatusCode
+		definition.Response.Header = transaction.Response.Headers
+		definition.Response.Body = transaction.Response.Body
+	}
+	return definition, nil
+}
+
+// appendMockToFile appends a mock definition to a JSON file in the mock definitions directory, the file is created
+// when it does not exist. The path of the file is returned.
+func (sms *StaticMockService) appendMockToFile(definition StaticMockDefinition, fileName string) (string, error) {
+	mocksPath := sms.mockDefinitionsDir()
+	if mocksPath == "" {
+		return "", fmt.Errorf("no mock definitions directory is configured")
+	}
+	if fileName == "" {
+		fileName = DefaultExportFile
+	}
+	if formatFromExtension(fileName) != MockDefinitionFormatJSON {
+		return "", fmt.Errorf("mock definitions can only be appended to JSON files")
+	}
+	filePath := filepath.Join(mocksPath, filepath.Base(fileName))
+
+	var definitions []interface{}
+	if data, err := os.ReadFile(filePath); err == nil && len(data) > 0 {
+		var existing interface{}
+		if err = json.Unmarshal(data, &existing); err != nil {
+			return "", fmt.Errorf("unable to parse existing mock definition file: %w", err)
+		}
+		switch e := existing.(type) {
+		case []interface{}:
+			definitions = e
+		case map[string]interface{}:
+			definitions = []interface{}{e}
+		default:
+			return "", fmt.Errorf("existing mock definition file is not an object or an array")
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	definitions = append(definitions, definition)
+	data, err := json.MarshalIndent(definitions, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// handleExportMock converts a captured transaction into a mock definition.
+func (sms *StaticMockService) handleExportMock(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var exportRequest ExportMockRequest
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&exportRequest); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "Invalid export request", err.Error(), r.URL.Path)
+			return
+		}
+	}
+
+	transaction := sms.wiretapService.GetTransaction(id)
+	if transaction == nil {
+		writeAdminError(w, http.StatusNotFound, "Transaction not found",
+			fmt.Sprintf("no transaction with the id '%s' has been captured", id), r.URL.Path)
+		return
+	}
+
+	definition, err := buildMockFromTransaction(transaction, exportRequest.Headers)
+	if err != nil {
+		writeAdminError(w, http.StatusConflict, "Transaction cannot be exported", err.Error(), r.URL.Path)
+		return
+	}
+
+	response := &ExportMockResponse{Mock: definition}
+	if exportRequest.Append {
+		file, err := sms.appendMockToFile(response.Mock, exportRequest.FileName)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, "Unable to append mock definition", err.Error(), r.URL.Path)
+			return
+		}
+		response.File = file
+		sms.logger.Info("Exported mock definition", "transaction", id, "file", file)
+	}
+	writeAdminResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,98 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMockFromTransaction(t *testing.T) {
+	transaction := &daemon.HttpTransaction{
+		Request: &daemon.HttpRequest{
+			Method:       "GET",
+			Path:         "/rewritten/pets",
+			OriginalPath: "/pets",
+			Headers:      map[string]any{"Accept": "application/json", "Authorization": "Bearer abc"},
+		},
+		Response: &daemon.HttpResponse{
+			StatusCode: 200,
+			Headers:    map[string]any{"Content-Type": "application/json"},
+			Body:       `[{"name": "fido"}]`,
+		},
+	}
+
+	definition, err := buildMockFromTransaction(transaction, []string{"accept"})
+	assert.NoError(t, err)
+	assert.Equal(t, "GET", definition.Request.Method)
+	assert.Equal(t, "/pets", definition.Request.UrlPath)
+	assert.Equal(t, map[string]any{"Accept": "application/json"}, *definition.Request.Header)
+	assert.Equal(t, 200, definition.Response.StatusCode)
+	assert.Equal(t, `[{"name": "fido"}]`, definition.Response.Body)
+
+	definition, _ = buildMockFromTransaction(transaction, nil)
+	assert.Nil(t, definition.Request.Header)
+
+	definition, _ = buildMockFromTransaction(transaction, []string{ExportAllHeaders})
+	assert.Len(t, *definition.Request.Header, 2)
+}
+
+func TestBuildMockFromTransaction_CapturedBodyChanged(t *testing.T) {
+	capture := func(config *shared.WiretapConfiguration) *daemon.HttpTransaction {
+		id, _ := uuid.NewUUID()
+		response := &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"name": "fido", "password": "b33f"}`)),
+		}
+		return daemon.BuildResponse(&model.Request{Id: &id}, response, config)
+	}
+
+	// a truncated body would be exported as broken JSON.
+	_, err := buildMockFromTransaction(capture(&shared.WiretapConfiguration{MaxBodyBytesInReport: 10}), nil)
+	assert.ErrorContains(t, err, "truncated from 36 bytes")
+
+	// a redacted body would be exported with the redacted values.
+	_, err = buildMockFromTransaction(capture(&shared.WiretapConfiguration{RedactFields: []string{"password"}}), nil)
+	assert.ErrorContains(t, err, "were redacted")
+
+	// a body within the limit is exported as it was sent.
+	definition, err := buildMockFromTransaction(capture(&shared.WiretapConfiguration{MaxBodyBytesInReport: 100}), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "fido", "password": "b33f"}`, definition.Response.Body)
+}
+
+func TestStaticMockService_AppendMockToFile(t *testing.T) {
+	sms := newTestStaticMockService()
+	sms.config.MockDefinitionsDir = t.TempDir()
+
+	definition := StaticMockDefinition{Request: StaticMockDefinitionRequest{Method: "GET", UrlPath: "/pets"}}
+	file, err := sms.appendMockToFile(definition, "")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(sms.config.MockDefinitionsDir, DefaultExportFile), file)
+
+	_, err = sms.appendMockToFile(definition, "")
+	assert.NoError(t, err)
+
+	definitions, err := sms.loadMockDefinitionFile(file)
+	assert.NoError(t, err)
+	assert.Len(t, definitions, 2)
+
+	_, err = sms.appendMockToFile(definition, "mocks.yaml")
+	assert.Error(t, err)
+	_, statErr := os.Stat(filepath.Join(sms.config.MockDefinitionsDir, "mocks.yaml"))
+	assert.True(t, os.IsNotExist(statErr))
+}
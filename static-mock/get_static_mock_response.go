@@ -15,6 +15,14 @@ import (
 func (sms *StaticMockService) getBodyFromMockDefinition(matchedMockDefinition StaticMockDefinition, request *http.Request) string {
 	bodyStr := matchedMockDefinition.Response.Body
 
+	// body files are read when the definition is loaded, binary files are served as they are.
+	if matchedMockDefinition.Response.bodyFileContent != nil {
+		if !isTextContentType(matchedMockDefinition.Response.bodyFileContentType) {
+			return string(matchedMockDefinition.Response.bodyFileContent)
+		}
+		bodyStr = string(matchedMockDefinition.Response.bodyFileContent)
+	}
+
 	// If the BodyJsonPath is defined then set the body to contents of the file
 	if matchedMockDefinition.Response.BodyJsonFilename != "" {
 		bodyJsonFilePath := sms.wiretapService.StaticMockDir + MockBodyJsonsPath + matchedMockDefinition.Response.BodyJsonFilename
@@ -76,10 +84,15 @@ func (sms *StaticMockService) getHeadersFromMockDefinition(matchedMockDefinition
 	headers := make(map[string][]string)
 	shared.SetCORSHeaders(headers)
 	headers["Content-Type"] = []string{"application/json"}
+	if matchedMockDefinition.Response.bodyFileContentType != "" {
+		headers["Content-Type"] = []string{matchedMockDefinition.Response.bodyFileContentType}
+	}
 
 	// Add cors and content-type headers
 	for k, v := range headers {
-		header.Add(k, fmt.Sprint(v))
+		for _, value := range v {
+			header.Add(k, value)
+		}
 	}
 
 	// Add headers from mock definition JSON
@@ -92,15 +105,30 @@ func (sms *StaticMockService) getHeadersFromMockDefinition(matchedMockDefinition
 
 // getStaticMockResponse returns response from the matched static mock
 func (sms *StaticMockService) getStaticMockResponse(matchedMockDefinition StaticMockDefinition, request *http.Request) *http.Response {
-	body := sms.getBodyFromMockDefinition(matchedMockDefinition, request)
+	body := []byte(sms.getBodyFromMockDefinition(matchedMockDefinition, request))
+	header := sms.getHeadersFromMockDefinition(matchedMockDefinition)
 
-	buff := bytes.NewBuffer([]byte(body))
+	if matchedMockDefinition.Response.CompressResponse {
+		algorithm := compressionAlgorithm(&matchedMockDefinition.Response)
+		compressed, err := compressBody(body, algorithm)
+		if err != nil {
+			sms.logger.Error("Unable to compress mock response, serving it uncompressed",
+				"id", matchedMockDefinition.Id, "error", err.Error())
+		} else {
+			body = compressed
+			header.Set("Content-Encoding", algorithm)
+			header.Add("Vary", "Accept-Encoding")
+		}
+	}
 
 	response := &http.Response{
 		StatusCode: matchedMockDefinition.Response.StatusCode,
-		Body:       io.NopCloser(buff),
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewBuffer(body)),
+	}
+	if matchedMockDefinition.Response.ChunkedResponse {
+		response.TransferEncoding = []string{"chunked"}
 	}
-	response.Header = sms.getHeadersFromMockDefinition(matchedMockDefinition)
 
 	return response
 }
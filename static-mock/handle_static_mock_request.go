@@ -9,36 +9,119 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pb33f/ranch/model"
 	"github.com/pb33f/wiretap/shared"
 )
 
-// getBodyFromHttpRequest reads the body of the incoming request and returns it as an interface{}
-func (sms *StaticMockService) getBodyFromHttpRequest(request *http.Request) interface{} {
+// binaryContentTypes are the media types, other than the image, audio, video and font families,
+// that are passed through as raw bytes instead of being parsed as JSON.
+var binaryContentTypes = map[string]bool{
+	"application/octet-stream": true,
+	"application/pdf":          true,
+	"application/zip":          true,
+	"application/gzip":         true,
+	"application/protobuf":     true,
+	"application/x-protobuf":   true,
+	"application/wasm":         true,
+}
+
+// isBinaryContentType checks if a Content-Type header value is a binary media type.
+func isBinaryContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch strings.SplitN(mediaType, "/", 2)[0] {
+	case "image", "audio", "video", "font":
+		return true
+	}
+	return binaryContentTypes[mediaType]
+}
+
+// bufferedBody is a request body that has been read into memory. Matching a request against many mock definitions
+// reads the buffered bytes directly, instead of reading and copying the body again for each definition, and the
+// body is only parsed as JSON once.
+type bufferedBody struct {
+	*bytes.Reader
+	data   []byte
+	parsed *parsedBody
+}
+
+// parsedBody is the JSON body of a request, parsed the first time it is needed. It is shared by the copies of a
+// request that are matched at the same time.
+type parsedBody struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+func newBufferedBody(data []byte, parsed *parsedBody) *bufferedBody {
+	return &bufferedBody{Reader: bytes.NewReader(data), data: data, parsed: parsed}
+}
+
+func (bb *bufferedBody) Close() error {
+	return nil
+}
+
+// bufferBody reads the body of the incoming request once, and replaces it with the buffered body. Later calls
+// return the same buffered body, rewound for the next reader. Nil is returned when the request has no body.
+func bufferBody(request *http.Request) *bufferedBody {
+	if request.Body == nil {
+		return nil
+	}
+	if bb, ok := request.Body.(*bufferedBody); ok {
+		bb.Reset(bb.data)
+		return bb
+	}
 	bodyBytes, err := io.ReadAll(request.Body)
 	if err != nil {
 		panic(err)
 	}
+	_ = request.Body.Close()
+	bb := newBufferedBody(bodyBytes, &parsedBody{})
+	request.Body = bb
+	return bb
+}
 
-	// Restore request.Body so it can be read again
-	request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+// readRawBody reads the body of the incoming request and restores it, so it can be read again.
+func readRawBody(request *http.Request) []byte {
+	if bb := bufferBody(request); bb != nil {
+		return bb.data
+	}
+	return nil
+}
 
-	var bodyJsonObj interface{}
+// getBodyFromHttpRequest reads the body of the incoming request and returns it as an interface{}. Binary bodies
+// are returned as a []byte, without any attempt to parse them as JSON. JSON bodies are parsed once per request,
+// the parsed body is shared and must not be modified.
+func (sms *StaticMockService) getBodyFromHttpRequest(request *http.Request) interface{} {
+	bb := bufferBody(request)
+	if bb == nil {
+		return nil
+	}
 
-	if len(bodyBytes) == 0 {
-		return bodyJsonObj
+	if isBinaryContentType(request.Header.Get("Content-Type")) {
+		return bb.data
 	}
 
-	err = json.Unmarshal(bodyBytes, &bodyJsonObj)
-	if err != nil {
-		sms.logger.Error("Error decoding JSON of incoming request. JSON => \n%s", string(bodyBytes), err)
-		panic(err)
+	bb.parsed.once.Do(func() {
+		if len(bb.data) > 0 {
+			bb.parsed.err = json.Unmarshal(bb.data, &bb.parsed.value)
+		}
+	})
+	if bb.parsed.err != nil {
+		sms.logger.Error("Error decoding JSON of incoming request. JSON => \n%s", string(bb.data), bb.parsed.err)
+		panic(bb.parsed.err)
 	}
 
-	return bodyJsonObj
+	return bb.parsed.value
 }
 
 // compareJsonBody compares the JSON body of the incoming request with the mock definition
@@ -63,16 +146,31 @@ func (sms *StaticMockService) transStrArrToInterfaceArr(strArr []string) []inter
 	return strArrTransformedValues
 }
 
+// headerValues returns the values of a header, header names are case-insensitive. Headers parsed by the server
+// have canonical names, headers set straight on the map may not, so those are folded as well.
+func headerValues(header http.Header, name string) []string {
+	if values := header.Values(name); len(values) > 0 {
+		return values
+	}
+	for key, values := range header {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
+
 // compareHeaders compares the headers of the incoming request with the mock definition
 func (sms *StaticMockService) compareHeaders(mockHeaders map[string]any, incoming *http.Request) bool {
 	found := true
 	// Check if all headers in mockHeaders are subset of incoming headers
 	for key, value := range mockHeaders {
+		incomingValues := sms.transStrArrToInterfaceArr(headerValues(incoming.Header, key))
 		switch v := value.(type) {
 		case string:
-			found = found && shared.IsSubset([]interface{}{v}, sms.transStrArrToInterfaceArr(incoming.Header[key]))
+			found = found && shared.IsSubset([]interface{}{v}, incomingValues)
 		case []interface{}:
-			found = found && shared.IsSubset(value, sms.transStrArrToInterfaceArr(incoming.Header[key]))
+			found = found && shared.IsSubset(value, incomingValues)
 		}
 	}
 
@@ -98,13 +196,8 @@ func (sms *StaticMockService) compareQueryParams(mockQueryParams map[string]any,
 // compareBody compares the body of the incoming request with the mock definition
 func (sms *StaticMockService) compareBody(mock StaticMockDefinitionRequest, incoming *http.Request) bool {
 	switch mb := mock.Body.(type) {
-	case string: // Case string body
-		incomingBodyBytes, err := io.ReadAll(incoming.Body)
-		if err != nil {
-			panic(err)
-		}
-
-		if string(incomingBodyBytes) != string(mb) {
+	case string: // Case string body, compared byte for byte, binary bodies as well
+		if string(readRawBody(incoming)) != mb {
 			return false
 		}
 	case map[string]interface{}: // Case JSON Object
@@ -125,8 +218,10 @@ func (sms *StaticMockService) compareBody(mock StaticMockDefinitionRequest, inco
 
 // isRequestMatch checks if the incoming request matches a mock definition
 func (sms *StaticMockService) isRequestMatch(mock StaticMockDefinitionRequest, incoming *http.Request) bool {
+	opts := shared.StringCompareOptions{CaseInsensitive: mock.CaseInsensitive}
+
 	// Compare Host if defined
-	if mock.Host != "" && !shared.StringCompare(mock.Host, incoming.Host) {
+	if mock.Host != "" && !shared.StringCompareWithOptions(mock.Host, incoming.Host, opts) {
 		return false
 	}
 
@@ -136,7 +231,7 @@ func (sms *StaticMockService) isRequestMatch(mock StaticMockDefinitionRequest, i
 	}
 
 	// Compare url of the request
-	if mock.UrlPath != "" && !shared.StringCompare(mock.UrlPath, incoming.URL.Path) {
+	if mock.UrlPath != "" && !shared.StringCompareWithOptions(mock.UrlPath, incoming.URL.Path, opts) {
 		return false
 	}
 
@@ -161,6 +256,18 @@ func (sms *StaticMockService) isRequestMatch(mock StaticMockDefinitionRequest, i
 		}
 	}
 
+	// Compare the length of the body
+	if mock.BodyLength != nil && len(readRawBody(incoming)) != *mock.BodyLength {
+		return false
+	}
+
+	// Evaluate JSONPath conditions against the body
+	if len(mock.JSONPathConditions) > 0 {
+		if !sms.compareJSONPathConditions(mock, incoming) {
+			return false
+		}
+	}
+
 	// If all checks passed, the requests match
 	return true
 }
@@ -168,13 +275,20 @@ func (sms *StaticMockService) isRequestMatch(mock StaticMockDefinitionRequest, i
 // checkStaticMockExists checks if a static mock definition exists for the incoming request.
 func (sms *StaticMockService) checkStaticMockExists(request *http.Request) *StaticMockDefinition {
 	var matchedMockDefinition *StaticMockDefinition
-	// check for a static mock definition.
-	for _, mockDefinition := range sms.mockDefinitions {
-		if sms.isRequestMatch(mockDefinition.Request, request) {
-			// found a match
-			matchedMockDefinition = &mockDefinition
-			break
-		}
+	now := time.Now()
+	activeTags := sms.getActiveTags()
+	if sms.config.NormalizeRequestPath {
+		original, matchRequest := request, sms.normalizedRequest(request)
+		// matching reads and restores the body of the copy, the restored body is handed back to the request.
+		defer func() { original.Body = matchRequest.Body }()
+		request = matchRequest
+	}
+	// check for a static mock definition, only the definitions that may match the method and path are checked.
+	check := &mockCheck{candidates: sms.getMockCandidates(request), activeTags: activeTags, now: now}
+	if position := sms.findMatch(check, request); position >= 0 {
+		// found a match
+		matchedMockDefinition = &check.candidates[position]
+		matchedMockDefinition.recordHit()
 	}
 
 	return matchedMockDefinition
@@ -198,6 +312,11 @@ func (sms *StaticMockService) handleStaticMockRequest(request *model.Request) {
 		}
 	}()
 
+	// oversized headers, blocked paths and methods, and paths that are not allowed, are refused before mocks match.
+	if sms.wiretapService.RefuseRequest(request) {
+		return
+	}
+
 	// check for a static mock definition.
 	matchedMockDefinition := sms.checkStaticMockExists(request.HttpRequest)
 
@@ -210,5 +329,10 @@ func (sms *StaticMockService) handleStaticMockRequest(request *model.Request) {
 	// found a static mock, handle it.
 	response := sms.getStaticMockResponse(*matchedMockDefinition, request.HttpRequest)
 
+	if matchedMockDefinition.Response.ChunkedResponse {
+		request.HttpResponseWriter = newChunkedResponseWriter(request.HttpRequest.Context(),
+			request.HttpResponseWriter, &matchedMockDefinition.Response)
+	}
+
 	sms.wiretapService.HandleStaticMockResponse(request, response)
 }
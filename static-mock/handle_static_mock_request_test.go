@@ -0,0 +1,253 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBinaryRequest(body []byte) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "image/png")
+	return request
+}
+
+func TestIsBinaryContentType(t *testing.T) {
+	assert.True(t, isBinaryContentType("image/png"))
+	assert.True(t, isBinaryContentType("application/octet-stream"))
+	assert.True(t, isBinaryContentType("Application/PDF; name=doc.pdf"))
+	assert.False(t, isBinaryContentType("application/json"))
+	assert.False(t, isBinaryContentType(""))
+}
+
+func TestStaticMockService_GetBodyFromHttpRequest_Binary(t *testing.T) {
+	sms := newTestStaticMockService()
+	body := []byte{0x89, 0x50, 0x4e, 0x47, 0x00, 0xff}
+
+	request := newBinaryRequest(body)
+	assert.NotPanics(t, func() {
+		assert.Equal(t, body, sms.getBodyFromHttpRequest(request))
+	})
+
+	// the body can still be read afterward
+	assert.Equal(t, body, readRawBody(request))
+}
+
+// countingReader counts the reads of a body.
+type countingReader struct {
+	io.Reader
+	closed bool
+	reads  int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	cr.reads++
+	return cr.Reader.Read(p)
+}
+
+func (cr *countingReader) Close() error {
+	cr.closed = true
+	return nil
+}
+
+func TestReadRawBody_ReadsOnce(t *testing.T) {
+	source := &countingReader{Reader: strings.NewReader(`{"name":"dog"}`)}
+	request := httptest.NewRequest(http.MethodPost, "/pets", nil)
+	request.Body = source
+
+	assert.Equal(t, `{"name":"dog"}`, string(readRawBody(request)))
+	reads := source.reads
+	assert.True(t, source.closed)
+
+	// the body is buffered, later reads do not read the source again.
+	partial := make([]byte, 4)
+	_, _ = request.Body.Read(partial)
+	assert.Equal(t, `{"name":"dog"}`, string(readRawBody(request)))
+	assert.Equal(t, reads, source.reads)
+
+	// the body is rewound for the next reader.
+	forwarded, _ := io.ReadAll(request.Body)
+	assert.Equal(t, `{"name":"dog"}`, string(forwarded))
+}
+
+func TestStaticMockService_GetBodyFromHttpRequest_ParsedOnce(t *testing.T) {
+	sms := newTestStaticMockService()
+	request := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(`{"name":"dog"}`))
+	request.Header.Set("Content-Type", "application/json")
+
+	assert.Equal(t, map[string]interface{}{"name": "dog"}, sms.getBodyFromHttpRequest(request))
+
+	// the parsed body is kept with the buffered body, and not parsed again.
+	request.Body.(*bufferedBody).parsed.value = "parsed"
+	assert.Equal(t, "parsed", sms.getBodyFromHttpRequest(request))
+	assert.Equal(t, `{"name":"dog"}`, string(readRawBody(request)))
+}
+
+func TestStaticMockService_CompareBody_Binary(t *testing.T) {
+	sms := newTestStaticMockService()
+	body := []byte{0x89, 0x50, 0x4e, 0x47, 0x00, 0xff}
+
+	assert.True(t, sms.compareBody(StaticMockDefinitionRequest{Body: string(body)}, newBinaryRequest(body)))
+	assert.False(t, sms.compareBody(StaticMockDefinitionRequest{Body: "\x01"}, newBinaryRequest(body)))
+	// a number is not a body.
+	assert.False(t, sms.compareBody(StaticMockDefinitionRequest{Body: float64(len(body))}, newBinaryRequest(body)))
+}
+
+func TestStaticMockService_IsRequestMatch_BodyLength(t *testing.T) {
+	sms := newTestStaticMockService()
+	body := []byte{0x89, 0x50, 0x4e, 0x47, 0x00, 0xff}
+
+	var mock StaticMockDefinitionRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"method": "POST", "urlPath": "/upload", "bodyLength": 6}`), &mock))
+	assert.True(t, sms.isRequestMatch(mock, newBinaryRequest(body)))
+	assert.False(t, sms.isRequestMatch(mock, newBinaryRequest(body[:2])))
+
+	// the body is compared as well as its length.
+	mock.Body = string(body)
+	assert.True(t, sms.isRequestMatch(mock, newBinaryRequest(body)))
+	mock.Body = "\x01\x02\x03\x04\x05\x06"
+	assert.False(t, sms.isRequestMatch(mock, newBinaryRequest(body)))
+}
+
+func TestStaticMockService_CompareHeaders_CaseInsensitive(t *testing.T) {
+	sms := newTestStaticMockService()
+	request := httptest.NewRequest(http.MethodGet, "/pets", nil)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer pizza")
+	// set straight on the map, without canonicalizing the name.
+	request.Header["x-api-key"] = []string{"beef"}
+
+	assert.True(t, sms.compareHeaders(map[string]any{
+		"content-type":  "application/json",
+		"authorization": "~^Bearer .+",
+		"X-Api-Key":     "beef",
+	}, request))
+	assert.True(t, sms.compareHeaders(map[string]any{"AUTHORIZATION": []interface{}{"Bearer pizza"}}, request))
+	assert.False(t, sms.compareHeaders(map[string]any{"authorization": "Basic pizza"}, request))
+}
+
+func TestStaticMockService_IsRequestMatch_CaseInsensitive(t *testing.T) {
+	sms := newTestStaticMockService()
+	request := httptest.NewRequest(http.MethodGet, "http://API.example.com/Pets/123", nil)
+	mock := StaticMockDefinitionRequest{Method: http.MethodGet, Host: "api.example.com", UrlPath: "/pets/[0-9]+"}
+
+	// matching is case-sensitive by default.
+	assert.False(t, sms.isRequestMatch(mock, request))
+
+	mock.CaseInsensitive = true
+	assert.True(t, sms.isRequestMatch(mock, request))
+}
+
+// newRefusingStaticMockService serves a mock for /pets/1 and /pets/secret, through a wiretap service with the config.
+func newRefusingStaticMockService(t *testing.T, config *shared.WiretapConfiguration) *StaticMockService {
+	if config.Logger == nil {
+		config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	sms := newTestStaticMockService(StaticMockDefinition{
+		Request:  StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "/pets/1"},
+		Response: StaticMockDefinitionResponse{StatusCode: http.StatusOK, Body: `{"name":"mocked"}`},
+	}, StaticMockDefinition{
+		Request:  StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "/pets/secret"},
+		Response: StaticMockDefinitionResponse{StatusCode: http.StatusOK, Body: `{"name":"mocked"}`},
+	})
+	sms.config = config
+	sms.wiretapService = daemon.NewWiretapService(nil, config)
+
+	// registering the service creates the channel served mocks are broadcast on.
+	channel := "refusing-" + t.Name()
+	require.NoError(t, service.GetServiceRegistry().RegisterService(sms.wiretapService, channel))
+	t.Cleanup(func() { service.GetServiceRegistry().UnregisterService(channel) })
+	return sms
+}
+
+func serveStaticMock(sms *StaticMockService, request *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	id := uuid.New()
+	sms.HandleStaticMockRequest(&model.Request{Id: &id, HttpRequest: request, HttpResponseWriter: rec})
+	return rec
+}
+
+func TestStaticMockService_BlockedPathsRefusedBeforeMocks(t *testing.T) {
+	config := &shared.WiretapConfiguration{BlockedPaths: []string{"/pets/secret"}, AllowedPaths: []string{"/pets/**"}}
+	config.CompileBlockedPaths()
+	config.CompileAllowedPaths()
+	sms := newRefusingStaticMockService(t, config)
+
+	rec := serveStaticMock(sms, httptest.NewRequest(http.MethodGet, "/pets/secret", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "mocked")
+
+	rec = serveStaticMock(sms, httptest.NewRequest(http.MethodGet, "/pets/1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "mocked")
+
+	// a mock matching a path outside the allowed paths is not served.
+	sms.lock.Lock()
+	sms.definitionsByFile[""] = []StaticMockDefinition{{
+		hits:     &atomic.Int64{},
+		Request:  StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "/owners/1"},
+		Response: StaticMockDefinitionResponse{StatusCode: http.StatusOK, Body: `{"name":"mocked"}`},
+	}}
+	require.NoError(t, sms.mergeMockDefinitions())
+	sms.lock.Unlock()
+	rec = serveStaticMock(sms, httptest.NewRequest(http.MethodGet, "/owners/1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestStaticMockService_BlockedMethodsRefusedBeforeMocks(t *testing.T) {
+	var logs bytes.Buffer
+	sms := newRefusingStaticMockService(t, &shared.WiretapConfiguration{BlockedMethods: []string{"delete"},
+		Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+	sms.lock.Lock()
+	sms.definitionsByFile[""] = []StaticMockDefinition{{
+		Id:       "delete",
+		Request:  StaticMockDefinitionRequest{Method: http.MethodDelete, UrlPath: "/pets/1"},
+		Response: StaticMockDefinitionResponse{StatusCode: http.StatusOK, Body: `{"deleted":true}`},
+	}}
+	require.NoError(t, sms.mergeMockDefinitions())
+	sms.lock.Unlock()
+
+	rec := serveStaticMock(sms, httptest.NewRequest(http.MethodDelete, "/pets/1", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD, POST, PUT, PATCH, OPTIONS, TRACE", rec.Header().Get("Allow"))
+	for _, definition := range sms.getMockDefinitions() {
+		assert.Zero(t, definition.HitCount(), definition.Id)
+	}
+	assert.Contains(t, logs.String(), "security event: blocked method")
+}
+
+func TestStaticMockService_OversizedHeadersRefusedBeforeMocks(t *testing.T) {
+	sms := newRefusingStaticMockService(t, &shared.WiretapConfiguration{MaxSingleHeaderBytes: 64})
+
+	request := httptest.NewRequest(http.MethodGet, "/pets/1", nil)
+	request.Header.Set("X-Pet-Notes", strings.Repeat("beef", 32))
+	rec := serveStaticMock(sms, request)
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "mocked")
+	for _, definition := range sms.getMockDefinitions() {
+		assert.Zero(t, definition.HitCount())
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/pets/1", nil)
+	request.Header.Set("X-Pet-Notes", "beef")
+	assert.Equal(t, http.StatusOK, serveStaticMock(sms, request).Code)
+}
@@ -0,0 +1,146 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/ohler55/ojg/jp"
+	"github.com/pb33f/wiretap/shared"
+)
+
+const (
+	JSONPathOperatorEquals    = "eq"
+	JSONPathOperatorNotEquals = "ne"
+	JSONPathOperatorGreater   = "gt"
+	JSONPathOperatorLess      = "lt"
+	JSONPathOperatorContains  = "contains"
+	JSONPathOperatorMatches   = "matches"
+)
+
+// JSONPathCondition is a single condition evaluated against the JSON body of an incoming request.
+// Path is a JSONPath expression (e.g. `$.order.items[0].sku`), Operator is one of `eq`, `ne`, `gt`, `lt`,
+// `contains` or `matches` and Value is the value the result of the expression is compared with.
+type JSONPathCondition struct {
+	Path     string      `json:"path,omitempty"`
+	Operator string      `json:"operator,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+
+	// the parsed Path, parsed when the definition is loaded.
+	expr jp.Expr
+}
+
+// UnmarshalJSON parses the path of a condition as it is loaded, so a definition with an invalid path is reported
+// when it is loaded and the path is not parsed again for every request.
+func (condition *JSONPathCondition) UnmarshalJSON(data []byte) error {
+	type jsonPathCondition JSONPathCondition
+	var decoded jsonPathCondition
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*condition = JSONPathCondition(decoded)
+	if condition.Path == "" {
+		return nil
+	}
+	expr, err := jp.ParseString(condition.Path)
+	if err != nil {
+		return fmt.Errorf("invalid JSONPath expression '%s' in mock definition: %w", condition.Path, err)
+	}
+	condition.expr = expr
+	return nil
+}
+
+// compareJSONPathConditions evaluates all JSONPath conditions of the mock definition against the incoming
+// request body. All conditions must pass for the request to match.
+func (sms *StaticMockService) compareJSONPathConditions(mock StaticMockDefinitionRequest, request *http.Request) bool {
+	// conditions can only be evaluated against a JSON body
+	if request.Header.Get("Content-Type") != "application/json" {
+		return false
+	}
+
+	incomingBody := sms.getBodyFromHttpRequest(request)
+
+	for _, condition := range mock.JSONPathConditions {
+		if !sms.evaluateJSONPathCondition(condition, incomingBody) {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluateJSONPathCondition runs the JSONPath expression of the condition against the body and applies the operator.
+// When an expression returns multiple results, the condition passes if any of them satisfy the operator, except for
+// `ne`, which requires that none of them are equal to the value.
+func (sms *StaticMockService) evaluateJSONPathCondition(condition JSONPathCondition, body interface{}) bool {
+	expr := condition.expr
+	if expr == nil {
+		// conditions that were not loaded from JSON are parsed as they are used.
+		var err error
+		if expr, err = jp.ParseString(condition.Path); err != nil {
+			sms.logger.Error("invalid JSONPath expression in mock definition", "path", condition.Path,
+				"error", err.Error())
+			return false
+		}
+	}
+
+	results := expr.Get(body)
+
+	if condition.Operator == JSONPathOperatorNotEquals {
+		for _, result := range results {
+			if reflect.DeepEqual(result, condition.Value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, result := range results {
+		switch condition.Operator {
+		case JSONPathOperatorEquals:
+			if reflect.DeepEqual(result, condition.Value) {
+				return true
+			}
+		case JSONPathOperatorGreater, JSONPathOperatorLess:
+			actual, aOk := result.(float64)
+			expected, eOk := condition.Value.(float64)
+			if aOk && eOk {
+				if condition.Operator == JSONPathOperatorGreater && actual > expected {
+					return true
+				}
+				if condition.Operator == JSONPathOperatorLess && actual < expected {
+					return true
+				}
+			}
+		case JSONPathOperatorContains:
+			switch r := result.(type) {
+			case string:
+				if strings.Contains(r, fmt.Sprint(condition.Value)) {
+					return true
+				}
+			case []interface{}:
+				for _, item := range r {
+					if reflect.DeepEqual(item, condition.Value) {
+						return true
+					}
+				}
+			}
+		case JSONPathOperatorMatches:
+			if r, ok := result.(string); ok {
+				if shared.StringCompare(shared.RegexSigil+fmt.Sprint(condition.Value), r) {
+					return true
+				}
+			}
+		default:
+			sms.logger.Error("unsupported JSONPath operator in mock definition", "operator", condition.Operator)
+			return false
+		}
+	}
+	return false
+}
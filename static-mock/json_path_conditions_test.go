@@ -0,0 +1,113 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jsonPathOrder = `{"order": {"total": 42.5, "note": "leave at the door", "tags": ["gift", "fragile"],
+	"items": [{"sku": "BEEF-1", "quantity": 2}, {"sku": "PORK-7", "quantity": 1}]}}`
+
+func TestStaticMockService_CompareJSONPathConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions string
+		body       string
+		expected   bool
+	}{
+		{"eq", `[{"path": "$.order.items[0].sku", "operator": "eq", "value": "BEEF-1"}]`, jsonPathOrder, true},
+		{"eq with another value", `[{"path": "$.order.items[0].sku", "operator": "eq", "value": "PORK-7"}]`,
+			jsonPathOrder, false},
+		{"eq with any of multiple results", `[{"path": "$.order.items[*].sku", "operator": "eq", "value": "PORK-7"}]`,
+			jsonPathOrder, true},
+		{"ne", `[{"path": "$.order.note", "operator": "ne", "value": "ring the bell"}]`, jsonPathOrder, true},
+		{"ne with one of multiple results equal", `[{"path": "$.order.items[*].quantity", "operator": "ne",
+			"value": 1}]`, jsonPathOrder, false},
+		{"ne with none of multiple results equal", `[{"path": "$.order.items[*].quantity", "operator": "ne",
+			"value": 3}]`, jsonPathOrder, true},
+		{"gt", `[{"path": "$.order.total", "operator": "gt", "value": 40}]`, jsonPathOrder, true},
+		{"gt with a greater value", `[{"path": "$.order.total", "operator": "gt", "value": 50}]`, jsonPathOrder, false},
+		{"gt with a string", `[{"path": "$.order.note", "operator": "gt", "value": 1}]`, jsonPathOrder, false},
+		{"lt", `[{"path": "$.order.total", "operator": "lt", "value": 50}]`, jsonPathOrder, true},
+		{"lt with a smaller value", `[{"path": "$.order.total", "operator": "lt", "value": 40}]`, jsonPathOrder, false},
+		{"contains a substring", `[{"path": "$.order.note", "operator": "contains", "value": "door"}]`,
+			jsonPathOrder, true},
+		{"contains an array item", `[{"path": "$.order.tags", "operator": "contains", "value": "fragile"}]`,
+			jsonPathOrder, true},
+		{"contains a missing array item", `[{"path": "$.order.tags", "operator": "contains", "value": "urgent"}]`,
+			jsonPathOrder, false},
+		{"matches", `[{"path": "$.order.items[1].sku", "operator": "matches", "value": "^PORK-[0-9]+$"}]`,
+			jsonPathOrder, true},
+		{"matches a different pattern", `[{"path": "$.order.items[1].sku", "operator": "matches",
+			"value": "^BEEF-"}]`, jsonPathOrder, false},
+		{"every condition passes", `[{"path": "$.order.total", "operator": "gt", "value": 40},
+			{"path": "$.order.tags", "operator": "contains", "value": "gift"}]`, jsonPathOrder, true},
+		{"one condition fails", `[{"path": "$.order.total", "operator": "gt", "value": 40},
+			{"path": "$.order.tags", "operator": "contains", "value": "urgent"}]`, jsonPathOrder, false},
+		{"a missing path", `[{"path": "$.order.discount", "operator": "eq", "value": 10}]`, jsonPathOrder, false},
+		{"ne with a missing path", `[{"path": "$.order.discount", "operator": "ne", "value": 10}]`,
+			jsonPathOrder, true},
+		{"an unsupported operator", `[{"path": "$.order.total", "operator": "between", "value": 40}]`,
+			jsonPathOrder, false},
+	}
+
+	sms := newTestStaticMockService()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mock StaticMockDefinitionRequest
+			require.NoError(t, json.Unmarshal([]byte(`{"jsonPathConditions": `+test.conditions+`}`), &mock))
+			for _, condition := range mock.JSONPathConditions {
+				require.NotNil(t, condition.expr, "the path is parsed when the definition is loaded")
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(test.body))
+			request.Header.Set("Content-Type", "application/json")
+			assert.Equal(t, test.expected, sms.compareJSONPathConditions(mock, request))
+		})
+	}
+}
+
+func TestStaticMockService_CompareJSONPathConditions_NotJSON(t *testing.T) {
+	sms := newTestStaticMockService()
+	mock := StaticMockDefinitionRequest{JSONPathConditions: []JSONPathCondition{
+		{Path: "$.order.note", Operator: JSONPathOperatorContains, Value: "door"},
+	}}
+
+	// conditions are only evaluated against JSON bodies.
+	request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("order.note=leave at the door"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.False(t, sms.compareJSONPathConditions(mock, request))
+
+	// conditions that were not loaded from JSON are parsed as they are used.
+	request = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(jsonPathOrder))
+	request.Header.Set("Content-Type", "application/json")
+	assert.True(t, sms.compareJSONPathConditions(mock, request))
+}
+
+func TestReadMockDefinitions_InvalidJSONPath(t *testing.T) {
+	sms := newTestStaticMockService()
+
+	_, err := sms.readMockDefinitions("mocks.json", []byte(`{"request": {"method": "POST", "urlPath": "/orders",
+		"jsonPathConditions": [{"path": "$.order[", "operator": "eq", "value": 1}]}}`), MockDefinitionFormatJSON)
+	assert.ErrorContains(t, err, "invalid JSONPath expression '$.order[' in mock definition")
+
+	// a definition with an invalid path is left out of a file of definitions.
+	definitions, err := sms.readMockDefinitions("mocks.json", []byte(`[
+		{"id": "invalid", "request": {"jsonPathConditions": [{"path": "$.order[", "operator": "eq", "value": 1}]}},
+		{"id": "valid", "request": {"jsonPathConditions": [{"path": "$.order", "operator": "ne", "value": 1}]}}]`),
+		MockDefinitionFormatJSON)
+	require.NoError(t, err)
+	require.Len(t, definitions, 1)
+	assert.Equal(t, "valid", definitions[0].Id)
+}
@@ -0,0 +1,72 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveBodyFile returns the path of a response body file, relative paths are relative to the directory of the
+// mock definition file.
+func resolveBodyFile(definitionFile, bodyFile string) string {
+	if filepath.IsAbs(bodyFile) {
+		return bodyFile
+	}
+	return filepath.Join(filepath.Dir(definitionFile), bodyFile)
+}
+
+// bodyFileContentType returns the content type of a body file from its extension, or by sniffing the content
+// when the extension is not known.
+func bodyFileContentType(path string, content []byte) string {
+	if ct := mime.TypeByExtension(filepath.Ext(path)); ct != "" {
+		return ct
+	}
+	return http.DetectContentType(content)
+}
+
+// isTextContentType returns true for content types that are served as text, and can use request templating.
+// Anything else is served as raw bytes.
+func isTextContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.Contains(mediaType, "json") ||
+		strings.Contains(mediaType, "xml") ||
+		strings.Contains(mediaType, "yaml") ||
+		strings.Contains(mediaType, "javascript")
+}
+
+// readBodyFile reads the body file of a mock response, so it is cached on the definition and not read per request.
+func readBodyFile(definitionFile string, response *StaticMockDefinitionResponse) error {
+	path := resolveBodyFile(definitionFile, response.BodyFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read response body file '%s': %w", response.BodyFile, err)
+	}
+	response.bodyFileContent = content
+	response.bodyFileContentType = bodyFileContentType(path, content)
+	return nil
+}
+
+// loadBodyFiles reads the response body file of every definition that has one. Definitions whose body file
+// cannot be read are dropped, like definitions that cannot be parsed.
+func (sms *StaticMockService) loadBodyFiles(filePath string, definitions []StaticMockDefinition) []StaticMockDefinition {
+	loaded := definitions[:0]
+	for _, definition := range definitions {
+		if definition.Response.BodyFile != "" {
+			if err := readBodyFile(filePath, &definition.Response); err != nil {
+				sms.logger.Error(err.Error(), "file", filePath, "id", definition.Id)
+				continue
+			}
+		}
+		loaded = append(loaded, definition)
+	}
+	return loaded
+}
@@ -0,0 +1,56 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMockDefinitionFile_BodyFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "files"), 0755))
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00{{ not a template }}")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "files", "avatar.png"), png, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "files", "user.json"),
+		[]byte(`{"method": "${method}"}`), 0644))
+	mockFile := filepath.Join(dir, "mocks.json")
+	require.NoError(t, os.WriteFile(mockFile, []byte(`[
+		{"id": "binary", "request": {"method": "GET"}, "response": {"statusCode": 200, "bodyFile": "files/avatar.png"}},
+		{"id": "json", "request": {"method": "GET"}, "response": {"statusCode": 200, "bodyFile": "files/user.json"}},
+		{"id": "missing", "request": {"method": "GET"}, "response": {"bodyFile": "files/missing.json"}}
+	]`), 0644))
+
+	sms := newTestStaticMockService()
+	definitions, err := sms.loadMockDefinitionFile(mockFile)
+	require.NoError(t, err)
+	require.Len(t, definitions, 2)
+
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/avatar", nil)
+	response := sms.getStaticMockResponse(definitions[0], request)
+	body, _ := io.ReadAll(response.Body)
+	assert.Equal(t, png, body)
+	assert.Equal(t, "image/png", response.Header.Get("Content-Type"))
+
+	response = sms.getStaticMockResponse(definitions[1], request)
+	body, _ = io.ReadAll(response.Body)
+	assert.JSONEq(t, `{"method": "GET"}`, string(body))
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+}
+
+func TestIsTextContentType(t *testing.T) {
+	assert.True(t, isTextContentType("application/json"))
+	assert.True(t, isTextContentType("text/plain; charset=utf-8"))
+	assert.True(t, isTextContentType("application/problem+xml"))
+	assert.False(t, isTextContentType("image/png"))
+	assert.False(t, isTextContentType("application/octet-stream"))
+}
@@ -0,0 +1,80 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultChunkSizeBytes is the size of each chunk of a chunked response, when the definition does not set one.
+const DefaultChunkSizeBytes = 1024
+
+// chunkedResponseWriter splits everything written to it into chunks, flushing each chunk to the client and
+// pausing between chunks. Flushing before the body is complete makes the server use chunked transfer encoding.
+type chunkedResponseWriter struct {
+	http.ResponseWriter
+	ctx       context.Context
+	chunkSize int
+	delay     time.Duration
+	written   bool
+}
+
+// newChunkedResponseWriter wraps the writer for a chunked mock response. The pause between chunks is cut short
+// when the request context is done, so a client going away does not hold the handler.
+func newChunkedResponseWriter(ctx context.Context, w http.ResponseWriter,
+	response *StaticMockDefinitionResponse) *chunkedResponseWriter {
+
+	chunkSize := response.ChunkSizeBytes
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSizeBytes
+	}
+	return &chunkedResponseWriter{
+		ResponseWriter: w,
+		ctx:            ctx,
+		chunkSize:      chunkSize,
+		delay:          time.Duration(response.ChunkDelayMs) * time.Millisecond,
+	}
+}
+
+func (cw *chunkedResponseWriter) WriteHeader(statusCode int) {
+	// chunked responses never have a length, the header would make clients wait for the whole body.
+	cw.Header().Del("Content-Length")
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *chunkedResponseWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if cw.written && cw.delay > 0 {
+			select {
+			case <-time.After(cw.delay):
+			case <-cw.ctx.Done():
+				return written, cw.ctx.Err()
+			}
+		}
+		chunk := p
+		if len(chunk) > cw.chunkSize {
+			chunk = chunk[:cw.chunkSize]
+		}
+		n, err := cw.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		cw.Flush()
+		cw.written = true
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (cw *chunkedResponseWriter) Flush() {
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkRecorder records every write and flush made to it.
+type chunkRecorder struct {
+	*httptest.ResponseRecorder
+	writes  []string
+	flushes int
+}
+
+func (cr *chunkRecorder) Write(p []byte) (int, error) {
+	cr.writes = append(cr.writes, string(p))
+	return cr.ResponseRecorder.Write(p)
+}
+
+func (cr *chunkRecorder) Flush() {
+	cr.flushes++
+}
+
+func TestChunkedResponseWriter(t *testing.T) {
+	recorder := &chunkRecorder{ResponseRecorder: httptest.NewRecorder()}
+	cw := newChunkedResponseWriter(context.Background(), recorder,
+		&StaticMockDefinitionResponse{ChunkSizeBytes: 4, ChunkDelayMs: 10})
+
+	start := time.Now()
+	n, err := cw.Write([]byte("0123456789"))
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, []string{"0123", "4567", "89"}, recorder.writes)
+	assert.Equal(t, 3, recorder.flushes)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Equal(t, "0123456789", recorder.Body.String())
+}
+
+func TestChunkedResponseWriter_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder := &chunkRecorder{ResponseRecorder: httptest.NewRecorder()}
+	cw := newChunkedResponseWriter(ctx, recorder, &StaticMockDefinitionResponse{ChunkSizeBytes: 2, ChunkDelayMs: 1000})
+
+	n, err := cw.Write([]byte("0123"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, n)
+}
+
+func TestGetStaticMockResponse_Chunked(t *testing.T) {
+	sms := newTestStaticMockService()
+	definition := StaticMockDefinition{
+		Request: StaticMockDefinitionRequest{Method: http.MethodGet},
+		Response: StaticMockDefinitionResponse{StatusCode: 200, Body: `{"pizza": "margherita"}`,
+			ChunkedResponse: true, ChunkSizeBytes: 5},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := sms.getStaticMockResponse(definition, r)
+		cw := newChunkedResponseWriter(r.Context(), w, &definition.Response)
+		for k, v := range response.Header {
+			cw.Header()[k] = v
+		}
+		cw.WriteHeader(response.StatusCode)
+		_, _ = io.Copy(cw, response.Body)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	body, _ := io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"pizza": "margherita"}`, string(body))
+}
@@ -0,0 +1,118 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+const (
+	CompressionGzip    = "gzip"
+	CompressionDeflate = "deflate"
+	CompressionBrotli  = "br"
+)
+
+// compressionAlgorithm returns the algorithm a response is compressed with, gzip is used when none is set.
+func compressionAlgorithm(response *StaticMockDefinitionResponse) string {
+	if response.CompressionAlgorithm == "" {
+		return CompressionGzip
+	}
+	return strings.ToLower(response.CompressionAlgorithm)
+}
+
+// isCompressionAlgorithm checks if the algorithm is one mock responses can be compressed with.
+func isCompressionAlgorithm(algorithm string) bool {
+	switch strings.ToLower(algorithm) {
+	case CompressionGzip, CompressionDeflate, CompressionBrotli:
+		return true
+	}
+	return false
+}
+
+// compressBody compresses a response body with the algorithm, as named by the Content-Encoding header.
+func compressBody(body []byte, algorithm string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch algorithm {
+	case CompressionGzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionDeflate:
+		// the 'deflate' content coding is the zlib format (RFC 1950), not a raw deflate stream.
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionBrotli:
+		writeBrotliStored(&buf, body)
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm '%s', expected one of %s, %s or %s",
+			algorithm, CompressionGzip, CompressionDeflate, CompressionBrotli)
+	}
+	return buf.Bytes(), nil
+}
+
+// brotliMaxStoredBlock is the largest uncompressed meta-block that fits a four nibble length.
+const brotliMaxStoredBlock = 1 << 16
+
+// writeBrotliStored writes a valid brotli stream (RFC 7932) made of uncompressed meta-blocks. The body is not made
+// any smaller, the goal is to check that clients decode the 'br' content coding, without a brotli encoder dependency.
+func writeBrotliStored(buf *bytes.Buffer, body []byte) {
+	bw := &bitWriter{buf: buf}
+	bw.write(0, 1) // WBITS: a window of 16 bits.
+	for len(body) > 0 {
+		block := body
+		if len(block) > brotliMaxStoredBlock {
+			block = block[:brotliMaxStoredBlock]
+		}
+		bw.write(0, 1)                     // ISLAST: uncompressed meta-blocks are never the last one.
+		bw.write(0, 2)                     // MNIBBLES: four nibbles.
+		bw.write(uint64(len(block)-1), 16) // MLEN - 1.
+		bw.write(1, 1)                     // ISUNCOMPRESSED.
+		bw.flush()
+		buf.Write(block)
+		body = body[len(block):]
+	}
+	bw.write(1, 1) // ISLAST.
+	bw.write(1, 1) // ISLASTEMPTY.
+	bw.flush()
+}
+
+// bitWriter writes bits least significant first, as brotli expects.
+type bitWriter struct {
+	buf   *bytes.Buffer
+	bits  uint64
+	count uint
+}
+
+func (bw *bitWriter) write(value uint64, n uint) {
+	bw.bits |= value << bw.count
+	bw.count += n
+	for bw.count >= 8 {
+		bw.buf.WriteByte(byte(bw.bits))
+		bw.bits >>= 8
+		bw.count -= 8
+	}
+}
+
+// flush pads the pending bits with zeros, to the next byte boundary.
+func (bw *bitWriter) flush() {
+	if bw.count > 0 {
+		bw.buf.WriteByte(byte(bw.bits))
+	}
+	bw.bits, bw.count = 0, 0
+}
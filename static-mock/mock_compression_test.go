@@ -0,0 +1,63 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStaticMockResponse_Compressed(t *testing.T) {
+	sms := newTestStaticMockService()
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost/pizza", nil)
+	definition := StaticMockDefinition{
+		Request:  StaticMockDefinitionRequest{Method: http.MethodGet},
+		Response: StaticMockDefinitionResponse{StatusCode: 200, Body: `{"pizza": "margherita"}`, CompressResponse: true},
+	}
+
+	response := sms.getStaticMockResponse(definition, request)
+	assert.Equal(t, "gzip", response.Header.Get("Content-Encoding"))
+	reader, err := gzip.NewReader(response.Body)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(reader)
+	assert.JSONEq(t, `{"pizza": "margherita"}`, string(body))
+
+	definition.Response.CompressionAlgorithm = "deflate"
+	response = sms.getStaticMockResponse(definition, request)
+	assert.Equal(t, "deflate", response.Header.Get("Content-Encoding"))
+	reader2, err := zlib.NewReader(response.Body)
+	require.NoError(t, err)
+	body, _ = io.ReadAll(reader2)
+	assert.JSONEq(t, `{"pizza": "margherita"}`, string(body))
+
+	definition.Response.CompressionAlgorithm = "zstd"
+	response = sms.getStaticMockResponse(definition, request)
+	assert.Empty(t, response.Header.Get("Content-Encoding"))
+	body, _ = io.ReadAll(response.Body)
+	assert.JSONEq(t, `{"pizza": "margherita"}`, string(body))
+}
+
+func TestWriteBrotliStored(t *testing.T) {
+	var buf bytes.Buffer
+	writeBrotliStored(&buf, nil)
+	assert.Equal(t, []byte{0x06}, buf.Bytes())
+
+	buf.Reset()
+	writeBrotliStored(&buf, []byte("hi"))
+	assert.Equal(t, []byte{0x10, 0x00, 0x10, 'h', 'i', 0x03}, buf.Bytes())
+
+	// bodies larger than a meta-block are split.
+	buf.Reset()
+	writeBrotliStored(&buf, make([]byte, brotliMaxStoredBlock+1))
+	assert.Equal(t, 3+brotliMaxStoredBlock+3+1+1, buf.Len())
+}
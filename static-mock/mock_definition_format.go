@@ -0,0 +1,127 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	MockDefinitionFormatJSON = "json"
+	MockDefinitionFormatYAML = "yaml"
+	MockDefinitionFormatTOML = "toml"
+
+	// TOMLMockDefinitionsKey is the array of tables used to hold multiple definitions in a single TOML file,
+	// as TOML documents cannot be a top level array.
+	TOMLMockDefinitionsKey = "mocks"
+)
+
+var mockDefinitionExtensions = map[string]string{
+	".json": MockDefinitionFormatJSON,
+	".yaml": MockDefinitionFormatYAML,
+	".yml":  MockDefinitionFormatYAML,
+	".toml": MockDefinitionFormatTOML,
+}
+
+// formatFromExtension returns the mock definition format for a file name, or an empty string
+// if the extension is not recognized.
+func formatFromExtension(fileName string) string {
+	return mockDefinitionExtensions[strings.ToLower(filepath.Ext(fileName))]
+}
+
+// mockDefinitionFormat determines which parser to use for a mock definition file. The file extension wins,
+// when it does not identify a format, the configured override is used, falling back to JSON.
+func mockDefinitionFormat(fileName, override string) string {
+	if format := formatFromExtension(fileName); format != "" {
+		return format
+	}
+	if override != "" {
+		return strings.ToLower(override)
+	}
+	return MockDefinitionFormatJSON
+}
+
+// parseMockDefinitions decodes the raw bytes of a mock definition file into a generic object or array, ready
+// to be converted into StaticMockDefinitions. Every format produces the same generic structure as JSON does.
+func parseMockDefinitions(data []byte, format string) (interface{}, error) {
+	var mockDefinitions interface{}
+
+	switch format {
+	case MockDefinitionFormatJSON:
+		if err := json.Unmarshal(data, &mockDefinitions); err != nil {
+			return nil, err
+		}
+	case MockDefinitionFormatYAML:
+		if err := yaml.Unmarshal(data, &mockDefinitions); err != nil {
+			return nil, err
+		}
+	case MockDefinitionFormatTOML:
+		var tomlDefinitions map[string]interface{}
+		if err := toml.Unmarshal(data, &tomlDefinitions); err != nil {
+			return nil, err
+		}
+		// a TOML file holds either a single definition, or an array of tables under the 'mocks' key.
+		if mocks, ok := tomlDefinitions[TOMLMockDefinitionsKey]; ok {
+			return toGenericArray(mocks), nil
+		}
+		mockDefinitions = tomlDefinitions
+	default:
+		return nil, fmt.Errorf("unsupported mock definition format '%s'", format)
+	}
+	return mockDefinitions, nil
+}
+
+// toGenericArray converts the typed array of tables returned by the TOML decoder into an []interface{},
+// which is what the JSON and YAML decoders return for arrays.
+func toGenericArray(value interface{}) interface{} {
+	if tables, ok := value.([]map[string]interface{}); ok {
+		items := make([]interface{}, len(tables))
+		for i := range tables {
+			items[i] = tables[i]
+		}
+		return items
+	}
+	return value
+}
+
+// marshalMockDefinitions encodes definitions in the given format, so they can be written back to a mock definition
+// file. Definitions are converted through JSON first, so every format uses the same field names.
+func marshalMockDefinitions(definitions []StaticMockDefinition, format string) ([]byte, error) {
+	if definitions == nil {
+		definitions = []StaticMockDefinition{}
+	}
+	data, err := json.MarshalIndent(definitions, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case MockDefinitionFormatJSON:
+		return data, nil
+	case MockDefinitionFormatYAML, MockDefinitionFormatTOML:
+		var generic []interface{}
+		if err = json.Unmarshal(data, &generic); err != nil {
+			return nil, err
+		}
+		if format == MockDefinitionFormatYAML {
+			return yaml.Marshal(generic)
+		}
+		var buf bytes.Buffer
+		if err = toml.NewEncoder(&buf).Encode(map[string]interface{}{TOMLMockDefinitionsKey: generic}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported mock definition format '%s'", format)
+	}
+}
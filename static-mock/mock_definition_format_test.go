@@ -0,0 +1,79 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockDefinitionFormat(t *testing.T) {
+	assert.Equal(t, MockDefinitionFormatJSON, mockDefinitionFormat("mock.json", ""))
+	assert.Equal(t, MockDefinitionFormatYAML, mockDefinitionFormat("mock.yml", ""))
+	assert.Equal(t, MockDefinitionFormatYAML, mockDefinitionFormat("mock.YAML", "toml"))
+	assert.Equal(t, MockDefinitionFormatTOML, mockDefinitionFormat("mock.toml", ""))
+	assert.Equal(t, MockDefinitionFormatTOML, mockDefinitionFormat("mock.def", "TOML"))
+	assert.Equal(t, MockDefinitionFormatJSON, mockDefinitionFormat("mock.def", ""))
+}
+
+func TestParseMockDefinitions_AllFormatsMatch(t *testing.T) {
+	jsonDef := `[{"request": {"method": "GET", "urlPath": "/test"}, "response": {"statusCode": 201, "body": "ok"}}]`
+
+	yamlDef := `- request:
+    method: GET
+    urlPath: /test
+  response:
+    statusCode: 201
+    body: ok`
+
+	tomlDef := `[[mocks]]
+[mocks.request]
+method = "GET"
+urlPath = "/test"
+[mocks.response]
+statusCode = 201
+body = "ok"`
+
+	var results [][]StaticMockDefinition
+	for format, def := range map[string]string{
+		MockDefinitionFormatJSON: jsonDef,
+		MockDefinitionFormatYAML: yamlDef,
+		MockDefinitionFormatTOML: tomlDef,
+	} {
+		parsed, err := parseMockDefinitions([]byte(def), format)
+		assert.NoError(t, err)
+
+		items, ok := parsed.([]interface{})
+		assert.True(t, ok, format)
+
+		var defs []StaticMockDefinition
+		for _, item := range items {
+			d, e := getDefinitionFromJson(item.(map[string]interface{}))
+			assert.NoError(t, e)
+			defs = append(defs, d)
+		}
+		results = append(results, defs)
+	}
+
+	for _, defs := range results {
+		assert.Len(t, defs, 1)
+		assert.Equal(t, results[0], defs)
+		assert.Equal(t, "GET", defs[0].Request.Method)
+		assert.Equal(t, "/test", defs[0].Request.UrlPath)
+		assert.Equal(t, 201, defs[0].Response.StatusCode)
+	}
+}
+
+func TestParseMockDefinitions_TOMLSingle(t *testing.T) {
+	parsed, err := parseMockDefinitions([]byte("[request]\nmethod = \"POST\"\n"), MockDefinitionFormatTOML)
+	assert.NoError(t, err)
+	_, ok := parsed.(map[string]interface{})
+	assert.True(t, ok)
+
+	_, err = parseMockDefinitions([]byte("{}"), "xml")
+	assert.Error(t, err)
+}
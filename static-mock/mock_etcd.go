@@ -0,0 +1,387 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pb33f/wiretap/shared"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// DefaultEtcdPrefix is the etcd prefix mock definitions are synchronized under, when none is configured.
+	DefaultEtcdPrefix = "/wiretap/mocks"
+
+	defaultEtcdDialTimeout = 5 * time.Second
+	etcdRequestTimeout     = 10 * time.Second
+	etcdRetryInterval      = 5 * time.Second
+	etcdPublishBuffer      = 64
+	etcdRuntimeSetKey      = "/runtime"
+	etcdFileSetKeyStart    = "/files/"
+)
+
+// etcdSync synchronizes definition sets between wiretap instances through etcd. Each definition set is stored as a
+// single key under the prefix, file sets are keyed by their path relative to the mock definitions directory, so every
+// instance applies them to its own directory. Changes made by this instance are published in order, and changes made
+// by other instances are applied as they are watched. Changes carry the instance that made them, so an instance does
+// not apply its own changes twice. When a lease TTL is configured, the keys an instance published are removed by etcd
+// once the instance stops keeping its lease alive.
+type etcdSync struct {
+	sms      *StaticMockService
+	kv       clientv3.KV
+	watcher  clientv3.Watcher
+	lease    clientv3.Lease
+	closer   io.Closer
+	prefix   string
+	origin   string
+	leaseTTL int64
+	leaseID  atomic.Int64
+	publish  chan *etcdDefinitionSet
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// etcdDefinitionSet is the value stored for a definition set.
+type etcdDefinitionSet struct {
+	Origin      string                 `json:"origin"`
+	Definitions []StaticMockDefinition `json:"definitions"`
+
+	// the etcd key the set is stored under.
+	key string
+}
+
+// StartEtcdSync loads the definition sets stored in etcd, then watches them for changes in the background and starts
+// publishing the changes made through the admin API. Nothing happens unless etcd endpoints are configured.
+func (sms *StaticMockService) StartEtcdSync() {
+	config := sms.config.Etcd
+	if config == nil || len(config.Endpoints) == 0 {
+		return
+	}
+	clientConfig, err := etcdClientConfig(config)
+	var client *clientv3.Client
+	if err == nil {
+		client, err = clientv3.New(clientConfig)
+	}
+	if err != nil {
+		sms.logger.Error("Unable to connect to etcd, mock definitions are not synchronized",
+			"endpoints", strings.Join(config.Endpoints, ", "), "error", err.Error())
+		return
+	}
+	sms.startEtcdSync(client.KV, client.Watcher, client.Lease, client)
+}
+
+// startEtcdSync starts synchronizing through an etcd client.
+func (sms *StaticMockService) startEtcdSync(kv clientv3.KV, watcher clientv3.Watcher, lease clientv3.Lease,
+	closer io.Closer) {
+
+	config := sms.config.Etcd
+	prefix := strings.TrimSuffix(config.Prefix, "/")
+	if prefix == "" {
+		prefix = DefaultEtcdPrefix
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	es := &etcdSync{
+		sms:      sms,
+		kv:       kv,
+		watcher:  watcher,
+		lease:    lease,
+		closer:   closer,
+		prefix:   prefix,
+		origin:   uuid.NewString(),
+		leaseTTL: int64(config.LeaseTTLSeconds),
+		publish:  make(chan *etcdDefinitionSet, etcdPublishBuffer),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	sms.lock.Lock()
+	sms.etcd = es
+	sms.lock.Unlock()
+
+	revision, err := es.load(true)
+	if err != nil {
+		sms.logger.Error("Unable to load mock definitions from etcd", "prefix", prefix, "error", err.Error())
+	}
+	go es.run(revision)
+	go es.publishChanges()
+}
+
+// etcdClientConfig builds the etcd client configuration. TLS is used when a certificate is configured, or when an
+// endpoint is an https URL.
+func etcdClientConfig(config *shared.EtcdConfig) (clientv3.Config, error) {
+	dialTimeout := defaultEtcdDialTimeout
+	if config.DialTimeoutMs > 0 {
+		dialTimeout = time.Duration(config.DialTimeoutMs) * time.Millisecond
+	}
+	clientConfig := clientv3.Config{
+		Endpoints:   config.Endpoints,
+		Username:    config.Username,
+		Password:    config.Password,
+		DialTimeout: dialTimeout,
+	}
+	useTLS := config.CACertFile != "" || config.CertFile != ""
+	for _, endpoint := range config.Endpoints {
+		if u, err := url.Parse(endpoint); err == nil && u.Scheme == "https" {
+			useTLS = true
+		}
+	}
+	if useTLS {
+		tlsInfo := transport.TLSInfo{
+			CertFile:      config.CertFile,
+			KeyFile:       config.KeyFile,
+			TrustedCAFile: config.CACertFile,
+		}
+		tlsConfig, err := tlsInfo.ClientConfig()
+		if err != nil {
+			return clientv3.Config{}, fmt.Errorf("unable to configure etcd TLS: %w", err)
+		}
+		clientConfig.TLS = tlsConfig
+	}
+	return clientConfig, nil
+}
+
+// stopEtcdSync stops synchronizing. The lease of this instance is revoked, so its keys are removed right away.
+func (sms *StaticMockService) stopEtcdSync() {
+	sms.lock.Lock()
+	es := sms.etcd
+	sms.etcd = nil
+	sms.lock.Unlock()
+	if es == nil {
+		return
+	}
+	if leaseID := clientv3.LeaseID(es.leaseID.Load()); leaseID != clientv3.NoLease {
+		ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+		if _, err := es.lease.Revoke(ctx, leaseID); err != nil {
+			sms.logger.Warn("Unable to revoke etcd lease", "error", err.Error())
+		}
+		cancel()
+	}
+	es.cancel()
+	_ = es.closer.Close()
+}
+
+// publishDefinitionSet queues a definition set changed through the admin API, to be written to etcd. Only the runtime
+// set and the sets of the mock definitions directory are published. Sets loaded from a URL or a git repository are
+// not, every instance loads those itself. The caller must hold the write lock.
+func (sms *StaticMockService) publishDefinitionSet(key string, definitions []StaticMockDefinition) {
+	if sms.etcd == nil {
+		return
+	}
+	etcdKey, ok := sms.etcd.key(key)
+	if !ok {
+		return
+	}
+	set := &etcdDefinitionSet{Origin: sms.etcd.origin, Definitions: definitions, key: etcdKey}
+	select {
+	case sms.etcd.publish <- set:
+	default:
+		sms.logger.Error("etcd publish buffer is full, mock definition change is not synchronized", "set", key)
+	}
+}
+
+// run watches the prefix from the revision after the one loaded, reloading and watching again when the watch ends.
+func (es *etcdSync) run(revision int64) {
+	for {
+		err := es.watch(revision + 1)
+		if es.ctx.Err() != nil {
+			return
+		}
+		es.sms.logger.Warn("etcd watch of mock definitions ended, reconnecting", "prefix", es.prefix,
+			"error", fmt.Sprint(err))
+		select {
+		case <-es.ctx.Done():
+			return
+		case <-time.After(etcdRetryInterval):
+		}
+		if loaded, err := es.load(false); err == nil {
+			revision = loaded
+		}
+	}
+}
+
+// publishChanges writes queued definition sets to etcd, in the order they were changed.
+func (es *etcdSync) publishChanges() {
+	for {
+		select {
+		case <-es.ctx.Done():
+			return
+		case set := <-es.publish:
+			if err := es.put(set); err != nil {
+				es.sms.logger.Error("Unable to publish mock definitions to etcd", "key", set.key, "error", err.Error())
+				continue
+			}
+			es.sms.logger.Info("Mock definitions published to etcd", "key", set.key,
+				"definitions", len(set.Definitions))
+		}
+	}
+}
+
+// put writes a definition set, attached to the lease of this instance when a lease TTL is configured.
+func (es *etcdSync) put(set *etcdDefinitionSet) error {
+	value, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(es.ctx, etcdRequestTimeout)
+	defer cancel()
+	var options []clientv3.OpOption
+	if es.leaseTTL > 0 {
+		leaseID, err := es.currentLease(ctx)
+		if err != nil {
+			return err
+		}
+		options = append(options, clientv3.WithLease(leaseID))
+	}
+	_, err = es.kv.Put(ctx, set.key, string(value), options...)
+	return err
+}
+
+// currentLease returns the lease of this instance, granting a new one and keeping it alive when there is none yet,
+// or when the previous one was lost.
+func (es *etcdSync) currentLease(ctx context.Context) (clientv3.LeaseID, error) {
+	if leaseID := clientv3.LeaseID(es.leaseID.Load()); leaseID != clientv3.NoLease {
+		return leaseID, nil
+	}
+	grant, err := es.lease.Grant(ctx, es.leaseTTL)
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+	keepAlive, err := es.lease.KeepAlive(es.ctx, grant.ID)
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+	es.leaseID.Store(int64(grant.ID))
+	go func() {
+		for range keepAlive {
+		}
+		// the lease expired, or wiretap is stopping. Keys published from now on need a new lease.
+		es.leaseID.CompareAndSwap(int64(grant.ID), int64(clientv3.NoLease))
+	}()
+	return grant.ID, nil
+}
+
+// load applies every definition set stored under the prefix, and returns the revision they were read at.
+func (es *etcdSync) load(starting bool) (int64, error) {
+	ctx, cancel := context.WithTimeout(es.ctx, etcdRequestTimeout)
+	defer cancel()
+	response, err := es.kv.Get(ctx, es.prefix+"/", clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+	for _, kv := range response.Kvs {
+		es.apply(string(kv.Key), kv.Value, false, starting)
+	}
+	return response.Header.Revision, nil
+}
+
+// watch applies the changes under the prefix from a revision, until the watch ends.
+func (es *etcdSync) watch(revision int64) error {
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(es.ctx))
+	defer cancel()
+	for response := range es.watcher.Watch(ctx, es.prefix+"/", clientv3.WithPrefix(), clientv3.WithRev(revision)) {
+		if err := response.Err(); err != nil {
+			return err
+		}
+		for _, event := range response.Events {
+			es.apply(string(event.Kv.Key), event.Kv.Value, event.Type == clientv3.EventTypeDelete, false)
+		}
+	}
+	return errors.New("etcd watch closed")
+}
+
+// apply applies a stored definition set, or removes it when it was deleted. The changes of this instance are only
+// applied when it starts, as they are already active otherwise.
+func (es *etcdSync) apply(etcdKey string, value []byte, deleted, starting bool) {
+	sms := es.sms
+	key, ok := es.setKey(etcdKey)
+	if !ok {
+		sms.logger.Warn("Ignoring etcd key outside of the mock definitions", "key", etcdKey)
+		return
+	}
+	if deleted {
+		sms.lock.Lock()
+		previous, existed := sms.definitionsByFile[key]
+		var err error
+		if existed {
+			delete(sms.definitionsByFile, key)
+			if err = sms.mergeMockDefinitions(); err != nil {
+				sms.definitionsByFile[key] = previous
+			}
+		}
+		sms.lock.Unlock()
+		if err != nil {
+			sms.logger.Error("Unable to remove mock definitions deleted from etcd", "set", key, "error", err.Error())
+			return
+		}
+		sms.logger.Info("Mock definitions removed through etcd", "set", key)
+		return
+	}
+
+	var set etcdDefinitionSet
+	if err := json.Unmarshal(value, &set); err != nil {
+		sms.logger.Error("Unable to parse mock definitions from etcd", "key", etcdKey, "error", err.Error())
+		return
+	}
+	if set.Origin == es.origin && !starting {
+		return
+	}
+	// body files are not stored in etcd, they are read by every instance from its own directory.
+	definitions := sms.loadBodyFiles(key, set.Definitions)
+	sms.lock.Lock()
+	err := sms.swapDefinitionSet(key, definitions)
+	sms.lock.Unlock()
+	if err != nil {
+		sms.logger.Error("Unable to apply mock definitions from etcd, keeping previous definitions",
+			"set", key, "error", err.Error())
+		return
+	}
+	sms.logger.Info("Mock definitions synchronized from etcd", "set", key, "definitions", len(definitions))
+}
+
+// key is the etcd key of a definition set: the runtime set, or a file by its path relative to the mock definitions
+// directory. Files outside of the directory have no key.
+func (es *etcdSync) key(setKey string) (string, bool) {
+	if setKey == runtimeDefinitionsKey {
+		return es.prefix + etcdRuntimeSetKey, true
+	}
+	dir := es.sms.mockDefinitionsDir()
+	if dir == "" {
+		return "", false
+	}
+	relative, err := filepath.Rel(dir, setKey)
+	if err != nil || !filepath.IsLocal(relative) {
+		return "", false
+	}
+	return es.prefix + etcdFileSetKeyStart + filepath.ToSlash(relative), true
+}
+
+// setKey is the definition set of an etcd key, files are resolved against the local mock definitions directory.
+func (es *etcdSync) setKey(etcdKey string) (string, bool) {
+	key, ok := strings.CutPrefix(etcdKey, es.prefix)
+	if !ok {
+		return "", false
+	}
+	if key == etcdRuntimeSetKey {
+		return runtimeDefinitionsKey, true
+	}
+	relative, ok := strings.CutPrefix(key, etcdFileSetKeyStart)
+	dir := es.sms.mockDefinitionsDir()
+	if !ok || dir == "" || !filepath.IsLocal(filepath.FromSlash(relative)) {
+		return "", false
+	}
+	return filepath.Join(dir, filepath.FromSlash(relative)), true
+}
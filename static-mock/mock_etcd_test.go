@@ -0,0 +1,233 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeEtcd answers the get, put, watch and lease calls of the etcd client. The embedded interfaces are left nil,
+// the calls wiretap does not make panic.
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Watcher
+	clientv3.Lease
+
+	lock     sync.Mutex
+	revision int64
+	values   map[string][]byte
+	events   chan clientv3.WatchResponse
+	granted  []clientv3.LeaseID
+	revoked  []clientv3.LeaseID
+	closed   bool
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{values: make(map[string][]byte), events: make(chan clientv3.WatchResponse, 10)}
+}
+
+func (fe *fakeEtcd) change(key string, value []byte, deleted bool) {
+	fe.lock.Lock()
+	fe.revision++
+	event := &clientv3.Event{Type: mvccpb.PUT,
+		Kv: &mvccpb.KeyValue{Key: []byte(key), Value: value, ModRevision: fe.revision}}
+	if deleted {
+		delete(fe.values, key)
+		event.Type = mvccpb.DELETE
+	} else {
+		fe.values[key] = value
+	}
+	fe.lock.Unlock()
+	fe.events <- clientv3.WatchResponse{Events: []*clientv3.Event{event}}
+}
+
+func (fe *fakeEtcd) value(key string) []byte {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	return fe.values[key]
+}
+
+func (fe *fakeEtcd) Get(_ context.Context, key string, _ ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	response := &clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: fe.revision}}
+	for k, value := range fe.values {
+		if strings.HasPrefix(k, key) {
+			response.Kvs = append(response.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: value})
+		}
+	}
+	return response, nil
+}
+
+func (fe *fakeEtcd) Put(_ context.Context, key, value string, _ ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	fe.change(key, []byte(value), false)
+	return &clientv3.PutResponse{}, nil
+}
+
+func (fe *fakeEtcd) Watch(_ context.Context, _ string, _ ...clientv3.OpOption) clientv3.WatchChan {
+	return fe.events
+}
+
+func (fe *fakeEtcd) Grant(_ context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	id := clientv3.LeaseID(len(fe.granted) + 1)
+	fe.granted = append(fe.granted, id)
+	return &clientv3.LeaseGrantResponse{ID: id, TTL: ttl}, nil
+}
+
+func (fe *fakeEtcd) KeepAlive(ctx context.Context, _ clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse,
+	error) {
+	keepAlive := make(chan *clientv3.LeaseKeepAliveResponse)
+	go func() {
+		<-ctx.Done()
+		close(keepAlive)
+	}()
+	return keepAlive, nil
+}
+
+func (fe *fakeEtcd) Revoke(_ context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	fe.revoked = append(fe.revoked, id)
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+func (fe *fakeEtcd) Close() error {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	fe.closed = true
+	return nil
+}
+
+func peerDefinitionSet(ids ...string) []byte {
+	var definitions []StaticMockDefinition
+	for _, id := range ids {
+		definitions = append(definitions, StaticMockDefinition{Id: id,
+			Request: StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "/" + id}})
+	}
+	data, _ := json.Marshal(etcdDefinitionSet{Origin: "peer", Definitions: definitions})
+	return data
+}
+
+func TestStaticMockService_EtcdSync(t *testing.T) {
+	etcd := newFakeEtcd()
+	etcd.values["/mocks/runtime"] = peerDefinitionSet("shared")
+
+	dir := t.TempDir()
+	sms := newTestStaticMockService(StaticMockDefinition{Id: "local"})
+	sms.config.MockDefinitionsDir = dir
+	sms.config.Etcd = &shared.EtcdConfig{Endpoints: []string{"http://etcd:2379"}, Prefix: "/mocks/",
+		LeaseTTLSeconds: 30}
+	sms.startEtcdSync(etcd, etcd, etcd, etcd)
+
+	ids := func() []string {
+		var ids []string
+		for _, definition := range sms.getMockDefinitions() {
+			ids = append(ids, definition.Id)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []string{"local", "shared"}, ids())
+
+	// changes made through the admin API are published, attached to a lease.
+	require.NoError(t, sms.AddMockDefinition(StaticMockDefinition{Id: "added",
+		Request: StaticMockDefinitionRequest{Method: http.MethodGet}}, false))
+	require.Eventually(t, func() bool {
+		var set etcdDefinitionSet
+		_ = json.Unmarshal(etcd.value("/mocks/runtime"), &set)
+		return len(set.Definitions) == 2 && set.Origin == sms.etcd.origin
+	}, time.Second, 10*time.Millisecond)
+	etcd.lock.Lock()
+	assert.Equal(t, []clientv3.LeaseID{1}, etcd.granted)
+	etcd.lock.Unlock()
+
+	// changes made by peers are watched, files are applied to the local mock definitions directory.
+	etcd.change("/mocks/files/pets/dogs.json", peerDefinitionSet("dogs"), false)
+	require.Eventually(t, func() bool { return len(ids()) == 4 }, time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"local", "shared", "added", "dogs"}, ids())
+	sms.lock.RLock()
+	assert.Contains(t, sms.definitionsByFile, filepath.Join(dir, "pets", "dogs.json"))
+	sms.lock.RUnlock()
+
+	// keys that would leave the mock definitions directory are ignored.
+	etcd.change("/mocks/files/../escaped.json", peerDefinitionSet("escaped"), false)
+	etcd.change("/mocks/runtime", nil, true)
+	require.Eventually(t, func() bool { return len(ids()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"local", "dogs"}, ids())
+
+	// stopping revokes the lease and closes the client.
+	es := sms.etcd
+	sms.OnServerShutdown()
+	assert.Nil(t, sms.etcd)
+	assert.Error(t, es.ctx.Err())
+	etcd.lock.Lock()
+	assert.Equal(t, []clientv3.LeaseID{1}, etcd.revoked)
+	assert.True(t, etcd.closed)
+	etcd.lock.Unlock()
+}
+
+func TestEtcdSync_Keys(t *testing.T) {
+	dir := t.TempDir()
+	sms := newTestStaticMockService()
+	sms.config.MockDefinitionsDir = dir
+	es := &etcdSync{sms: sms, prefix: "/wiretap/mocks"}
+
+	for key, etcdKey := range map[string]string{
+		runtimeDefinitionsKey:                        "/wiretap/mocks/runtime",
+		filepath.Join(dir, "pets.json"):              "/wiretap/mocks/files/pets.json",
+		filepath.Join(dir, "pets", "100% dogs.json"): "/wiretap/mocks/files/pets/100% dogs.json",
+	} {
+		storedKey, ok := es.key(key)
+		require.True(t, ok)
+		assert.Equal(t, etcdKey, storedKey)
+		setKey, ok := es.setKey(storedKey)
+		assert.True(t, ok)
+		assert.Equal(t, key, setKey)
+	}
+
+	// sets outside of the mock definitions directory, from a URL or a git repository, are not stored.
+	for _, key := range []string{"https://mocks.example.com/mocks.json", filepath.Join(t.TempDir(), "git.json")} {
+		_, ok := es.key(key)
+		assert.False(t, ok)
+	}
+	for _, etcdKey := range []string{"/wiretap/mocks/unknown", "/wiretap/mocks/files/../pets.json", "/other/runtime"} {
+		_, ok := es.setKey(etcdKey)
+		assert.False(t, ok)
+	}
+}
+
+func TestEtcdClientConfig(t *testing.T) {
+	config, err := etcdClientConfig(&shared.EtcdConfig{Endpoints: []string{"http://etcd:2379"},
+		Username: "dave", Password: "b33f"})
+	require.NoError(t, err)
+	assert.Nil(t, config.TLS)
+	assert.Equal(t, "dave", config.Username)
+	assert.Equal(t, "b33f", config.Password)
+	assert.Equal(t, defaultEtcdDialTimeout, config.DialTimeout)
+
+	config, err = etcdClientConfig(&shared.EtcdConfig{Endpoints: []string{"https://etcd:2379"}, DialTimeoutMs: 250})
+	require.NoError(t, err)
+	assert.NotNil(t, config.TLS)
+	assert.Equal(t, 250*time.Millisecond, config.DialTimeout)
+
+	_, err = etcdClientConfig(&shared.EtcdConfig{Endpoints: []string{"etcd:2379"}, CACertFile: "/missing/ca.pem"})
+	assert.ErrorContains(t, err, "unable to configure etcd TLS")
+}
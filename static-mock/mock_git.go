@@ -0,0 +1,161 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// syncGitMockDefinitions clones the mock definitions git repository to a temporary directory the first time, and
+// pulls it after that, then reloads the mock definition files at the root of the repository. When git fails, the
+// last loaded definitions keep being served.
+func (sms *StaticMockService) syncGitMockDefinitions() {
+	repo, branch := sms.config.MockDefinitionsGitRepo, sms.config.MockDefinitionsGitBranch
+	sms.logger.Info("Syncing mock definitions from git repository", "repo", repo, "branch", branch)
+
+	err := sms.pullGitRepository()
+	if err == nil {
+		err = sms.reloadMockDefinitionDir(sms.gitDir)
+	}
+	if err != nil {
+		sms.logger.Error("Unable to sync mock definitions from git repository, keeping last loaded definitions",
+			"repo", repo, "branch", branch, "error", err.Error())
+		return
+	}
+	sms.logger.Info("Mock definitions synced from git repository", "repo", repo, "branch", branch,
+		"definitions", len(sms.getMockDefinitions()))
+}
+
+// pullGitRepository brings the working copy up to date with the branch, cloning the repository when there is no
+// working copy yet. Only the latest commit is fetched.
+func (sms *StaticMockService) pullGitRepository() error {
+	branch := sms.config.MockDefinitionsGitBranch
+	if sms.gitDir == "" {
+		dir, err := os.MkdirTemp("", "wiretap-mock-definitions-")
+		if err != nil {
+			return err
+		}
+		args := []string{"clone", "--depth", "1", "--single-branch"}
+		if branch != "" {
+			args = append(args, "--branch", branch)
+		}
+		if err = sms.git(append(args, "--", sms.config.MockDefinitionsGitRepo, dir)...); err != nil {
+			_ = os.RemoveAll(dir)
+			return err
+		}
+		sms.lock.Lock()
+		sms.gitDir = dir
+		sms.lock.Unlock()
+		return nil
+	}
+	if branch == "" {
+		branch = "HEAD"
+	}
+	if err := sms.git("-C", sms.gitDir, "fetch", "--depth", "1", "origin", branch); err != nil {
+		return err
+	}
+	return sms.git("-C", sms.gitDir, "reset", "--hard", "FETCH_HEAD")
+}
+
+// removeGitWorkingCopy deletes the temporary clone of the mock definitions git repository, if there is one.
+func (sms *StaticMockService) removeGitWorkingCopy() {
+	sms.lock.Lock()
+	dir := sms.gitDir
+	sms.gitDir = ""
+	sms.lock.Unlock()
+	if dir == "" {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		sms.logger.Error("Unable to remove mock definitions git working copy", "dir", dir, "error", err.Error())
+	}
+}
+
+// git runs a git command, without prompting for credentials.
+func (sms *StaticMockService) git(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), sms.gitEnvironment()...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		command := args[0]
+		if command == "-C" && len(args) > 2 {
+			command = args[2]
+		}
+		return fmt.Errorf("git %s failed: %w: %s", command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// gitEnvironment authenticates git with the configured SSH key, or with HTTPS basic auth. The credentials are passed
+// in the environment, so they do not show up in the process list or in the repository configuration.
+func (sms *StaticMockService) gitEnvironment() []string {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	if sms.config.GitSSHKeyFile != "" {
+		keyFile := strings.ReplaceAll(sms.config.GitSSHKeyFile, "'", `'\''`)
+		env = append(env, "GIT_SSH_COMMAND=ssh -i '"+keyFile+"' -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new")
+	}
+	if sms.config.GitUsername != "" || sms.config.GitPassword != "" {
+		credentials := sms.config.ReplaceWithVariables(sms.config.GitUsername) + ":" +
+			sms.config.ReplaceWithVariables(sms.config.GitPassword)
+		env = append(env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	return env
+}
+
+// reloadMockDefinitionDir replaces the definition sets of the files in a directory with the files it holds now.
+// Sets of files that are gone are dropped. If the new sets cannot be applied, the previous sets are kept.
+func (sms *StaticMockService) reloadMockDefinitionDir(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	loaded := make(map[string][]StaticMockDefinition)
+	for _, file := range files {
+		filePath := filepath.Join(dir, file.Name())
+		if file.IsDir() || !sms.isMockDefinitionFile(filePath) {
+			continue
+		}
+		definitions, err := sms.loadMockDefinitionFile(filePath)
+		if err != nil {
+			sms.logger.Error("Error loading mock definition file", "file", filePath, "error", err.Error())
+			continue
+		}
+		loaded[filePath] = definitions
+	}
+
+	sms.lock.Lock()
+	defer sms.lock.Unlock()
+	previous := make(map[string][]StaticMockDefinition)
+	for key, definitions := range sms.definitionsByFile {
+		if sms.inMockDefinitionDir(dir, key) {
+			previous[key] = definitions
+			delete(sms.definitionsByFile, key)
+		}
+	}
+	for key, definitions := range loaded {
+		sms.definitionsByFile[key] = definitions
+	}
+	if err = sms.mergeMockDefinitions(); err != nil {
+		for key := range loaded {
+			delete(sms.definitionsByFile, key)
+		}
+		for key, definitions := range previous {
+			sms.definitionsByFile[key] = definitions
+		}
+		return err
+	}
+	return nil
+}
+
+// inMockDefinitionDir is true when a definition set was loaded from a file in the directory.
+func (sms *StaticMockService) inMockDefinitionDir(dir, key string) bool {
+	return dir != "" && filepath.Dir(key) == filepath.Clean(dir)
+}
@@ -0,0 +1,94 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticMockService_SyncGitMockDefinitions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	commit := func(file, content string) {
+		if content == "" {
+			require.NoError(t, os.Remove(filepath.Join(repo, file)))
+		} else {
+			require.NoError(t, os.WriteFile(filepath.Join(repo, file), []byte(content), 0o644))
+		}
+		for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", "mocks"}} {
+			cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=pb33f",
+				"-c", "user.email=pb33f@example.com"}, args...)...)
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(out))
+		}
+	}
+	out, err := exec.Command("git", "init", "-q", "-b", "mocks", repo).CombinedOutput()
+	require.NoError(t, err, string(out))
+	commit("pets.json", `{"id": "pets", "request": {"method": "GET", "urlPath": "/pets"}}`)
+
+	sms := newTestStaticMockService(StaticMockDefinition{Id: "local"})
+	sms.config.MockDefinitionsGitRepo = repo
+	sms.config.MockDefinitionsGitBranch = "mocks"
+	sms.syncGitMockDefinitions()
+	gitDir := sms.gitDir
+	require.DirExists(t, gitDir)
+	defer os.RemoveAll(gitDir)
+
+	ids := func() []string {
+		var ids []string
+		for _, definition := range sms.getMockDefinitions() {
+			ids = append(ids, definition.Id)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []string{"local", "pets"}, ids())
+
+	// pulled changes replace the definitions of the repository.
+	commit("owners.yaml", "id: owners\nrequest:\n  urlPath: /owners\n")
+	commit("pets.json", "")
+	sms.syncGitMockDefinitions()
+	assert.ElementsMatch(t, []string{"local", "owners"}, ids())
+
+	// the last loaded definitions are kept when the repository is gone.
+	require.NoError(t, os.RemoveAll(repo))
+	sms.syncGitMockDefinitions()
+	assert.ElementsMatch(t, []string{"local", "owners"}, ids())
+
+	// definitions from the repository cannot be written back.
+	sms.lock.Lock()
+	err = sms.updateDefinitionSet(filepath.Join(sms.gitDir, "owners.yaml"), nil, true)
+	sms.lock.Unlock()
+	assert.ErrorContains(t, err, "cannot be persisted")
+
+	// the working copy is removed when wiretap is stopped.
+	sms.OnServerShutdown()
+	assert.NoDirExists(t, gitDir)
+	assert.Empty(t, sms.gitDir)
+}
+
+func TestStaticMockService_GitEnvironment(t *testing.T) {
+	sms := newTestStaticMockService()
+	assert.Equal(t, []string{"GIT_TERMINAL_PROMPT=0"}, sms.gitEnvironment())
+
+	sms.config.GitSSHKeyFile = "/keys/dave's key"
+	sms.config.GitUsername = "dave"
+	sms.config.GitPassword = "b33f"
+	assert.Equal(t, []string{
+		"GIT_TERMINAL_PROMPT=0",
+		`GIT_SSH_COMMAND=ssh -i '/keys/dave'\''s key' -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new`,
+		"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte("dave:b33f")),
+	}, sms.gitEnvironment())
+}
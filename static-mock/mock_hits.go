@@ -0,0 +1,54 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+// recordHit increments the hit count of the mock definition.
+func (smd *StaticMockDefinition) recordHit() {
+	if smd.hits != nil {
+		smd.hits.Add(1)
+	}
+}
+
+// HitCount returns the number of requests the mock definition has served.
+func (smd *StaticMockDefinition) HitCount() int64 {
+	if smd.hits == nil {
+		return 0
+	}
+	return smd.hits.Load()
+}
+
+// resetHits sets the hit count of the mock definition back to zero.
+func (smd *StaticMockDefinition) resetHits() {
+	if smd.hits != nil {
+		smd.hits.Store(0)
+	}
+}
+
+// ResetMocks zeroes the hit count of every mock definition and returns the number of definitions reset.
+func (sms *StaticMockService) ResetMocks() int {
+	definitions := sms.getMockDefinitions()
+	for i := range definitions {
+		definitions[i].resetHits()
+	}
+	sms.logger.Info("Mock state reset", "definitions", len(definitions))
+	return len(definitions)
+}
+
+// ResetMock zeroes the hit count of the mock definitions with the given ID and returns the number of definitions
+// reset, zero means no mock definition with the ID exists.
+func (sms *StaticMockService) ResetMock(id string) int {
+	reset := 0
+	for _, definition := range sms.getMockDefinitions() {
+		if definition.Id == id {
+			definition.resetHits()
+			reset++
+		}
+	}
+	if reset > 0 {
+		sms.logger.Info("Mock state reset", "id", id)
+	}
+	return reset
+}
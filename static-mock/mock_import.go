@@ -0,0 +1,169 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/renderer"
+	"github.com/pb33f/wiretap/shared"
+)
+
+const AdminImportFromSpecPath = AdminApiBasePath + "/import-from-spec"
+
+// MockImportResponse is returned by the import from spec endpoint.
+type MockImportResponse struct {
+	Imported    int                    `json:"imported"`
+	Skipped     []string               `json:"skipped,omitempty"` // ids of operations that already have a definition.
+	Definitions []StaticMockDefinition `json:"definitions"`
+}
+
+var pathParameter = regexp.MustCompile(`\{[^/{}]+}`)
+
+// specPathPattern returns the URL path a generated definition matches. Path parameters match any single segment,
+// a path without parameters is matched literally.
+func specPathPattern(path string) string {
+	if !pathParameter.MatchString(path) {
+		return path
+	}
+	literals := pathParameter.Split(path, -1)
+	for i := range literals {
+		literals[i] = regexp.QuoteMeta(literals[i])
+	}
+	return shared.RegexSigil + "^" + strings.Join(literals, "[^/]+") + "$"
+}
+
+// generatedMockId is the operation id, or the method and path when the operation has none.
+func generatedMockId(method, path string, operation *v3.Operation) string {
+	if operation.OperationId != "" {
+		return operation.OperationId
+	}
+	return strings.ToUpper(method) + " " + path
+}
+
+// successResponse picks the response a generated definition returns: the lowest 2xx code, then the lowest code,
+// then the default response as a 200.
+func successResponse(operation *v3.Operation) (int, *v3.Response) {
+	if operation.Responses == nil {
+		return http.StatusOK, nil
+	}
+	var codes []int
+	responses := make(map[int]*v3.Response)
+	if operation.Responses.Codes != nil {
+		for code, response := range operation.Responses.Codes.FromOldest() {
+			if c, err := strconv.Atoi(code); err == nil {
+				codes = append(codes, c)
+				responses[c] = response
+			}
+		}
+	}
+	sort.Ints(codes)
+	for _, c := range codes {
+		if c >= 200 && c < 300 {
+			return c, responses[c]
+		}
+	}
+	if len(codes) > 0 {
+		return codes[0], responses[codes[0]]
+	}
+	return http.StatusOK, operation.Responses.Default
+}
+
+// generateMockDefinitions creates a skeleton definition for every operation of the specification, in the order
+// they are defined. The response body is the first example of the response's first media type, JSON preferred,
+// or is generated from its schema when there is no example.
+func generateMockDefinitions(doc *v3.Document) []StaticMockDefinition {
+	if doc == nil || doc.Paths == nil || doc.Paths.PathItems == nil {
+		return nil
+	}
+	generator := renderer.NewMockGenerator(renderer.JSON)
+	generator.SetPretty()
+
+	var definitions []StaticMockDefinition
+	for path, pathItem := range doc.Paths.PathItems.FromOldest() {
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			definition := StaticMockDefinition{
+				Id:        generatedMockId(method, path, operation),
+				Generated: true,
+				Request: StaticMockDefinitionRequest{
+					Method:  strings.ToUpper(method),
+					UrlPath: specPathPattern(path),
+				},
+			}
+			code, response := successResponse(operation)
+			definition.Response.StatusCode = code
+			if response != nil && response.Content != nil && response.Content.Len() > 0 {
+				mediaType, contentType := response.Content.GetOrZero("application/json"), "application/json"
+				if mediaType == nil {
+					contentType, mediaType = response.Content.First().Key(), response.Content.First().Value()
+				}
+				definition.Response.Header = map[string]any{"Content-Type": contentType}
+				if body, err := generator.GenerateMock(mediaType, ""); err == nil && body != nil {
+					definition.Response.Body = string(body)
+				}
+			}
+			definitions = append(definitions, definition)
+		}
+	}
+	return definitions
+}
+
+// ImportMocksFromSpec adds a generated definition for every operation of the loaded specification to the runtime
+// definitions. Operations that already have a definition with the same id are skipped, so importing again only
+// adds the operations that are new to the specification.
+func (sms *StaticMockService) ImportMocksFromSpec() (*MockImportResponse, error) {
+	var doc *v3.Document
+	if sms.wiretapService != nil {
+		doc = sms.wiretapService.DocModel()
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("no OpenAPI specification is loaded")
+	}
+	return sms.importMockDefinitions(generateMockDefinitions(doc))
+}
+
+// importMockDefinitions adds generated definitions to the runtime definitions, skipping ids that already exist.
+func (sms *StaticMockService) importMockDefinitions(generated []StaticMockDefinition) (*MockImportResponse, error) {
+	sms.lock.Lock()
+	defer sms.lock.Unlock()
+
+	result := &MockImportResponse{Definitions: []StaticMockDefinition{}}
+	definitions := sms.copyDefinitionSet(runtimeDefinitionsKey)
+	for _, definition := range generated {
+		if key, _ := sms.findMockDefinition(definition.Id); key != nil {
+			result.Skipped = append(result.Skipped, definition.Id)
+			continue
+		}
+		definitions = append(definitions, definition)
+		result.Definitions = append(result.Definitions, definition)
+	}
+	result.Imported = len(result.Definitions)
+	if result.Imported == 0 {
+		return result, nil
+	}
+	if err := sms.updateDefinitionSet(runtimeDefinitionsKey, definitions, false); err != nil {
+		return nil, err
+	}
+	sms.logger.Info("Mock definitions imported from the specification", "imported", result.Imported,
+		"skipped", len(result.Skipped))
+	return result, nil
+}
+
+// handleImportFromSpec adds generated definitions for the operations of the loaded specification.
+func (sms *StaticMockService) handleImportFromSpec(w http.ResponseWriter, r *http.Request) {
+	result, err := sms.ImportMocksFromSpec()
+	if err != nil {
+		writeAdminError(w, http.StatusConflict, "Unable to import mock definitions", err.Error(), r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, http.StatusOK, result)
+}
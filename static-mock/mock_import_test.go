@@ -0,0 +1,85 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var importSpec = []byte(`openapi: 3.1.0
+info:
+  title: pets
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              example: [{"name": "fido"}]
+  /pets/{id}:
+    delete:
+      responses:
+        "404":
+          description: missing
+        "204":
+          description: deleted
+`)
+
+func TestSpecPathPattern(t *testing.T) {
+	assert.Equal(t, "/pets", specPathPattern("/pets"))
+	assert.Equal(t, `~^/pets/[^/]+/toys\.json$`, specPathPattern("/pets/{id}/toys.json"))
+}
+
+func TestStaticMockService_ImportMockDefinitions(t *testing.T) {
+	doc, err := libopenapi.NewDocument(importSpec)
+	require.NoError(t, err)
+	m, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+
+	generated := generateMockDefinitions(&m.Model)
+	require.Len(t, generated, 2)
+	assert.Equal(t, "listPets", generated[0].Id)
+	assert.True(t, generated[0].Generated)
+	assert.Equal(t, "GET", generated[0].Request.Method)
+	assert.Equal(t, "/pets", generated[0].Request.UrlPath)
+	assert.Equal(t, http.StatusOK, generated[0].Response.StatusCode)
+	assert.Equal(t, "application/json", generated[0].Response.Header["Content-Type"])
+	assert.JSONEq(t, `[{"name": "fido"}]`, generated[0].Response.Body)
+
+	assert.Equal(t, "DELETE /pets/{id}", generated[1].Id)
+	assert.Equal(t, http.StatusNoContent, generated[1].Response.StatusCode)
+	assert.Empty(t, generated[1].Response.Body)
+
+	sms := newTestStaticMockService(StaticMockDefinition{Id: "listPets", Request: StaticMockDefinitionRequest{Method: "GET"}})
+	result, err := sms.importMockDefinitions(generated)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, []string{"listPets"}, result.Skipped)
+	assert.Len(t, sms.ListMockDefinitions(), 2)
+
+	// the generated definition now answers requests.
+	request := httptest.NewRequest(http.MethodDelete, "/pets/7", nil)
+	match := sms.findMatch(&mockCheck{candidates: sms.getMockCandidates(request)}, request)
+	assert.GreaterOrEqual(t, match, 0)
+}
+
+func TestStaticMockService_ImportFromSpec_NoSpec(t *testing.T) {
+	mux := http.NewServeMux()
+	newTestStaticMockService().RegisterAdminRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, AdminImportFromSpecPath, nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
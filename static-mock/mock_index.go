@@ -0,0 +1,124 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// mockIndex narrows down the mock definitions a request is matched against. Definitions with a literal URL path
+// are inserted into a trie of path segments, any other definition (no path, a regex path or case-insensitive
+// matching) can match any path and is a candidate for every request. Looking a request up walks the trie once per
+// segment of its path, however many definitions there are. Candidates are still matched in full.
+type mockIndex struct {
+	root      *pathNode
+	unindexed []int // positions of definitions that are always candidates, in matching order.
+}
+
+// pathNode is a segment of the URL paths in the trie.
+type pathNode struct {
+	children map[string]*pathNode
+	methods  map[string][]int // positions of the definitions for the path ending at this node, by method.
+}
+
+// pathSegments splits a path on '/'. Empty segments are kept, so '/pets' and '/pets/' are different paths, as they
+// are when a mock is matched.
+func pathSegments(path string) []string {
+	return strings.Split(path, "/")
+}
+
+// insert adds the position of a definition for a method and path.
+func (pn *pathNode) insert(method, path string, position int) {
+	node := pn
+	for _, segment := range pathSegments(path) {
+		child, ok := node.children[segment]
+		if !ok {
+			child = &pathNode{children: make(map[string]*pathNode)}
+			node.children[segment] = child
+		}
+		node = child
+	}
+	if node.methods == nil {
+		node.methods = make(map[string][]int)
+	}
+	node.methods[method] = append(node.methods[method], position)
+}
+
+// lookup returns the positions of the definitions for a method and path.
+func (pn *pathNode) lookup(method, path string) []int {
+	node := pn
+	for _, segment := range pathSegments(path) {
+		if node = node.children[segment]; node == nil {
+			return nil
+		}
+	}
+	return node.methods[method]
+}
+
+// isIndexable is true when a definition only ever matches its own URL path, exactly.
+func isIndexable(request StaticMockDefinitionRequest) bool {
+	if request.UrlPath == "" || request.CaseInsensitive {
+		return false
+	}
+	isRegex, _ := shared.CheckPattern(request.UrlPath)
+	return !isRegex
+}
+
+// buildMockIndex indexes merged mock definitions, built whenever the definitions are merged.
+func buildMockIndex(definitions []StaticMockDefinition) *mockIndex {
+	mi := &mockIndex{root: &pathNode{children: make(map[string]*pathNode)}}
+	for i, definition := range definitions {
+		if !isIndexable(definition.Request) {
+			mi.unindexed = append(mi.unindexed, i)
+			continue
+		}
+		mi.root.insert(definition.Request.Method, definition.Request.UrlPath, i)
+	}
+	return mi
+}
+
+// candidates returns the positions of the definitions that may match the request, in matching order.
+func (mi *mockIndex) candidates(request *http.Request) []int {
+	indexed := mi.root.lookup(request.Method, request.URL.Path)
+	if len(indexed) == 0 {
+		return mi.unindexed
+	}
+	if len(mi.unindexed) == 0 {
+		return indexed
+	}
+	merged := make([]int, 0, len(indexed)+len(mi.unindexed))
+	i, j := 0, 0
+	for i < len(indexed) && j < len(mi.unindexed) {
+		if indexed[i] < mi.unindexed[j] {
+			merged = append(merged, indexed[i])
+			i++
+		} else {
+			merged = append(merged, mi.unindexed[j])
+			j++
+		}
+	}
+	merged = append(merged, indexed[i:]...)
+	return append(merged, mi.unindexed[j:]...)
+}
+
+// getMockCandidates returns the mock definitions that may match the request, in matching order. Every definition
+// is a candidate when the definitions have not been indexed.
+func (sms *StaticMockService) getMockCandidates(request *http.Request) []StaticMockDefinition {
+	sms.lock.RLock()
+	defer sms.lock.RUnlock()
+	if sms.mockIndex == nil {
+		return sms.mockDefinitions
+	}
+	positions := sms.mockIndex.candidates(request)
+	candidates := make([]StaticMockDefinition, len(positions))
+	for i, position := range positions {
+		candidates[i] = sms.mockDefinitions[position]
+	}
+	return candidates
+}
@@ -0,0 +1,62 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockIndex_Candidates(t *testing.T) {
+	definitions := []StaticMockDefinition{
+		{Id: "pets", Request: StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "/pets"}},
+		{Id: "any", Request: StaticMockDefinitionRequest{Method: http.MethodGet}},
+		{Id: "pet", Request: StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "~^/pets/[0-9]+$"}},
+		{Id: "post", Request: StaticMockDefinitionRequest{Method: http.MethodPost, UrlPath: "/pets"}},
+		{Id: "pets-again", Request: StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "/pets"}},
+		{Id: "folded", Request: StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "/PETS", CaseInsensitive: true}},
+	}
+	mi := buildMockIndex(definitions)
+
+	// indexed and unindexed definitions are merged back into matching order.
+	assert.Equal(t, []int{0, 1, 2, 4, 5}, mi.candidates(httptest.NewRequest(http.MethodGet, "/pets", nil)))
+	assert.Equal(t, []int{1, 2, 3, 5}, mi.candidates(httptest.NewRequest(http.MethodPost, "/pets", nil)))
+	assert.Equal(t, []int{1, 2, 5}, mi.candidates(httptest.NewRequest(http.MethodGet, "/pets/1", nil)))
+}
+
+func TestStaticMockService_CheckStaticMockExists_Indexed(t *testing.T) {
+	sms := newTestStaticMockService(
+		StaticMockDefinition{Id: "pets", Request: StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "/pets",
+			Header: &map[string]any{"X-Scenario": "empty"}}},
+		StaticMockDefinition{Id: "pet", Request: StaticMockDefinitionRequest{Method: http.MethodGet, UrlPath: "~^/pets"}},
+	)
+
+	// the first matching definition wins, whether it is indexed or not.
+	request := httptest.NewRequest(http.MethodGet, "/pets", nil)
+	request.Header.Set("X-Scenario", "empty")
+	assert.Equal(t, "pets", sms.checkStaticMockExists(request).Id)
+	assert.Equal(t, "pet", sms.checkStaticMockExists(httptest.NewRequest(http.MethodGet, "/pets", nil)).Id)
+	assert.Equal(t, "pet", sms.checkStaticMockExists(httptest.NewRequest(http.MethodGet, "/pets/2", nil)).Id)
+	assert.Nil(t, sms.checkStaticMockExists(httptest.NewRequest(http.MethodDelete, "/pets", nil)))
+}
+
+func TestPathNode_Lookup(t *testing.T) {
+	root := &pathNode{children: make(map[string]*pathNode)}
+	root.insert(http.MethodGet, "/pets", 0)
+	root.insert(http.MethodGet, "/pets/", 1)
+	root.insert(http.MethodGet, "/pets/cats", 2)
+	root.insert(http.MethodGet, "/pets", 3)
+
+	assert.Equal(t, []int{0, 3}, root.lookup(http.MethodGet, "/pets"))
+	assert.Equal(t, []int{1}, root.lookup(http.MethodGet, "/pets/"))
+	assert.Equal(t, []int{2}, root.lookup(http.MethodGet, "/pets/cats"))
+	assert.Empty(t, root.lookup(http.MethodPost, "/pets"))
+	assert.Empty(t, root.lookup(http.MethodGet, "/pets/cats/1"))
+	assert.Empty(t, root.lookup(http.MethodGet, "/"))
+}
@@ -0,0 +1,174 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resolveInheritance returns a copy of the definitions where every definition that inherits from another has the
+// parent's fields merged underneath its own. Inheritance can be chained; circular chains and references to unknown
+// mock IDs are returned as an error. When more than one definition shares an ID, the first one is used as the parent.
+func resolveInheritance(definitions []StaticMockDefinition) ([]StaticMockDefinition, error) {
+	byId := make(map[string]int)
+	for i := range definitions {
+		if definitions[i].Id == "" {
+			continue
+		}
+		if _, ok := byId[definitions[i].Id]; !ok {
+			byId[definitions[i].Id] = i
+		}
+	}
+
+	resolved := make([]StaticMockDefinition, len(definitions))
+	done := make([]bool, len(definitions))
+
+	var resolve func(idx int, chain []string) error
+	resolve = func(idx int, chain []string) error {
+		if done[idx] {
+			return nil
+		}
+		definition := definitions[idx]
+		if definition.Inherits == "" {
+			resolved[idx] = definition
+			done[idx] = true
+			return nil
+		}
+
+		for _, id := range chain {
+			if id == definition.Id {
+				return fmt.Errorf("circular mock definition inheritance detected: %s -> %s",
+					strings.Join(chain, " -> "), definition.Id)
+			}
+		}
+
+		parentIdx, ok := byId[definition.Inherits]
+		if !ok {
+			return fmt.Errorf("mock definition '%s' inherits from unknown mock definition '%s'",
+				definition.Id, definition.Inherits)
+		}
+		if err := resolve(parentIdx, append(chain, definition.Id)); err != nil {
+			return err
+		}
+
+		resolved[idx] = mergeMockDefinition(resolved[parentIdx], definition)
+		done[idx] = true
+		return nil
+	}
+
+	for i := range definitions {
+		if err := resolve(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// mergeMockDefinition layers the child definition on top of the parent. Scalar fields set on the child win,
+// header, query parameter and JSON object bodies are merged key by key and JSONPath conditions are combined.
+func mergeMockDefinition(parent, child StaticMockDefinition) StaticMockDefinition {
+	merged := child
+
+	if len(merged.Tags) == 0 {
+		merged.Tags = parent.Tags
+	}
+
+	// schedule
+	if merged.ActiveFrom == nil {
+		merged.ActiveFrom = parent.ActiveFrom
+	}
+	if merged.ActiveUntil == nil {
+		merged.ActiveUntil = parent.ActiveUntil
+	}
+	if len(merged.ActiveDays) == 0 {
+		merged.ActiveDays = parent.ActiveDays
+	}
+	if merged.ActiveHours == nil {
+		merged.ActiveHours = parent.ActiveHours
+	}
+
+	// request
+	if merged.Request.Method == "" {
+		merged.Request.Method = parent.Request.Method
+	}
+	if merged.Request.UrlPath == "" {
+		merged.Request.UrlPath = parent.Request.UrlPath
+	}
+	if merged.Request.Host == "" {
+		merged.Request.Host = parent.Request.Host
+	}
+	merged.Request.Header = mergeOptionalMaps(parent.Request.Header, child.Request.Header)
+	merged.Request.QueryParams = mergeOptionalMaps(parent.Request.QueryParams, child.Request.QueryParams)
+	merged.Request.Body = mergeBodies(parent.Request.Body, child.Request.Body)
+	if merged.Request.BodyLength == nil {
+		merged.Request.BodyLength = parent.Request.BodyLength
+	}
+	if len(parent.Request.JSONPathConditions) > 0 {
+		merged.Request.JSONPathConditions = append(append([]JSONPathCondition{},
+			parent.Request.JSONPathConditions...), child.Request.JSONPathConditions...)
+	}
+
+	// response
+	merged.Response.Header = mergeMaps(parent.Response.Header, child.Response.Header)
+	if merged.Response.StatusCode == 0 {
+		merged.Response.StatusCode = parent.Response.StatusCode
+	}
+	if merged.Response.Body == "" && merged.Response.BodyJsonFilename == "" && merged.Response.BodyFile == "" {
+		merged.Response.Body = parent.Response.Body
+		merged.Response.BodyJsonFilename = parent.Response.BodyJsonFilename
+		merged.Response.BodyFile = parent.Response.BodyFile
+		merged.Response.bodyFileContent = parent.Response.bodyFileContent
+		merged.Response.bodyFileContentType = parent.Response.bodyFileContentType
+	}
+	return merged
+}
+
+func mergeMaps(parent, child map[string]any) map[string]any {
+	if parent == nil {
+		return child
+	}
+	merged := make(map[string]any, len(parent)+len(child))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range child {
+		merged[k] = v
+	}
+	return merged
+}
+
+func mergeOptionalMaps(parent, child *map[string]any) *map[string]any {
+	if parent == nil {
+		return child
+	}
+	var childMap map[string]any
+	if child != nil {
+		childMap = *child
+	}
+	merged := mergeMaps(*parent, childMap)
+	return &merged
+}
+
+// mergeBodies deep merges JSON object bodies. Any other body type on the child replaces the parent body.
+func mergeBodies(parent, child interface{}) interface{} {
+	if child == nil {
+		return parent
+	}
+	parentObj, pOk := parent.(map[string]interface{})
+	childObj, cOk := child.(map[string]interface{})
+	if !pOk || !cOk {
+		return child
+	}
+	merged := make(map[string]interface{}, len(parentObj)+len(childObj))
+	for k, v := range parentObj {
+		merged[k] = v
+	}
+	for k, v := range childObj {
+		merged[k] = mergeBodies(parentObj[k], v)
+	}
+	return merged
+}
@@ -0,0 +1,72 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveInheritance(t *testing.T) {
+	parentHeader := map[string]any{"Authorization": "~^Bearer", "X-Tenant": "a"}
+	childHeader := map[string]any{"X-Tenant": "b"}
+
+	definitions := []StaticMockDefinition{
+		{
+			Id:       "child",
+			Inherits: "parent",
+			Request: StaticMockDefinitionRequest{
+				UrlPath: "/child",
+				Header:  &childHeader,
+				Body:    map[string]interface{}{"nested": map[string]interface{}{"b": "2"}},
+			},
+		},
+		{
+			Id: "parent",
+			Request: StaticMockDefinitionRequest{
+				Method:  "GET",
+				UrlPath: "/parent",
+				Header:  &parentHeader,
+				Body:    map[string]interface{}{"nested": map[string]interface{}{"a": "1"}},
+			},
+			Response: StaticMockDefinitionResponse{StatusCode: 201, Body: "{}"},
+		},
+	}
+
+	resolved, err := resolveInheritance(definitions)
+	assert.NoError(t, err)
+	assert.Len(t, resolved, 2)
+
+	child := resolved[0]
+	assert.Equal(t, "GET", child.Request.Method)
+	assert.Equal(t, "/child", child.Request.UrlPath)
+	assert.Equal(t, map[string]any{"Authorization": "~^Bearer", "X-Tenant": "b"}, *child.Request.Header)
+	assert.Equal(t, map[string]interface{}{"nested": map[string]interface{}{"a": "1", "b": "2"}}, child.Request.Body)
+	assert.Equal(t, 201, child.Response.StatusCode)
+	assert.Equal(t, "{}", child.Response.Body)
+
+	// the parent must not be modified by the merge
+	assert.Equal(t, "a", parentHeader["X-Tenant"])
+	assert.Equal(t, "/parent", resolved[1].Request.UrlPath)
+}
+
+func TestResolveInheritance_Circular(t *testing.T) {
+	definitions := []StaticMockDefinition{
+		{Id: "a", Inherits: "b"},
+		{Id: "b", Inherits: "c"},
+		{Id: "c", Inherits: "a"},
+	}
+	_, err := resolveInheritance(definitions)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "circular")
+}
+
+func TestResolveInheritance_UnknownParent(t *testing.T) {
+	_, err := resolveInheritance([]StaticMockDefinition{{Id: "a", Inherits: "missing"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "missing")
+}
@@ -0,0 +1,466 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi-validator/paths"
+	"github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/pb33f/wiretap/validation"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+
+	LintRuleInvalidDefinition = "invalid-definition"
+	LintRuleDuplicateId       = "duplicate-id"
+	LintRuleInheritance       = "inheritance"
+	LintRuleMissingMethod     = "missing-method"
+	LintRuleUnreachable       = "unreachable"
+	LintRuleInvalidRegex      = "invalid-regex"
+	LintRulePathNotInSpec     = "path-not-in-spec"
+	LintRuleResponseSchema    = "response-schema"
+)
+
+// MockLintFinding is a single problem found in a mock definition file. Line is the line the definition starts on,
+// or zero when the problem is with the file itself.
+type MockLintFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	MockId   string `json:"mockId,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+func (f *MockLintFinding) String() string {
+	location := f.File
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return fmt.Sprintf("%s: %s [%s] %s", location, f.Severity, f.Rule, f.Message)
+}
+
+// MockLintOptions configures LintMockDefinitions.
+type MockLintOptions struct {
+	// Format overrides the mock definition format of files without a recognized extension.
+	Format string
+	// StaticMockDir is used to resolve the bodyJsonFilename of mock responses.
+	StaticMockDir string
+	// Document is the OpenAPI specification mocks are checked against, spec checks are skipped when it is nil.
+	Document *v3.Document
+}
+
+// lintedDefinition is a mock definition, along with where it was defined.
+type lintedDefinition struct {
+	definition StaticMockDefinition
+	file       string
+	line       int
+}
+
+func (ld *lintedDefinition) finding(severity, rule, message string) *MockLintFinding {
+	return &MockLintFinding{
+		File:     ld.file,
+		Line:     ld.line,
+		MockId:   ld.definition.Id,
+		Severity: severity,
+		Rule:     rule,
+		Message:  message,
+	}
+}
+
+// LintMockDefinitions checks mock definition files for problems: definitions that cannot be read, duplicate IDs,
+// broken inheritance, mocks shadowed by an earlier mock with identical match criteria, invalid regex patterns and,
+// when a specification is provided, paths that are not in the specification and responses that do not match it.
+// Locations may be files or directories, directories are scanned for mock definition files like the service does.
+func LintMockDefinitions(locations []string, options MockLintOptions) ([]*MockLintFinding, error) {
+	files, err := mockDefinitionFiles(locations, options.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []*MockLintFinding
+	var definitions []*lintedDefinition
+	for _, file := range files {
+		fileDefinitions, fileFindings := lintMockDefinitionFile(file, options.Format)
+		definitions = append(definitions, fileDefinitions...)
+		findings = append(findings, fileFindings...)
+	}
+
+	findings = append(findings, lintDuplicateIds(definitions)...)
+
+	// the remaining checks run against definitions as they are matched, with inheritance resolved.
+	raw := make([]StaticMockDefinition, len(definitions))
+	for i := range definitions {
+		raw[i] = definitions[i].definition
+	}
+	if resolved, rErr := resolveInheritance(raw); rErr != nil {
+		findings = append(findings, &MockLintFinding{
+			Severity: LintSeverityError,
+			Rule:     LintRuleInheritance,
+			Message:  rErr.Error(),
+		})
+	} else {
+		for i := range definitions {
+			definitions[i].definition = resolved[i]
+		}
+	}
+
+	findings = append(findings, lintUnreachable(definitions)...)
+
+	var validator validation.HttpValidator
+	if options.Document != nil {
+		validator = validation.NewHttpValidator(options.Document)
+	}
+	for _, ld := range definitions {
+		findings = append(findings, lintMockDefinition(ld, options, validator)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// mockDefinitionFiles expands directories into the mock definition files they contain, in name order.
+func mockDefinitionFiles(locations []string, format string) ([]string, error) {
+	var files []string
+	for _, path := range locations {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var dirFiles []string
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if formatFromExtension(entry.Name()) != "" || format != "" {
+				dirFiles = append(dirFiles, filepath.Join(path, entry.Name()))
+			}
+		}
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
+
+// lintMockDefinitionFile reads the definitions in a file, reporting any that cannot be read.
+func lintMockDefinitionFile(file, formatOverride string) ([]*lintedDefinition, []*MockLintFinding) {
+	fileFinding := func(message string) []*MockLintFinding {
+		return []*MockLintFinding{{File: file, Severity: LintSeverityError, Rule: LintRuleInvalidDefinition, Message: message}}
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fileFinding(err.Error())
+	}
+	format := mockDefinitionFormat(file, formatOverride)
+	parsed, err := parseMockDefinitions(data, format)
+	if err != nil {
+		return nil, fileFinding(fmt.Sprintf("unable to parse %s mock definition: %s", format, err.Error()))
+	}
+
+	var items []interface{}
+	switch md := parsed.(type) {
+	case map[string]interface{}:
+		items = []interface{}{md}
+	case []interface{}:
+		items = md
+	default:
+		return nil, fileFinding("mock definition not in the right format, expected an object or an array")
+	}
+
+	lines := definitionLines(data, format)
+	var definitions []*lintedDefinition
+	var findings []*MockLintFinding
+	for i, item := range items {
+		ld := &lintedDefinition{file: file}
+		if i < len(lines) {
+			ld.line = lines[i]
+		}
+		mdItem, ok := item.(map[string]interface{})
+		if !ok {
+			findings = append(findings, ld.finding(LintSeverityError, LintRuleInvalidDefinition,
+				"mock definition array item is not an object"))
+			continue
+		}
+		ld.definition, err = getDefinitionFromJson(mdItem)
+		if err != nil {
+			findings = append(findings, ld.finding(LintSeverityError, LintRuleInvalidDefinition, err.Error()))
+			continue
+		}
+		definitions = append(definitions, ld)
+	}
+	return definitions, findings
+}
+
+// definitionLines returns the line each definition in a file starts on, in order.
+func definitionLines(data []byte, format string) []int {
+	switch format {
+	case MockDefinitionFormatJSON:
+		return jsonDefinitionLines(data)
+	case MockDefinitionFormatYAML:
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil || len(node.Content) == 0 {
+			return nil
+		}
+		doc := node.Content[0]
+		if doc.Kind != yaml.SequenceNode {
+			return []int{doc.Line}
+		}
+		lines := make([]int, len(doc.Content))
+		for i := range doc.Content {
+			lines[i] = doc.Content[i].Line
+		}
+		return lines
+	case MockDefinitionFormatTOML:
+		var lines []int
+		for i, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) == "[["+TOMLMockDefinitionsKey+"]]" {
+				lines = append(lines, i+1)
+			}
+		}
+		if len(lines) == 0 {
+			return []int{1}
+		}
+		return lines
+	}
+	return nil
+}
+
+func jsonDefinitionLines(data []byte) []int {
+	lineAt := func(offset int64) int {
+		// skip the whitespace and separators between the end of the previous value and the start of this one.
+		for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+			offset++
+		}
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	start := decoder.InputOffset()
+	token, err := decoder.Token()
+	if err != nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return []int{lineAt(start)}
+	}
+	var lines []int
+	for decoder.More() {
+		lines = append(lines, lineAt(decoder.InputOffset()))
+		var item json.RawMessage
+		if err = decoder.Decode(&item); err != nil {
+			break
+		}
+	}
+	return lines
+}
+
+func lintDuplicateIds(definitions []*lintedDefinition) []*MockLintFinding {
+	var findings []*MockLintFinding
+	first := make(map[string]*lintedDefinition)
+	for _, ld := range definitions {
+		id := ld.definition.Id
+		if id == "" {
+			continue
+		}
+		if original, ok := first[id]; ok {
+			findings = append(findings, ld.finding(LintSeverityError, LintRuleDuplicateId,
+				fmt.Sprintf("mock id '%s' is already used at %s:%d", id, original.file, original.line)))
+			continue
+		}
+		first[id] = ld
+	}
+	return findings
+}
+
+// lintUnreachable reports definitions that can never match, because an earlier definition has identical match
+// criteria and is active under the same tags and schedule. Definitions are matched in order, first match wins.
+func lintUnreachable(definitions []*lintedDefinition) []*MockLintFinding {
+	var findings []*MockLintFinding
+	for i, ld := range definitions {
+		for _, earlier := range definitions[:i] {
+			if sameMatchCriteria(earlier.definition, ld.definition) {
+				findings = append(findings, ld.finding(LintSeverityWarning, LintRuleUnreachable,
+					fmt.Sprintf("mock is shadowed by the mock at %s:%d, which has identical match criteria",
+						earlier.file, earlier.line)))
+				break
+			}
+		}
+	}
+	return findings
+}
+
+func sameMatchCriteria(a, b StaticMockDefinition) bool {
+	return reflect.DeepEqual(a.Request, b.Request) &&
+		reflect.DeepEqual(a.Tags, b.Tags) &&
+		reflect.DeepEqual(a.ActiveFrom, b.ActiveFrom) &&
+		reflect.DeepEqual(a.ActiveUntil, b.ActiveUntil) &&
+		reflect.DeepEqual(a.ActiveDays, b.ActiveDays) &&
+		reflect.DeepEqual(a.ActiveHours, b.ActiveHours)
+}
+
+// lintMockDefinition runs the checks that apply to a single definition.
+func lintMockDefinition(ld *lintedDefinition, options MockLintOptions,
+	validator validation.HttpValidator) []*MockLintFinding {
+
+	var findings []*MockLintFinding
+	request := ld.definition.Request
+
+	if request.Method == "" {
+		findings = append(findings, ld.finding(LintSeverityError, LintRuleMissingMethod,
+			"mock has no request method, it will never match a request"))
+	}
+
+	checkPattern := func(field, value string) {
+		isRegex, err := shared.CheckPattern(value)
+		if err == nil {
+			return
+		}
+		if isRegex {
+			findings = append(findings, ld.finding(LintSeverityError, LintRuleInvalidRegex,
+				fmt.Sprintf("%s '%s' is not a valid regex, it will never match: %s", field, value, err.Error())))
+			return
+		}
+		findings = append(findings, ld.finding(LintSeverityWarning, LintRuleInvalidRegex,
+			fmt.Sprintf("%s '%s' looks like a regex but does not compile, it will be compared as a literal string: %s",
+				field, value, err.Error())))
+	}
+	if response := ld.definition.Response; response.CompressResponse && !isCompressionAlgorithm(compressionAlgorithm(&response)) {
+		findings = append(findings, ld.finding(LintSeverityError, LintRuleInvalidDefinition,
+			fmt.Sprintf("compression algorithm '%s' is not supported, expected gzip, deflate or br",
+				response.CompressionAlgorithm)))
+	}
+
+	checkPattern("urlPath", request.UrlPath)
+	checkPattern("host", request.Host)
+	for _, values := range []*map[string]any{request.Header, request.QueryParams} {
+		if values == nil {
+			continue
+		}
+		keys := make([]string, 0, len(*values))
+		for key := range *values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if s, ok := (*values)[key].(string); ok {
+				checkPattern(fmt.Sprintf("'%s'", key), s)
+			}
+		}
+	}
+
+	if options.Document != nil {
+		findings = append(findings, lintAgainstSpec(ld, options, validator)...)
+	}
+	return findings
+}
+
+// lintAgainstSpec checks the path of a mock is defined in the specification, and that the response matches it.
+// Mocks with a regex path cannot be checked, and neither can templated response bodies.
+func lintAgainstSpec(ld *lintedDefinition, options MockLintOptions, validator validation.HttpValidator) []*MockLintFinding {
+	request := ld.definition.Request
+	if request.Method == "" || request.UrlPath == "" {
+		return nil
+	}
+	if isRegex, err := shared.CheckPattern(request.UrlPath); isRegex || err != nil {
+		return nil
+	}
+
+	httpRequest, err := http.NewRequest(request.Method, (&url.URL{Path: request.UrlPath}).String(), nil)
+	if err != nil {
+		return []*MockLintFinding{ld.finding(LintSeverityError, LintRuleInvalidDefinition, err.Error())}
+	}
+	if _, errs, _ := paths.FindPath(httpRequest, options.Document); len(errs) > 0 {
+		return []*MockLintFinding{ld.finding(LintSeverityWarning, LintRulePathNotInSpec,
+			fmt.Sprintf("%s %s is not defined in the specification", request.Method, request.UrlPath))}
+	}
+
+	body := ld.definition.Response.Body
+	if ld.definition.Response.BodyJsonFilename != "" {
+		data, rErr := os.ReadFile(filepath.Join(options.StaticMockDir, MockBodyJsonsPath,
+			ld.definition.Response.BodyJsonFilename))
+		if rErr != nil {
+			return []*MockLintFinding{ld.finding(LintSeverityError, LintRuleInvalidDefinition,
+				fmt.Sprintf("response body file cannot be read: %s", rErr.Error()))}
+		}
+		body = string(data)
+	}
+	contentType := "application/json"
+	if ld.definition.Response.BodyFile != "" {
+		data, rErr := os.ReadFile(resolveBodyFile(ld.file, ld.definition.Response.BodyFile))
+		if rErr != nil {
+			return []*MockLintFinding{ld.finding(LintSeverityError, LintRuleInvalidDefinition,
+				fmt.Sprintf("response body file cannot be read: %s", rErr.Error()))}
+		}
+		contentType = bodyFileContentType(ld.definition.Response.BodyFile, data)
+		if !isTextContentType(contentType) {
+			return nil // binary bodies cannot be validated against a schema.
+		}
+		body = string(data)
+	}
+	if strings.Contains(body, "{{") {
+		return nil // templated bodies depend on the request, they are only known at runtime.
+	}
+
+	for name, value := range ld.definition.Response.Header {
+		if ct, ok := value.(string); ok && ct != "" && strings.EqualFold(name, "Content-Type") {
+			contentType = ct
+		}
+	}
+	statusCode := ld.definition.Response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	response := &http.Response{
+		StatusCode: statusCode,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+
+	var findings []*MockLintFinding
+	if _, errs := validator.ValidateHttpResponse(httpRequest, response); len(errs) > 0 {
+		for _, vErr := range errs {
+			message := vErr.Message
+			if vErr.Reason != "" {
+				message = fmt.Sprintf("%s: %s", vErr.Message, vErr.Reason)
+			}
+			for _, sErr := range vErr.SchemaValidationErrors {
+				message = fmt.Sprintf("%s; %s", message, sErr.Reason)
+			}
+			findings = append(findings, ld.finding(LintSeverityError, LintRuleResponseSchema,
+				fmt.Sprintf("response does not match the specification: %s", message)))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,103 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lintRules(findings []*MockLintFinding) map[string][]int {
+	rules := make(map[string][]int)
+	for _, f := range findings {
+		rules[f.Rule] = append(rules[f.Rule], f.Line)
+	}
+	return rules
+}
+
+func TestLintMockDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`[
+  {
+    "id": "pets",
+    "request": {"method": "GET", "urlPath": "/pets"},
+    "response": {"statusCode": 200, "body": "{\"name\": 1}"}
+  },
+  {"id": "pets", "request": {"method": "GET", "urlPath": "/pets"}},
+  {"request": {"method": "GET", "urlPath": "~/pets/[0-9"}},
+  {"request": {"urlPath": "/nope"}}
+]`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.yaml"), []byte(`- id: child
+  inherits: pets
+  response:
+    statusCode: 200
+    body: '{"name": "fido"}'
+- request:
+    method: GET
+    urlPath: /owners
+`), 0644))
+
+	spec := `openapi: 3.1.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  name:
+                    type: string
+`
+	d, _ := libopenapi.NewDocument([]byte(spec))
+	doc, _ := d.BuildV3Model()
+
+	findings, err := LintMockDefinitions([]string{dir}, MockLintOptions{Document: &doc.Model})
+	require.NoError(t, err)
+
+	rules := lintRules(findings)
+	assert.Equal(t, []int{7}, rules[LintRuleDuplicateId])
+	assert.Equal(t, []int{2}, rules[LintRuleResponseSchema])
+	assert.Equal(t, []int{8}, rules[LintRuleInvalidRegex])
+	assert.Equal(t, []int{9}, rules[LintRuleMissingMethod])
+	// the yaml child inherits the request of its parent, so it is shadowed by it. Its response matches the spec.
+	assert.Equal(t, []int{7, 1}, rules[LintRuleUnreachable])
+	assert.Equal(t, []int{6}, rules[LintRulePathNotInSpec])
+	assert.Equal(t, filepath.Join(dir, "b.yaml"), findings[len(findings)-1].File)
+}
+
+func TestLintMockDefinitions_Inheritance(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mocks.toml"), []byte(`[[mocks]]
+id = "child"
+inherits = "missing"
+`), 0644))
+
+	findings, err := LintMockDefinitions([]string{dir}, MockLintOptions{})
+	require.NoError(t, err)
+	rules := lintRules(findings)
+	assert.Contains(t, rules, LintRuleInheritance)
+	assert.Equal(t, []int{1}, rules[LintRuleMissingMethod])
+}
+
+func TestLintMockDefinitions_UnreadableFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "broken.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"request": `), 0644))
+
+	findings, err := LintMockDefinitions([]string{file}, MockLintOptions{})
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, LintRuleInvalidDefinition, findings[0].Rule)
+	assert.Equal(t, LintSeverityError, findings[0].Severity)
+}
@@ -0,0 +1,189 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// runtimeDefinitionsKey holds definitions added through the admin API that are not persisted to a file. The empty
+// key sorts before every file path, so runtime definitions are matched first.
+const runtimeDefinitionsKey = ""
+
+// DefaultPersistFile is the file new definitions are written to when they are added with persist enabled.
+const DefaultPersistFile = "runtime-mocks.json"
+
+// MockNotFoundError is returned when no mock definition with the requested ID exists.
+type MockNotFoundError struct {
+	Id string
+}
+
+func (e *MockNotFoundError) Error() string {
+	return fmt.Sprintf("no mock definition with the id '%s' exists", e.Id)
+}
+
+// validateMockDefinition checks a definition submitted through the admin API before it is added.
+func validateMockDefinition(definition StaticMockDefinition) error {
+	if definition.Id == "" {
+		return fmt.Errorf("mock definition requires an 'id'")
+	}
+	if definition.Request.Method == "" && definition.Inherits == "" {
+		return fmt.Errorf("mock definition '%s' requires a request 'method'", definition.Id)
+	}
+	if code := definition.Response.StatusCode; code != 0 && (code < 100 || code > 599) {
+		return fmt.Errorf("mock definition '%s' has an invalid response status code %d", definition.Id, code)
+	}
+	return nil
+}
+
+// ListMockDefinitions returns every active mock definition, with inheritance resolved.
+func (sms *StaticMockService) ListMockDefinitions() []StaticMockDefinition {
+	return sms.getMockDefinitions()
+}
+
+// AddMockDefinition adds a new mock definition. When persist is set, the definition is written to the
+// DefaultPersistFile in the mock definitions directory, otherwise it only lives in memory.
+func (sms *StaticMockService) AddMockDefinition(definition StaticMockDefinition, persist bool) error {
+	if err := validateMockDefinition(definition); err != nil {
+		return err
+	}
+
+	sms.lock.Lock()
+	defer sms.lock.Unlock()
+
+	if file, _ := sms.findMockDefinition(definition.Id); file != nil {
+		return fmt.Errorf("a mock definition with the id '%s' already exists", definition.Id)
+	}
+
+	key := runtimeDefinitionsKey
+	if persist {
+		mocksPath := sms.mockDefinitionsDir()
+		if mocksPath == "" {
+			return fmt.Errorf("no mock definitions directory is configured")
+		}
+		key = filepath.Join(mocksPath, DefaultPersistFile)
+	}
+	return sms.updateDefinitionSet(key, append(sms.copyDefinitionSet(key), definition), persist)
+}
+
+// ReplaceMockDefinition replaces the mock definition with the given ID, in the set it was loaded from.
+func (sms *StaticMockService) ReplaceMockDefinition(id string, definition StaticMockDefinition, persist bool) error {
+	if definition.Id == "" {
+		definition.Id = id
+	}
+	if definition.Id != id {
+		return fmt.Errorf("mock definition id '%s' does not match '%s'", definition.Id, id)
+	}
+	if err := validateMockDefinition(definition); err != nil {
+		return err
+	}
+
+	sms.lock.Lock()
+	defer sms.lock.Unlock()
+
+	key, idx := sms.findMockDefinition(id)
+	if key == nil {
+		return &MockNotFoundError{Id: id}
+	}
+	definitions := sms.copyDefinitionSet(*key)
+	definitions[idx] = definition
+	return sms.updateDefinitionSet(*key, definitions, persist)
+}
+
+// DeleteMockDefinition removes the mock definition with the given ID.
+func (sms *StaticMockService) DeleteMockDefinition(id string, persist bool) error {
+	sms.lock.Lock()
+	defer sms.lock.Unlock()
+
+	key, idx := sms.findMockDefinition(id)
+	if key == nil {
+		return &MockNotFoundError{Id: id}
+	}
+	definitions := sms.copyDefinitionSet(*key)
+	definitions = append(definitions[:idx], definitions[idx+1:]...)
+	return sms.updateDefinitionSet(*key, definitions, persist)
+}
+
+// findMockDefinition returns the definition set key and index of the first definition with the given ID.
+// The caller must hold the lock.
+func (sms *StaticMockService) findMockDefinition(id string) (*string, int) {
+	keys := make([]string, 0, len(sms.definitionsByFile))
+	for key := range sms.definitionsByFile {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		definitions := sms.definitionsByFile[key]
+		for i := range definitions {
+			if definitions[i].Id == id {
+				return &key, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// copyDefinitionSet returns a copy of a definition set, so it can be changed without touching the active set.
+// The caller must hold the lock.
+func (sms *StaticMockService) copyDefinitionSet(key string) []StaticMockDefinition {
+	return append([]StaticMockDefinition{}, sms.definitionsByFile[key]...)
+}
+
+// updateDefinitionSet swaps in a changed definition set and rebuilds the merged definitions. If the change breaks
+// inheritance, it is rolled back. The change is published to the other instances when etcd synchronization is on.
+// When persist is set, the set is written back to its file. The caller must hold the write lock.
+func (sms *StaticMockService) updateDefinitionSet(key string, definitions []StaticMockDefinition, persist bool) error {
+	if persist && key != runtimeDefinitionsKey &&
+		(key == sms.config.MockDefinitionsURL || sms.inMockDefinitionDir(sms.gitDir, key)) {
+		return fmt.Errorf("mock definitions loaded from '%s' cannot be persisted", key)
+	}
+	if err := sms.swapDefinitionSet(key, definitions); err != nil {
+		return err
+	}
+	sms.publishDefinitionSet(key, definitions)
+
+	if persist && key != runtimeDefinitionsKey {
+		format := mockDefinitionFormat(key, sms.config.MockDefinitionFormat)
+		data, err := marshalMockDefinitions(definitions, format)
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(key, data, 0644); err != nil {
+			return err
+		}
+		sms.logger.Info("Mock definitions persisted", "file", key, "definitions", len(definitions))
+	}
+	return nil
+}
+
+// swapDefinitionSet swaps in a changed definition set and rebuilds the merged definitions, rolling the change back
+// if it breaks inheritance. The caller must hold the write lock.
+func (sms *StaticMockService) swapDefinitionSet(key string, definitions []StaticMockDefinition) error {
+	previous, existed := sms.definitionsByFile[key]
+	sms.definitionsByFile[key] = definitions
+	if err := sms.mergeMockDefinitions(); err != nil {
+		if existed {
+			sms.definitionsByFile[key] = previous
+		} else {
+			delete(sms.definitionsByFile, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// mockManagementErrorStatus maps a mock management error to an HTTP status code.
+func mockManagementErrorStatus(err error) int {
+	if _, ok := err.(*MockNotFoundError); ok {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,166 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/fnv"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/pb33f/wiretap/shared"
+)
+
+// maskMockDefinitions masks the response bodies of definitions loaded from a file, using the configured mask rules.
+// Masking happens once, when definitions are loaded, and the masked body is stored on the definition. Bodies loaded
+// from a body JSON file are inlined, so the raw file is not read again at request time.
+// A warning is logged for every definition whose file still contains raw values.
+func (sms *StaticMockService) maskMockDefinitions(filePath string, definitions []StaticMockDefinition) {
+	if len(sms.config.MaskFields) == 0 {
+		return
+	}
+	for i := range definitions {
+		response := &definitions[i].Response
+		body, source := response.Body, filePath
+		if response.bodyFileContent != nil {
+			// body files are already cached on the definition, the cached copy is masked.
+			masked, count := maskBody(string(response.bodyFileContent), sms.config.MaskFields)
+			if count > 0 {
+				response.bodyFileContent = []byte(masked)
+				sms.logger.Warn("Mock definition contains unmasked values, serving a masked copy",
+					"file", resolveBodyFile(filePath, response.BodyFile), "id", definitions[i].Id, "values", count)
+			}
+			continue
+		}
+		if response.BodyJsonFilename != "" {
+			if sms.wiretapService == nil {
+				continue
+			}
+			source = sms.wiretapService.StaticMockDir + MockBodyJsonsPath + response.BodyJsonFilename
+			data, err := os.ReadFile(source)
+			if err != nil {
+				// the missing file is reported when the mock is served.
+				continue
+			}
+			body = string(data)
+		}
+
+		masked, count := maskBody(body, sms.config.MaskFields)
+		if count == 0 {
+			continue
+		}
+		response.Body = masked
+		response.BodyJsonFilename = ""
+		sms.logger.Warn("Mock definition contains unmasked values, serving a masked copy",
+			"file", source, "id", definitions[i].Id, "values", count)
+	}
+}
+
+// maskBody applies the mask rules to a JSON body, returning the masked body and the number of values that were
+// masked. Values already equal to their mask are not counted. Bodies that are not JSON are returned untouched.
+func maskBody(body string, rules []*shared.MaskRule) (string, int) {
+	if body == "" {
+		return body, 0
+	}
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var parsed any
+	if err := decoder.Decode(&parsed); err != nil {
+		return body, 0
+	}
+
+	count := 0
+	for _, rule := range rules {
+		if rule.CompiledPath == nil {
+			continue
+		}
+		parsed = rule.CompiledPath.MustModify(parsed, func(element any) (any, bool) {
+			masked, changed := maskValue(element, rule.MaskWith)
+			if changed {
+				count++
+			}
+			return masked, changed
+		})
+	}
+	if count == 0 {
+		return body, 0
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(parsed); err != nil {
+		return body, 0
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), count
+}
+
+// maskValue replaces a value with the mask, or with a format preserving fake value when the mask is empty.
+// Objects and arrays are replaced as a whole with a mask, or have every value masked when preserving the format.
+func maskValue(value any, maskWith string) (any, bool) {
+	if maskWith != "" {
+		if s, ok := value.(string); ok && s == maskWith {
+			return value, false
+		}
+		return maskWith, true
+	}
+	switch v := value.(type) {
+	case string:
+		return preserveFormat(v), v != ""
+	case json.Number:
+		faked := preserveFormat(v.String())
+		return json.Number(faked), faked != v.String()
+	case map[string]any:
+		changed := false
+		for k := range v {
+			var c bool
+			v[k], c = maskValue(v[k], maskWith)
+			changed = changed || c
+		}
+		return v, changed
+	case []any:
+		changed := false
+		for i := range v {
+			var c bool
+			v[i], c = maskValue(v[i], maskWith)
+			changed = changed || c
+		}
+		return v, changed
+	}
+	return value, false
+}
+
+// preserveFormat replaces every letter and digit with a random letter (of the same case) or digit, keeping
+// punctuation, so masked values still look like emails, phone numbers or IDs. The replacement is seeded from
+// the value, so the same value is always masked the same way, across reloads.
+func preserveFormat(value string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(value))
+	r := rand.New(rand.NewPCG(h.Sum64(), 0))
+
+	var sb strings.Builder
+	for i, c := range value {
+		switch {
+		case unicode.IsDigit(c):
+			// numbers keep a non-zero leading digit, so they stay valid numbers.
+			if i == 0 && c != '0' {
+				sb.WriteRune(rune('1' + r.IntN(9)))
+			} else {
+				sb.WriteRune(rune('0' + r.IntN(10)))
+			}
+		case unicode.IsUpper(c):
+			sb.WriteRune(rune('A' + r.IntN(26)))
+		case unicode.IsLetter(c):
+			sb.WriteRune(rune('a' + r.IntN(26)))
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,69 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskBody(t *testing.T) {
+	config := &shared.WiretapConfiguration{MaskFields: []*shared.MaskRule{
+		{JSONPath: "$.customers[*].email", MaskWith: "***"},
+		{JSONPath: "$.customers[*].phone"},
+	}}
+	require.NoError(t, config.CompileMaskFields())
+
+	body := `{"customers": [{"email": "jane@example.com", "phone": "+1 (555) 123-4567"}, {"email": "***"}]}`
+	masked, count := maskBody(body, config.MaskFields)
+	assert.Equal(t, 2, count)
+	assert.Contains(t, masked, `"email":"***"`)
+	assert.NotContains(t, masked, "jane@example.com")
+	assert.NotContains(t, masked, "123-4567")
+	assert.Regexp(t, regexp.MustCompile(`"phone":"\+\d \(\d{3}\) \d{3}-\d{4}"`), masked)
+
+	// masking is stable, so reloads serve the same values.
+	again, _ := maskBody(body, config.MaskFields)
+	assert.Equal(t, masked, again)
+
+	// already masked and non-JSON bodies are left alone.
+	_, count = maskBody(masked, config.MaskFields[:1])
+	assert.Zero(t, count)
+	notJSON, count := maskBody("{{ .name }}", config.MaskFields)
+	assert.Equal(t, "{{ .name }}", notJSON)
+	assert.Zero(t, count)
+}
+
+func TestLoadMockDefinitionFile_Masked(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "body-jsons"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "body-jsons", "user.json"),
+		[]byte(`{"ssn": "123-45-6789"}`), 0644))
+	mockFile := filepath.Join(dir, "mocks.json")
+	require.NoError(t, os.WriteFile(mockFile, []byte(`[
+		{"id": "inline", "request": {"method": "GET"}, "response": {"body": "{\"ssn\": \"987-65-4321\"}"}},
+		{"id": "file", "request": {"method": "GET"}, "response": {"bodyJsonFilename": "user.json"}}
+	]`), 0644))
+
+	sms := newTestStaticMockService()
+	sms.wiretapService = &daemon.WiretapService{StaticMockDir: dir}
+	sms.config.MaskFields = []*shared.MaskRule{{JSONPath: "$.ssn", MaskWith: "XXX-XX-XXXX"}}
+	require.NoError(t, sms.config.CompileMaskFields())
+
+	definitions, err := sms.loadMockDefinitionFile(mockFile)
+	require.NoError(t, err)
+	require.Len(t, definitions, 2)
+	assert.JSONEq(t, `{"ssn": "XXX-XX-XXXX"}`, definitions[0].Response.Body)
+	assert.JSONEq(t, `{"ssn": "XXX-XX-XXXX"}`, definitions[1].Response.Body)
+	assert.Empty(t, definitions[1].Response.BodyJsonFilename)
+}
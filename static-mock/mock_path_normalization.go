@@ -0,0 +1,84 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// isUnreservedByte checks if a byte is an unreserved URI character (RFC 3986 section 2.3), percent-encoded
+// unreserved characters mean the same thing decoded, so they are safe to decode.
+func isUnreservedByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// normalizePath normalizes an escaped request path: safe percent-encoded characters are decoded, the remaining
+// percent-encodings are lower cased, then duplicate slashes, dot segments and trailing slashes are removed.
+// Encoded slashes are left encoded, so they are not treated as segment separators.
+func normalizePath(escapedPath string) string {
+	var sb strings.Builder
+	for i := 0; i < len(escapedPath); i++ {
+		c := escapedPath[i]
+		if c == '%' && i+2 < len(escapedPath) {
+			hi, hiOk := unhex(escapedPath[i+1])
+			lo, loOk := unhex(escapedPath[i+2])
+			if hiOk && loOk {
+				if decoded := hi<<4 | lo; isUnreservedByte(decoded) {
+					sb.WriteByte(decoded)
+				} else {
+					sb.WriteString(strings.ToLower(escapedPath[i : i+3]))
+				}
+				i += 2
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+	normalized := sb.String()
+	if normalized == "" {
+		return "/"
+	}
+	// dot segments are only decoded above, so they are cleaned along with literal ones.
+	return path.Clean("/" + normalized)
+}
+
+// normalizedRequest returns a shallow copy of the request with a normalized path, to match mock definitions
+// against. The request sent upstream, when no mock matches, keeps the original path.
+func (sms *StaticMockService) normalizedRequest(request *http.Request) *http.Request {
+	original := request.URL.EscapedPath()
+	normalized := normalizePath(original)
+	if normalized == original {
+		return request
+	}
+	decoded, err := url.PathUnescape(normalized)
+	if err != nil {
+		return request
+	}
+	sms.logger.Debug("Normalized request path for mock matching", "path", original, "normalized", normalized)
+
+	normalizedURL := *request.URL
+	normalizedURL.Path = decoded
+	normalizedURL.RawPath = normalized
+	matchRequest := *request
+	matchRequest.URL = &normalizedURL
+	return &matchRequest
+}
@@ -0,0 +1,52 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePath(t *testing.T) {
+	tests := map[string]string{
+		"":                 "/",
+		"/":                "/",
+		"/pets/":           "/pets",
+		"//pets//1":        "/pets/1",
+		"/pets/./1/../2":   "/pets/2",
+		"/%70ets/%7E1":     "/pets/~1",
+		"/pets/a%2Fb":      "/pets/a%2fb",
+		"/pets/%2E%2E/cat": "/cat",
+		"/pets/%zz":        "/pets/%zz",
+	}
+	for input, expected := range tests {
+		assert.Equal(t, expected, normalizePath(input), input)
+	}
+}
+
+func TestCheckStaticMockExists_NormalizeRequestPath(t *testing.T) {
+	sms := newTestStaticMockService(StaticMockDefinition{
+		Request:  StaticMockDefinitionRequest{Method: http.MethodPost, UrlPath: "/pets/1", Body: "cat"},
+		Response: StaticMockDefinitionResponse{StatusCode: 200},
+	})
+
+	request, _ := http.NewRequest(http.MethodPost, "http://localhost//pets/%31/", strings.NewReader("cat"))
+	assert.Nil(t, sms.checkStaticMockExists(request))
+
+	sms.config.NormalizeRequestPath = true
+	request, _ = http.NewRequest(http.MethodPost, "http://localhost//pets/%31/", strings.NewReader("cat"))
+	require.NotNil(t, sms.checkStaticMockExists(request))
+
+	// the request keeps its original path and a readable body.
+	assert.Equal(t, "//pets/1/", request.URL.Path)
+	body, _ := io.ReadAll(request.Body)
+	assert.Equal(t, "cat", string(body))
+}
@@ -0,0 +1,111 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// remoteMockDefinitionsTimeout limits how long a fetch of remote mock definitions may take.
+const remoteMockDefinitionsTimeout = 30 * time.Second
+
+// fetchRemoteMockDefinitions fetches and parses the mock definitions at the mock definitions URL, with the
+// configured headers (for authentication). The format comes from the extension of the URL path, then from the
+// content type of the response, then from the format override, falling back to JSON. Relative body files are
+// relative to the working directory.
+func (sms *StaticMockService) fetchRemoteMockDefinitions() ([]StaticMockDefinition, error) {
+	location := sms.config.MockDefinitionsURL
+	req, err := http.NewRequest(http.MethodGet, sms.config.ReplaceWithVariables(location), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range sms.config.MockDefinitionsHeaders {
+		req.Header.Set(k, sms.config.ReplaceWithVariables(v))
+	}
+
+	client := &http.Client{Timeout: remoteMockDefinitionsTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mock definitions request returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return sms.readMockDefinitions(location, data, remoteMockDefinitionFormat(req.URL,
+		resp.Header.Get("Content-Type"), sms.config.MockDefinitionFormat))
+}
+
+// remoteMockDefinitionFormat determines which parser to use for remote mock definitions.
+func remoteMockDefinitionFormat(location *url.URL, contentType, override string) string {
+	if format := formatFromExtension(location.Path); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.Contains(mediaType, "json"):
+		return MockDefinitionFormatJSON
+	case strings.Contains(mediaType, "yaml"):
+		return MockDefinitionFormatYAML
+	case strings.Contains(mediaType, "toml"):
+		return MockDefinitionFormatTOML
+	}
+	return mockDefinitionFormat("", override)
+}
+
+// refreshRemoteMockDefinitions replaces the remote mock definitions with a fresh copy. When the fetch fails, or the
+// fresh definitions cannot be applied, the last successfully loaded definitions keep being served.
+func (sms *StaticMockService) refreshRemoteMockDefinitions() {
+	location := sms.config.MockDefinitionsURL
+	sms.logger.Info("Refreshing remote mock definitions", "url", location)
+
+	definitions, err := sms.fetchRemoteMockDefinitions()
+	if err == nil {
+		sms.lock.Lock()
+		err = sms.swapDefinitionSet(location, definitions)
+		sms.lock.Unlock()
+	}
+	if err != nil {
+		sms.lock.RLock()
+		stale := len(sms.definitionsByFile[location])
+		sms.lock.RUnlock()
+		sms.logger.Error("Unable to refresh remote mock definitions, keeping last loaded definitions",
+			"url", location, "definitions", stale, "error", err.Error())
+		return
+	}
+	sms.logger.Info("Remote mock definitions loaded", "url", location, "definitions", len(definitions))
+}
+
+// StartRemoteRefresh refreshes the remote mock definitions, and pulls the mock definitions git repository,
+// periodically, when a refresh interval is configured.
+func (sms *StaticMockService) StartRemoteRefresh() {
+	if (sms.config.MockDefinitionsURL == "" && sms.config.MockDefinitionsGitRepo == "") ||
+		sms.config.MockDefinitionsRefreshIntervalSec <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(sms.config.MockDefinitionsRefreshIntervalSec) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			if sms.config.MockDefinitionsURL != "" {
+				sms.refreshRemoteMockDefinitions()
+			}
+			if sms.config.MockDefinitionsGitRepo != "" {
+				sms.syncGitMockDefinitions()
+			}
+		}
+	}()
+}
@@ -0,0 +1,68 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticMockService_RefreshRemoteMockDefinitions(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() || r.Header.Get("Authorization") != "Bearer pb33f" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write([]byte("- id: remote\n  request:\n    method: GET\n    urlPath: /remote\n  response:\n    statusCode: 200\n"))
+	}))
+	defer server.Close()
+
+	sms := newTestStaticMockService(StaticMockDefinition{Id: "local"})
+	sms.config.MockDefinitionsURL = server.URL + "/mocks"
+	sms.config.MockDefinitionsHeaders = map[string]string{"Authorization": "Bearer ${token}"}
+	sms.config.Variables = map[string]string{"token": "pb33f"}
+	sms.config.CompileVariables()
+
+	sms.refreshRemoteMockDefinitions()
+	ids := func() []string {
+		var ids []string
+		for _, definition := range sms.getMockDefinitions() {
+			ids = append(ids, definition.Id)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []string{"local", "remote"}, ids())
+
+	// the last loaded definitions are kept when the remote is down.
+	failing.Store(true)
+	sms.refreshRemoteMockDefinitions()
+	assert.ElementsMatch(t, []string{"local", "remote"}, ids())
+
+	// remote definitions cannot be written back.
+	sms.lock.Lock()
+	err := sms.updateDefinitionSet(sms.config.MockDefinitionsURL, nil, true)
+	sms.lock.Unlock()
+	assert.ErrorContains(t, err, "cannot be persisted")
+}
+
+func TestRemoteMockDefinitionFormat(t *testing.T) {
+	location, err := url.Parse("https://pb33f.io/mocks.toml?version=2")
+	require.NoError(t, err)
+	assert.Equal(t, MockDefinitionFormatTOML, remoteMockDefinitionFormat(location, "application/json", ""))
+
+	location.Path = "/mocks"
+	assert.Equal(t, MockDefinitionFormatJSON, remoteMockDefinitionFormat(location, "application/json; charset=utf-8", ""))
+	assert.Equal(t, MockDefinitionFormatYAML, remoteMockDefinitionFormat(location, "text/plain", "YAML"))
+	assert.Equal(t, MockDefinitionFormatJSON, remoteMockDefinitionFormat(location, "", ""))
+}
@@ -0,0 +1,51 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"strings"
+	"time"
+)
+
+// isActive checks if a mock definition is active at the given time. A definition without any schedule is always
+// active. ActiveFrom is inclusive and ActiveUntil is exclusive. ActiveDays and ActiveHours are evaluated in UTC,
+// ActiveHours is a [start, end) range of hours that wraps past midnight when start is greater than end.
+func (smd *StaticMockDefinition) isActive(now time.Time) bool {
+	if smd.ActiveFrom != nil && now.Before(*smd.ActiveFrom) {
+		return false
+	}
+	if smd.ActiveUntil != nil && !now.Before(*smd.ActiveUntil) {
+		return false
+	}
+
+	utc := now.UTC()
+	if len(smd.ActiveDays) > 0 {
+		today := utc.Weekday().String()[:3]
+		found := false
+		for _, day := range smd.ActiveDays {
+			if len(day) >= 3 && strings.EqualFold(day[:3], today) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if smd.ActiveHours == nil {
+		return true
+	}
+	start, end := smd.ActiveHours[0], smd.ActiveHours[1]
+	if start != end {
+		hour := utc.Hour()
+		if start < end {
+			return hour >= start && hour < end
+		}
+		return hour >= start || hour < end
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticMockDefinition_IsActive(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	assert.True(t, (&StaticMockDefinition{}).isActive(now))
+	assert.True(t, (&StaticMockDefinition{ActiveFrom: &before, ActiveUntil: &after}).isActive(now))
+	assert.False(t, (&StaticMockDefinition{ActiveFrom: &after}).isActive(now))
+	assert.False(t, (&StaticMockDefinition{ActiveUntil: &before}).isActive(now))
+
+	assert.True(t, (&StaticMockDefinition{ActiveDays: []string{"Mon", "wed"}}).isActive(now))
+	assert.False(t, (&StaticMockDefinition{ActiveDays: []string{"Sat", "Sun"}}).isActive(now))
+
+	assert.True(t, (&StaticMockDefinition{ActiveHours: &[2]int{9, 17}}).isActive(now))
+	assert.False(t, (&StaticMockDefinition{ActiveHours: &[2]int{11, 17}}).isActive(now))
+	assert.True(t, (&StaticMockDefinition{ActiveHours: &[2]int{22, 11}}).isActive(now))
+	assert.False(t, (&StaticMockDefinition{ActiveHours: &[2]int{22, 6}}).isActive(now))
+}
@@ -0,0 +1,58 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/pb33f/ranch/model"
+	"github.com/pb33f/ranch/service"
+)
+
+// hasActiveTag checks if a mock definition should be considered for the active tags. When no tags are active, every
+// mock definition is considered, otherwise the mock definition must carry at least one of the active tags.
+func (smd *StaticMockDefinition) hasActiveTag(activeTags []string) bool {
+	if len(activeTags) == 0 {
+		return true
+	}
+	for _, tag := range smd.Tags {
+		for _, activeTag := range activeTags {
+			if tag == activeTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getActiveTags returns the tags that are currently active.
+func (sms *StaticMockService) getActiveTags() []string {
+	sms.lock.RLock()
+	defer sms.lock.RUnlock()
+	return sms.activeTags
+}
+
+// SetActiveTags replaces the active tags, an empty list makes every mock definition available again.
+func (sms *StaticMockService) SetActiveTags(tags []string) {
+	sms.lock.Lock()
+	sms.activeTags = tags
+	sms.lock.Unlock()
+	sms.logger.Info("Active mock tags changed", "tags", tags)
+}
+
+// activateTags handles a request to switch the active tags at runtime.
+func (sms *StaticMockService) activateTags(request *model.Request, core service.FabricServiceCore) {
+	if payload, ok := request.Payload.(map[string]interface{}); ok {
+		var r ActivateTagsPayload
+		if err := mapstructure.Decode(payload, &r); err != nil {
+			core.SendErrorResponse(request, 400, "Invalid tags value")
+			return
+		}
+		sms.SetActiveTags(r.Tags)
+		core.SendResponse(request, &r)
+	} else {
+		core.SendErrorResponse(request, 400, "Invalid tags value")
+	}
+}
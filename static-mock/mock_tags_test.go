@@ -0,0 +1,23 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticMockDefinition_HasActiveTag(t *testing.T) {
+	tagged := &StaticMockDefinition{Tags: []string{"checkout", "happy-path"}}
+	untagged := &StaticMockDefinition{}
+
+	assert.True(t, tagged.hasActiveTag(nil))
+	assert.True(t, untagged.hasActiveTag(nil))
+	assert.True(t, tagged.hasActiveTag([]string{"happy-path"}))
+	assert.False(t, tagged.hasActiveTag([]string{"outage"}))
+	assert.False(t, untagged.hasActiveTag([]string{"outage"}))
+}
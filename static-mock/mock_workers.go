@@ -0,0 +1,171 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MinConcurrentMockDefinitions is the number of candidate definitions below which mocks are always checked
+// serially, as starting the workers costs more than checking a handful of definitions.
+const MinConcurrentMockDefinitions = 64
+
+// mockCheck is what a request is checked against: the candidate definitions, the active tags and the time.
+type mockCheck struct {
+	candidates []StaticMockDefinition
+	activeTags []string
+	now        time.Time
+}
+
+// matches is true when the candidate at the position is active and matches the request.
+func (sms *StaticMockService) matches(check *mockCheck, position int, request *http.Request) bool {
+	definition := &check.candidates[position]
+	// skip mocks that are not part of the active scenario, or outside their active window
+	if !definition.hasActiveTag(check.activeTags) || !definition.isActive(check.now) {
+		return false
+	}
+	return sms.isRequestMatch(definition.Request, request)
+}
+
+// mockWorkers is the fixed pool of workers that check chunks of candidate definitions, started once with the
+// service. It is only used for requests with enough candidates to be worth splitting.
+type mockWorkers struct {
+	size   int
+	chunks chan *mockChunk
+	done   chan struct{}
+	stop   sync.Once
+}
+
+// mockSearch is a request being checked by the workers. The first match in matching order wins, so a worker stops
+// as soon as an earlier match has been found.
+type mockSearch struct {
+	check   *mockCheck
+	request *http.Request
+	body    *bufferedBody
+	best    atomic.Int64
+	panics  chan any
+	wg      sync.WaitGroup
+}
+
+// mockChunk is the range of candidates of a search that a single worker checks.
+type mockChunk struct {
+	search     *mockSearch
+	start, end int
+}
+
+// startMockWorkers starts a pool of the configured number of mock check workers, nil is returned when mocks are
+// checked serially.
+func (sms *StaticMockService) startMockWorkers(size int) *mockWorkers {
+	if size <= 1 {
+		return nil
+	}
+	// chunks are unbuffered, so a chunk is only handed over to a worker that is running.
+	workers := &mockWorkers{size: size, chunks: make(chan *mockChunk), done: make(chan struct{})}
+	for i := 0; i < size; i++ {
+		go sms.runMockWorker(workers)
+	}
+	return workers
+}
+
+// stopMockWorkers stops the pool of mock check workers, requests still being checked finish serially.
+func (sms *StaticMockService) stopMockWorkers() {
+	if sms.mockWorkers != nil {
+		sms.mockWorkers.stop.Do(func() { close(sms.mockWorkers.done) })
+	}
+}
+
+// runMockWorker checks chunks until the pool is stopped. Matching only reads the request, so the worker reuses a
+// shallow copy of it with its own reader over the buffered body, instead of cloning the request for every chunk.
+func (sms *StaticMockService) runMockWorker(workers *mockWorkers) {
+	var request http.Request
+	body := newBufferedBody(nil, nil)
+	for {
+		select {
+		case chunk := <-workers.chunks:
+			request = *chunk.search.request
+			if chunk.search.body != nil {
+				body.data, body.parsed = chunk.search.body.data, chunk.search.body.parsed
+				body.Reset(body.data)
+				request.Body = body
+			}
+			sms.checkChunk(chunk, &request)
+			request, body.data, body.parsed = http.Request{}, nil, nil
+		case <-workers.done:
+			return
+		}
+	}
+}
+
+// checkChunk checks the candidates of the chunk against the request, keeping the earliest match of the search. A
+// panic while matching is passed back to the search.
+func (sms *StaticMockService) checkChunk(chunk *mockChunk, request *http.Request) {
+	search := chunk.search
+	defer search.wg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			search.panics <- r
+		}
+	}()
+	for i := chunk.start; i < chunk.end && int64(i) < search.best.Load(); i++ {
+		if sms.matches(search.check, i, request) {
+			// keep the earliest match, a later chunk may have found one first.
+			for current := search.best.Load(); int64(i) < current; current = search.best.Load() {
+				if search.best.CompareAndSwap(current, int64(i)) {
+					break
+				}
+			}
+			return
+		}
+	}
+}
+
+// findMatch returns the position of the first candidate that matches the request, or -1 if none match. The
+// candidates are checked by the pool of workers when configured, and there are enough candidates to be worth it.
+func (sms *StaticMockService) findMatch(check *mockCheck, request *http.Request) int {
+	if sms.mockWorkers == nil || len(check.candidates) < MinConcurrentMockDefinitions {
+		for i := range check.candidates {
+			if sms.matches(check, i, request) {
+				return i
+			}
+		}
+		return -1
+	}
+	return sms.findMatchConcurrently(check, request)
+}
+
+// findMatchConcurrently splits the candidates into a chunk per worker, and hands the chunks to the pool. The body
+// is buffered, and parsed, once for all workers. Chunks are checked by the caller once the pool has been stopped.
+// A panic while matching is raised again here, where the request handler recovers from it.
+func (sms *StaticMockService) findMatchConcurrently(check *mockCheck, request *http.Request) int {
+	workers := sms.mockWorkers
+	search := &mockSearch{check: check, request: request, body: bufferBody(request),
+		panics: make(chan any, workers.size)}
+	search.best.Store(int64(len(check.candidates)))
+	chunkSize := (len(check.candidates) + workers.size - 1) / workers.size
+
+	for start := 0; start < len(check.candidates); start += chunkSize {
+		chunk := &mockChunk{search: search, start: start, end: min(start+chunkSize, len(check.candidates))}
+		search.wg.Add(1)
+		select {
+		case workers.chunks <- chunk:
+		case <-workers.done:
+			sms.checkChunk(chunk, request)
+		}
+	}
+	search.wg.Wait()
+	close(search.panics)
+
+	if r, ok := <-search.panics; ok {
+		panic(r)
+	}
+	if position := int(search.best.Load()); position < len(check.candidates) {
+		return position
+	}
+	return -1
+}
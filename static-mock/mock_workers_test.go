@@ -0,0 +1,98 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newWorkerTestService(workers int) *StaticMockService {
+	definitions := make([]StaticMockDefinition, 0, 200)
+	for i := 0; i < 200; i++ {
+		definitions = append(definitions, StaticMockDefinition{Id: fmt.Sprint(i), Request: StaticMockDefinitionRequest{
+			Method: http.MethodPost, UrlPath: "~^/pets", Body: map[string]interface{}{"id": fmt.Sprint(i % 50)},
+		}})
+	}
+	sms := newTestStaticMockService(definitions...)
+	sms.config.MockCheckWorkers = workers
+	sms.mockWorkers = sms.startMockWorkers(workers)
+	return sms
+}
+
+func newPetRequest(id string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(`{"id":"`+id+`"}`))
+	request.Header.Set("Content-Type", "application/json")
+	return request
+}
+
+func TestStaticMockService_CheckStaticMockExists_Workers(t *testing.T) {
+	serial, concurrent := newWorkerTestService(0), newWorkerTestService(4)
+	for _, id := range []string{"0", "7", "42", "49", "50"} {
+		expected := serial.checkStaticMockExists(newPetRequest(id))
+		request := newPetRequest(id)
+		matched := concurrent.checkStaticMockExists(request)
+
+		// the first matching definition wins, as it does when checked serially.
+		assert.Equal(t, expected, matched, id)
+
+		// the request body can still be read afterward.
+		body, _ := io.ReadAll(request.Body)
+		assert.Equal(t, `{"id":"`+id+`"}`, string(body))
+	}
+	assert.Equal(t, "42", concurrent.checkStaticMockExists(newPetRequest("42")).Id)
+	assert.Nil(t, concurrent.checkStaticMockExists(newPetRequest("50")))
+}
+
+func TestStaticMockService_FindMatchConcurrently_Panic(t *testing.T) {
+	sms := newWorkerTestService(4)
+	request := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(`{not json`))
+	request.Header.Set("Content-Type", "application/json")
+
+	// a panic in a worker is raised again for the request handler to recover from.
+	assert.Panics(t, func() { sms.checkStaticMockExists(request) })
+}
+
+func TestStaticMockService_StopMockWorkers(t *testing.T) {
+	sms := newWorkerTestService(4)
+	assert.Equal(t, "7", sms.checkStaticMockExists(newPetRequest("7")).Id)
+
+	// once the pool is stopped, the chunks are checked by the request handler.
+	sms.OnServerShutdown()
+	sms.OnServerShutdown()
+	assert.Equal(t, "42", sms.checkStaticMockExists(newPetRequest("42")).Id)
+	assert.Nil(t, sms.checkStaticMockExists(newPetRequest("50")))
+
+	// one worker, or none, checks mocks serially.
+	assert.Nil(t, sms.startMockWorkers(1))
+}
+
+func TestStaticMockService_ConvertMockDefinitions_Workers(t *testing.T) {
+	items := make([]interface{}, 0, 201)
+	for i := 0; i < 200; i++ {
+		items = append(items, map[string]interface{}{"id": fmt.Sprint(i),
+			"request": map[string]interface{}{"method": "GET", "urlPath": fmt.Sprintf("/pets/%d", i)}})
+	}
+	// items that are not definitions are left out.
+	items = append(items[:100], append([]interface{}{"not a definition"}, items[100:]...)...)
+
+	serial := newTestStaticMockService().convertMockDefinitions("mocks.json", items)
+	sms := newTestStaticMockService()
+	sms.config.MockLoadWorkers = 8
+	concurrent := sms.convertMockDefinitions("mocks.json", items)
+
+	assert.Len(t, concurrent, 200)
+	assert.Equal(t, serial, concurrent)
+	for i, definition := range concurrent {
+		assert.Equal(t, fmt.Sprint(i), definition.Id)
+	}
+}
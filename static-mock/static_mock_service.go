@@ -7,30 +7,44 @@ package staticMock
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
-	"strings"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/pb33f/ranch/model"
 	"github.com/pb33f/ranch/service"
 	"github.com/pb33f/wiretap/daemon"
+	"github.com/pb33f/wiretap/shared"
 )
 
 const (
 	StaticMockServiceChan = "static-mock-service"
 	IncomingHttpRequest   = "incoming-http-request"
+	ActivateTagsRequest   = "activate-tags"
 	MockDefinitionsPath   = "/mock-definitions"
 	MockBodyJsonsPath     = "/body-jsons/"
 )
 
 type StaticMockDefinitionRequest struct {
-	Method      string          `json:"method,omitempty"`
-	UrlPath     string          `json:"urlPath,omitempty"`
-	Host        string          `json:"host,omitempty"`
-	Header      *map[string]any `json:"header,omitempty"`
-	Body        interface{}     `json:"body,omitempty"`
-	QueryParams *map[string]any `json:"queryParams,omitempty"`
+	Method             string              `json:"method,omitempty"`
+	UrlPath            string              `json:"urlPath,omitempty"`
+	Host               string              `json:"host,omitempty"`
+	Header             *map[string]any     `json:"header,omitempty"`
+	Body               interface{}         `json:"body,omitempty"`
+	QueryParams        *map[string]any     `json:"queryParams,omitempty"`
+	JSONPathConditions []JSONPathCondition `json:"jsonPathConditions,omitempty"`
+
+	// BodyLength matches bodies of this length in bytes, useful for binary bodies.
+	BodyLength *int `json:"bodyLength,omitempty"`
+
+	// CaseInsensitive matches the host and URL path regardless of case.
+	CaseInsensitive bool `json:"caseInsensitive,omitempty"`
 }
 
 type StaticMockDefinitionResponse struct {
@@ -38,27 +52,87 @@ type StaticMockDefinitionResponse struct {
 	StatusCode       int            `json:"statusCode,omitempty"`
 	Body             string         `json:"body,omitempty"`
 	BodyJsonFilename string         `json:"bodyJsonFilename,omitempty"`
+	BodyFile         string         `json:"bodyFile,omitempty"`
+
+	// CompressResponse compresses the body with CompressionAlgorithm (gzip, deflate or br, gzip by default),
+	// setting the Content-Encoding header, to check that clients decompress responses.
+	CompressResponse     bool   `json:"compressResponse,omitempty"`
+	CompressionAlgorithm string `json:"compressionAlgorithm,omitempty"`
+
+	// ChunkedResponse streams the body in chunks of ChunkSizeBytes, pausing ChunkDelayMs between chunks.
+	ChunkedResponse bool `json:"chunkedResponse,omitempty"`
+	ChunkSizeBytes  int  `json:"chunkSizeBytes,omitempty"`
+	ChunkDelayMs    int  `json:"chunkDelayMs,omitempty"`
+
+	// the content of BodyFile, read when the definition is loaded.
+	bodyFileContent     []byte
+	bodyFileContentType string
 }
 
 type StaticMockDefinition struct {
-	Request  StaticMockDefinitionRequest  `json:"request,omitempty"`
-	Response StaticMockDefinitionResponse `json:"response,omitempty"`
+	Id          string                       `json:"id,omitempty"`
+	Inherits    string                       `json:"inherits,omitempty"`
+	Tags        []string                     `json:"tags,omitempty"`
+	ActiveFrom  *time.Time                   `json:"activeFrom,omitempty"`
+	ActiveUntil *time.Time                   `json:"activeUntil,omitempty"`
+	ActiveDays  []string                     `json:"activeDays,omitempty"`
+	ActiveHours *[2]int                      `json:"activeHours,omitempty"`
+	Request     StaticMockDefinitionRequest  `json:"request,omitempty"`
+	Response    StaticMockDefinitionResponse `json:"response,omitempty"`
+
+	// Generated marks a skeleton definition imported from the OpenAPI specification.
+	Generated bool `json:"generated,omitempty"`
+
+	// hits counts the requests the definition has served, it is shared between copies of the definition.
+	hits *atomic.Int64
 }
 
 type StaticMockService struct {
-	logger          *slog.Logger
-	wiretapService  *daemon.WiretapService
-	mockDefinitions []StaticMockDefinition
+	logger            *slog.Logger
+	wiretapService    *daemon.WiretapService
+	config            *shared.WiretapConfiguration
+	lock              sync.RWMutex
+	definitionsByFile map[string][]StaticMockDefinition
+	mockDefinitions   []StaticMockDefinition
+	mockIndex         *mockIndex
+	activeTags        []string
+	gitDir            string // the working copy of the mock definitions git repository.
+	etcd              *etcdSync
+	mockWorkers       *mockWorkers
 }
 
-func NewStaticMockService(wiretapService *daemon.WiretapService, logger *slog.Logger) *StaticMockService {
-	mockDefinitions := loadStaticMockRequestsAndResponses(wiretapService, logger)
+type ActivateTagsPayload struct {
+	Tags []string `json:"tags"`
+}
+
+func NewStaticMockService(wiretapService *daemon.WiretapService, config *shared.WiretapConfiguration,
+	logger *slog.Logger) (*StaticMockService, error) {
 
-	return &StaticMockService{
-		logger:          logger,
-		wiretapService:  wiretapService,
-		mockDefinitions: mockDefinitions,
+	sms := &StaticMockService{
+		logger:         logger,
+		wiretapService: wiretapService,
+		config:         config,
+		activeTags:     config.ActiveTags,
+	}
+	if err := sms.loadStaticMockRequestsAndResponses(); err != nil {
+		return nil, err
 	}
+	sms.mockWorkers = sms.startMockWorkers(config.MockCheckWorkers)
+	if sms.config.MockDefinitionsURL != "" {
+		sms.refreshRemoteMockDefinitions()
+	}
+	if sms.config.MockDefinitionsGitRepo != "" {
+		sms.syncGitMockDefinitions()
+	}
+	return sms, nil
+}
+
+// OnServerShutdown stops the etcd synchronization and the mock check workers, and removes the working copy of the
+// mock definitions git repository, when wiretap is stopped.
+func (sms *StaticMockService) OnServerShutdown() {
+	sms.stopEtcdSync()
+	sms.stopMockWorkers()
+	sms.removeGitWorkingCopy()
 }
 
 // getDefinitionFromJson converts a JSON object to a StaticMockDefinition
@@ -78,88 +152,217 @@ func getDefinitionFromJson(mockInterface map[string]interface{}) (StaticMockDefi
 	return mockDefinition, nil
 }
 
-// loadStaticMockRequestsAndResponses loads the static mock definitions from the JSON files
-func loadStaticMockRequestsAndResponses(wiretapService *daemon.WiretapService, logger *slog.Logger) []StaticMockDefinition {
+// isMockDefinitionFile returns true if the file name has a supported mock definition extension, or if a format
+// override has been configured, in which case any file in the mock definitions directory is a candidate.
+func (sms *StaticMockService) isMockDefinitionFile(fileName string) bool {
+	return formatFromExtension(fileName) != "" || sms.config.MockDefinitionFormat != ""
+}
+
+// mockDefinitionsDir returns the directory mock definitions are loaded from. An explicitly configured
+// mockDefinitionsDir wins, otherwise the 'mock-definitions' folder inside the static mock directory is used.
+func (sms *StaticMockService) mockDefinitionsDir() string {
+	if sms.config.MockDefinitionsDir != "" {
+		return sms.config.MockDefinitionsDir
+	}
+	if len(sms.wiretapService.StaticMockDir) == 0 {
+		return ""
+	}
+	return sms.wiretapService.StaticMockDir + MockDefinitionsPath
+}
+
+// loadMockDefinitionFile reads and parses a single mock definition file.
+func (sms *StaticMockService) loadMockDefinitionFile(filePath string) ([]StaticMockDefinition, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return sms.readMockDefinitions(filePath, data, mockDefinitionFormat(filePath, sms.config.MockDefinitionFormat))
+}
+
+// readMockDefinitions parses the mock definitions read from a file or a URL, in the given format.
+func (sms *StaticMockService) readMockDefinitions(filePath string, data []byte,
+	format string) ([]StaticMockDefinition, error) {
+
 	var staticMockDefinitions []StaticMockDefinition
+	mockDefinitions, err := parseMockDefinitions(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s mock definition: %w", format, err)
+	}
 
-	if len(wiretapService.StaticMockDir) == 0 {
-		return staticMockDefinitions
+	switch mdJson := mockDefinitions.(type) {
+	// If the content of the file is an object (key-value pairs)
+	case map[string]interface{}:
+		mockDefinition, err := getDefinitionFromJson(mdJson)
+		if err != nil {
+			return nil, err
+		}
+		staticMockDefinitions = append(staticMockDefinitions, mockDefinition)
+
+	// If the content of the file is an array (array of requests)
+	case []interface{}:
+		staticMockDefinitions = sms.convertMockDefinitions(filePath, mdJson)
+
+	default:
+		// If it's neither an object nor an array
+		return nil, fmt.Errorf("mock definition not in the right format, expected an object or an array")
+	}
+
+	staticMockDefinitions = sms.loadBodyFiles(filePath, staticMockDefinitions)
+	sms.maskMockDefinitions(filePath, staticMockDefinitions)
+	return staticMockDefinitions, nil
+}
+
+// convertMockDefinitions converts the items of a mock definition array, in order. Large arrays are split into a
+// chunk per worker when mock load workers are configured, and the chunks are converted at the same time. Items that
+// are not valid definitions are logged and left out.
+func (sms *StaticMockService) convertMockDefinitions(filePath string, items []interface{}) []StaticMockDefinition {
+	converted := make([]*StaticMockDefinition, len(items))
+	convert := func(start, end int) {
+		for i := start; i < end; i++ {
+			mdItem, ok := items[i].(map[string]interface{})
+			if !ok {
+				sms.logger.Error("Mock definition array item is not an object", "file", filePath)
+				continue
+			}
+			mockDefinition, err := getDefinitionFromJson(mdItem)
+			if err != nil {
+				sms.logger.Error(err.Error())
+				continue
+			}
+			converted[i] = &mockDefinition
+		}
+	}
+
+	workers := sms.config.MockLoadWorkers
+	if workers <= 1 || len(items) < MinConcurrentMockDefinitions {
+		convert(0, len(items))
+	} else {
+		// each worker fills its own part of the slice, so the definitions keep the order of the file.
+		var wg sync.WaitGroup
+		chunkSize := (len(items) + workers - 1) / workers
+		for start := 0; start < len(items); start += chunkSize {
+			wg.Add(1)
+			go func(start, end int) {
+				defer wg.Done()
+				convert(start, end)
+			}(start, min(start+chunkSize, len(items)))
+		}
+		wg.Wait()
+	}
+
+	definitions := make([]StaticMockDefinition, 0, len(items))
+	for _, definition := range converted {
+		if definition != nil {
+			definitions = append(definitions, *definition)
+		}
 	}
+	return definitions
+}
 
-	mocksPath := wiretapService.StaticMockDir + MockDefinitionsPath
+// loadStaticMockRequestsAndResponses loads every JSON, YAML and TOML mock definition file in the mock definitions
+// directory. Each file is kept as a separate definition set, so a change to a single file can be applied on its own.
+func (sms *StaticMockService) loadStaticMockRequestsAndResponses() error {
+	sms.lock.Lock()
+	defer sms.lock.Unlock()
+
+	sms.definitionsByFile = make(map[string][]StaticMockDefinition)
+	sms.mockDefinitions = nil
+	sms.mockIndex = nil
+
+	mocksPath := sms.mockDefinitionsDir()
+	if mocksPath == "" {
+		return nil
+	}
 
 	files, err := os.ReadDir(mocksPath)
 	if err != nil {
-		logger.Error(err.Error())
+		sms.logger.Error(err.Error())
 	}
 
 	// Loop through & read each mock definition file
 	for _, file := range files {
 		// Check if it's a regular file (not a directory)
-		if !file.IsDir() {
-			filePath := mocksPath + "/" + file.Name()
-			data, err := os.ReadFile(filePath)
-			if err != nil {
-				logger.Error("Error reading file %s: %v\n", filePath, err)
-				continue
-			}
+		if file.IsDir() {
+			continue
+		}
+		filePath := filepath.Join(mocksPath, file.Name())
+		if !sms.isMockDefinitionFile(filePath) {
+			continue
+		}
+		definitions, err := sms.loadMockDefinitionFile(filePath)
+		if err != nil {
+			sms.logger.Error("Error loading mock definition file", "file", filePath, "error", err.Error())
+			continue
+		}
+		sms.definitionsByFile[filePath] = definitions
+	}
+	return sms.mergeMockDefinitions()
+}
 
-			var mockDefinitions interface{}
+// mergeMockDefinitions rebuilds the merged definition slice from the per-file definition sets. Files are merged in
+// name order, so the matching order is the same regardless of the order files were changed in. Inheritance is
+// resolved across all files here, at load time, so matching does not need to walk parent definitions.
+// If inheritance cannot be resolved, the previously merged definitions are kept. The caller must hold the write lock.
+func (sms *StaticMockService) mergeMockDefinitions() error {
+	fileNames := make([]string, 0, len(sms.definitionsByFile))
+	for fileName := range sms.definitionsByFile {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
 
-			err = json.Unmarshal(data, &mockDefinitions)
-			if err != nil {
-				logger.Error("Error parsing json file %s: %v\n", filePath, err)
-				continue
-			}
+	var merged []StaticMockDefinition
+	for _, fileName := range fileNames {
+		merged = append(merged, sms.definitionsByFile[fileName]...)
+	}
 
-			switch mdJson := mockDefinitions.(type) {
-			// If the content of the file is a JSON object (key-value pairs)
-			case map[string]interface{}:
-				mockDefinition, err := getDefinitionFromJson(mdJson)
-				if err != nil {
-					logger.Error(err.Error())
-					continue
-				}
-				staticMockDefinitions = append(staticMockDefinitions, mockDefinition)
-
-			// If the content of the file is a JSON array (array of requests)
-			case []interface{}:
-				// You can iterate over the array
-				for _, item := range mdJson {
-					mockDefinition, err := getDefinitionFromJson(item.(map[string]interface{}))
-					if err != nil {
-						logger.Error(err.Error())
-						continue
-					}
-					staticMockDefinitions = append(staticMockDefinitions, mockDefinition)
-				}
+	resolved, err := resolveInheritance(merged)
+	if err != nil {
+		return err
+	}
 
-			default:
-				// If it's neither an object nor an array
-				logger.Error("JSON not in the right format. \nFile => %s\n JSON => \n%s", file.Name(), string(data))
-			}
+	// hit counts of definitions with an ID survive a reload.
+	previousHits := make(map[string]*atomic.Int64)
+	for _, definition := range sms.mockDefinitions {
+		if definition.Id != "" && definition.hits != nil {
+			previousHits[definition.Id] = definition.hits
+		}
+	}
+	for i := range resolved {
+		if hits, ok := previousHits[resolved[i].Id]; ok && resolved[i].Id != "" {
+			resolved[i].hits = hits
+		} else {
+			resolved[i].hits = &atomic.Int64{}
 		}
 	}
 
-	return staticMockDefinitions
+	sms.mockDefinitions = resolved
+	sms.mockIndex = buildMockIndex(resolved)
+	return nil
+}
+
+// getMockDefinitions returns the current set of merged mock definitions.
+func (sms *StaticMockService) getMockDefinitions() []StaticMockDefinition {
+	sms.lock.RLock()
+	defer sms.lock.RUnlock()
+	return sms.mockDefinitions
 }
 
-// StartWatcher Function to start a watcher on mock-definitions folder
+// StartWatcher Function to start a watcher on the mock definitions folder
 func (sms *StaticMockService) StartWatcher() {
-	if len(sms.wiretapService.StaticMockDir) == 0 {
+	pathToWatch := sms.mockDefinitionsDir()
+	if pathToWatch == "" {
 		return
 	}
 
-	pathToWatch := sms.wiretapService.StaticMockDir + MockDefinitionsPath
-
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		sms.logger.Error("Error when creating fsnotify.NewWatcher '%s'", err.Error(), err)
+		sms.logger.Error("Error when creating fsnotify.NewWatcher", "error", err.Error())
+		return
 	}
 
 	err = watcher.Add(pathToWatch)
 	if err != nil {
-		sms.logger.Error("Error adding path to watch. path => '%s'", pathToWatch, err)
+		sms.logger.Error("Error adding path to watch", "path", pathToWatch, "error", err.Error())
 	}
 
 	go func(sms *StaticMockService) {
@@ -171,14 +374,17 @@ func (sms *StaticMockService) StartWatcher() {
 				if !ok {
 					return
 				}
-				eventsToWatch := event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
-				fileTypeIsJson := strings.HasSuffix(strings.ToLower(event.Name), ".json")
-				if eventsToWatch && fileTypeIsJson {
-					sms.handleStaticMockChange()
+				if !sms.isMockDefinitionFile(event.Name) {
+					continue
+				}
+				if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+					sms.handleStaticMockRemoved(event.Name)
+				} else if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+					sms.handleStaticMockChange(event.Name)
 				}
 			case err := <-watcher.Errors:
 				if err != nil {
-					sms.logger.Error("Error while watching files => '%s'", err.Error(), err)
+					sms.logger.Error("Error while watching files", "error", err.Error())
 				}
 				watcher.Close()
 			}
@@ -186,18 +392,64 @@ func (sms *StaticMockService) StartWatcher() {
 	}(sms)
 }
 
-// handleStaticMockChange Function to handle changes to mock definitions. It loads the mock definitions in realtime
-// so that the entire wiretap service doesn't need a restart
-func (sms *StaticMockService) handleStaticMockChange() {
-	sms.logger.Info("Mock definitions modified. Rebuilding mocks...")
-	sms.mockDefinitions = loadStaticMockRequestsAndResponses(sms.wiretapService, sms.logger)
-	sms.logger.Info("New mock definitions loaded")
+// handleStaticMockChange Function to handle changes to a mock definition file. It reloads only the mock
+// definitions in the changed file in realtime, so that the entire wiretap service doesn't need a restart
+func (sms *StaticMockService) handleStaticMockChange(filePath string) {
+	sms.logger.Info("Mock definitions modified. Rebuilding mocks...", "file", filePath)
+	definitions, err := sms.loadMockDefinitionFile(filePath)
+	if err != nil {
+		// keep the previous definitions for the file, it may be mid-write.
+		sms.logger.Error("Error loading mock definition file", "file", filePath, "error", err.Error())
+		return
+	}
+
+	sms.lock.Lock()
+	previous, existed := sms.definitionsByFile[filePath]
+	sms.definitionsByFile[filePath] = definitions
+	err = sms.mergeMockDefinitions()
+	if err != nil {
+		// roll back the file so the stored sets stay consistent with the active definitions.
+		if existed {
+			sms.definitionsByFile[filePath] = previous
+		} else {
+			delete(sms.definitionsByFile, filePath)
+		}
+	}
+	sms.lock.Unlock()
+
+	if err != nil {
+		sms.logger.Error("Unable to apply mock definition change, keeping previous definitions",
+			"file", filePath, "error", err.Error())
+		return
+	}
+
+	sms.logger.Info("New mock definitions loaded", "file", filePath, "definitions", len(definitions))
+}
+
+// handleStaticMockRemoved drops the mock definitions of a file that has been deleted or renamed.
+func (sms *StaticMockService) handleStaticMockRemoved(filePath string) {
+	sms.lock.Lock()
+	defer sms.lock.Unlock()
+	previous, ok := sms.definitionsByFile[filePath]
+	if !ok {
+		return
+	}
+	delete(sms.definitionsByFile, filePath)
+	if err := sms.mergeMockDefinitions(); err != nil {
+		sms.definitionsByFile[filePath] = previous
+		sms.logger.Error("Unable to remove mock definitions, keeping previous definitions",
+			"file", filePath, "error", err.Error())
+		return
+	}
+	sms.logger.Info("Mock definitions removed", "file", filePath)
 }
 
 func (sms *StaticMockService) HandleServiceRequest(request *model.Request, core service.FabricServiceCore) {
 	switch request.RequestCommand {
 	case IncomingHttpRequest:
 		sms.HandleStaticMockRequest(request)
+	case ActivateTagsRequest:
+		sms.activateTags(request, core)
 	default:
 		core.HandleUnknownRequest(request)
 	}
@@ -0,0 +1,126 @@
+// Copyright 2023-2024 Princess Beef Heavy Industries, LLC / Dave Shanley
+// https://pb33f.io
+//
+// SPDX-License-Identifier: AGPL
+
+package staticMock
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pb33f/wiretap/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDirStaticMockService loads the mock definitions in a directory, the files are written first.
+func newDirStaticMockService(t *testing.T, dir string, files map[string]string) *StaticMockService {
+	for name, content := range files {
+		writeMockDefinitionFile(t, dir, name, content)
+	}
+	sms := &StaticMockService{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		config: &shared.WiretapConfiguration{MockDefinitionsDir: dir},
+	}
+	require.NoError(t, sms.loadStaticMockRequestsAndResponses())
+	return sms
+}
+
+func writeMockDefinitionFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// mockHits returns the hit count of each definition, by ID.
+func mockHits(sms *StaticMockService) map[string]int64 {
+	hits := make(map[string]int64)
+	for _, definition := range sms.getMockDefinitions() {
+		hits[definition.Id] = definition.HitCount()
+	}
+	return hits
+}
+
+func mockUrlPaths(sms *StaticMockService) []string {
+	var urlPaths []string
+	for _, definition := range sms.getMockDefinitions() {
+		urlPaths = append(urlPaths, definition.Request.UrlPath)
+	}
+	return urlPaths
+}
+
+func TestStaticMockService_HandleStaticMockChange(t *testing.T) {
+	dir := t.TempDir()
+	sms := newDirStaticMockService(t, dir, map[string]string{
+		"a.json": `{"id": "one", "request": {"method": "GET", "urlPath": "/one"}}`,
+		"c.json": `[{"id": "three", "request": {"method": "GET", "urlPath": "/three"}}]`,
+	})
+	sms.getMockDefinitions()[0].recordHit()
+	sms.getMockDefinitions()[0].recordHit()
+
+	// an added file is merged in name order.
+	sms.handleStaticMockChange(writeMockDefinitionFile(t, dir, "b.json",
+		`[{"id": "two", "request": {"method": "GET", "urlPath": "/two"}}]`))
+	assert.Equal(t, []string{"/one", "/two", "/three"}, mockUrlPaths(sms))
+	assert.Equal(t, map[string]int64{"one": 2, "two": 0, "three": 0}, mockHits(sms))
+
+	// a modified file replaces the definitions of that file only.
+	sms.handleStaticMockChange(writeMockDefinitionFile(t, dir, "a.json",
+		`{"id": "one", "request": {"method": "GET", "urlPath": "/uno"}}`))
+	assert.Equal(t, []string{"/uno", "/two", "/three"}, mockUrlPaths(sms))
+	assert.Equal(t, int64(2), mockHits(sms)["one"])
+
+	// the definitions of a deleted file are dropped.
+	path := filepath.Join(dir, "b.json")
+	require.NoError(t, os.Remove(path))
+	sms.handleStaticMockRemoved(path)
+	assert.Equal(t, []string{"/uno", "/three"}, mockUrlPaths(sms))
+	assert.NotContains(t, sms.definitionsByFile, path)
+
+	// files that were never loaded are ignored.
+	sms.handleStaticMockRemoved(filepath.Join(dir, "unknown.json"))
+	assert.Equal(t, []string{"/uno", "/three"}, mockUrlPaths(sms))
+}
+
+func TestStaticMockService_HandleStaticMockChange_KeepsPrevious(t *testing.T) {
+	dir := t.TempDir()
+	sms := newDirStaticMockService(t, dir, map[string]string{
+		"a.json": `{"id": "parent", "request": {"method": "GET", "urlPath": "/parent"}}`,
+		"b.json": `{"id": "child", "inherits": "parent", "request": {"urlPath": "/child"}}`,
+	})
+	sms.getMockDefinitions()[1].recordHit()
+	previous := mockUrlPaths(sms)
+	require.Equal(t, []string{"/parent", "/child"}, previous)
+
+	// a file that cannot be parsed, it may be mid-write, keeps the definitions it had.
+	sms.handleStaticMockChange(writeMockDefinitionFile(t, dir, "b.json", `{"id": "child", "request": `))
+	assert.Equal(t, previous, mockUrlPaths(sms))
+	assert.Equal(t, map[string]int64{"parent": 0, "child": 1}, mockHits(sms))
+
+	// an added file that inherits from a missing definition is not applied.
+	path := writeMockDefinitionFile(t, dir, "c.json", `{"id": "orphan", "inherits": "missing"}`)
+	sms.handleStaticMockChange(path)
+	assert.Equal(t, previous, mockUrlPaths(sms))
+	assert.NotContains(t, sms.definitionsByFile, path)
+
+	// a modified file that breaks inheritance keeps the definitions it had.
+	sms.handleStaticMockChange(writeMockDefinitionFile(t, dir, "a.json",
+		`{"id": "renamed", "request": {"method": "GET", "urlPath": "/renamed"}}`))
+	assert.Equal(t, previous, mockUrlPaths(sms))
+	assert.Equal(t, "/parent", sms.definitionsByFile[filepath.Join(dir, "a.json")][0].Request.UrlPath)
+
+	// a deleted file that others inherit from keeps its definitions.
+	path = filepath.Join(dir, "a.json")
+	require.NoError(t, os.Remove(path))
+	sms.handleStaticMockRemoved(path)
+	assert.Equal(t, previous, mockUrlPaths(sms))
+	assert.Contains(t, sms.definitionsByFile, path)
+	assert.Equal(t, map[string]int64{"parent": 0, "child": 1}, mockHits(sms))
+
+	// the child inherits the method of its parent.
+	assert.Equal(t, "GET", sms.getMockDefinitions()[1].Request.Method)
+}